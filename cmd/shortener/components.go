@@ -0,0 +1,340 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/alrund/yp-1/internal/app"
+	"github.com/alrund/yp-1/internal/app/certificate"
+	"github.com/alrund/yp-1/internal/app/config"
+	"github.com/alrund/yp-1/internal/app/flags"
+	"github.com/alrund/yp-1/internal/app/grpcserver"
+	"github.com/alrund/yp-1/internal/app/lifecycle"
+	"github.com/alrund/yp-1/internal/app/logging"
+	"github.com/alrund/yp-1/internal/app/session"
+	"github.com/alrund/yp-1/internal/app/tracing"
+	pb "github.com/alrund/yp-1/internal/proto"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+func tracingComponent(cfg *config.Config) lifecycle.Component {
+	var shutdown tracing.ShutdownFunc
+
+	return lifecycle.Component{
+		Name: "Tracing",
+		Start: func(ctx context.Context) (err error) {
+			shutdown, err = tracing.Init(ctx, cfg)
+			return err
+		},
+		Stop: func(ctx context.Context) error {
+			return shutdown(ctx)
+		},
+	}
+}
+
+func storageComponent(us *app.URLShortener) lifecycle.Component {
+	return lifecycle.Component{
+		Name: "Storage",
+		Start: func(ctx context.Context) (err error) {
+			us.Storage, err = getStorage(us.Config)
+			return err
+		},
+		Stop: func(ctx context.Context) error {
+			if closer, ok := us.Storage.(io.Closer); ok {
+				return closer.Close()
+			}
+			return nil
+		},
+	}
+}
+
+func removeQueueComponent(us *app.URLShortener) lifecycle.Component {
+	return lifecycle.Component{
+		Name: "Remove queue",
+		Start: func(ctx context.Context) error {
+			us.RemoveQueue.Start(us)
+			return nil
+		},
+		Stop: us.RemoveQueue.Stop,
+	}
+}
+
+func certificateComponent(us *app.URLShortener, certs *certificate.Manager) lifecycle.Component {
+	var cancelWatch context.CancelFunc
+
+	return lifecycle.Component{
+		Name: "Certificate watcher",
+		Start: func(ctx context.Context) error {
+			us.OnConfigReload(func(cfg *config.Config) {
+				if cfg.CertFile == "" {
+					return
+				}
+				if err := certs.SetFiles(cfg.CertFile, cfg.KeyFile); err != nil {
+					logging.Errorf("Certificate reload failed, keeping the current one: %v", err)
+				}
+			})
+
+			var watchCtx context.Context
+			watchCtx, cancelWatch = context.WithCancel(context.Background())
+			go certs.Watch(watchCtx, certWatchInterval)
+
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			cancelWatch()
+			return nil
+		},
+	}
+}
+
+// watchComponent runs the watch in the background till the component stops.
+func watchComponent(name string, watch func(ctx context.Context)) lifecycle.Component {
+	var (
+		cancelWatch context.CancelFunc
+		done        chan struct{}
+	)
+
+	return lifecycle.Component{
+		Name: name,
+		Start: func(ctx context.Context) error {
+			var watchCtx context.Context
+			watchCtx, cancelWatch = context.WithCancel(context.Background())
+			done = make(chan struct{})
+			go func() {
+				defer close(done)
+				watch(watchCtx)
+			}()
+
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			cancelWatch()
+			select {
+			case <-done:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		},
+	}
+}
+
+func linkCheckComponent(us *app.URLShortener, lc *app.LinkChecker, poll time.Duration) lifecycle.Component {
+	return watchComponent("Link checker", func(ctx context.Context) {
+		us.WatchLinks(ctx, lc, poll)
+	})
+}
+
+func rateLimitComponent(us *app.URLShortener) lifecycle.Component {
+	return watchComponent("Rate limit pruning", func(ctx context.Context) {
+		us.WatchRateLimits(ctx, rateLimitPruneInterval)
+	})
+}
+
+// clickFlushComponent flushes the clicks periodically, the storage flushes the rest on its stop.
+func clickFlushComponent(us *app.URLShortener) lifecycle.Component {
+	return watchComponent("Click flush", func(ctx context.Context) {
+		us.WatchClicks(ctx, clickFlushInterval)
+	})
+}
+
+func httpComponent(us *app.URLShortener, certs *certificate.Manager, sessions session.Codec) lifecycle.Component {
+	cfg := us.Config
+	server := &http.Server{
+		Addr:              cfg.ServerAddress,
+		Handler:           getRouter(us, cfg, sessions),
+		ReadHeaderTimeout: 1 * time.Second,
+	}
+	if certs != nil {
+		server.TLSConfig = certs.TLSConfig()
+	}
+
+	var listener net.Listener
+
+	return lifecycle.Component{
+		Name: "HTTP server",
+		Start: func(ctx context.Context) (err error) {
+			logging.Infof("Starting HTTP server %s", cfg.ServerAddress)
+			listener, err = net.Listen("tcp", cfg.ServerAddress)
+			return err
+		},
+		Run: func() error {
+			var err error
+			if certs != nil {
+				err = server.ServeTLS(listener, "", "")
+			} else {
+				err = server.Serve(listener)
+			}
+			if errors.Is(err, http.ErrServerClosed) {
+				return nil
+			}
+			return err
+		},
+		Stop: server.Shutdown,
+	}
+}
+
+func adminComponent(us *app.URLShortener) lifecycle.Component {
+	cfg := us.Config
+	server := &http.Server{
+		Addr:              cfg.AdminServerAddress,
+		Handler:           getAdminRouter(us, cfg),
+		ReadHeaderTimeout: 1 * time.Second,
+	}
+
+	var listener net.Listener
+
+	return lifecycle.Component{
+		Name: "Admin HTTP server",
+		Start: func(ctx context.Context) (err error) {
+			logging.Infof("Starting admin HTTP server %s", cfg.AdminServerAddress)
+			listener, err = net.Listen("tcp", cfg.AdminServerAddress)
+			return err
+		},
+		Run: func() error {
+			err := server.Serve(listener)
+			if errors.Is(err, http.ErrServerClosed) {
+				return nil
+			}
+			return err
+		},
+		Stop: server.Shutdown,
+	}
+}
+
+func grpcComponent(us *app.URLShortener, certs *certificate.Manager, sessions session.Codec) lifecycle.Component {
+	var (
+		serverGRPC  *grpc.Server
+		listener    net.Listener
+		cancelWatch context.CancelFunc
+		cfg         = us.Config
+	)
+
+	return lifecycle.Component{
+		Name: "GRPC server",
+		Start: func(ctx context.Context) (err error) {
+			serverGRPC, err = newGRPCServer(us, certs, sessions)
+			if err != nil {
+				return err
+			}
+
+			healthServer := grpchealth.NewServer()
+			healthpb.RegisterHealthServer(serverGRPC, healthServer)
+			reflection.Register(serverGRPC)
+
+			var watchCtx context.Context
+			watchCtx, cancelWatch = context.WithCancel(context.Background())
+			go grpcserver.WatchHealth(watchCtx, us, healthServer, healthWatchInterval)
+
+			logging.Infof("Starting GRPC server %s", cfg.GrpcServerAddress)
+			listener, err = net.Listen("tcp", cfg.GrpcServerAddress)
+			return err
+		},
+		Run: func() error {
+			return serverGRPC.Serve(listener)
+		},
+		Stop: func(ctx context.Context) error {
+			cancelWatch()
+
+			stopped := make(chan struct{})
+			go func() {
+				serverGRPC.GracefulStop()
+				close(stopped)
+			}()
+
+			select {
+			case <-stopped:
+				return nil
+			case <-ctx.Done():
+				serverGRPC.Stop()
+				return ctx.Err()
+			}
+		},
+	}
+}
+
+func newGRPCServer(us *app.URLShortener, certs *certificate.Manager, sessions session.Codec) (*grpc.Server, error) {
+	cfg := us.Config
+
+	services := make(map[string][]string, len(cfg.GrpcServices))
+	for name, permissions := range cfg.GrpcServices {
+		services[name] = strings.Fields(permissions)
+	}
+
+	opts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(
+			otelgrpc.UnaryServerInterceptor(),
+			grpcserver.ServiceIdentityInterceptor(services),
+			grpcserver.AuthInterceptor(sessions, us),
+			grpcserver.RateLimitInterceptor(us.RateLimiter, grpcserver.MethodClasses),
+			grpcserver.AuditInterceptor(),
+		),
+		grpc.ChainStreamInterceptor(
+			otelgrpc.StreamServerInterceptor(),
+			grpcserver.AuthStreamInterceptor(sessions, us),
+		),
+	}
+
+	if certs != nil {
+		tlsConfig := certs.TLSConfig()
+		if cfg.GrpcClientCAFile != "" {
+			clientCAs, err := certificate.LoadCertPool(cfg.GrpcClientCAFile)
+			if err != nil {
+				return nil, err
+			}
+			tlsConfig.ClientCAs = clientCAs
+			tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+			if cfg.GrpcRequireClientCert {
+				tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+			}
+		}
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+
+	serverGRPC := grpc.NewServer(opts...)
+	pb.RegisterAppServer(serverGRPC, grpcserver.New(us))
+
+	return serverGRPC, nil
+}
+
+func reloadComponent(us *app.URLShortener, f *flags.Flags) lifecycle.Component {
+	sighup := make(chan os.Signal, 1)
+	done := make(chan struct{})
+
+	return lifecycle.Component{
+		Name: "Config reloader",
+		Start: func(ctx context.Context) error {
+			signal.Notify(sighup, syscall.SIGHUP)
+			return nil
+		},
+		Run: func() error {
+			for {
+				select {
+				case <-done:
+					return nil
+				case <-sighup:
+					reloadConfig(us, f)
+				}
+			}
+		},
+		Stop: func(ctx context.Context) error {
+			signal.Stop(sighup)
+			close(done)
+			return nil
+		},
+	}
+}
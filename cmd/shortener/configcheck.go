@@ -0,0 +1,61 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/alrund/yp-1/internal/app/config"
+	"github.com/alrund/yp-1/internal/app/flags"
+)
+
+var errUnknownCommand = errors.New("unknown command, usage: shortener config check [flags]")
+
+// runCommand runs the subcommand given after the flags.
+func runCommand(f *flags.Flags) error {
+	if len(f.Args) < 2 || f.Args[0] != "config" || f.Args[1] != "check" {
+		return errUnknownCommand
+	}
+
+	// The flags may be given both before and after the subcommand.
+	if rest := f.Args[2:]; len(rest) > 0 {
+		leading := os.Args[1 : len(os.Args)-len(f.Args)]
+		f = flags.Parse(append(append([]string{}, leading...), rest...))
+		if len(f.Args) > 0 {
+			return errUnknownCommand
+		}
+	}
+
+	return configCheck(os.Stdout, f)
+}
+
+// configCheck prints the effective config with the source of every value and validates it.
+func configCheck(w io.Writer, f *flags.Flags) error {
+	cfg, err := config.Load(f)
+	if err != nil {
+		return err
+	}
+
+	settings, err := config.Describe(cfg, f)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SETTING\tVALUE\tSOURCE")
+	for _, s := range settings {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", s.Name, s.Value, s.Source)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(w, "Config is valid")
+	return nil
+}
@@ -2,31 +2,45 @@ package main
 
 import (
 	"context"
+	"expvar"
 	"fmt"
 	"log"
 	"net"
 	"net/http"
 	"net/http/pprof"
 	"os/signal"
-	"sync"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/alrund/yp-1/internal/app"
+	"github.com/alrund/yp-1/internal/app/certificate"
 	"github.com/alrund/yp-1/internal/app/config"
 	"github.com/alrund/yp-1/internal/app/encryption"
-	"github.com/alrund/yp-1/internal/app/grpcserver"
+	"github.com/alrund/yp-1/internal/app/flags"
 	"github.com/alrund/yp-1/internal/app/handler"
+	"github.com/alrund/yp-1/internal/app/lifecycle"
+	"github.com/alrund/yp-1/internal/app/linkcheck"
+	"github.com/alrund/yp-1/internal/app/logging"
 	"github.com/alrund/yp-1/internal/app/middleware"
+	"github.com/alrund/yp-1/internal/app/ratelimit"
+	"github.com/alrund/yp-1/internal/app/screening"
+	"github.com/alrund/yp-1/internal/app/session"
 	"github.com/alrund/yp-1/internal/app/storage"
 	"github.com/alrund/yp-1/internal/app/token/generator"
-	pb "github.com/alrund/yp-1/internal/proto"
+	"github.com/alrund/yp-1/internal/app/tracing"
 	"github.com/gorilla/mux"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gorilla/mux/otelmux"
 )
 
-const defaultBuildValue string = "N/A"
+const (
+	defaultBuildValue      string = "N/A"
+	healthWatchInterval           = 5 * time.Second
+	certWatchInterval             = 10 * time.Second
+	linkCheckPollInterval         = time.Minute
+	rateLimitPruneInterval        = 10 * time.Minute
+	clickFlushInterval            = 10 * time.Second
+)
 
 var (
 	buildVersion = defaultBuildValue
@@ -35,37 +49,100 @@ var (
 )
 
 func main() {
+	f := flags.NewFlags()
+	if len(f.Args) > 0 {
+		if err := runCommand(f); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	printBuildInfo()
 
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT, syscall.SIGQUIT)
 	defer stop()
 
-	cfg := config.GetConfig()
+	cfg, err := config.Load(f)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := cfg.Validate(); err != nil {
+		log.Fatal(err)
+	}
+
+	certs, err := getCertificates(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	sessions, err := getSessions(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	us := &app.URLShortener{
 		Config:         cfg,
-		Storage:        getStorage(cfg),
 		TokenGenerator: generator.NewSimple(),
+		RemoveQueue:    app.NewRemoveQueue(cfg.RemoveQueueSize, cfg.RemoveWorkers),
+		Sessions:       sessions,
+	}
+	setLogLevel(cfg, us)
+	us.RateLimiter, err = getRateLimiter(cfg, us)
+	if err != nil {
+		log.Fatal(err)
+	}
+	us.Screener, err = getScreener(cfg, us)
+	if err != nil {
+		log.Fatal(err)
 	}
 
-	var wg sync.WaitGroup
-	wg.Add(2)
-
-	go func() {
-		defer wg.Done()
-		if err := run(ctx, us); err != nil {
-			log.Fatalf("HTTP server ListenAndServe: %v", err)
+	lm := lifecycle.NewManager(cfg.ShutdownTimeout.Duration())
+	lm.Add(tracingComponent(cfg))
+	lm.Add(storageComponent(us))
+	lm.Add(clickFlushComponent(us))
+	lm.Add(removeQueueComponent(us))
+	lm.Add(rateLimitComponent(us))
+	if cfg.LinkCheckInterval > 0 {
+		poll := linkCheckPollInterval
+		if interval := cfg.LinkCheckInterval.Duration(); interval < poll {
+			poll = interval
 		}
-	}()
+		lm.Add(linkCheckComponent(us, getLinkChecker(cfg, us), poll))
+	}
+	if cfg.AdminServerAddress != "" {
+		lm.Add(adminComponent(us))
+	}
+	if certs != nil {
+		lm.Add(certificateComponent(us, certs))
+	}
+	lm.Add(httpComponent(us, certs, sessions))
+	lm.Add(grpcComponent(us, certs, sessions))
+	lm.Add(reloadComponent(us, f))
 
-	go func() {
-		defer wg.Done()
-		if err := runGRPC(ctx, us); err != nil {
-			log.Fatalf("GRPC server ListenAndServe: %v", err)
-		}
-	}()
+	if err := lm.Run(ctx); err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println("Service Shutdown gracefully")
+}
 
-	wg.Wait()
+func reloadConfig(us *app.URLShortener, f *flags.Flags) {
+	next, err := config.Load(f)
+	if err != nil {
+		logging.Errorf("Config reload failed, keeping the current config: %v", err)
+		return
+	}
+
+	restartRequired, err := us.ReloadConfig(next)
+	if err != nil {
+		logging.Errorf("Config reload rejected, keeping the current config: %v", err)
+		return
+	}
+
+	logging.Infof("Config reloaded")
+	if len(restartRequired) > 0 {
+		logging.Errorf("Config settings changed but require a restart: %s", strings.Join(restartRequired, ", "))
+	}
 }
 
 func printBuildInfo() {
@@ -74,129 +151,204 @@ func printBuildInfo() {
 	fmt.Printf("Build commit: %s\n", buildCommit)
 }
 
-func run(ctx context.Context, us *app.URLShortener) error {
-	httpShutdownCh := make(chan struct{})
+func getStorage(cfg *config.Config) (app.Storage, error) {
+	if cfg.DatabaseDsn != "" {
+		return storage.NewDB(cfg.DatabaseDsn)
+	}
+	if cfg.FileStoragePath != "" {
+		return storage.NewFile(cfg.FileStoragePath)
+	}
 
-	cfg := us.Config
+	return storage.NewMap(), nil
+}
 
-	server := &http.Server{
-		Addr:              cfg.ServerAddress,
-		Handler:           getRouter(us, cfg),
-		ReadHeaderTimeout: 1 * time.Second,
+// getCertificates returns the certificates shared by the HTTP and GRPC servers, nil without HTTPS.
+func getCertificates(cfg *config.Config) (*certificate.Manager, error) {
+	if !cfg.EnableHTTPS {
+		return nil, nil
+	}
+	if cfg.CertFile != "" {
+		return certificate.NewManager(cfg.CertFile, cfg.KeyFile)
 	}
 
-	go func() {
-		<-ctx.Done()
-
-		if err := server.Shutdown(context.Background()); err != nil {
-			log.Printf("HTTP server Shutdown: %v", err)
+	logging.Infof("Using a self-signed certificate, for development only")
+	hosts := []string{"localhost", "127.0.0.1", "::1"}
+	for _, address := range []string{cfg.ServerAddress, cfg.GrpcServerAddress} {
+		if host, _, err := net.SplitHostPort(address); err == nil && host != "" {
+			hosts = append(hosts, host)
 		}
+	}
+	return certificate.NewSelfSigned(hosts...)
+}
 
-		close(httpShutdownCh)
-	}()
+// getSessions returns the codec of the user session tokens selected by the auth mode.
+func getSessions(cfg *config.Config) (session.Codec, error) {
+	if cfg.AuthMode == config.AuthModeJWT {
+		jwt, err := session.LoadJWT(cfg.JWTAlgorithm, cfg.JWTKeyFile, cfg.JWTTTL.Duration())
+		if err != nil {
+			return nil, err
+		}
+		jwt.Issuer = cfg.JWTIssuer
+		jwt.Scopes = cfg.JWTScopes
+		return jwt, nil
+	}
 
-	log.Println("Starting HTTP server", cfg.ServerAddress)
+	enc := encryption.NewEncryption(cfg.CipherPass, cfg.CipherOldPasses...)
+	enc.AcceptLegacy = cfg.CipherAcceptLegacy
+	return session.NewEncrypted(enc), nil
+}
 
-	var err error
-	if cfg.EnableHTTPS && cfg.CertFile != "" && cfg.KeyFile != "" {
-		err = server.ListenAndServeTLS(cfg.CertFile, cfg.KeyFile)
-	} else {
-		err = server.ListenAndServe()
+// getRateLimiter returns the limiter keeping its state in memory or in the URL storage.
+// The limits follow the config reloads.
+func getRateLimiter(cfg *config.Config, us *app.URLShortener) (*ratelimit.Limiter, error) {
+	limits, err := ratelimit.ParseLimits(cfg.RateLimits)
+	if err != nil {
+		return nil, err
 	}
 
-	if err != http.ErrServerClosed {
-		return err
+	// The URL storage is opened on start, the limiter reaches it through us.
+	var store ratelimit.Store = us
+	if cfg.RateLimitStore == config.RateLimitStoreMemory {
+		store = storage.NewMap()
 	}
 
-	<-httpShutdownCh
-	fmt.Println("HTTP Server Shutdown gracefully")
+	limiter := ratelimit.New(store, limits, cfg.DailyCreateQuota)
+	us.OnConfigReload(func(cfg *config.Config) {
+		limits, err := ratelimit.ParseLimits(cfg.RateLimits)
+		if err != nil {
+			logging.Errorf("Rate limits reload failed, keeping the current ones: %v", err)
+			return
+		}
+		limiter.SetLimits(limits, cfg.DailyCreateQuota)
+	})
 
-	return nil
+	return limiter, nil
 }
 
-func runGRPC(ctx context.Context, us *app.URLShortener) error {
-	var (
-		err        error
-		serverGRPC *grpc.Server
-		cfg        = us.Config
-	)
-
-	enc := encryption.NewEncryption(cfg.CipherPass)
-
-	if cfg.EnableHTTPS && cfg.CertFile != "" && cfg.KeyFile != "" {
-		creds, err := credentials.NewServerTLSFromFile(cfg.CertFile, cfg.KeyFile)
+// setLogLevel applies the log level of the config, again on each config reload.
+func setLogLevel(cfg *config.Config, us *app.URLShortener) {
+	apply := func(cfg *config.Config) {
+		level, err := logging.ParseLevel(cfg.LogLevel)
 		if err != nil {
-			return err
+			logging.Errorf("Log level: %v", err)
+			return
 		}
-		serverGRPC = grpc.NewServer(grpc.Creds(creds), grpc.UnaryInterceptor(grpcserver.AuthInterceptor(enc)))
-	} else {
-		serverGRPC = grpc.NewServer(grpc.UnaryInterceptor(grpcserver.AuthInterceptor(enc)))
+		logging.SetLevel(level)
 	}
 
-	pb.RegisterAppServer(serverGRPC, grpcserver.New(us))
-
-	grpcShutdownCh := make(chan struct{})
-
-	go func() {
-		<-ctx.Done()
-
-		serverGRPC.GracefulStop()
-
-		close(grpcShutdownCh)
-	}()
-
-	log.Println("Starting GRPC server", cfg.GrpcServerAddress)
-
-	listen, err := net.Listen("tcp", cfg.GrpcServerAddress)
-	if err != nil {
-		return err
-	}
+	apply(cfg)
+	us.OnConfigReload(apply)
+}
 
-	err = serverGRPC.Serve(listen)
+// getScreener returns the screening of the URLs with the blocklist files.
+// The files are read again on each config reload.
+func getScreener(cfg *config.Config, us *app.URLShortener) (*screening.Screener, error) {
+	blocklist, err := screening.LoadBlocklist(cfg.BlocklistDomainsFile, cfg.BlocklistPatternsFile)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	<-grpcShutdownCh
-	fmt.Println("GRPC server Shutdown gracefully")
+	us.OnConfigReload(func(cfg *config.Config) {
+		if err := blocklist.Load(cfg.BlocklistDomainsFile, cfg.BlocklistPatternsFile); err != nil {
+			logging.Errorf("Blocklist reload failed, keeping the current one: %v", err)
+		}
+	})
 
-	return nil
+	return &screening.Screener{Blocklist: blocklist}, nil
 }
 
-func getStorage(cfg *config.Config) app.Storage {
-	var (
-		err  error
-		strg app.Storage = storage.NewMap()
+// getLinkChecker returns the settings of the background checks of the link destinations.
+// The destinations and their redirects blocked by the screener are not requested.
+func getLinkChecker(cfg *config.Config, us *app.URLShortener) *app.LinkChecker {
+	checker := linkcheck.NewChecker(
+		cfg.LinkCheckTimeout.Duration(),
+		cfg.LinkCheckConcurrency,
+		cfg.LinkCheckHostDelay.Duration(),
 	)
+	checker.Blocked = us.Screener.Blocked
 
-	if cfg.FileStoragePath != "" {
-		strg, err = storage.NewFile(cfg.FileStoragePath)
-		if err != nil {
-			log.Fatal(err)
-		}
+	return &app.LinkChecker{
+		Checker:   checker,
+		Webhook:   linkcheck.NewWebhook(cfg.LinkCheckTimeout.Duration()),
+		Interval:  cfg.LinkCheckInterval.Duration(),
+		BatchSize: cfg.LinkCheckBatchSize,
 	}
-	if cfg.DatabaseDsn != "" {
-		strg, err = storage.NewDB(cfg.DatabaseDsn)
-		if err != nil {
-			log.Fatal(err)
+}
+
+func getRouter(us *app.URLShortener, cfg *config.Config, sessions session.Codec) *mux.Router {
+	r := mux.NewRouter()
+
+	hc := handler.NewCollection(us)
+	limit := func(class ratelimit.Class, h http.HandlerFunc) http.HandlerFunc {
+		return middleware.RateLimit(us.RateLimiter, class)(h).ServeHTTP
+	}
+	// The scoped session tokens reach only the routes of their scopes.
+	scoped := func(scope string) func(h http.HandlerFunc) http.HandlerFunc {
+		return func(h http.HandlerFunc) http.HandlerFunc {
+			return middleware.RequireScope(scope)(h).ServeHTTP
 		}
 	}
+	urls, keys, workspaces := scoped(session.ScopeURLs), scoped(session.ScopeKeys), scoped(session.ScopeWorkspaces)
+
+	r.Handle("/", urls(limit(ratelimit.ClassCreate, hc.Add()))).Methods(http.MethodPost)
+	r.Handle("/api/shorten", urls(limit(ratelimit.ClassCreate, hc.AddJSON()))).Methods(http.MethodPost)
+	r.Handle("/api/shorten/batch", urls(limit(ratelimit.ClassCreate, hc.AddBatchJSON()))).Methods(http.MethodPost)
+	r.Handle("/api/lookup", limit(ratelimit.ClassRedirect, hc.LookupURL())).Methods(http.MethodGet)
+	r.Handle("/api/resolve/batch", limit(ratelimit.ClassRedirect, hc.ResolveBatch())).Methods(http.MethodPost)
+	r.HandleFunc("/ping", hc.Ping()).Methods(http.MethodGet)
+	r.Handle("/{id}", limit(ratelimit.ClassRedirect, hc.Get())).Methods(http.MethodGet)
+	r.Handle("/api/user/urls", urls(limit(ratelimit.ClassList, hc.GetUserURLs()))).Methods(http.MethodGet)
+	r.Handle("/api/user/urls", urls(limit(ratelimit.ClassDelete, hc.DeleteURLs()))).Methods(http.MethodDelete)
+	r.Handle("/api/user/urls/search", urls(limit(ratelimit.ClassList, hc.SearchURLs()))).Methods(http.MethodGet)
+	r.Handle("/api/user/urls/{token}", urls(hc.UpdateURL())).Methods(http.MethodPatch)
+	r.Handle("/api/user/tags", urls(hc.GetTags())).Methods(http.MethodGet)
+	r.Handle("/api/user/tags/rename", urls(hc.RenameTag())).Methods(http.MethodPost)
+	r.Handle("/api/user/webhook", urls(hc.GetLinkWebhook())).Methods(http.MethodGet)
+	r.Handle("/api/user/webhook", urls(hc.SetLinkWebhook())).Methods(http.MethodPut)
+	r.Handle("/api/user/webhook", urls(hc.RemoveLinkWebhook())).Methods(http.MethodDelete)
+	r.HandleFunc("/api/user/register", hc.Register()).Methods(http.MethodPost)
+	r.HandleFunc("/api/user/login", hc.Login()).Methods(http.MethodPost)
+	r.Handle("/api/user/keys", keys(hc.CreateAPIKey())).Methods(http.MethodPost)
+	r.Handle("/api/user/keys", keys(hc.GetAPIKeys())).Methods(http.MethodGet)
+	r.Handle("/api/user/keys/{id}", keys(hc.RevokeAPIKey())).Methods(http.MethodDelete)
+	r.Handle("/api/workspaces", workspaces(hc.CreateWorkspace())).Methods(http.MethodPost)
+	r.Handle("/api/workspaces", workspaces(hc.GetWorkspaces())).Methods(http.MethodGet)
+	r.Handle("/api/workspaces/{id}/members", workspaces(hc.GetWorkspaceMembers())).Methods(http.MethodGet)
+	r.Handle("/api/workspaces/{id}/members/{user_id}", workspaces(hc.SetWorkspaceMember())).Methods(http.MethodPut)
+	r.Handle("/api/workspaces/{id}/members/{user_id}", workspaces(hc.RemoveWorkspaceMember())).Methods(http.MethodDelete)
+	r.HandleFunc("/api/internal/stats", hc.Stats()).Methods(http.MethodGet)
+	r.HandleFunc("/api/internal/audit", hc.Audit()).Methods(http.MethodGet)
+
+	r.Use(otelmux.Middleware(tracing.ServiceName))
+	r.Use(middleware.Compress)
+	r.Use(middleware.Decompress)
+	r.Use(middleware.Auth(sessions, us))
+	r.Use(middleware.Audit)
 
-	return strg
+	return r
 }
 
-func getRouter(us *app.URLShortener, cfg *config.Config) *mux.Router {
+func getAdminRouter(us *app.URLShortener, cfg *config.Config) *mux.Router {
+	var trustedSubnet func() *net.IPNet
+	if cfg.AdminRestrictToSubnet {
+		trustedSubnet = func() *net.IPNet {
+			ipnet, err := us.GetTrustedSubnet()
+			if err != nil {
+				logging.Errorf("Admin trusted subnet: %v", err)
+			}
+			return ipnet
+		}
+	}
+
 	r := mux.NewRouter()
 
 	hc := handler.NewCollection(us)
-	r.HandleFunc("/", hc.Add()).Methods(http.MethodPost)
-	r.HandleFunc("/api/shorten", hc.AddJSON()).Methods(http.MethodPost)
-	r.HandleFunc("/api/shorten/batch", hc.AddBatchJSON()).Methods(http.MethodPost)
 	r.HandleFunc("/ping", hc.Ping()).Methods(http.MethodGet)
-	r.HandleFunc("/{id}", hc.Get()).Methods(http.MethodGet)
-	r.HandleFunc("/api/user/urls", hc.GetUserURLs()).Methods(http.MethodGet)
-	r.HandleFunc("/api/user/urls", hc.DeleteURLs()).Methods(http.MethodDelete)
-	r.HandleFunc("/api/internal/stats", hc.Stats()).Methods(http.MethodGet)
+	r.HandleFunc("/healthz", hc.Healthz()).Methods(http.MethodGet)
+	r.HandleFunc("/readyz", hc.Readyz()).Methods(http.MethodGet)
+	r.HandleFunc("/stats", hc.AdminStats()).Methods(http.MethodGet)
+	r.HandleFunc("/audit", hc.AdminAudit()).Methods(http.MethodGet)
+	r.Handle("/metrics", expvar.Handler()).Methods(http.MethodGet)
 
 	subRouter := r.PathPrefix("/debug/pprof").Subrouter()
 	subRouter.HandleFunc("/", pprof.Index)
@@ -206,9 +358,7 @@ func getRouter(us *app.URLShortener, cfg *config.Config) *mux.Router {
 	subRouter.HandleFunc("/trace", pprof.Trace)
 	subRouter.HandleFunc("/{name}", pprof.Index)
 
-	r.Use(middleware.Compress)
-	r.Use(middleware.Decompress)
-	r.Use(middleware.Auth(encryption.NewEncryption(cfg.CipherPass)))
+	r.Use(middleware.AdminAccess(cfg.AdminToken, trustedSubnet))
 
 	return r
 }
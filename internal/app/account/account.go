@@ -0,0 +1,62 @@
+package account
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	MinPasswordLength = 8
+	MaxPasswordLength = 72 // bcrypt ignores the rest
+	MaxLoginLength    = 255
+)
+
+var (
+	ErrInvalidCredentials = errors.New("invalid login or password")
+	ErrEmptyLogin         = errors.New("login is empty")
+	ErrLongLogin          = errors.New("login is too long")
+	ErrShortPassword      = errors.New("password is too short")
+	ErrLongPassword       = errors.New("password is too long")
+)
+
+// Account a registered user, its ID is used as the user ID of the links.
+type Account struct {
+	ID           string
+	Login        string
+	PasswordHash string
+	Created      time.Time
+}
+
+// New creates an account with the bcrypt hash of the password.
+func New(login, password string) (*Account, error) {
+	switch {
+	case login == "":
+		return nil, ErrEmptyLogin
+	case len(login) > MaxLoginLength:
+		return nil, ErrLongLogin
+	case len(password) < MinPasswordLength:
+		return nil, ErrShortPassword
+	case len(password) > MaxPasswordLength:
+		return nil, ErrLongPassword
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Account{
+		ID:           uuid.NewString(),
+		Login:        login,
+		PasswordHash: string(hash),
+		Created:      time.Now(),
+	}, nil
+}
+
+// CheckPassword reports whether the password matches the account.
+func (a *Account) CheckPassword(password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(a.PasswordHash), []byte(password)) == nil
+}
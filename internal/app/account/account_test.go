@@ -0,0 +1,63 @@
+package account
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name     string
+		login    string
+		password string
+		wantErr  error
+	}{
+		{
+			name:     "success",
+			login:    "alice",
+			password: "correct horse",
+		},
+		{
+			name:     "empty login",
+			password: "correct horse",
+			wantErr:  ErrEmptyLogin,
+		},
+		{
+			name:     "long login",
+			login:    strings.Repeat("a", MaxLoginLength+1),
+			password: "correct horse",
+			wantErr:  ErrLongLogin,
+		},
+		{
+			name:     "short password",
+			login:    "alice",
+			password: "horse",
+			wantErr:  ErrShortPassword,
+		},
+		{
+			name:     "long password",
+			login:    "alice",
+			password: strings.Repeat("a", MaxPasswordLength+1),
+			wantErr:  ErrLongPassword,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := New(tt.login, tt.password)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.NotEmpty(t, a.ID)
+			assert.Equal(t, tt.login, a.Login)
+			assert.NotContains(t, a.PasswordHash, tt.password)
+			assert.True(t, a.CheckPassword(tt.password))
+			assert.False(t, a.CheckPassword(tt.password+"!"))
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package app
+
+import (
+	"context"
+	"errors"
+
+	"github.com/alrund/yp-1/internal/app/account"
+	"github.com/alrund/yp-1/internal/app/storage"
+)
+
+// Register creates an account and merges the links of the current anonymous user into it.
+func (us *URLShortener) Register(ctx context.Context, userID, login, password string) (_ *account.Account, err error) {
+	ctx, span := startSpan(ctx, "URLShortener.Register")
+	defer func() { endSpan(span, err) }()
+
+	a, err := account.New(login, password)
+	if err != nil {
+		return nil, err
+	}
+
+	s := us.storage(ctx)
+	err = s.SetAccount(a)
+	if err != nil {
+		return nil, err
+	}
+
+	err = mergeAnonymous(s, userID, a.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// Login checks the credentials and merges the links of the current anonymous user into the account.
+func (us *URLShortener) Login(ctx context.Context, userID, login, password string) (_ *account.Account, err error) {
+	ctx, span := startSpan(ctx, "URLShortener.Login")
+	defer func() { endSpan(span, err) }()
+
+	s := us.storage(ctx)
+	a, err := s.GetAccountByLogin(login)
+	if err != nil {
+		if errors.Is(err, storage.ErrAccountNotFound) {
+			return nil, account.ErrInvalidCredentials
+		}
+		return nil, err
+	}
+
+	if !a.CheckPassword(password) {
+		return nil, account.ErrInvalidCredentials
+	}
+
+	err = mergeAnonymous(s, userID, a.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// mergeAnonymous moves the links, the API keys and the workspace memberships of the anonymous user to the account.
+// The links of another account are never moved.
+func mergeAnonymous(s Storage, userID, accountID string) error {
+	if userID == "" || userID == accountID {
+		return nil
+	}
+
+	_, err := s.GetAccountByID(userID)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, storage.ErrAccountNotFound) {
+		return err
+	}
+
+	return s.ReassignUser(userID, accountID)
+}
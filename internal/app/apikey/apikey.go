@@ -0,0 +1,64 @@
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// Prefix starts every API key, so the keys are easy to tell from the encrypted user IDs.
+	Prefix = "sk_"
+
+	secretSize = 32
+	hintSize   = len(Prefix) + 6
+)
+
+// ErrInvalid the key is unknown or revoked.
+var ErrInvalid = errors.New("invalid api key")
+
+// Key an API key of a user, only the hash of the secret is stored.
+type Key struct {
+	ID      string
+	UserID  string
+	Name    string
+	Hash    string
+	Hint    string
+	Created time.Time
+	Revoked bool
+}
+
+// New creates a key for the user and returns it with the secret, which is shown only once.
+func New(userID, name string) (*Key, string, error) {
+	b := make([]byte, secretSize)
+	if _, err := rand.Read(b); err != nil {
+		return nil, "", err
+	}
+	secret := Prefix + base64.RawURLEncoding.EncodeToString(b)
+
+	return &Key{
+		ID:      uuid.NewString(),
+		UserID:  userID,
+		Name:    name,
+		Hash:    Hash(secret),
+		Hint:    secret[:hintSize],
+		Created: time.Now(),
+	}, secret, nil
+}
+
+// Hash returns the stored form of the secret.
+func Hash(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// IsKey reports whether the value looks like an API key.
+func IsKey(value string) bool {
+	return strings.HasPrefix(value, Prefix)
+}
@@ -0,0 +1,31 @@
+package apikey
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	key, secret, err := New("XXX-YYY-ZZZ", "ci")
+	require.NoError(t, err)
+
+	assert.True(t, IsKey(secret))
+	assert.Equal(t, "XXX-YYY-ZZZ", key.UserID)
+	assert.Equal(t, "ci", key.Name)
+	assert.NotEmpty(t, key.ID)
+	assert.Equal(t, Hash(secret), key.Hash)
+	assert.NotContains(t, key.Hash, secret)
+	assert.Equal(t, secret[:hintSize], key.Hint)
+	assert.False(t, key.Revoked)
+
+	_, other, err := New("XXX-YYY-ZZZ", "ci")
+	require.NoError(t, err)
+	assert.NotEqual(t, secret, other)
+}
+
+func TestIsKey(t *testing.T) {
+	assert.True(t, IsKey("sk_abc"))
+	assert.False(t, IsKey("v1.abcdef.0123"))
+}
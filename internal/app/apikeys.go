@@ -0,0 +1,81 @@
+package app
+
+import (
+	"context"
+	"errors"
+
+	"github.com/alrund/yp-1/internal/app/apikey"
+	"github.com/alrund/yp-1/internal/app/storage"
+)
+
+const maxAPIKeyNameLength = 255
+
+var (
+	ErrEmptyAPIKeyName = errors.New("api key name is empty")
+	ErrLongAPIKeyName  = errors.New("api key name is too long")
+)
+
+// CreateAPIKey creates a named API key of the user.
+// The returned secret is not stored and can not be shown again.
+func (us *URLShortener) CreateAPIKey(ctx context.Context, userID, name string) (_ *apikey.Key, _ string, err error) {
+	ctx, span := startSpan(ctx, "URLShortener.CreateAPIKey")
+	defer func() { endSpan(span, err) }()
+
+	if name == "" {
+		return nil, "", ErrEmptyAPIKeyName
+	}
+	if len(name) > maxAPIKeyNameLength {
+		return nil, "", ErrLongAPIKeyName
+	}
+
+	key, secret, err := apikey.New(userID, name)
+	if err != nil {
+		return nil, "", err
+	}
+
+	err = us.storage(ctx).SetAPIKey(key)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return key, secret, nil
+}
+
+// GetAPIKeys returns the API keys of the user including the revoked ones.
+func (us *URLShortener) GetAPIKeys(ctx context.Context, userID string) (_ []*apikey.Key, err error) {
+	ctx, span := startSpan(ctx, "URLShortener.GetAPIKeys")
+	defer func() { endSpan(span, err) }()
+
+	return us.storage(ctx).GetAPIKeysByUserID(userID)
+}
+
+// RevokeAPIKey revokes the API key of the user.
+func (us *URLShortener) RevokeAPIKey(ctx context.Context, userID, id string) (err error) {
+	ctx, span := startSpan(ctx, "URLShortener.RevokeAPIKey")
+	defer func() { endSpan(span, err) }()
+
+	return us.storage(ctx).RevokeAPIKey(id, userID)
+}
+
+// AuthenticateAPIKey returns the ID of the user who owns the active API key.
+func (us *URLShortener) AuthenticateAPIKey(ctx context.Context, secret string) (_ string, err error) {
+	ctx, span := startSpan(ctx, "URLShortener.AuthenticateAPIKey")
+	defer func() { endSpan(span, err) }()
+
+	if !apikey.IsKey(secret) {
+		return "", apikey.ErrInvalid
+	}
+
+	key, err := us.storage(ctx).GetAPIKey(apikey.Hash(secret))
+	if err != nil {
+		if errors.Is(err, storage.ErrAPIKeyNotFound) {
+			return "", apikey.ErrInvalid
+		}
+		return "", err
+	}
+	if key.Revoked {
+		return "", apikey.ErrInvalid
+	}
+
+	return key.UserID, nil
+}
@@ -3,12 +3,30 @@ package app
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/alrund/yp-1/internal/app/account"
+	"github.com/alrund/yp-1/internal/app/apikey"
+	"github.com/alrund/yp-1/internal/app/audit"
 	"github.com/alrund/yp-1/internal/app/config"
+	"github.com/alrund/yp-1/internal/app/linkcheck"
+	"github.com/alrund/yp-1/internal/app/linkmeta"
+	"github.com/alrund/yp-1/internal/app/listing"
+	"github.com/alrund/yp-1/internal/app/logging"
+	"github.com/alrund/yp-1/internal/app/ratelimit"
+	"github.com/alrund/yp-1/internal/app/screening"
+	"github.com/alrund/yp-1/internal/app/search"
+	"github.com/alrund/yp-1/internal/app/session"
 	"github.com/alrund/yp-1/internal/app/storage"
 	tkn "github.com/alrund/yp-1/internal/app/token"
+	"github.com/alrund/yp-1/internal/app/urlcheck"
+	"github.com/alrund/yp-1/internal/app/workspace"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Storage data storage.
@@ -20,20 +38,62 @@ type Storage interface {
 	GetTokensByUserID(userID string) ([]*tkn.Token, error)
 	GetURL(tokenValue string) (string, error)
 	GetURLsByUserID(userID string) ([]storage.URLpairs, error)
+	GetURLsPage(ownerIDs []string, query listing.Query) ([]listing.Item, string, error)
+	GetURLsByTokens(tokenValues []string) ([]listing.Item, error)
+	AddClick(tokenValue string) error
+	FlushClicks() error // writes the clicks counted in memory
+	SetLinkMeta(tokenValue string, m linkmeta.Meta) error
+	GetLinkMeta(tokenValue string) (linkmeta.Meta, error)
+	UpdateLinkMeta(ownerIDs []string, tokenValue string, update linkmeta.Update) (linkmeta.Meta, error)
+	GetTags(ownerIDs []string) ([]linkmeta.TagCount, error)
+	RenameTag(ownerIDs []string, from, to string) (int, error)
+	SearchURLs(ownerIDs []string, query search.Query) ([]listing.Item, error)
+	GetLinksToCheck(checkedBefore time.Time, limit int) ([]listing.Item, error)
+	SetLinkHealth(health map[string]linkcheck.Health) error
+	SetLinkWebhook(userID, url string) error // an empty URL removes the webhook
+	GetLinkWebhook(userID string) (string, error)
 	HasURL(url string) (bool, error)
 	HasToken(tokenValue string) (bool, error)
 	Ping(ctx context.Context) error
 	RemoveTokens(tokenValues []string, userID string) error
 	GetURLCount() (int, error)
 	GetUserIDCount() (int, error)
+	SetAPIKey(key *apikey.Key) error
+	GetAPIKey(hash string) (*apikey.Key, error)
+	GetAPIKeysByUserID(userID string) ([]*apikey.Key, error)
+	RevokeAPIKey(id, userID string) error
+	SetAccount(a *account.Account) error
+	GetAccountByLogin(login string) (*account.Account, error)
+	GetAccountByID(id string) (*account.Account, error)
+	ReassignUser(fromUserID, toUserID string) error // moves the links, the API keys, the workspace memberships and the webhook
+	HasUser(userID string) (bool, error)            // the user owns links, an account, API keys or memberships
+	SetWorkspace(w *workspace.Workspace) error
+	GetWorkspace(id string) (*workspace.Workspace, error)
+	SetMember(m *workspace.Member) error // fails with storage.ErrLastAdmin if the workspace would lose its last admin
+	GetMember(workspaceID, userID string) (*workspace.Member, error)
+	GetMembers(workspaceID string) ([]*workspace.Member, error)
+	GetMembershipsByUserID(userID string) ([]*workspace.Member, error)
+	RemoveMember(workspaceID, userID string) error // fails with storage.ErrLastAdmin as SetMember
+	GetRateBucket(key string) (ratelimit.Bucket, error)
+	SetRateBucket(key string, b ratelimit.Bucket) error
+	AddQuotaUsage(key, day string, n int) (int, error)
+	PruneRateLimits(updatedBefore time.Time, day string) error
+	AddAuditEvents(events []*audit.Event) error
+	GetAuditEvents(filter audit.Filter) ([]*audit.Event, error)
 }
 
 // URLShortener url shortening application.
 type URLShortener struct {
 	Config *config.Config
 	Storage
-	TokenGenerator tkn.Generator
-	TrustedSubnet  *net.IPNet
+	TokenGenerator  tkn.Generator
+	RemoveQueue     *RemoveQueue
+	Sessions        session.Codec
+	RateLimiter     *ratelimit.Limiter  // nil disables the quota
+	Screener        *screening.Screener // nil screens nothing
+	trustedSubnet   *net.IPNet
+	reloadListeners []func(cfg *config.Config)
+	configMx        sync.RWMutex
 }
 
 type Stat struct {
@@ -42,35 +102,91 @@ type Stat struct {
 
 // GetConfig returns configuration data.
 func (us *URLShortener) GetConfig() *config.Config {
+	us.configMx.RLock()
+	defer us.configMx.RUnlock()
 	return us.Config
 }
 
 // GetServerAddress returns server address.
 func (us *URLShortener) GetServerAddress() string {
-	return us.Config.ServerAddress
+	return us.GetConfig().ServerAddress
 }
 
 // GetBaseURL returns base url.
 func (us *URLShortener) GetBaseURL() string {
-	return strings.TrimRight(us.Config.BaseURL, "/") + "/"
+	return strings.TrimRight(us.GetConfig().BaseURL, "/") + "/"
 }
 
-// Add adds a URL string to shorten.
-func (us *URLShortener) Add(userID, url string) (*tkn.Token, error) {
-	ok, err := us.HasURL(url)
+// GetTrustedSubnet returns the parsed trusted subnet, nil if it is not configured.
+func (us *URLShortener) GetTrustedSubnet() (*net.IPNet, error) {
+	us.configMx.RLock()
+	trustedSubnet, cidr := us.trustedSubnet, us.Config.TrustedSubnet
+	us.configMx.RUnlock()
+
+	if trustedSubnet != nil || cidr == "" {
+		return trustedSubnet, nil
+	}
+
+	_, trustedSubnet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return nil, err
+	}
+
+	us.configMx.Lock()
+	defer us.configMx.Unlock()
+	if us.Config.TrustedSubnet == cidr {
+		us.trustedSubnet = trustedSubnet
+	}
+
+	return trustedSubnet, nil
+}
+
+// GetURLPolicy returns the rules the URLs to shorten are checked and canonicalized with.
+func (us *URLShortener) GetURLPolicy() urlcheck.Policy {
+	cfg := us.GetConfig()
+	if cfg == nil {
+		return urlcheck.Policy{}
+	}
+	return urlcheck.Policy{
+		Schemes:          cfg.URLSchemes,
+		MaxLength:        cfg.URLMaxLength,
+		DenyPrivateHosts: cfg.URLDenyPrivateHosts,
+		SortQuery:        cfg.URLSortQuery,
+		StripParams:      cfg.URLStripParams,
+	}
+}
+
+// Add adds a URL string to shorten, a new link gets the meta.
+// The URL is stored in its canonical form, an invalid URL is rejected with a urlcheck error
+// and a URL refused by the screening with a screening.ErrBlocked.
+func (us *URLShortener) Add(ctx context.Context, userID, url string, m linkmeta.Meta) (_ *tkn.Token, err error) {
+	ctx, span := startSpan(ctx, "URLShortener.Add")
+	defer func() { endSpan(span, err) }()
+
+	url, err = us.GetURLPolicy().Canonicalize(url)
+	if err != nil {
+		return nil, err
+	}
+	if err = us.Screener.Screen(ctx, url); err != nil {
+		return nil, err
+	}
+
+	s := us.storage(ctx)
+	ok, err := s.HasURL(url)
 	if err != nil {
 		return nil, err
 	}
 	if ok {
-		token, err := us.GetTokenByURL(url)
+		token, err := s.GetTokenByURL(url)
 		if err != nil {
 			return nil, err
 		}
 		if token.IsExpired() {
-			err = us.Set(userID, url, token.Refresh())
+			err = s.Set(userID, url, token.Refresh())
 			if err != nil {
 				return nil, err
 			}
+			recordAudit(s, audit.NewEvent(ctx, audit.ActionRefresh, userID, token.Value, url))
 		}
 		return token, storage.ErrURLAlreadyExists
 	}
@@ -78,57 +194,125 @@ func (us *URLShortener) Add(userID, url string) (*tkn.Token, error) {
 	if err != nil {
 		return nil, err
 	}
-	err = us.Set(userID, url, token)
+	err = s.Set(userID, url, token)
 	if err != nil {
 		return nil, err
 	}
+	recordAudit(s, audit.NewEvent(ctx, audit.ActionCreate, userID, token.Value, url))
+	if !m.IsZero() {
+		if err = s.SetLinkMeta(token.Value, m); err != nil {
+			return nil, err
+		}
+	}
 	return token, nil
 }
 
-// AddBatch adds multiple URLs at once for shortening.
-func (us *URLShortener) AddBatch(userID string, urls []string) (map[string]*tkn.Token, error) {
+// AddBatch adds multiple URLs at once for shortening, the new links get the meta of their URL.
+// The URLs are stored in their canonical form, the tokens are keyed by the given URLs.
+// A single invalid or blocked URL rejects the whole batch,
+// the URLs shortened already are reported with a storage.ErrURLAlreadyExists while the others are added anyway.
+// It returns the number of the links added, the same URLs are added once.
+func (us *URLShortener) AddBatch(
+	ctx context.Context,
+	userID string,
+	urls []string,
+	metas map[string]linkmeta.Meta,
+) (_ map[string]*tkn.Token, added int, err error) {
+	ctx, span := startSpan(ctx, "URLShortener.AddBatch")
+	span.SetAttributes(attribute.Int("urls.count", len(urls)))
+	defer func() { endSpan(span, err) }()
+
+	policy := us.GetURLPolicy()
+	canonicals := make([]string, 0, len(urls))
+	url2canonical := make(map[string]string, len(urls))
+	canonicalMetas := make(map[string]linkmeta.Meta, len(urls))
+	for _, url := range urls {
+		canonical, err := policy.Canonicalize(url)
+		if err != nil {
+			return nil, 0, fmt.Errorf("%q: %w", url, err)
+		}
+		if err = us.Screener.Screen(ctx, canonical); err != nil {
+			return nil, 0, fmt.Errorf("%q: %w", url, err)
+		}
+		if _, ok := canonicalMetas[canonical]; !ok {
+			canonicals = append(canonicals, canonical)
+			canonicalMetas[canonical] = metas[url]
+		}
+		url2canonical[url] = canonical
+	}
+
+	s := us.storage(ctx)
 	url2token := map[string]*tkn.Token{}
 	url2newtoken := map[string]*tkn.Token{}
-	var storageErr error
+	events := make([]*audit.Event, 0, len(canonicals))
+	conflict := false
 
-	for _, url := range urls {
-		token, err := us.GetTokenByURL(url)
+	for _, url := range canonicals {
+		token, err := s.GetTokenByURL(url)
 		if err != nil && !errors.Is(err, storage.ErrTokenNotFound) {
-			return nil, err
+			return nil, 0, err
 		}
 		if token != nil && token.IsExpired() {
-			err = us.Set(userID, url, token.Refresh())
+			err = s.Set(userID, url, token.Refresh())
 			if err != nil {
-				return nil, err
+				return nil, 0, err
 			}
+			events = append(events, audit.NewEvent(ctx, audit.ActionRefresh, userID, token.Value, url))
 		}
 		if token != nil {
-			storageErr = storage.ErrURLAlreadyExists
+			conflict = true
 		} else {
 			token, err = tkn.NewToken(us.TokenGenerator)
 			if err != nil {
-				return nil, err
+				return nil, 0, err
 			}
 			url2newtoken[url] = token
 		}
 		url2token[url] = token
 	}
 
-	err := us.SetBatch(userID, url2newtoken)
+	err = s.SetBatch(userID, url2newtoken)
 	if err != nil {
-		return nil, err
+		recordAudit(s, events...)
+		return nil, 0, err
+	}
+	for url, token := range url2newtoken {
+		events = append(events, audit.NewEvent(ctx, audit.ActionCreate, userID, token.Value, url))
 	}
+	recordAudit(s, events...)
 
-	return url2token, storageErr
+	for url, token := range url2newtoken {
+		if m := canonicalMetas[url]; !m.IsZero() {
+			if err = s.SetLinkMeta(token.Value, m); err != nil {
+				return nil, len(url2newtoken), err
+			}
+		}
+	}
+
+	tokens := make(map[string]*tkn.Token, len(url2canonical))
+	for url, canonical := range url2canonical {
+		tokens[url] = url2token[canonical]
+	}
+
+	if conflict {
+		return tokens, len(url2newtoken), storage.ErrURLAlreadyExists
+	}
+
+	return tokens, len(url2newtoken), nil
 }
 
 // Get returns a URL by token.
-func (us *URLShortener) Get(tokenValue string) (string, error) {
-	ok, err := us.HasToken(tokenValue)
+// A URL blocklisted after it was shortened is returned with a screening.ErrBlocked and the click is not counted.
+func (us *URLShortener) Get(ctx context.Context, tokenValue string) (_ string, err error) {
+	ctx, span := startSpan(ctx, "URLShortener.Get")
+	defer func() { endSpan(span, err) }()
+
+	s := us.storage(ctx)
+	ok, err := s.HasToken(tokenValue)
 	if err != nil {
 		return "", err
 	} else if ok {
-		token, err := us.GetToken(tokenValue)
+		token, err := s.GetToken(tokenValue)
 		if err != nil {
 			return "", err
 		}
@@ -138,33 +322,187 @@ func (us *URLShortener) Get(tokenValue string) (string, error) {
 		if token.Removed {
 			return "", tkn.ErrTokenRemovedError
 		}
-		return us.GetURL(tokenValue)
+		url, err := s.GetURL(tokenValue)
+		if err != nil {
+			return "", err
+		}
+		if err := us.Screener.Blocked(url); err != nil {
+			return url, err
+		}
+		if err := s.AddClick(tokenValue); err != nil {
+			logging.Errorf("Click count: %v", err)
+		}
+		return url, nil
 	}
 	return "", storage.ErrTokenNotFound
 }
 
-// GetUserURLs returns a URL by user ID.
-func (us *URLShortener) GetUserURLs(userID string) ([]storage.URLpairs, error) {
+// GetUserURLs returns a page of the URLs of the user and of the user's workspaces
+// with the cursor of the next page, which is empty on the last page.
+func (us *URLShortener) GetUserURLs(
+	ctx context.Context,
+	userID string,
+	query listing.Query,
+) (_ []storage.URLpairs, _ string, err error) {
+	ctx, span := startSpan(ctx, "URLShortener.GetUserURLs")
+	defer func() { endSpan(span, err) }()
+
+	if err := query.Validate(); err != nil {
+		return nil, "", err
+	}
+	if query.Tag != "" {
+		if query.Tag, err = linkmeta.NormalizeTag(query.Tag); err != nil {
+			return nil, "", err
+		}
+	}
+
+	s := us.storage(ctx)
+	owners, err := linkOwners(s, userID, func(workspace.Role) bool { return true })
+	if err != nil {
+		return nil, "", err
+	}
+
+	items, next, err := s.GetURLsPage(owners, query)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if len(items) == 0 {
+		return nil, "", storage.ErrTokenNotFound
+	}
+
+	return us.urlPairs(userID, items), next, nil
+}
+
+// urlPairs returns the items as seen by the user.
+func (us *URLShortener) urlPairs(userID string, items []listing.Item) []storage.URLpairs {
 	baseURL := us.GetBaseURL()
-	URLPairs, err := us.GetURLsByUserID(userID)
+	now := time.Now()
+	URLPairs := make([]storage.URLpairs, 0, len(items))
+	for _, item := range items {
+		pair := storage.URLpairs{
+			ShortURL:    baseURL + item.Token,
+			OriginalURL: item.URL,
+			Expire:      item.Expire,
+			Removed:     item.Removed,
+			Clicks:      item.Clicks,
+			Status:      item.Status(now),
+			Title:       item.Title,
+			Notes:       item.Notes,
+			Tags:        item.Tags,
+		}
+		if !item.Health.IsZero() {
+			health := item.Health
+			pair.Health = &health
+		}
+		if item.OwnerID != userID {
+			pair.WorkspaceID = item.OwnerID
+		}
+		if !item.Created.IsZero() {
+			created := item.Created
+			pair.Created = &created
+		}
+		URLPairs = append(URLPairs, pair)
+	}
+
+	return URLPairs
+}
+
+// RemoveTokens marks the tokens of the user and of the workspaces the user can edit as removed.
+func (us *URLShortener) RemoveTokens(ctx context.Context, tokenValues []string, userID string) (err error) {
+	ctx, span := startSpan(ctx, "URLShortener.RemoveTokens")
+	span.SetAttributes(attribute.Int("tokens.count", len(tokenValues)))
+	defer func() { endSpan(span, err) }()
+
+	s := us.storage(ctx)
+	owners, err := linkOwners(s, userID, workspace.Role.CanEdit)
 	if err != nil {
+		return err
+	}
+
+	events := make([]*audit.Event, 0, len(tokenValues))
+	for _, owner := range owners {
+		owned, err := ownedTokens(s, owner, tokenValues)
+		if err != nil {
+			return err
+		}
+		err = s.RemoveTokens(tokenValues, owner)
+		if err != nil {
+			recordAudit(s, events...)
+			return err
+		}
+		for _, tokenValue := range owned {
+			events = append(events, audit.NewEvent(ctx, audit.ActionDelete, owner, tokenValue, ""))
+		}
+	}
+	recordAudit(s, events...)
+
+	return nil
+}
+
+// ownedTokens returns the given tokens of the owner which are not removed yet.
+func ownedTokens(s Storage, owner string, tokenValues []string) ([]string, error) {
+	tokens, err := s.GetTokensByUserID(owner)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, nil
+		}
 		return nil, err
 	}
 
-	for i := 0; i < len(URLPairs); i++ {
-		URLPairs[i].ShortURL = baseURL + URLPairs[i].ShortURL
+	requested := make(map[string]bool, len(tokenValues))
+	for _, tokenValue := range tokenValues {
+		requested[tokenValue] = true
 	}
 
-	return URLPairs, nil
+	owned := make([]string, 0)
+	for _, token := range tokens {
+		if requested[token.Value] && !token.Removed {
+			owned = append(owned, token.Value)
+		}
+	}
+
+	return owned, nil
+}
+
+func isNotFound(err error) bool {
+	return errors.Is(err, storage.ErrTokenNotFound) || errors.Is(err, storage.ErrURLNotFound)
+}
+
+// RemoveTokensAsync schedules the removal of the user's tokens.
+// Without a remove queue the removal runs in its own goroutine.
+func (us *URLShortener) RemoveTokensAsync(ctx context.Context, tokenValues []string, userID string) error {
+	// The removal outlives the request, so only the trace and the audit actor are carried over.
+	actor := audit.ActorFromContext(ctx)
+	ctx = trace.ContextWithSpanContext(context.Background(), trace.SpanContextFromContext(ctx))
+	ctx = audit.WithActor(ctx, actor)
+
+	if us.RemoveQueue == nil {
+		go func() {
+			_ = us.RemoveTokens(ctx, tokenValues, userID)
+		}()
+		return nil
+	}
+
+	return us.RemoveQueue.Push(ctx, tokenValues, userID)
+}
+
+// Ping checks the storage connection.
+func (us *URLShortener) Ping(ctx context.Context) error {
+	return us.storage(ctx).Ping(ctx)
 }
 
 // GetStats returns statistic.
-func (us *URLShortener) GetStats() (*Stat, error) {
-	urlCount, err := us.GetURLCount()
+func (us *URLShortener) GetStats(ctx context.Context) (_ *Stat, err error) {
+	ctx, span := startSpan(ctx, "URLShortener.GetStats")
+	defer func() { endSpan(span, err) }()
+
+	s := us.storage(ctx)
+	urlCount, err := s.GetURLCount()
 	if err != nil {
 		return nil, err
 	}
-	userCount, err := us.GetUserIDCount()
+	userCount, err := s.GetUserIDCount()
 	if err != nil {
 		return nil, err
 	}
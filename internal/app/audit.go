@@ -0,0 +1,27 @@
+package app
+
+import (
+	"context"
+
+	"github.com/alrund/yp-1/internal/app/audit"
+	"github.com/alrund/yp-1/internal/app/logging"
+)
+
+// GetAuditEvents returns the audit events selected by the filter, the newest first.
+func (us *URLShortener) GetAuditEvents(ctx context.Context, filter audit.Filter) (_ []*audit.Event, err error) {
+	ctx, span := startSpan(ctx, "URLShortener.GetAuditEvents")
+	defer func() { endSpan(span, err) }()
+
+	return us.storage(ctx).GetAuditEvents(filter)
+}
+
+// recordAudit appends the events to the audit log.
+// The change is already made, so a failed write is only logged.
+func recordAudit(s Storage, events ...*audit.Event) {
+	if len(events) == 0 {
+		return
+	}
+	if err := s.AddAuditEvents(events); err != nil {
+		logging.Errorf("Audit log: %v", err)
+	}
+}
@@ -0,0 +1,98 @@
+package audit
+
+import (
+	"context"
+	"time"
+)
+
+// Action what happened to the link.
+type Action string
+
+const (
+	ActionCreate  Action = "create"
+	ActionRefresh Action = "refresh" // an expired link was added again and got a new expiry
+	ActionDelete  Action = "delete"
+)
+
+// Sources of the requests.
+const (
+	SourceHTTP = "http"
+	SourceGRPC = "grpc"
+)
+
+// DefaultLimit the number of events returned when the filter does not set it.
+const DefaultLimit = 100
+
+// Event a record of the append-only audit log.
+type Event struct {
+	ID        int64     `json:"id"`
+	Time      time.Time `json:"time"`
+	Action    Action    `json:"action"`
+	Token     string    `json:"token"`
+	URL       string    `json:"url,omitempty"`
+	OwnerID   string    `json:"owner_id"` // the user or the workspace the link belongs to
+	ActorID   string    `json:"actor_id"` // the user who made the request
+	Source    string    `json:"source"`
+	ClientIP  string    `json:"client_ip"`
+	RequestID string    `json:"request_id"`
+}
+
+// Filter selects the events by the token or by the user, who is either the owner or the actor.
+// The newest events come first.
+type Filter struct {
+	Token  string
+	UserID string
+	Limit  int
+}
+
+// Match reports whether the event passes the filter, the limit aside.
+func (f Filter) Match(e *Event) bool {
+	if f.Token != "" && e.Token != f.Token {
+		return false
+	}
+	if f.UserID != "" && e.OwnerID != f.UserID && e.ActorID != f.UserID {
+		return false
+	}
+	return true
+}
+
+// Actor who made the request and where it came from.
+type Actor struct {
+	UserID    string
+	Source    string
+	ClientIP  string
+	RequestID string
+}
+
+type contextKey struct{}
+
+// WithActor returns the context carrying the actor of the request.
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, contextKey{}, actor)
+}
+
+// ActorFromContext returns the actor of the request, the zero actor if it is unknown.
+func ActorFromContext(ctx context.Context) Actor {
+	actor, _ := ctx.Value(contextKey{}).(Actor)
+	return actor
+}
+
+// NewEvent returns the event of the action made by the actor of the context.
+func NewEvent(ctx context.Context, action Action, ownerID, token, url string) *Event {
+	actor := ActorFromContext(ctx)
+	if actor.UserID == "" {
+		actor.UserID = ownerID
+	}
+
+	return &Event{
+		Time:      time.Now(),
+		Action:    action,
+		Token:     token,
+		URL:       url,
+		OwnerID:   ownerID,
+		ActorID:   actor.UserID,
+		Source:    actor.Source,
+		ClientIP:  actor.ClientIP,
+		RequestID: actor.RequestID,
+	}
+}
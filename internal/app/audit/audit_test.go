@@ -0,0 +1,35 @@
+package audit
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewEvent(t *testing.T) {
+	e := NewEvent(context.Background(), ActionCreate, "owner", "qwerty", "http://ya.ru")
+	assert.Equal(t, "owner", e.ActorID, "without an actor the owner made the change")
+	assert.Empty(t, e.Source)
+
+	ctx := WithActor(context.Background(), Actor{UserID: "user", Source: SourceHTTP, ClientIP: "10.0.0.1", RequestID: "req"})
+	e = NewEvent(ctx, ActionDelete, "workspace", "qwerty", "")
+	assert.Equal(t, ActionDelete, e.Action)
+	assert.Equal(t, "workspace", e.OwnerID)
+	assert.Equal(t, "user", e.ActorID)
+	assert.Equal(t, SourceHTTP, e.Source)
+	assert.Equal(t, "10.0.0.1", e.ClientIP)
+	assert.Equal(t, "req", e.RequestID)
+	assert.False(t, e.Time.IsZero())
+}
+
+func TestFilterMatch(t *testing.T) {
+	e := &Event{Token: "qwerty", OwnerID: "workspace", ActorID: "user"}
+
+	assert.True(t, Filter{}.Match(e))
+	assert.True(t, Filter{Token: "qwerty"}.Match(e))
+	assert.False(t, Filter{Token: "other"}.Match(e))
+	assert.True(t, Filter{UserID: "workspace"}.Match(e))
+	assert.True(t, Filter{UserID: "user", Token: "qwerty"}.Match(e))
+	assert.False(t, Filter{UserID: "stranger"}.Match(e))
+}
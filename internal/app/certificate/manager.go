@@ -0,0 +1,239 @@
+package certificate
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/alrund/yp-1/internal/app/logging"
+)
+
+const selfSignedValidity = 365 * 24 * time.Hour
+
+var ErrNoCertificate = errors.New("no certificate")
+
+// Manager serves the TLS certificate of both HTTP and GRPC servers.
+// A certificate loaded from files is reloaded when the files change.
+type Manager struct {
+	certFile string
+	keyFile  string
+	modTime  time.Time
+	cert     *tls.Certificate
+	mx       sync.RWMutex
+}
+
+// NewManager loads the certificate from the files.
+func NewManager(certFile, keyFile string) (*Manager, error) {
+	m := &Manager{}
+	if err := m.SetFiles(certFile, keyFile); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// NewSelfSigned returns a manager with an ephemeral self-signed certificate for the hosts.
+// It is meant for local development only.
+func NewSelfSigned(hosts ...string) (*Manager, error) {
+	cert, err := SelfSigned(hosts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Manager{cert: cert}, nil
+}
+
+// SetFiles switches the manager to other certificate files.
+// On error the current certificate is kept.
+func (m *Manager) SetFiles(certFile, keyFile string) error {
+	cert, modTime, err := load(certFile, keyFile)
+	if err != nil {
+		return err
+	}
+
+	m.mx.Lock()
+	defer m.mx.Unlock()
+	m.certFile, m.keyFile = certFile, keyFile
+	m.cert, m.modTime = cert, modTime
+
+	return nil
+}
+
+// Reload reloads the certificate if the files have changed since the last load.
+// It reports whether the certificate was replaced.
+func (m *Manager) Reload() (bool, error) {
+	m.mx.RLock()
+	certFile, keyFile, loaded := m.certFile, m.keyFile, m.modTime
+	m.mx.RUnlock()
+
+	if certFile == "" {
+		return false, nil
+	}
+
+	modTime, err := lastModified(certFile, keyFile)
+	if err != nil {
+		return false, err
+	}
+	if !modTime.After(loaded) {
+		return false, nil
+	}
+
+	cert, modTime, err := load(certFile, keyFile)
+	if err != nil {
+		return false, err
+	}
+
+	m.mx.Lock()
+	defer m.mx.Unlock()
+	if m.certFile != certFile || m.keyFile != keyFile {
+		return false, nil
+	}
+	m.cert, m.modTime = cert, modTime
+
+	return true, nil
+}
+
+// Watch checks the files every interval until ctx is done.
+// A broken certificate is logged and the current one is kept.
+func (m *Manager) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reloaded, err := m.Reload()
+			if err != nil {
+				logging.Errorf("Certificate reload failed, keeping the current one: %v", err)
+				continue
+			}
+			if reloaded {
+				logging.Infof("Certificate reloaded")
+			}
+		}
+	}
+}
+
+// GetCertificate implements tls.Config.GetCertificate.
+func (m *Manager) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	m.mx.RLock()
+	defer m.mx.RUnlock()
+
+	if m.cert == nil {
+		return nil, ErrNoCertificate
+	}
+	return m.cert, nil
+}
+
+// TLSConfig returns a server config which always serves the current certificate.
+func (m *Manager) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: m.GetCertificate,
+	}
+}
+
+// SelfSigned generates a self-signed certificate for the hosts, which are DNS names or IP addresses.
+func SelfSigned(hosts ...string) (*tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serialNumber,
+		Subject:               pkix.Name{Organization: []string{"Shortener development"}},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(selfSignedValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else if host != "" {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	cert, err := tls.X509KeyPair(
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &cert, nil
+}
+
+func load(certFile, keyFile string) (*tls.Certificate, time.Time, error) {
+	modTime, err := lastModified(certFile, keyFile)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	return &cert, modTime, nil
+}
+
+func lastModified(files ...string) (time.Time, error) {
+	var last time.Time
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if info.ModTime().After(last) {
+			last = info.ModTime()
+		}
+	}
+	return last, nil
+}
+
+// LoadCertPool reads the PEM encoded CA certificates from the file.
+func LoadCertPool(file string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("%s: %w", file, ErrNoCertificate)
+	}
+
+	return pool, nil
+}
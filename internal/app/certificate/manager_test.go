@@ -0,0 +1,118 @@
+package certificate
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeCertificate(t *testing.T, certFile, keyFile string, modTime time.Time) *tls.Certificate {
+	t.Helper()
+
+	cert, err := SelfSigned("localhost")
+	require.NoError(t, err)
+
+	keyDer, err := x509.MarshalECPrivateKey(cert.PrivateKey.(*ecdsa.PrivateKey))
+	require.NoError(t, err)
+
+	certPem := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]})
+	keyPem := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+	require.NoError(t, os.WriteFile(certFile, certPem, 0o600))
+	require.NoError(t, os.WriteFile(keyFile, keyPem, 0o600))
+	require.NoError(t, os.Chtimes(certFile, modTime, modTime))
+	require.NoError(t, os.Chtimes(keyFile, modTime, modTime))
+
+	return cert
+}
+
+func TestManagerReload(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	now := time.Now()
+
+	first := writeCertificate(t, certFile, keyFile, now.Add(-time.Minute))
+
+	m, err := NewManager(certFile, keyFile)
+	require.NoError(t, err)
+
+	got, err := m.GetCertificate(nil)
+	require.NoError(t, err)
+	assert.Equal(t, first.Certificate, got.Certificate)
+
+	reloaded, err := m.Reload()
+	require.NoError(t, err)
+	assert.False(t, reloaded)
+
+	second := writeCertificate(t, certFile, keyFile, now)
+
+	reloaded, err = m.Reload()
+	require.NoError(t, err)
+	assert.True(t, reloaded)
+
+	got, err = m.GetCertificate(nil)
+	require.NoError(t, err)
+	assert.Equal(t, second.Certificate, got.Certificate)
+
+	require.NoError(t, os.WriteFile(certFile, []byte("broken"), 0o600))
+	require.NoError(t, os.Chtimes(certFile, now.Add(time.Minute), now.Add(time.Minute)))
+
+	_, err = m.Reload()
+	assert.Error(t, err)
+
+	got, err = m.GetCertificate(nil)
+	require.NoError(t, err)
+	assert.Equal(t, second.Certificate, got.Certificate)
+}
+
+func TestManagerWatch(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	now := time.Now()
+
+	writeCertificate(t, certFile, keyFile, now.Add(-time.Minute))
+
+	m, err := NewManager(certFile, keyFile)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go m.Watch(ctx, 10*time.Millisecond)
+
+	second := writeCertificate(t, certFile, keyFile, now)
+
+	assert.Eventually(t, func() bool {
+		got, err := m.GetCertificate(nil)
+		return err == nil && assert.ObjectsAreEqual(second.Certificate, got.Certificate)
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestNewManagerMissingFiles(t *testing.T) {
+	_, err := NewManager("missing-cert.pem", "missing-key.pem")
+	assert.Error(t, err)
+}
+
+func TestNewSelfSigned(t *testing.T) {
+	m, err := NewSelfSigned("localhost", "127.0.0.1")
+	require.NoError(t, err)
+
+	cert, err := m.GetCertificate(nil)
+	require.NoError(t, err)
+
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+	assert.Equal(t, []string{"localhost"}, leaf.DNSNames)
+	assert.Len(t, leaf.IPAddresses, 1)
+	assert.NoError(t, leaf.VerifyHostname("127.0.0.1"))
+	assert.True(t, leaf.NotAfter.After(time.Now()))
+}
@@ -0,0 +1,27 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	"github.com/alrund/yp-1/internal/app/logging"
+)
+
+// WatchClicks flushes the clicks counted in memory every interval till the context is done.
+// The storage flushes the rest on close.
+func (us *URLShortener) WatchClicks(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := us.FlushClicks(); err != nil {
+			logging.Errorf("Click flush: %v", err)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+
+	"github.com/alrund/yp-1/internal/app/flags"
+)
+
+const (
+	SourceDefault = "default"
+	SourceFile    = "file"
+	SourceEnv     = "env"
+	SourceFlag    = "flag"
+
+	redacted = "******"
+)
+
+var dsnPasswordRe = regexp.MustCompile(`(password=)\S+`)
+
+// Setting an effective configuration value and where it came from.
+type Setting struct {
+	Name   string
+	Value  string
+	Source string
+}
+
+// Describe lists the settings of cfg with their sources, secrets are redacted.
+func Describe(cfg *Config, f *flags.Flags) ([]Setting, error) {
+	fileKeys := make(map[string]json.RawMessage)
+	if configFile := ConfigFile(f); configFile != "" {
+		data, err := os.ReadFile(configFile)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, &fileKeys); err != nil {
+			return nil, fmt.Errorf("%s: %w", configFile, err)
+		}
+	}
+
+	flagValues := flagValues(f)
+
+	cfgValue := reflect.ValueOf(cfg).Elem()
+	cfgType := cfgValue.Type()
+
+	settings := make([]Setting, 0, cfgType.NumField())
+	for i := 0; i < cfgType.NumField(); i++ {
+		field := cfgType.Field(i)
+
+		source := SourceDefault
+		if _, ok := fileKeys[field.Tag.Get("json")]; ok {
+			source = SourceFile
+		}
+		if _, ok := os.LookupEnv(field.Tag.Get("env")); ok {
+			source = SourceEnv
+		}
+		if v, ok := flagValues[field.Name]; ok && v != flags.NotAvailable {
+			source = SourceFlag
+		}
+
+		settings = append(settings, Setting{
+			Name:   SettingName(field),
+			Value:  redact(field, fmt.Sprint(cfgValue.Field(i).Interface())),
+			Source: source,
+		})
+	}
+
+	return settings, nil
+}
+
+// flagValues returns the raw flag values by the config field they set, see ReadFlags.
+func flagValues(f *flags.Flags) map[string]string {
+	return map[string]string{
+		"ServerAddress":      f.A,
+		"GrpcServerAddress":  f.GA,
+		"AdminServerAddress": f.AA,
+		"BaseURL":            f.B,
+		"FileStoragePath":    f.F,
+		"DatabaseDsn":        f.D,
+		"EnableHTTPS":        f.S,
+		"CertFile":           f.Crt,
+		"KeyFile":            f.Key,
+		"TrustedSubnet":      f.T,
+	}
+}
+
+func redact(field reflect.StructField, value string) string {
+	if value == "" {
+		return value
+	}
+
+	switch field.Tag.Get("secret") {
+	case "true":
+		return redacted
+	case "dsn":
+		if u, err := url.Parse(value); err == nil && u.Scheme != "" {
+			return u.Redacted()
+		}
+		return dsnPasswordRe.ReplaceAllString(value, "${1}"+redacted)
+	}
+
+	return value
+}
@@ -0,0 +1,40 @@
+package config
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Duration a time.Duration which is read from strings like "10s" in both env and JSON.
+type Duration time.Duration
+
+// SetValue parses the env value.
+func (d *Duration) SetValue(s string) error {
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	return d.SetValue(s)
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// Duration returns the value as time.Duration.
+func (d Duration) Duration() time.Duration {
+	return time.Duration(d)
+}
+
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
@@ -0,0 +1,60 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+)
+
+// reloadable settings which can change without a restart.
+var reloadable = map[string]bool{
+	"BaseURL":               true,
+	"TrustedSubnet":         true,
+	"CertFile":              true,
+	"KeyFile":               true,
+	"RateLimits":            true,
+	"DailyCreateQuota":      true,
+	"URLSchemes":            true,
+	"URLMaxLength":          true,
+	"URLDenyPrivateHosts":   true,
+	"URLSortQuery":          true,
+	"URLStripParams":        true,
+	"BlocklistDomainsFile":  true,
+	"BlocklistPatternsFile": true,
+	"LogLevel":              true,
+}
+
+// Merge returns a copy of current with the reloadable settings taken from next
+// and the names of the changed settings which require a restart.
+func Merge(current, next *Config) (*Config, []string) {
+	merged := *current
+	restartRequired := make([]string, 0)
+
+	mergedValue := reflect.ValueOf(&merged).Elem()
+	nextValue := reflect.ValueOf(next).Elem()
+	cfgType := mergedValue.Type()
+
+	for i := 0; i < cfgType.NumField(); i++ {
+		field := cfgType.Field(i)
+		if reflect.DeepEqual(mergedValue.Field(i).Interface(), nextValue.Field(i).Interface()) {
+			continue
+		}
+
+		if reloadable[field.Name] {
+			mergedValue.Field(i).Set(nextValue.Field(i))
+			continue
+		}
+
+		restartRequired = append(restartRequired, SettingName(field))
+	}
+
+	return &merged, restartRequired
+}
+
+// SettingName returns the name of the setting as it is written in the config file.
+func SettingName(field reflect.StructField) string {
+	name := strings.Split(field.Tag.Get("json"), ",")[0]
+	if name == "" || name == "-" {
+		return field.Tag.Get("env")
+	}
+	return name
+}
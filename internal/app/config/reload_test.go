@@ -0,0 +1,76 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMerge(t *testing.T) {
+	current := &Config{
+		ServerAddress: "localhost:8080",
+		BaseURL:       "http://localhost:8080/",
+		TrustedSubnet: "192.168.1.0/24",
+		CipherPass:    "PASS",
+	}
+
+	tests := []struct {
+		name                string
+		next                *Config
+		want                *Config
+		wantRestartRequired []string
+	}{
+		{
+			name: "nothing changed",
+			next: &Config{
+				ServerAddress: "localhost:8080",
+				BaseURL:       "http://localhost:8080/",
+				TrustedSubnet: "192.168.1.0/24",
+				CipherPass:    "PASS",
+			},
+			want:                current,
+			wantRestartRequired: []string{},
+		},
+		{
+			name: "reloadable settings",
+			next: &Config{
+				ServerAddress: "localhost:8080",
+				BaseURL:       "https://sho.rt/",
+				TrustedSubnet: "10.0.0.0/8",
+				CipherPass:    "PASS",
+				LogLevel:      "error",
+			},
+			want: &Config{
+				ServerAddress: "localhost:8080",
+				BaseURL:       "https://sho.rt/",
+				TrustedSubnet: "10.0.0.0/8",
+				CipherPass:    "PASS",
+				LogLevel:      "error",
+			},
+			wantRestartRequired: []string{},
+		},
+		{
+			name: "settings which require a restart",
+			next: &Config{
+				ServerAddress: "localhost:9000",
+				BaseURL:       "https://sho.rt/",
+				TrustedSubnet: "192.168.1.0/24",
+				CipherPass:    "NEWPASS",
+			},
+			want: &Config{
+				ServerAddress: "localhost:8080",
+				BaseURL:       "https://sho.rt/",
+				TrustedSubnet: "192.168.1.0/24",
+				CipherPass:    "PASS",
+			},
+			wantRestartRequired: []string{"server_address", "CIPHER_PASSWORD"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, restartRequired := Merge(current, tt.next)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantRestartRequired, restartRequired)
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package config
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/alrund/yp-1/internal/app/certificate"
+	"github.com/alrund/yp-1/internal/app/logging"
+	"github.com/alrund/yp-1/internal/app/ratelimit"
+	"github.com/alrund/yp-1/internal/app/screening"
+	"github.com/alrund/yp-1/internal/app/session"
+	"github.com/alrund/yp-1/internal/app/urlcheck"
+)
+
+var ErrInvalidConfig = errors.New("invalid config")
+
+// ValidationError all the problems found in the configuration.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s:\n  - %s", ErrInvalidConfig, strings.Join(e.Problems, "\n  - "))
+}
+
+func (e *ValidationError) Unwrap() error {
+	return ErrInvalidConfig
+}
+
+func (e *ValidationError) add(setting, format string, args ...interface{}) {
+	e.Problems = append(e.Problems, setting+": "+fmt.Sprintf(format, args...))
+}
+
+// Validate checks the whole configuration and reports every problem at once.
+func (c *Config) Validate() error {
+	v := &ValidationError{}
+
+	validateAddress(v, "server_address", c.ServerAddress, true)
+	validateAddress(v, "grpc_server_address", c.GrpcServerAddress, true)
+	validateAddress(v, "admin_server_address", c.AdminServerAddress, false)
+
+	u, err := url.Parse(c.BaseURL)
+	switch {
+	case err != nil:
+		v.add("base_url", "%v", err)
+	case (u.Scheme != "http" && u.Scheme != "https") || u.Host == "":
+		v.add("base_url", "%q is not an absolute http(s) URL", c.BaseURL)
+	}
+
+	switch c.AuthMode {
+	case AuthModeCookie:
+		switch {
+		case c.CipherPass == "":
+			v.add("CIPHER_PASSWORD", "is not set")
+		case c.CipherPass == DefaultCipherPass && !c.DevMode:
+			v.add("CIPHER_PASSWORD", "the built-in default is allowed only with dev_mode")
+		}
+	case AuthModeJWT:
+		if c.JWTKeyFile == "" {
+			v.add("jwt_key_file", "is required by the jwt auth mode")
+		} else if _, err := session.LoadJWT(c.JWTAlgorithm, c.JWTKeyFile, c.JWTTTL.Duration()); err != nil {
+			v.add("jwt_key_file", "%v", err)
+		}
+		if c.JWTTTL <= 0 {
+			v.add("jwt_ttl", "must be positive, got %s", c.JWTTTL.Duration())
+		}
+		for _, scope := range c.JWTScopes {
+			if !session.HasScope(session.KnownScopes, scope) {
+				v.add("jwt_scopes", "unknown scope %q, expected one of %s", scope, strings.Join(session.KnownScopes, ", "))
+			}
+		}
+	default:
+		v.add("auth_mode", "unknown mode %q, expected cookie or jwt", c.AuthMode)
+	}
+
+	if c.FileStoragePath != "" && c.DatabaseDsn != "" {
+		v.add("file_storage_path", "is set together with database_dsn, only one storage can be used")
+	}
+
+	if c.TrustedSubnet != "" {
+		if _, _, err := net.ParseCIDR(c.TrustedSubnet); err != nil {
+			v.add("trusted_subnet", "%v", err)
+		}
+	}
+	if c.AdminRestrictToSubnet && c.TrustedSubnet == "" {
+		v.add("admin_restrict_to_subnet", "trusted_subnet is not set")
+	}
+	if c.AdminServerAddress != "" && c.AdminToken == "" && !c.AdminRestrictToSubnet {
+		v.add("admin_server_address", "admin_token or admin_restrict_to_subnet must be set")
+	}
+
+	switch {
+	case (c.CertFile == "") != (c.KeyFile == ""):
+		v.add("cert_file", "cert_file and key_file must be set together")
+	case c.CertFile != "":
+		if _, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile); err != nil {
+			v.add("cert_file", "%v", err)
+		}
+	case c.EnableHTTPS && !c.TLSSelfSigned:
+		v.add("enable_https", "cert_file and key_file are not set and tls_self_signed is off")
+	}
+
+	if c.GrpcClientCAFile != "" {
+		if !c.EnableHTTPS {
+			v.add("grpc_client_ca_file", "enable_https is off")
+		}
+		if _, err := certificate.LoadCertPool(c.GrpcClientCAFile); err != nil {
+			v.add("grpc_client_ca_file", "%v", err)
+		}
+	}
+	if c.GrpcRequireClientCert && c.GrpcClientCAFile == "" {
+		v.add("grpc_require_client_cert", "grpc_client_ca_file is not set")
+	}
+
+	switch c.RateLimitStore {
+	case RateLimitStoreMemory, RateLimitStoreStorage:
+	default:
+		v.add("rate_limit_store", "unknown store %q, expected memory or storage", c.RateLimitStore)
+	}
+	if _, err := ratelimit.ParseLimits(c.RateLimits); err != nil {
+		v.add("rate_limits", "%v", err)
+	}
+	if c.DailyCreateQuota < 0 {
+		v.add("daily_create_quota", "must not be negative, got %d", c.DailyCreateQuota)
+	}
+
+	for _, scheme := range c.URLSchemes {
+		if u, err := url.Parse(scheme + ":"); err != nil || !strings.EqualFold(u.Scheme, scheme) {
+			v.add("url_schemes", "invalid scheme %q", scheme)
+		}
+	}
+	if c.URLMaxLength < 1 || c.URLMaxLength > urlcheck.DefaultMaxLength {
+		v.add("url_max_length", "must be from 1 to %d, got %d", urlcheck.DefaultMaxLength, c.URLMaxLength)
+	}
+
+	if _, err := screening.LoadBlocklist(c.BlocklistDomainsFile, c.BlocklistPatternsFile); err != nil {
+		v.add("blocklist", "%v", err)
+	}
+
+	if c.LinkCheckInterval < 0 {
+		v.add("link_check_interval", "must not be negative, got %s", c.LinkCheckInterval.Duration())
+	}
+	if c.LinkCheckInterval > 0 {
+		if c.LinkCheckTimeout <= 0 {
+			v.add("link_check_timeout", "must be positive, got %s", c.LinkCheckTimeout.Duration())
+		}
+		if c.LinkCheckConcurrency < 1 {
+			v.add("link_check_concurrency", "must be positive, got %d", c.LinkCheckConcurrency)
+		}
+		if c.LinkCheckHostDelay < 0 {
+			v.add("link_check_host_delay", "must not be negative, got %s", c.LinkCheckHostDelay.Duration())
+		}
+		if c.LinkCheckBatchSize < 1 {
+			v.add("link_check_batch_size", "must be positive, got %d", c.LinkCheckBatchSize)
+		}
+	}
+
+	if c.RemoveQueueSize < 1 {
+		v.add("remove_queue_size", "must be positive, got %d", c.RemoveQueueSize)
+	}
+	if c.RemoveWorkers < 1 {
+		v.add("remove_workers", "must be positive, got %d", c.RemoveWorkers)
+	}
+	if _, err := logging.ParseLevel(c.LogLevel); err != nil {
+		v.add("log_level", "%v", err)
+	}
+	if c.ShutdownTimeout <= 0 {
+		v.add("shutdown_timeout", "must be positive, got %s", c.ShutdownTimeout.Duration())
+	}
+
+	switch c.TraceExporter {
+	case "", "stdout", "otlp":
+	case "file":
+		if c.TraceFile == "" {
+			v.add("trace_file", "is required by the file trace exporter")
+		}
+	default:
+		v.add("trace_exporter", "unknown exporter %q, expected otlp, stdout or file", c.TraceExporter)
+	}
+
+	if len(v.Problems) > 0 {
+		return v
+	}
+
+	return nil
+}
+
+func validateAddress(v *ValidationError, setting, address string, required bool) {
+	if address == "" {
+		if required {
+			v.add(setting, "is not set")
+		}
+		return
+	}
+
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		v.add(setting, "%v", err)
+	}
+}
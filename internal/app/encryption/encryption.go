@@ -3,31 +3,146 @@ package encryption
 import (
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
+	"io"
+	"strings"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// version prefixes the ciphertexts: "v2.<hex nonce and sealed data>".
+// The ciphertext does not name its key, the keys are tried in turn and GCM rejects the wrong ones.
+const version = "v2"
+
+// The AES keys are derived from the passwords with scrypt, the salt separates them from the other uses of the passwords.
+const (
+	kdfSalt = "github.com/alrund/yp-1/encryption"
+	kdfN    = 1 << 15
+	kdfR    = 8
+	kdfP    = 1
+	keySize = 32
+)
+
+var (
+	ErrMalformed  = errors.New("malformed ciphertext")
+	ErrUnknownKey = errors.New("unknown encryption key")
 )
 
+type key struct {
+	aead cipher.AEAD
+	pass string
+}
+
+// Encryption a keyring which encrypts with the current key
+// and decrypts with the current and the old ones.
 type Encryption struct {
-	CipherPass string
+	current *key
+	keys    []*key // the current key first
+	// AcceptLegacy allows to decrypt the unversioned ciphertexts of the old scheme with a fixed nonce.
+	// It is meant only for a migration period.
+	AcceptLegacy bool
 }
 
-func NewEncryption(cipherPass string) *Encryption {
-	return &Encryption{CipherPass: cipherPass}
+// NewEncryption returns a keyring with the current password and the old ones, which are kept for decryption.
+// The keys are derived once here, which takes a noticeable time per password.
+func NewEncryption(cipherPass string, oldCipherPasses ...string) *Encryption {
+	e := &Encryption{
+		current: newKey(cipherPass),
+		keys:    make([]*key, 0, len(oldCipherPasses)+1),
+	}
+	e.keys = append(e.keys, e.current)
+	for _, pass := range oldCipherPasses {
+		e.keys = append(e.keys, newKey(pass))
+	}
+
+	return e
 }
 
-// Encrypt encodes data string.
-func (e *Encryption) Encrypt(data string) (string, error) {
-	aesgcm, nonce, err := e.getAesgcm()
+func newKey(pass string) *key {
+	secret, err := scrypt.Key([]byte(pass), []byte(kdfSalt), kdfN, kdfR, kdfP, keySize)
 	if err != nil {
+		panic(err) // the parameters are constant
+	}
+
+	aead, err := newAesgcm(secret)
+	if err != nil {
+		panic(err) // the key size is constant
+	}
+
+	return &key{aead: aead, pass: pass}
+}
+
+// Encrypt encodes data string with the current key and a random nonce.
+func (e *Encryption) Encrypt(data string) (string, error) {
+	aesgcm := e.current.aead
+
+	nonce := make([]byte, aesgcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
 		return "", err
 	}
 
-	return hex.EncodeToString(aesgcm.Seal(nil, nonce, []byte(data), nil)), nil
+	sealed := aesgcm.Seal(nonce, nonce, []byte(data), nil)
+
+	return version + "." + hex.EncodeToString(sealed), nil
 }
 
-// Decrypt decodes data string.
+// Decrypt decodes data string with the key it was encrypted with.
 func (e *Encryption) Decrypt(encrypted string) (string, error) {
-	aesgcm, nonce, err := e.getAesgcm()
+	parts := strings.Split(encrypted, ".")
+	switch {
+	case len(parts) == 2 && parts[0] == version:
+		for _, k := range e.keys {
+			decrypted, err := open(k.aead, parts[1])
+			if errors.Is(err, ErrMalformed) {
+				return "", err
+			}
+			if err == nil {
+				return decrypted, nil
+			}
+		}
+		return "", ErrUnknownKey
+	case len(parts) == 1 && e.AcceptLegacy:
+		for _, k := range e.keys {
+			if decrypted, err := decryptLegacy(k.pass, encrypted); err == nil {
+				return decrypted, nil
+			}
+		}
+		return "", ErrUnknownKey
+	}
+
+	return "", ErrMalformed
+}
+
+// IsCurrent reports whether encrypted uses the current scheme and key,
+// otherwise it should be encrypted again.
+func (e *Encryption) IsCurrent(encrypted string) bool {
+	if !strings.HasPrefix(encrypted, version+".") {
+		return false
+	}
+	_, err := open(e.current.aead, strings.TrimPrefix(encrypted, version+"."))
+	return err == nil
+}
+
+func open(aesgcm cipher.AEAD, encoded string) (string, error) {
+	decoded, err := hex.DecodeString(encoded)
+	if err != nil || len(decoded) < aesgcm.NonceSize() {
+		return "", ErrMalformed
+	}
+
+	nonce, sealed := decoded[:aesgcm.NonceSize()], decoded[aesgcm.NonceSize():]
+	decrypted, err := aesgcm.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return "", err
+	}
+
+	return string(decrypted), nil
+}
+
+func decryptLegacy(pass, encrypted string) (string, error) {
+	aesgcm, err := getAesgcm(pass)
 	if err != nil {
 		return "", err
 	}
@@ -37,6 +152,9 @@ func (e *Encryption) Decrypt(encrypted string) (string, error) {
 		return "", err
 	}
 
+	key := sha256.Sum256([]byte(pass))
+	nonce := key[len(key)-aesgcm.NonceSize():]
+
 	decrypted, err := aesgcm.Open(nil, nonce, decoded, nil)
 	if err != nil {
 		return "", err
@@ -45,20 +163,17 @@ func (e *Encryption) Decrypt(encrypted string) (string, error) {
 	return string(decrypted), nil
 }
 
-func (e *Encryption) getAesgcm() (cipher.AEAD, []byte, error) {
-	key := sha256.Sum256([]byte(e.CipherPass))
-
-	aesblock, err := aes.NewCipher(key[:])
-	if err != nil {
-		return nil, nil, err
-	}
+// getAesgcm returns the cipher of the old scheme, its key is a hash of the password.
+func getAesgcm(pass string) (cipher.AEAD, error) {
+	key := sha256.Sum256([]byte(pass))
+	return newAesgcm(key[:])
+}
 
-	aesgcm, err := cipher.NewGCM(aesblock)
+func newAesgcm(key []byte) (cipher.AEAD, error) {
+	aesblock, err := aes.NewCipher(key)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
-	nonce := key[len(key)-aesgcm.NonceSize():]
-
-	return aesgcm, nonce, nil
+	return cipher.NewGCM(aesblock)
 }
@@ -1,67 +1,140 @@
 package encryption
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 var enc = NewEncryption("J53RPX6")
 
+// legacyEncrypted "раз два три" encrypted with "J53RPX6" by the old unversioned scheme.
+const legacyEncrypted = "d54f7a94af13b05cf383b5715e8b45d91dbe2ce8588d464304f67eddb237e752b2540951"
+
 func TestEncrypt(t *testing.T) {
-	tests := []struct {
-		name    string
-		data    string
-		want    string
-		wantErr bool
-	}{
-		{
-			name:    "success",
-			data:    "раз два три",
-			want:    "d54f7a94af13b05cf383b5715e8b45d91dbe2ce8588d464304f67eddb237e752b2540951",
-			wantErr: false,
-		},
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			ecrypted, err := enc.Encrypt(tt.data)
-			assert.NotEqual(t, ecrypted, tt.data)
-			assert.Equal(t, tt.want, ecrypted)
+	data := "раз два три"
 
-			if tt.wantErr {
-				assert.NotNil(t, err)
-			}
-		})
-	}
+	first, err := enc.Encrypt(data)
+	require.NoError(t, err)
+	second, err := enc.Encrypt(data)
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(first, "v2."))
+	assert.Len(t, strings.Split(first, "."), 2, "the ciphertext must not carry a key id")
+	assert.NotContains(t, first, data)
+	assert.NotEqual(t, first, second, "every ciphertext must have its own nonce")
+	assert.True(t, enc.IsCurrent(first))
 }
 
 func TestDecrypt(t *testing.T) {
+	encrypted, err := enc.Encrypt("раз два три")
+	require.NoError(t, err)
+
+	tampered := encrypted[:len(encrypted)-2] + "00"
+	if tampered == encrypted {
+		tampered = encrypted[:len(encrypted)-2] + "ff"
+	}
+
 	tests := []struct {
 		name    string
+		enc     *Encryption
 		data    string
 		want    string
-		wantErr bool
+		wantErr error
 	}{
 		{
-			name:    "success",
-			data:    "d54f7a94af13b05cf383b5715e8b45d91dbe2ce8588d464304f67eddb237e752b2540951",
-			want:    "раз два три",
-			wantErr: false,
+			name: "success",
+			enc:  enc,
+			data: encrypted,
+			want: "раз два три",
+		},
+		{
+			name: "old key",
+			enc:  NewEncryption("NEWPASS", "J53RPX6"),
+			data: encrypted,
+			want: "раз два три",
+		},
+		{
+			name:    "unknown key",
+			enc:     NewEncryption("NEWPASS"),
+			data:    encrypted,
+			wantErr: ErrUnknownKey,
+		},
+		{
+			name: "tampered",
+			enc:  enc,
+			data: tampered,
+		},
+		{
+			name:    "not hex",
+			enc:     enc,
+			data:    "v2.zz",
+			wantErr: ErrMalformed,
+		},
+		{
+			name:    "unknown version",
+			enc:     enc,
+			data:    "v1.f3c8e675.7ddbb95d12f400df89e75f9825cf3216",
+			wantErr: ErrMalformed,
+		},
+		{
+			name:    "legacy ciphertext",
+			enc:     enc,
+			data:    legacyEncrypted,
+			wantErr: ErrMalformed,
+		},
+		{
+			name: "legacy ciphertext accepted",
+			enc:  &Encryption{current: enc.current, keys: enc.keys, AcceptLegacy: true},
+			data: legacyEncrypted,
+			want: "раз два три",
+		},
+		{
+			name: "legacy ciphertext of an old key",
+			enc:  &Encryption{current: newKey("NEWPASS"), keys: []*key{newKey("NEWPASS"), enc.current}, AcceptLegacy: true},
+			data: legacyEncrypted,
+			want: "раз два три",
+		},
+		{
+			name:    "legacy ciphertext of an unknown key",
+			enc:     &Encryption{current: newKey("NEWPASS"), keys: []*key{newKey("NEWPASS")}, AcceptLegacy: true},
+			data:    legacyEncrypted,
+			wantErr: ErrUnknownKey,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			decrypted, err := enc.Decrypt(tt.data)
-			assert.NotEqual(t, decrypted, tt.data)
-			assert.Equal(t, tt.want, decrypted)
-
-			if tt.wantErr {
-				assert.NotNil(t, err)
+			decrypted, err := tt.enc.Decrypt(tt.data)
+			if tt.want == "" {
+				assert.Error(t, err)
+				if tt.wantErr != nil {
+					assert.ErrorIs(t, err, tt.wantErr)
+				}
+				return
 			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, decrypted)
 		})
 	}
 }
 
+func TestIsCurrent(t *testing.T) {
+	old := NewEncryption("J53RPX6")
+	encrypted, err := old.Encrypt("раз два три")
+	require.NoError(t, err)
+
+	rotated := NewEncryption("NEWPASS", "J53RPX6")
+	assert.False(t, rotated.IsCurrent(encrypted))
+	assert.False(t, rotated.IsCurrent(legacyEncrypted))
+
+	encrypted, err = rotated.Encrypt("раз два три")
+	require.NoError(t, err)
+	assert.True(t, rotated.IsCurrent(encrypted))
+}
+
 func BenchmarkEncrypt(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		_, _ = enc.Encrypt("раз два три")
@@ -69,7 +142,21 @@ func BenchmarkEncrypt(b *testing.B) {
 }
 
 func BenchmarkDecrypt(b *testing.B) {
+	encrypted, _ := enc.Encrypt("раз два три")
+	b.ResetTimer()
+
 	for i := 0; i < b.N; i++ {
-		_, _ = enc.Decrypt("d54f7a94af13b05cf383b5715e8b45d91dbe2ce8588d464304f67eddb237e752b2540951")
+		_, _ = enc.Decrypt(encrypted)
 	}
 }
+
+func TestKeyDerivation(t *testing.T) {
+	encrypted, err := enc.Encrypt("раз два три")
+	require.NoError(t, err)
+
+	// The key is not the plain hash of the password, as it was in the previous schemes.
+	hashed, err := getAesgcm("J53RPX6")
+	require.NoError(t, err)
+	_, err = open(hashed, strings.TrimPrefix(encrypted, "v2."))
+	assert.Error(t, err)
+}
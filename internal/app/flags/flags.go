@@ -1,6 +1,9 @@
 package flags
 
-import "flag"
+import (
+	"flag"
+	"os"
+)
 
 const NotAvailable string = "N/A"
 
@@ -15,22 +18,34 @@ type Flags struct {
 	Key string
 	T   string
 	GA  string
+	AA  string
+	// Args the arguments left after the flags.
+	Args []string
 }
 
+// NewFlags parses the command line flags.
 func NewFlags() *Flags {
+	return Parse(os.Args[1:])
+}
+
+// Parse parses args, the arguments left after the flags are kept in Args.
+func Parse(args []string) *Flags {
 	flags := &Flags{}
+	fs := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
 
-	flag.StringVar(&flags.C, "c", "", "Файл конфигурации")
-	flag.StringVar(&flags.A, "a", NotAvailable, "Адрес запуска HTTP-сервера")
-	flag.StringVar(&flags.GA, "ga", NotAvailable, "Адрес запуска GRPC-сервера")
-	flag.StringVar(&flags.B, "b", NotAvailable, "Базовый адрес результирующего сокращённого URL")
-	flag.StringVar(&flags.F, "f", NotAvailable, "Путь до файла с сокращёнными URL")
-	flag.StringVar(&flags.D, "d", NotAvailable, "Строка с адресом подключения к БД")
-	flag.StringVar(&flags.S, "s", NotAvailable, "Использовать HTTPS")
-	flag.StringVar(&flags.Crt, "crt", NotAvailable, "Файл с сертификатом")
-	flag.StringVar(&flags.Key, "key", NotAvailable, "Файл с приватным ключом")
-	flag.StringVar(&flags.T, "t", NotAvailable, "Доверенная подсеть (CIDR)")
-	flag.Parse()
+	fs.StringVar(&flags.C, "c", "", "Файл конфигурации")
+	fs.StringVar(&flags.A, "a", NotAvailable, "Адрес запуска HTTP-сервера")
+	fs.StringVar(&flags.GA, "ga", NotAvailable, "Адрес запуска GRPC-сервера")
+	fs.StringVar(&flags.AA, "aa", NotAvailable, "Адрес запуска служебного HTTP-сервера (pprof, метрики, статистика)")
+	fs.StringVar(&flags.B, "b", NotAvailable, "Базовый адрес результирующего сокращённого URL")
+	fs.StringVar(&flags.F, "f", NotAvailable, "Путь до файла с сокращёнными URL")
+	fs.StringVar(&flags.D, "d", NotAvailable, "Строка с адресом подключения к БД")
+	fs.StringVar(&flags.S, "s", NotAvailable, "Использовать HTTPS")
+	fs.StringVar(&flags.Crt, "crt", NotAvailable, "Файл с сертификатом")
+	fs.StringVar(&flags.Key, "key", NotAvailable, "Файл с приватным ключом")
+	fs.StringVar(&flags.T, "t", NotAvailable, "Доверенная подсеть (CIDR)")
+	_ = fs.Parse(args) // ExitOnError
+	flags.Args = fs.Args()
 
 	return flags
 }
@@ -0,0 +1,78 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+
+	"github.com/alrund/yp-1/internal/app/account"
+	"github.com/alrund/yp-1/internal/app/storage"
+	pb "github.com/alrund/yp-1/internal/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Register creates an account, the links of the current user are moved to it.
+// The client sends the returned token as the user ID metadata from now on.
+func (s *Server) Register(ctx context.Context, in *pb.RegisterRequest) (*pb.RegisterResponse, error) {
+	var response pb.RegisterResponse
+
+	contextUserID := ctx.Value(UserIDContextKey)
+	userID, ok := contextUserID.(string)
+	if !ok {
+		return &response, status.Error(codes.Internal, codes.Internal.String())
+	}
+
+	a, err := s.us.Register(ctx, userID, in.Login, in.Password)
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrAccountExists):
+			return &response, status.Error(codes.AlreadyExists, codes.AlreadyExists.String())
+		case errors.Is(err, account.ErrEmptyLogin),
+			errors.Is(err, account.ErrLongLogin),
+			errors.Is(err, account.ErrShortPassword),
+			errors.Is(err, account.ErrLongPassword):
+			return &response, status.Error(codes.InvalidArgument, codes.InvalidArgument.String())
+		}
+		return &response, status.Error(codes.Internal, codes.Internal.String())
+	}
+
+	token, err := s.us.Sessions.Issue(a.ID)
+	if err != nil {
+		return &response, status.Error(codes.Internal, codes.Internal.String())
+	}
+
+	response.UserId = a.ID
+	response.Token = token
+
+	return &response, nil
+}
+
+// Login signs the user in, the links of the current anonymous user are moved to the account.
+// The client sends the returned token as the user ID metadata from now on.
+func (s *Server) Login(ctx context.Context, in *pb.LoginRequest) (*pb.LoginResponse, error) {
+	var response pb.LoginResponse
+
+	contextUserID := ctx.Value(UserIDContextKey)
+	userID, ok := contextUserID.(string)
+	if !ok {
+		return &response, status.Error(codes.Internal, codes.Internal.String())
+	}
+
+	a, err := s.us.Login(ctx, userID, in.Login, in.Password)
+	if err != nil {
+		if errors.Is(err, account.ErrInvalidCredentials) {
+			return &response, status.Error(codes.Unauthenticated, codes.Unauthenticated.String())
+		}
+		return &response, status.Error(codes.Internal, codes.Internal.String())
+	}
+
+	token, err := s.us.Sessions.Issue(a.ID)
+	if err != nil {
+		return &response, status.Error(codes.Internal, codes.Internal.String())
+	}
+
+	response.UserId = a.ID
+	response.Token = token
+
+	return &response, nil
+}
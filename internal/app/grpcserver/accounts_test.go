@@ -0,0 +1,71 @@
+package grpcserver
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alrund/yp-1/internal/app"
+	"github.com/alrund/yp-1/internal/app/config"
+	"github.com/alrund/yp-1/internal/app/encryption"
+	"github.com/alrund/yp-1/internal/app/storage"
+	pb "github.com/alrund/yp-1/internal/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestAccounts(t *testing.T) {
+	testConfig := &config.Config{
+		GrpcServerAddress: "localhost:9090",
+		BaseURL:           "http://localhost:8080/",
+		CipherPass:        "PASS",
+	}
+	testEncryptor := encryption.NewEncryption(testConfig.CipherPass)
+	us := &app.URLShortener{
+		Config:         testConfig,
+		Storage:        storage.NewMap(),
+		TokenGenerator: new(TestGenerator),
+	}
+
+	conn, err := grpc.DialContext(
+		context.Background(),
+		testConfig.GrpcServerAddress,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(dialer(us)),
+	)
+	require.NoError(t, err)
+	defer conn.Close()
+	client := pb.NewAppClient(conn)
+
+	_, err = client.Register(context.Background(), &pb.RegisterRequest{Login: "alice", Password: "short"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	registered, err := client.Register(context.Background(), &pb.RegisterRequest{Login: "alice", Password: "correct horse"})
+	require.NoError(t, err)
+	require.NotEmpty(t, registered.Token)
+
+	_, err = client.Register(context.Background(), &pb.RegisterRequest{Login: "alice", Password: "correct horse"})
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+
+	_, err = client.Login(context.Background(), &pb.LoginRequest{Login: "alice", Password: "wrong password"})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	// The links of an anonymous user are merged into the account on login.
+	anonymousCtx := getContextWithUserID("XXX-YYY-ZZZ", testEncryptor)
+	_, err = client.Add(anonymousCtx, &pb.AddRequest{Url: "http://ya.ru"})
+	require.NoError(t, err)
+
+	loggedIn, err := client.Login(anonymousCtx, &pb.LoginRequest{Login: "alice", Password: "correct horse"})
+	require.NoError(t, err)
+	assert.Equal(t, registered.UserId, loggedIn.UserId)
+
+	accountCtx := metadata.AppendToOutgoingContext(context.Background(), string(UserIDContextKey), loggedIn.Token)
+	urls, err := client.GetUserURLs(accountCtx, &pb.GetUserURLsRequest{})
+	require.NoError(t, err)
+	require.Len(t, urls.Urls, 1)
+	assert.Equal(t, "http://ya.ru", urls.Urls[0].OriginalUrl)
+}
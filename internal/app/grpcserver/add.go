@@ -4,7 +4,10 @@ import (
 	"context"
 	"errors"
 
+	"github.com/alrund/yp-1/internal/app/linkmeta"
+	"github.com/alrund/yp-1/internal/app/screening"
 	"github.com/alrund/yp-1/internal/app/storage"
+	"github.com/alrund/yp-1/internal/app/urlcheck"
 	pb "github.com/alrund/yp-1/internal/proto"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -28,8 +31,30 @@ func (s *Server) Add(ctx context.Context, in *pb.AddRequest) (*pb.AddResponse, e
 		return &response, status.Error(codes.Internal, codes.Internal.String())
 	}
 
-	token, err := s.us.Add(userID, in.Url)
+	owner, err := linkOwner(ctx, s.us, userID, in.WorkspaceId)
 	if err != nil {
+		return &response, err
+	}
+
+	m, err := linkmeta.New(in.Title, in.Notes, in.Tags)
+	if err != nil {
+		return &response, status.Error(codes.InvalidArgument, codes.InvalidArgument.String())
+	}
+
+	refund, err := s.us.UseCreateQuota(userID, 1)
+	if err != nil {
+		return &response, rateLimitError(ctx, err)
+	}
+
+	token, err := s.us.Add(ctx, owner, in.Url, m)
+	if err != nil {
+		refund(1)
+		if urlcheck.IsInvalid(err) {
+			return &response, status.Error(codes.InvalidArgument, codes.InvalidArgument.String())
+		}
+		if errors.Is(err, screening.ErrBlocked) {
+			return &response, status.Error(codes.PermissionDenied, codes.PermissionDenied.String())
+		}
 		if !errors.Is(err, storage.ErrURLAlreadyExists) {
 			return &response, status.Error(codes.Internal, err.Error())
 		}
@@ -14,7 +14,9 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
 )
 
 func TestAdd(t *testing.T) {
@@ -26,7 +28,7 @@ func TestAdd(t *testing.T) {
 	testStorage := storage.NewMap()
 	_ = testStorage.Set(
 		"XXX-YYY-ZZZ",
-		"existsurl",
+		"http://exists.ru",
 		&tkn.Token{Value: "qwerty", Expire: time.Now().Add(tkn.LifeTime)},
 	)
 	testTokenGenerator := new(TestGenerator)
@@ -74,7 +76,7 @@ func TestAdd(t *testing.T) {
 			name: "exists",
 			request: &request{
 				userID:  "XXX-YYY-ZZZ",
-				request: &pb.AddRequest{Url: "existsurl"},
+				request: &pb.AddRequest{Url: "http://exists.ru"},
 			},
 			want: &pb.AddResponse{
 				ShortUrl: testConfig.BaseURL + testToken,
@@ -89,4 +91,12 @@ func TestAdd(t *testing.T) {
 			assert.Equal(t, tt.want.ShortUrl, resp.ShortUrl)
 		})
 	}
+	for _, url := range []string{"", "javascript:alert(1)", "ya.ru"} {
+		_, err = client.Add(getContextWithUserID("XXX-YYY-ZZZ", testEncryptor), &pb.AddRequest{Url: url})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err), url)
+	}
+	_, err = client.AddBatch(getContextWithUserID("XXX-YYY-ZZZ", testEncryptor), &pb.AddBatchRequest{
+		Urls: []*pb.AddBatchRequest_Url{{CorrelationId: "1", OriginalUrl: "mailto:me@ya.ru"}},
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
 }
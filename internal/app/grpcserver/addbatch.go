@@ -4,7 +4,10 @@ import (
 	"context"
 	"errors"
 
+	"github.com/alrund/yp-1/internal/app/linkmeta"
+	"github.com/alrund/yp-1/internal/app/screening"
 	"github.com/alrund/yp-1/internal/app/storage"
+	"github.com/alrund/yp-1/internal/app/urlcheck"
 	pb "github.com/alrund/yp-1/internal/proto"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -20,9 +23,34 @@ func (s *Server) AddBatch(ctx context.Context, in *pb.AddBatchRequest) (*pb.AddB
 		return &response, status.Error(codes.Internal, codes.Internal.String())
 	}
 
+	owner, err := linkOwner(ctx, s.us, userID, in.WorkspaceId)
+	if err != nil {
+		return &response, err
+	}
+
 	URLs, URL2Row := getURL2Row(in.Urls)
-	tokens, err := s.us.AddBatch(userID, URLs)
+	metas := make(map[string]linkmeta.Meta, len(URL2Row))
+	for URL, row := range URL2Row {
+		metas[URL], err = linkmeta.New(row.Title, row.Notes, row.Tags)
+		if err != nil {
+			return &response, status.Error(codes.InvalidArgument, codes.InvalidArgument.String())
+		}
+	}
+
+	refund, err := s.us.UseCreateQuota(userID, len(in.Urls))
 	if err != nil {
+		return &response, rateLimitError(ctx, err)
+	}
+
+	tokens, added, err := s.us.AddBatch(ctx, owner, URLs, metas)
+	refund(len(in.Urls) - added)
+	if err != nil {
+		if urlcheck.IsInvalid(err) {
+			return &response, status.Error(codes.InvalidArgument, codes.InvalidArgument.String())
+		}
+		if errors.Is(err, screening.ErrBlocked) {
+			return &response, status.Error(codes.PermissionDenied, codes.PermissionDenied.String())
+		}
 		if !errors.Is(err, storage.ErrURLAlreadyExists) {
 			return &response, status.Error(codes.Internal, err.Error())
 		}
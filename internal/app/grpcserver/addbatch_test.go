@@ -115,7 +115,7 @@ func TestAddBatchFail(t *testing.T) {
 	testStorage := storage.NewMap()
 	_ = testStorage.Set(
 		"XXX-YYY-ZZZ",
-		"existsurl",
+		"http://exists.ru",
 		&tkn.Token{Value: "qwerty", Expire: time.Now().Add(tkn.LifeTime)},
 	)
 	testTokenGenerator := new(TestGenerator)
@@ -157,7 +157,7 @@ func TestAddBatchFail(t *testing.T) {
 					Urls: []*pb.AddBatchRequest_Url{
 						{
 							CorrelationId: "6d6bb7ef-78a5-49cd-a043-95233a79b54d",
-							OriginalUrl:   "existsurl",
+							OriginalUrl:   "http://exists.ru",
 						},
 					},
 				},
@@ -0,0 +1,94 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+
+	"github.com/alrund/yp-1/internal/app"
+	"github.com/alrund/yp-1/internal/app/apikey"
+	"github.com/alrund/yp-1/internal/app/storage"
+	pb "github.com/alrund/yp-1/internal/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// CreateAPIKey creates a named API key of the user, the key is returned only once.
+func (s *Server) CreateAPIKey(ctx context.Context, in *pb.CreateAPIKeyRequest) (*pb.CreateAPIKeyResponse, error) {
+	var response pb.CreateAPIKeyResponse
+
+	contextUserID := ctx.Value(UserIDContextKey)
+	userID, ok := contextUserID.(string)
+	if !ok {
+		return &response, status.Error(codes.Internal, codes.Internal.String())
+	}
+
+	key, secret, err := s.us.CreateAPIKey(ctx, userID, in.Name)
+	if err != nil {
+		if errors.Is(err, app.ErrEmptyAPIKeyName) || errors.Is(err, app.ErrLongAPIKeyName) {
+			return &response, status.Error(codes.InvalidArgument, codes.InvalidArgument.String())
+		}
+		return &response, status.Error(codes.Internal, codes.Internal.String())
+	}
+
+	response.ApiKey = apiKeyMessage(key)
+	response.Key = secret
+
+	return &response, nil
+}
+
+// ListAPIKeys returns the API keys of the user.
+func (s *Server) ListAPIKeys(ctx context.Context, in *pb.ListAPIKeysRequest) (*pb.ListAPIKeysResponse, error) {
+	var response pb.ListAPIKeysResponse
+
+	contextUserID := ctx.Value(UserIDContextKey)
+	userID, ok := contextUserID.(string)
+	if !ok {
+		return &response, status.Error(codes.Internal, codes.Internal.String())
+	}
+
+	keys, err := s.us.GetAPIKeys(ctx, userID)
+	if err != nil {
+		return &response, status.Error(codes.Internal, codes.Internal.String())
+	}
+
+	for _, key := range keys {
+		response.ApiKeys = append(response.ApiKeys, apiKeyMessage(key))
+	}
+
+	return &response, nil
+}
+
+// RevokeAPIKey revokes the API key of the user.
+func (s *Server) RevokeAPIKey(ctx context.Context, in *pb.RevokeAPIKeyRequest) (*pb.RevokeAPIKeyResponse, error) {
+	var response pb.RevokeAPIKeyResponse
+
+	contextUserID := ctx.Value(UserIDContextKey)
+	userID, ok := contextUserID.(string)
+	if !ok {
+		return &response, status.Error(codes.Internal, codes.Internal.String())
+	}
+
+	if in.Id == "" {
+		return &response, status.Error(codes.InvalidArgument, codes.InvalidArgument.String())
+	}
+
+	err := s.us.RevokeAPIKey(ctx, userID, in.Id)
+	if err != nil {
+		if errors.Is(err, storage.ErrAPIKeyNotFound) {
+			return &response, status.Error(codes.NotFound, codes.NotFound.String())
+		}
+		return &response, status.Error(codes.Internal, codes.Internal.String())
+	}
+
+	return &response, nil
+}
+
+func apiKeyMessage(key *apikey.Key) *pb.APIKey {
+	return &pb.APIKey{
+		Id:      key.ID,
+		Name:    key.Name,
+		Hint:    key.Hint,
+		Created: key.Created.Unix(),
+		Revoked: key.Revoked,
+	}
+}
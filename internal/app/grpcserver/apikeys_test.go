@@ -0,0 +1,77 @@
+package grpcserver
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alrund/yp-1/internal/app"
+	"github.com/alrund/yp-1/internal/app/config"
+	"github.com/alrund/yp-1/internal/app/encryption"
+	"github.com/alrund/yp-1/internal/app/storage"
+	pb "github.com/alrund/yp-1/internal/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestAPIKeys(t *testing.T) {
+	testConfig := &config.Config{
+		GrpcServerAddress: "localhost:9090",
+		BaseURL:           "http://localhost:8080/",
+		CipherPass:        "PASS",
+	}
+	testEncryptor := encryption.NewEncryption(testConfig.CipherPass)
+	us := &app.URLShortener{
+		Config:         testConfig,
+		Storage:        storage.NewMap(),
+		TokenGenerator: new(TestGenerator),
+	}
+
+	conn, err := grpc.DialContext(
+		context.Background(),
+		testConfig.GrpcServerAddress,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(dialer(us)),
+	)
+	require.NoError(t, err)
+	defer conn.Close()
+	client := pb.NewAppClient(conn)
+
+	userCtx := getContextWithUserID("XXX-YYY-ZZZ", testEncryptor)
+
+	_, err = client.CreateAPIKey(userCtx, &pb.CreateAPIKeyRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	created, err := client.CreateAPIKey(userCtx, &pb.CreateAPIKeyRequest{Name: "bot"})
+	require.NoError(t, err)
+	assert.Equal(t, "bot", created.ApiKey.Name)
+	require.NotEmpty(t, created.Key)
+
+	// The key authenticates as its owner.
+	keyCtx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+created.Key)
+	_, err = client.Add(keyCtx, &pb.AddRequest{Url: "http://ya.ru"})
+	require.NoError(t, err)
+
+	urls, err := client.GetUserURLs(userCtx, &pb.GetUserURLsRequest{})
+	require.NoError(t, err)
+	require.Len(t, urls.Urls, 1)
+
+	listed, err := client.ListAPIKeys(keyCtx, &pb.ListAPIKeysRequest{})
+	require.NoError(t, err)
+	require.Len(t, listed.ApiKeys, 1)
+	assert.Equal(t, created.ApiKey.Id, listed.ApiKeys[0].Id)
+
+	otherCtx := getContextWithUserID("AAA-BBB-CCC", testEncryptor)
+	_, err = client.RevokeAPIKey(otherCtx, &pb.RevokeAPIKeyRequest{Id: created.ApiKey.Id})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = client.RevokeAPIKey(userCtx, &pb.RevokeAPIKeyRequest{Id: created.ApiKey.Id})
+	require.NoError(t, err)
+
+	_, err = client.Add(keyCtx, &pb.AddRequest{Url: "http://google.com"})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
@@ -0,0 +1,40 @@
+package grpcserver
+
+import (
+	"context"
+
+	"github.com/alrund/yp-1/internal/app/audit"
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// RequestIDKey the metadata key of the request ID, a missing one is generated and sent back.
+const RequestIDKey = "x-request-id"
+
+// AuditInterceptor puts the actor of the call into the context for the audit log.
+// It must run after the auth interceptor to see the user ID.
+func AuditInterceptor() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		var requestID string
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if values := md.Get(RequestIDKey); len(values) > 0 {
+				requestID = values[0]
+			}
+		}
+		if requestID == "" {
+			requestID = uuid.NewString()
+		}
+		_ = grpc.SetHeader(ctx, metadata.Pairs(RequestIDKey, requestID))
+
+		actor := audit.Actor{Source: audit.SourceGRPC, ClientIP: peerIP(ctx), RequestID: requestID}
+		actor.UserID, _ = ctx.Value(UserIDContextKey).(string)
+
+		return handler(audit.WithActor(ctx, actor), req)
+	}
+}
@@ -0,0 +1,39 @@
+package grpcserver
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/alrund/yp-1/internal/app/audit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+func TestAuditInterceptor(t *testing.T) {
+	var actor audit.Actor
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		actor = audit.ActorFromContext(ctx)
+		return nil, nil
+	}
+
+	ctx := context.WithValue(context.Background(), UserIDContextKey, "XXX-YYY-ZZZ")
+	ctx = peer.NewContext(ctx, &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 1234}})
+	ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(RequestIDKey, "req-1"))
+
+	_, err := AuditInterceptor()(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/app.App/Add"}, handler)
+	require.NoError(t, err)
+	assert.Equal(t, audit.Actor{
+		UserID:    "XXX-YYY-ZZZ",
+		Source:    audit.SourceGRPC,
+		ClientIP:  "10.0.0.1",
+		RequestID: "req-1",
+	}, actor)
+
+	_, err = AuditInterceptor()(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/app.App/Add"}, handler)
+	require.NoError(t, err)
+	assert.NotEmpty(t, actor.RequestID)
+}
@@ -2,15 +2,52 @@ package grpcserver
 
 import (
 	"context"
+	"errors"
+	"strings"
 
-	"github.com/alrund/yp-1/internal/app/encryption"
+	"github.com/alrund/yp-1/internal/app/apikey"
+	"github.com/alrund/yp-1/internal/app/session"
 	"github.com/google/uuid"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
-// AuthInterceptor authenticates the user.
-func AuthInterceptor(enc *encryption.Encryption) func(
+// APIKeyAuthenticator resolves an API key into the ID of its owner.
+type APIKeyAuthenticator interface {
+	AuthenticateAPIKey(ctx context.Context, secret string) (string, error)
+}
+
+// MethodScopes the scopes the methods require from the scoped session tokens.
+// The other methods are open to any token.
+var MethodScopes = map[string]string{
+	"/app.App/Add":                   session.ScopeURLs,
+	"/app.App/AddBatch":              session.ScopeURLs,
+	"/app.App/GetUserURLs":           session.ScopeURLs,
+	"/app.App/DeleteURLs":            session.ScopeURLs,
+	"/app.App/UpdateURL":             session.ScopeURLs,
+	"/app.App/ListTags":              session.ScopeURLs,
+	"/app.App/RenameTag":             session.ScopeURLs,
+	"/app.App/SearchURLs":            session.ScopeURLs,
+	"/app.App/GetLinkWebhook":        session.ScopeURLs,
+	"/app.App/SetLinkWebhook":        session.ScopeURLs,
+	"/app.App/RemoveLinkWebhook":     session.ScopeURLs,
+	"/app.App/CreateAPIKey":          session.ScopeKeys,
+	"/app.App/ListAPIKeys":           session.ScopeKeys,
+	"/app.App/RevokeAPIKey":          session.ScopeKeys,
+	"/app.App/CreateWorkspace":       session.ScopeWorkspaces,
+	"/app.App/ListWorkspaces":        session.ScopeWorkspaces,
+	"/app.App/ListWorkspaceMembers":  session.ScopeWorkspaces,
+	"/app.App/SetWorkspaceMember":    session.ScopeWorkspaces,
+	"/app.App/RemoveWorkspaceMember": session.ScopeWorkspaces,
+}
+
+// AuthInterceptor authenticates the user by the "authorization: Bearer <API key or session token>" metadata
+// or the user ID metadata. A new or re-issued session token is sent back in the response header metadata.
+// A scoped session token without the scope of the method in MethodScopes gets PermissionDenied.
+// apiKeys may be nil, then the API keys are not accepted.
+func AuthInterceptor(sessions session.Codec, apiKeys APIKeyAuthenticator) func(
 	ctx context.Context,
 	req interface{},
 	info *grpc.UnaryServerInfo,
@@ -22,28 +59,148 @@ func AuthInterceptor(enc *encryption.Encryption) func(
 		info *grpc.UnaryServerInfo,
 		handler grpc.UnaryHandler,
 	) (interface{}, error) {
-		var (
-			err    error
-			userID string
-		)
-
-		md, ok := metadata.FromIncomingContext(ctx)
-		if ok {
-			values := md.Get(string(UserIDContextKey))
-			if len(values) > 0 {
-				userID, err = enc.Decrypt(values[0])
-				if err != nil {
-					return nil, err
+		ctx, header, err := authenticate(ctx, sessions, apiKeys)
+		if err != nil {
+			return nil, err
+		}
+
+		if header != nil {
+			if err := grpc.SetHeader(ctx, header); err != nil {
+				return nil, err
+			}
+		}
+
+		scope, ok := MethodScopes[info.FullMethod]
+		if scopes, scoped := ctx.Value(ScopesContextKey).([]string); ok && scoped && !session.HasScope(scopes, scope) {
+			return nil, status.Error(codes.PermissionDenied, codes.PermissionDenied.String())
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// AuthStreamInterceptor authenticates the user of a stream like AuthInterceptor.
+func AuthStreamInterceptor(sessions session.Codec, apiKeys APIKeyAuthenticator) grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		ctx, header, err := authenticate(ss.Context(), sessions, apiKeys)
+		if err != nil {
+			return err
+		}
+
+		if header != nil {
+			if err := ss.SetHeader(header); err != nil {
+				return err
+			}
+		}
+
+		return handler(srv, &authServerStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+type authServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authServerStream) Context() context.Context {
+	return s.ctx
+}
+
+// authenticate puts the user ID and the scopes of a scoped session token into the context.
+// It returns the header metadata to send when the client has to store a new session token.
+// A token which can not be read, e.g. made with a removed key or expired, is replaced with a new one.
+func authenticate(
+	ctx context.Context,
+	sessions session.Codec,
+	apiKeys APIKeyAuthenticator,
+) (context.Context, metadata.MD, error) {
+	var (
+		token   string
+		userID  string
+		current bool
+	)
+
+	md, ok := metadata.FromIncomingContext(ctx)
+	if token, isBearer := bearerToken(md); ok && isBearer {
+		switch {
+		case !apikey.IsKey(token):
+			userID, _, err := sessions.Parse(token)
+			if err != nil {
+				return nil, nil, status.Error(codes.Unauthenticated, codes.Unauthenticated.String())
+			}
+			ctx, err = withScopes(ctx, sessions, token)
+			if err != nil {
+				return nil, nil, status.Error(codes.Unauthenticated, codes.Unauthenticated.String())
+			}
+			return context.WithValue(ctx, UserIDContextKey, userID), nil, nil
+		case apiKeys != nil:
+			userID, err := apiKeys.AuthenticateAPIKey(ctx, token)
+			if err != nil {
+				if errors.Is(err, apikey.ErrInvalid) {
+					return nil, nil, status.Error(codes.Unauthenticated, codes.Unauthenticated.String())
 				}
+				return nil, nil, status.Error(codes.Internal, codes.Internal.String())
 			}
+			return context.WithValue(ctx, UserIDContextKey, userID), nil, nil
 		}
+	}
 
-		if userID == "" {
-			userID = uuid.New().String()
+	if ok {
+		values := md.Get(string(UserIDContextKey))
+		if len(values) > 0 && values[0] != "" {
+			parsed, isCurrent, err := sessions.Parse(values[0])
+			if err == nil {
+				token, userID, current = values[0], parsed, isCurrent
+			}
 		}
+	}
 
-		ctx = context.WithValue(ctx, UserIDContextKey, userID)
+	if userID == "" {
+		userID = uuid.New().String()
+	}
 
-		return handler(ctx, req)
+	var header metadata.MD
+	if !current {
+		var err error
+		token, err = sessions.Issue(userID)
+		if err != nil {
+			return nil, nil, err
+		}
+		header = metadata.Pairs(string(UserIDContextKey), token)
+	}
+
+	ctx, err := withScopes(ctx, sessions, token)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return context.WithValue(ctx, UserIDContextKey, userID), header, nil
+}
+
+// withScopes puts the scopes granted by the session token into the context, the tokens of the other codecs
+// are not limited.
+func withScopes(ctx context.Context, sessions session.Codec, token string) (context.Context, error) {
+	scoped, ok := sessions.(session.Scoped)
+	if !ok {
+		return ctx, nil
+	}
+
+	scopes, err := scoped.ParseScopes(token)
+	if err != nil {
+		return nil, err
+	}
+	return context.WithValue(ctx, ScopesContextKey, scopes), nil
+}
+
+func bearerToken(md metadata.MD) (string, bool) {
+	values := md.Get("authorization")
+	if len(values) == 0 || !strings.HasPrefix(values[0], "Bearer ") {
+		return "", false
 	}
+	return strings.TrimPrefix(values[0], "Bearer "), true
 }
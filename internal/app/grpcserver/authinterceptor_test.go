@@ -0,0 +1,178 @@
+package grpcserver
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alrund/yp-1/internal/app"
+	"github.com/alrund/yp-1/internal/app/config"
+	"github.com/alrund/yp-1/internal/app/encryption"
+	"github.com/alrund/yp-1/internal/app/session"
+	"github.com/alrund/yp-1/internal/app/storage"
+	pb "github.com/alrund/yp-1/internal/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestAuthInterceptorIssuesIdentity(t *testing.T) {
+	testConfig := &config.Config{
+		GrpcServerAddress: "localhost:9090",
+		BaseURL:           "http://localhost:8080/",
+		CipherPass:        "PASS",
+	}
+	us := &app.URLShortener{
+		Config:         testConfig,
+		Storage:        storage.NewMap(),
+		TokenGenerator: new(TestGenerator),
+	}
+
+	conn, err := grpc.DialContext(
+		context.Background(),
+		testConfig.GrpcServerAddress,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(dialer(us)),
+	)
+	require.NoError(t, err)
+	defer conn.Close()
+	client := pb.NewAppClient(conn)
+
+	var header metadata.MD
+	_, err = client.Add(context.Background(), &pb.AddRequest{Url: "http://ya.ru"}, grpc.Header(&header))
+	require.NoError(t, err)
+
+	issued := header.Get(string(UserIDContextKey))
+	require.Len(t, issued, 1)
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), string(UserIDContextKey), issued[0])
+	header = nil
+	resp, err := client.GetUserURLs(ctx, &pb.GetUserURLsRequest{}, grpc.Header(&header))
+	require.NoError(t, err)
+	require.Len(t, resp.Urls, 1)
+	assert.Equal(t, "http://ya.ru", resp.Urls[0].OriginalUrl)
+	assert.Empty(t, header.Get(string(UserIDContextKey)), "a current identity is not issued again")
+
+	rotated := encryption.NewEncryption("NEWPASS", "PASS")
+	old, err := encryption.NewEncryption("PASS").Encrypt("XXX-YYY-ZZZ")
+	require.NoError(t, err)
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs(string(UserIDContextKey), old))
+	ctx, md, err := authenticate(ctx, session.NewEncrypted(rotated), nil)
+	require.NoError(t, err)
+	assert.Equal(t, "XXX-YYY-ZZZ", ctx.Value(UserIDContextKey))
+	require.Len(t, md.Get(string(UserIDContextKey)), 1)
+	assert.True(t, rotated.IsCurrent(md.Get(string(UserIDContextKey))[0]))
+}
+
+type testServerStream struct {
+	grpc.ServerStream
+	ctx    context.Context
+	header metadata.MD
+}
+
+func (s *testServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *testServerStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+func TestAuthStreamInterceptor(t *testing.T) {
+	enc := encryption.NewEncryption("PASS")
+	ss := &testServerStream{ctx: context.Background()}
+
+	var userID string
+	handler := func(srv interface{}, stream grpc.ServerStream) error {
+		userID, _ = stream.Context().Value(UserIDContextKey).(string)
+		return nil
+	}
+
+	err := AuthStreamInterceptor(session.NewEncrypted(enc), nil)(nil, ss, &grpc.StreamServerInfo{}, handler)
+	require.NoError(t, err)
+	require.NotEmpty(t, userID)
+
+	issued := ss.header.Get(string(UserIDContextKey))
+	require.Len(t, issued, 1)
+	decrypted, err := enc.Decrypt(issued[0])
+	require.NoError(t, err)
+	assert.Equal(t, userID, decrypted)
+}
+
+func TestAuthInterceptorJWT(t *testing.T) {
+	sessions, err := session.NewHS256([]byte("0123456789abcdef0123456789abcdef"), time.Hour)
+	require.NoError(t, err)
+	token, err := sessions.Issue("user-1")
+	require.NoError(t, err)
+
+	for _, md := range []metadata.MD{
+		metadata.Pairs("authorization", "Bearer "+token),
+		metadata.Pairs(string(UserIDContextKey), token),
+	} {
+		ctx, header, err := authenticate(metadata.NewIncomingContext(context.Background(), md), sessions, nil)
+		require.NoError(t, err)
+		assert.Equal(t, "user-1", ctx.Value(UserIDContextKey))
+		assert.Nil(t, header)
+	}
+
+	md := metadata.Pairs("authorization", "Bearer "+token+"x")
+	_, _, err = authenticate(metadata.NewIncomingContext(context.Background(), md), sessions, nil)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestAuthInterceptorScopes(t *testing.T) {
+	sessions, err := session.NewHS256([]byte("0123456789abcdef0123456789abcdef"), time.Hour)
+	require.NoError(t, err)
+	sessions.Scopes = []string{session.ScopeURLs}
+	token, err := sessions.Issue("user-1")
+	require.NoError(t, err)
+
+	interceptor := AuthInterceptor(sessions, nil)
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	}
+	call := func(md metadata.MD, method string) error {
+		ctx := grpc.NewContextWithServerTransportStream(
+			metadata.NewIncomingContext(context.Background(), md),
+			&testServerTransportStream{},
+		)
+		_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method}, handler)
+		return err
+	}
+
+	bearer := metadata.Pairs("authorization", "Bearer "+token)
+	assert.NoError(t, call(bearer, "/app.App/Add"))
+	assert.NoError(t, call(bearer, "/app.App/Ping"), "an unscoped method is open to any token")
+	assert.Equal(t, codes.PermissionDenied, status.Code(call(bearer, "/app.App/CreateAPIKey")))
+	assert.Equal(t, codes.PermissionDenied, status.Code(call(bearer, "/app.App/CreateWorkspace")))
+
+	cookie := metadata.Pairs(string(UserIDContextKey), token)
+	assert.NoError(t, call(cookie, "/app.App/GetUserURLs"))
+	assert.Equal(t, codes.PermissionDenied, status.Code(call(cookie, "/app.App/ListAPIKeys")))
+	assert.Equal(t, codes.PermissionDenied, status.Code(call(metadata.MD{}, "/app.App/ListWorkspaces")),
+		"a new user gets the configured scopes")
+
+	encrypted := session.NewEncrypted(encryption.NewEncryption("J53RPX6"))
+	encryptedToken, err := encrypted.Issue("user-1")
+	require.NoError(t, err)
+	_, err = AuthInterceptor(encrypted, nil)(
+		metadata.NewIncomingContext(context.Background(), metadata.Pairs(string(UserIDContextKey), encryptedToken)),
+		nil,
+		&grpc.UnaryServerInfo{FullMethod: "/app.App/CreateAPIKey"},
+		handler,
+	)
+	assert.NoError(t, err, "the encrypted tokens are not scoped")
+}
+
+// testServerTransportStream lets the interceptor set the header outside of a server.
+type testServerTransportStream struct{}
+
+func (s *testServerTransportStream) Method() string               { return "" }
+func (s *testServerTransportStream) SetHeader(metadata.MD) error  { return nil }
+func (s *testServerTransportStream) SendHeader(metadata.MD) error { return nil }
+func (s *testServerTransportStream) SetTrailer(metadata.MD) error { return nil }
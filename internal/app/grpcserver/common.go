@@ -7,6 +7,7 @@ import (
 
 	"github.com/alrund/yp-1/internal/app"
 	"github.com/alrund/yp-1/internal/app/encryption"
+	"github.com/alrund/yp-1/internal/app/session"
 	pb "github.com/alrund/yp-1/internal/proto"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/metadata"
@@ -17,6 +18,8 @@ type ContextKey string
 
 const (
 	UserIDContextKey ContextKey = "userID"
+	// ScopesContextKey the scopes granted to the call, missing when the credentials are not limited to scopes.
+	ScopesContextKey ContextKey = "scopes"
 	bufSize                     = 1024 * 1024
 )
 
@@ -29,8 +32,14 @@ func (st *TestGenerator) Generate() (string, error) {
 func dialer(us *app.URLShortener) func(context.Context, string) (net.Conn, error) {
 	listener := bufconn.Listen(bufSize)
 
-	enc := encryption.NewEncryption(us.Config.CipherPass)
-	server := grpc.NewServer(grpc.UnaryInterceptor(AuthInterceptor(enc)))
+	if us.Sessions == nil {
+		us.Sessions = session.NewEncrypted(encryption.NewEncryption(us.Config.CipherPass))
+	}
+	sessions := us.Sessions
+	server := grpc.NewServer(
+		grpc.UnaryInterceptor(AuthInterceptor(sessions, us)),
+		grpc.StreamInterceptor(AuthStreamInterceptor(sessions, us)),
+	)
 	pb.RegisterAppServer(server, New(us))
 
 	go func() {
@@ -2,7 +2,9 @@ package grpcserver
 
 import (
 	"context"
+	"errors"
 
+	"github.com/alrund/yp-1/internal/app"
 	pb "github.com/alrund/yp-1/internal/proto"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -23,9 +25,13 @@ func (s *Server) DeleteURLs(ctx context.Context, in *pb.DeleteURLsRequest) (*pb.
 		tokens = append(tokens, t.GetValue())
 	}
 
-	go func() {
-		_ = s.us.RemoveTokens(tokens, userID)
-	}()
+	err := s.us.RemoveTokensAsync(ctx, tokens, userID)
+	if err != nil {
+		if errors.Is(err, app.ErrRemoveQueueFull) || errors.Is(err, app.ErrRemoveQueueClosed) {
+			return &response, status.Error(codes.Unavailable, codes.Unavailable.String())
+		}
+		return &response, status.Error(codes.Internal, codes.Internal.String())
+	}
 
 	return &response, nil
 }
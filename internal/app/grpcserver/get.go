@@ -4,6 +4,9 @@ import (
 	"context"
 	"errors"
 
+	"github.com/alrund/yp-1/internal/app/linkmeta"
+	"github.com/alrund/yp-1/internal/app/listing"
+	"github.com/alrund/yp-1/internal/app/screening"
 	"github.com/alrund/yp-1/internal/app/storage"
 	"github.com/alrund/yp-1/internal/app/token"
 	pb "github.com/alrund/yp-1/internal/proto"
@@ -19,7 +22,7 @@ func (s *Server) Get(ctx context.Context, in *pb.GetRequest) (*pb.GetResponse, e
 		return &response, status.Error(codes.InvalidArgument, codes.InvalidArgument.String())
 	}
 
-	url, err := s.us.Get(in.Token)
+	url, err := s.us.Get(ctx, in.Token)
 	if err != nil {
 		if errors.Is(err, storage.ErrTokenNotFound) {
 			return &response, status.Error(codes.NotFound, codes.NotFound.String())
@@ -33,6 +36,12 @@ func (s *Server) Get(ctx context.Context, in *pb.GetRequest) (*pb.GetResponse, e
 			return &response, status.Error(codes.NotFound, codes.NotFound.String())
 		}
 
+		if errors.Is(err, screening.ErrBlocked) {
+			response.Url = url
+			response.Blocked = true
+			return &response, nil
+		}
+
 		return &response, status.Error(codes.Internal, codes.Internal.String())
 	}
 
@@ -41,7 +50,7 @@ func (s *Server) Get(ctx context.Context, in *pb.GetRequest) (*pb.GetResponse, e
 	return &response, nil
 }
 
-// GetUserURLs returns a URL by user ID.
+// GetUserURLs returns a page of the user's URLs with the cursor of the next page.
 func (s *Server) GetUserURLs(ctx context.Context, in *pb.GetUserURLsRequest) (*pb.GetUserURLsResponse, error) {
 	var response pb.GetUserURLsResponse
 
@@ -51,20 +60,54 @@ func (s *Server) GetUserURLs(ctx context.Context, in *pb.GetUserURLsRequest) (*p
 		return &response, status.Error(codes.Internal, codes.Internal.String())
 	}
 
-	urls, err := s.us.GetUserURLs(userID)
+	query := listing.Query{
+		Sort:     listing.Sort(in.Sort),
+		Status:   listing.Status(in.Status),
+		Contains: in.Contains,
+		Tag:      in.Tag,
+		Limit:    int(in.Limit),
+		Cursor:   in.Cursor,
+	}
+	urls, next, err := s.us.GetUserURLs(ctx, userID, query)
 	if err != nil {
 		if errors.Is(err, storage.ErrTokenNotFound) {
 			return &response, status.Error(codes.NotFound, codes.NotFound.String())
 		}
+		if listing.IsInvalid(err) || linkmeta.IsInvalid(err) {
+			return &response, status.Error(codes.InvalidArgument, codes.InvalidArgument.String())
+		}
 		return &response, status.Error(codes.Internal, codes.Internal.String())
 	}
 
 	for _, url := range urls {
-		response.Urls = append(response.Urls, &pb.GetUserURLsResponse_Url{
-			OriginalUrl: url.OriginalURL,
-			ShortUrl:    url.ShortURL,
-		})
+		response.Urls = append(response.Urls, newURL(url))
 	}
+	response.NextCursor = next
 
 	return &response, nil
 }
+
+func newURL(url storage.URLpairs) *pb.GetUserURLsResponse_Url {
+	u := &pb.GetUserURLsResponse_Url{
+		OriginalUrl: url.OriginalURL,
+		ShortUrl:    url.ShortURL,
+		WorkspaceId: url.WorkspaceID,
+		Expire:      url.Expire.Unix(),
+		Removed:     url.Removed,
+		Clicks:      url.Clicks,
+		Status:      string(url.Status),
+		Title:       url.Title,
+		Notes:       url.Notes,
+		Tags:        url.Tags,
+	}
+	if url.Created != nil {
+		u.Created = url.Created.Unix()
+	}
+	if url.Health != nil {
+		u.Checked = url.Health.Checked.Unix()
+		u.Reachable = url.Health.Reachable
+		u.StatusCode = int32(url.Health.StatusCode)
+		u.CheckError = url.Health.Error
+	}
+	return u
+}
@@ -2,12 +2,16 @@ package grpcserver
 
 import (
 	"context"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/alrund/yp-1/internal/app"
 	"github.com/alrund/yp-1/internal/app/config"
 	"github.com/alrund/yp-1/internal/app/encryption"
+	"github.com/alrund/yp-1/internal/app/linkcheck"
+	"github.com/alrund/yp-1/internal/app/screening"
 	"github.com/alrund/yp-1/internal/app/storage"
 	tkn "github.com/alrund/yp-1/internal/app/token"
 	pb "github.com/alrund/yp-1/internal/proto"
@@ -125,12 +129,21 @@ func TestGetUserURLs(t *testing.T) {
 		BaseURL:           "http://localhost:8080/",
 		CipherPass:        "PASS",
 	}
+	created := time.Now()
+	expire := created.Add(tkn.LifeTime)
 	testStorage := storage.NewMap()
 	_ = testStorage.Set(
 		"XXX-YYY-ZZZ",
 		"https://ya.ru",
-		&tkn.Token{Value: "qwerty", Expire: time.Now().Add(tkn.LifeTime)},
+		&tkn.Token{Value: "qwerty", Expire: expire},
+	)
+	_ = testStorage.Set(
+		"XXX-YYY-ZZZ",
+		"https://go.dev",
+		&tkn.Token{Value: "asdfgh", Expire: expire, Created: created, Removed: true},
 	)
+	_ = testStorage.AddClick("qwerty")
+	_ = testStorage.SetLinkHealth(map[string]linkcheck.Health{"qwerty": {Checked: created, StatusCode: 404}})
 	testTokenGenerator := new(TestGenerator)
 	testEncryptor := encryption.NewEncryption(testConfig.CipherPass)
 
@@ -170,14 +183,58 @@ func TestGetUserURLs(t *testing.T) {
 			},
 			want: &pb.GetUserURLsResponse{
 				Urls: []*pb.GetUserURLsResponse_Url{
+					{
+						OriginalUrl: "https://go.dev",
+						ShortUrl:    "http://localhost:8080/asdfgh",
+						Created:     created.Unix(),
+						Expire:      expire.Unix(),
+						Removed:     true,
+						Status:      "removed",
+					},
 					{
 						OriginalUrl: "https://ya.ru",
 						ShortUrl:    "http://localhost:8080/qwerty",
+						Expire:      expire.Unix(),
+						Clicks:      1,
+						Status:      "active",
+						Checked:     created.Unix(),
+						StatusCode:  404,
 					},
 				},
 			},
 			wantCode: codes.OK,
 		},
+		{
+			name: "page",
+			request: request{
+				userID:  "XXX-YYY-ZZZ",
+				request: &pb.GetUserURLsRequest{Limit: 1, Sort: "created"},
+			},
+			want: &pb.GetUserURLsResponse{
+				Urls: []*pb.GetUserURLsResponse_Url{
+					{
+						OriginalUrl: "https://ya.ru",
+						ShortUrl:    "http://localhost:8080/qwerty",
+						Expire:      expire.Unix(),
+						Clicks:      1,
+						Status:      "active",
+						Checked:     created.Unix(),
+						StatusCode:  404,
+					},
+				},
+				NextCursor: "Y3JlYXRlZAowCnF3ZXJ0eQ",
+			},
+			wantCode: codes.OK,
+		},
+		{
+			name: "invalid status",
+			request: request{
+				userID:  "XXX-YYY-ZZZ",
+				request: &pb.GetUserURLsRequest{Status: "lost"},
+			},
+			want:     &pb.GetUserURLsResponse{},
+			wantCode: codes.InvalidArgument,
+		},
 		{
 			name: "notfound",
 			request: request{
@@ -199,7 +256,58 @@ func TestGetUserURLs(t *testing.T) {
 				}
 			} else {
 				assert.Equal(t, tt.want.Urls, resp.Urls)
+				assert.Equal(t, tt.want.NextCursor, resp.NextCursor)
 			}
 		})
 	}
 }
+
+func TestGetBlocked(t *testing.T) {
+	testConfig := &config.Config{
+		GrpcServerAddress: "localhost:9090",
+		BaseURL:           "http://localhost:8080/",
+		CipherPass:        "PASS",
+	}
+	testEncryptor := encryption.NewEncryption(testConfig.CipherPass)
+	testStorage := storage.NewMap()
+	_ = testStorage.Set("XXX-YYY-ZZZ", "http://evil.com/page", &tkn.Token{Value: "qwerty", Expire: time.Now().Add(tkn.LifeTime)})
+
+	domainsFile := filepath.Join(t.TempDir(), "domains.txt")
+	_ = os.WriteFile(domainsFile, []byte("evil.com\n"), 0o600)
+	blocklist, err := screening.LoadBlocklist(domainsFile, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	us := &app.URLShortener{
+		Config:         testConfig,
+		Storage:        testStorage,
+		TokenGenerator: new(TestGenerator),
+		Screener:       &screening.Screener{Blocklist: blocklist},
+	}
+
+	conn, err := grpc.DialContext(
+		context.Background(),
+		testConfig.GrpcServerAddress,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(dialer(us)),
+	)
+	if err != nil {
+		t.Fatalf("Failed to dial bufnet: %v", err)
+	}
+	defer conn.Close()
+	client := pb.NewAppClient(conn)
+	ctx := getContextWithUserID("XXX-YYY-ZZZ", testEncryptor)
+
+	resp, err := client.Get(ctx, &pb.GetRequest{Token: "qwerty"})
+	assert.NoError(t, err)
+	assert.Equal(t, "http://evil.com/page", resp.Url)
+	assert.True(t, resp.Blocked)
+
+	_, err = client.Add(ctx, &pb.AddRequest{Url: "http://sub.evil.com/"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = client.AddBatch(ctx, &pb.AddBatchRequest{
+		Urls: []*pb.AddBatchRequest_Url{{CorrelationId: "1", OriginalUrl: "http://evil.com/other"}},
+	})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
@@ -0,0 +1,35 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+
+	"github.com/alrund/yp-1/internal/app"
+	pb "github.com/alrund/yp-1/internal/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// GetBatch resolves multiple tokens at once, without counting the clicks.
+func (s *Server) GetBatch(ctx context.Context, in *pb.GetBatchRequest) (*pb.GetBatchResponse, error) {
+	var response pb.GetBatchResponse
+
+	resolved, err := s.us.GetBatch(ctx, in.Tokens)
+	if err != nil {
+		if errors.Is(err, app.ErrEmptyResolveBatch) || errors.Is(err, app.ErrLongResolveBatch) {
+			return &response, status.Error(codes.InvalidArgument, codes.InvalidArgument.String())
+		}
+		return &response, status.Error(codes.Internal, codes.Internal.String())
+	}
+
+	response.Results = make([]*pb.GetBatchResponse_Result, 0, len(resolved))
+	for _, r := range resolved {
+		response.Results = append(response.Results, &pb.GetBatchResponse_Result{
+			Token:  r.Token,
+			Url:    r.URL,
+			Status: string(r.Status),
+		})
+	}
+
+	return &response, nil
+}
@@ -0,0 +1,70 @@
+package grpcserver
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alrund/yp-1/internal/app"
+	"github.com/alrund/yp-1/internal/app/config"
+	"github.com/alrund/yp-1/internal/app/encryption"
+	"github.com/alrund/yp-1/internal/app/storage"
+	tkn "github.com/alrund/yp-1/internal/app/token"
+	pb "github.com/alrund/yp-1/internal/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+func TestGetBatch(t *testing.T) {
+	testConfig := &config.Config{
+		GrpcServerAddress: "localhost:9090",
+		BaseURL:           "http://localhost:8080/",
+		CipherPass:        "PASS",
+	}
+	testEncryptor := encryption.NewEncryption(testConfig.CipherPass)
+	preparedStorage := storage.NewMap()
+	require.NoError(t, preparedStorage.Set("XXX-YYY-ZZZ", "http://ya.ru", &tkn.Token{
+		Value: "qwerty", Expire: time.Now().Add(tkn.LifeTime),
+	}))
+	require.NoError(t, preparedStorage.Set("XXX-YYY-ZZZ", "http://old.ru", &tkn.Token{
+		Value: "asdfgh", Expire: time.Now().Add(-time.Hour),
+	}))
+	us := &app.URLShortener{
+		Config:         testConfig,
+		Storage:        preparedStorage,
+		TokenGenerator: new(TestGenerator),
+	}
+
+	conn, err := grpc.DialContext(
+		context.Background(),
+		testConfig.GrpcServerAddress,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(dialer(us)),
+	)
+	require.NoError(t, err)
+	defer conn.Close()
+	client := pb.NewAppClient(conn)
+
+	ctx := getContextWithUserID("AAA-BBB-CCC", testEncryptor)
+
+	_, err = client.GetBatch(ctx, &pb.GetBatchRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.GetBatch(ctx, &pb.GetBatchRequest{Tokens: make([]string, app.MaxResolveBatch+1)})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	response, err := client.GetBatch(ctx, &pb.GetBatchRequest{Tokens: []string{"asdfgh", "unknown", "qwerty"}})
+	require.NoError(t, err)
+	require.Len(t, response.Results, 3)
+	assert.Equal(t, "asdfgh", response.Results[0].Token)
+	assert.Equal(t, "expired", response.Results[0].Status)
+	assert.Empty(t, response.Results[0].Url)
+	assert.Equal(t, "not_found", response.Results[1].Status)
+	assert.Equal(t, "qwerty", response.Results[2].Token)
+	assert.Equal(t, "ok", response.Results[2].Status)
+	assert.Equal(t, "http://ya.ru", response.Results[2].Url)
+}
@@ -0,0 +1,33 @@
+package grpcserver
+
+import (
+	"context"
+	"time"
+
+	"github.com/alrund/yp-1/internal/app"
+	pb "github.com/alrund/yp-1/internal/proto"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// WatchHealth keeps the serving status of the gRPC health service in sync with the application readiness.
+func WatchHealth(ctx context.Context, us *app.URLShortener, hs *grpchealth.Server, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		servingStatus := healthpb.HealthCheckResponse_SERVING
+		if !us.Readiness(ctx).IsUp() {
+			servingStatus = healthpb.HealthCheckResponse_NOT_SERVING
+		}
+		hs.SetServingStatus("", servingStatus)
+		hs.SetServingStatus(pb.App_ServiceDesc.ServiceName, servingStatus)
+
+		select {
+		case <-ctx.Done():
+			hs.Shutdown()
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package grpcserver
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alrund/yp-1/internal/app"
+	"github.com/alrund/yp-1/internal/app/config"
+	"github.com/alrund/yp-1/internal/app/storage"
+	pb "github.com/alrund/yp-1/internal/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestWatchHealth(t *testing.T) {
+	fullQueue := app.NewRemoveQueue(1, 1)
+	_ = fullQueue.Push(context.Background(), []string{"qwerty"}, "XXX-YYY-ZZZ")
+
+	tests := []struct {
+		name        string
+		removeQueue *app.RemoveQueue
+		want        healthpb.HealthCheckResponse_ServingStatus
+	}{
+		{
+			name:        "serving",
+			removeQueue: app.NewRemoveQueue(10, 1),
+			want:        healthpb.HealthCheckResponse_SERVING,
+		},
+		{
+			name:        "not serving - remove queue is full",
+			removeQueue: fullQueue,
+			want:        healthpb.HealthCheckResponse_NOT_SERVING,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			us := &app.URLShortener{
+				Config:         &config.Config{GrpcServerAddress: "localhost:9090"},
+				Storage:        storage.NewMap(),
+				TokenGenerator: new(TestGenerator),
+				RemoveQueue:    tt.removeQueue,
+			}
+			hs := grpchealth.NewServer()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan struct{})
+			go func() {
+				WatchHealth(ctx, us, hs, time.Hour)
+				close(done)
+			}()
+
+			assert.Eventually(t, func() bool {
+				resp, err := hs.Check(context.Background(), &healthpb.HealthCheckRequest{
+					Service: pb.App_ServiceDesc.ServiceName,
+				})
+				return err == nil && resp.Status == tt.want
+			}, time.Second, 10*time.Millisecond)
+
+			cancel()
+			<-done
+
+			resp, err := hs.Check(context.Background(), &healthpb.HealthCheckRequest{})
+			require.Nil(t, err)
+			assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, resp.Status)
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+
+	"github.com/alrund/yp-1/internal/app"
+	pb "github.com/alrund/yp-1/internal/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// GetLinkWebhook returns the user's link check webhook, the URL is empty when the user has none.
+func (s *Server) GetLinkWebhook(ctx context.Context, in *pb.GetLinkWebhookRequest) (*pb.GetLinkWebhookResponse, error) {
+	var response pb.GetLinkWebhookResponse
+
+	contextUserID := ctx.Value(UserIDContextKey)
+	userID, ok := contextUserID.(string)
+	if !ok {
+		return &response, status.Error(codes.Internal, codes.Internal.String())
+	}
+
+	webhookURL, err := s.us.GetLinkWebhook(ctx, userID)
+	if err != nil {
+		return &response, status.Error(codes.Internal, codes.Internal.String())
+	}
+
+	response.Url = webhookURL
+
+	return &response, nil
+}
+
+// SetLinkWebhook registers the user's link check webhook, replacing the former one.
+func (s *Server) SetLinkWebhook(ctx context.Context, in *pb.SetLinkWebhookRequest) (*pb.SetLinkWebhookResponse, error) {
+	var response pb.SetLinkWebhookResponse
+
+	contextUserID := ctx.Value(UserIDContextKey)
+	userID, ok := contextUserID.(string)
+	if !ok {
+		return &response, status.Error(codes.Internal, codes.Internal.String())
+	}
+
+	err := s.us.SetLinkWebhook(ctx, userID, in.Url)
+	if err != nil {
+		if errors.Is(err, app.ErrInvalidWebhookURL) {
+			return &response, status.Error(codes.InvalidArgument, codes.InvalidArgument.String())
+		}
+		return &response, status.Error(codes.Internal, codes.Internal.String())
+	}
+
+	return &response, nil
+}
+
+// RemoveLinkWebhook removes the user's link check webhook.
+func (s *Server) RemoveLinkWebhook(
+	ctx context.Context,
+	in *pb.RemoveLinkWebhookRequest,
+) (*pb.RemoveLinkWebhookResponse, error) {
+	var response pb.RemoveLinkWebhookResponse
+
+	contextUserID := ctx.Value(UserIDContextKey)
+	userID, ok := contextUserID.(string)
+	if !ok {
+		return &response, status.Error(codes.Internal, codes.Internal.String())
+	}
+
+	if err := s.us.RemoveLinkWebhook(ctx, userID); err != nil {
+		return &response, status.Error(codes.Internal, codes.Internal.String())
+	}
+
+	return &response, nil
+}
@@ -0,0 +1,66 @@
+package grpcserver
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alrund/yp-1/internal/app"
+	"github.com/alrund/yp-1/internal/app/config"
+	"github.com/alrund/yp-1/internal/app/encryption"
+	"github.com/alrund/yp-1/internal/app/storage"
+	pb "github.com/alrund/yp-1/internal/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+func TestLinkWebhook(t *testing.T) {
+	testConfig := &config.Config{
+		GrpcServerAddress: "localhost:9090",
+		BaseURL:           "http://localhost:8080/",
+		CipherPass:        "PASS",
+	}
+	testEncryptor := encryption.NewEncryption(testConfig.CipherPass)
+	us := &app.URLShortener{
+		Config:         testConfig,
+		Storage:        storage.NewMap(),
+		TokenGenerator: new(TestGenerator),
+	}
+
+	conn, err := grpc.DialContext(
+		context.Background(),
+		testConfig.GrpcServerAddress,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(dialer(us)),
+	)
+	require.NoError(t, err)
+	defer conn.Close()
+	client := pb.NewAppClient(conn)
+
+	ctx := getContextWithUserID("XXX-YYY-ZZZ", testEncryptor)
+	otherCtx := getContextWithUserID("AAA-BBB-CCC", testEncryptor)
+
+	_, err = client.SetLinkWebhook(ctx, &pb.SetLinkWebhookRequest{Url: "hooks.example.com"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.SetLinkWebhook(ctx, &pb.SetLinkWebhookRequest{Url: "https://hooks.example.com/links"})
+	require.NoError(t, err)
+
+	webhook, err := client.GetLinkWebhook(ctx, &pb.GetLinkWebhookRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, "https://hooks.example.com/links", webhook.Url)
+
+	webhook, err = client.GetLinkWebhook(otherCtx, &pb.GetLinkWebhookRequest{})
+	require.NoError(t, err)
+	assert.Empty(t, webhook.Url, "the webhook is the user's own")
+
+	_, err = client.RemoveLinkWebhook(ctx, &pb.RemoveLinkWebhookRequest{})
+	require.NoError(t, err)
+
+	webhook, err = client.GetLinkWebhook(ctx, &pb.GetLinkWebhookRequest{})
+	require.NoError(t, err)
+	assert.Empty(t, webhook.Url)
+}
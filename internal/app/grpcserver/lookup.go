@@ -0,0 +1,38 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+
+	"github.com/alrund/yp-1/internal/app/storage"
+	"github.com/alrund/yp-1/internal/app/urlcheck"
+	pb "github.com/alrund/yp-1/internal/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// LookupURL returns the short URL of an original URL, neither creating nor refreshing the link.
+func (s *Server) LookupURL(ctx context.Context, in *pb.LookupURLRequest) (*pb.LookupURLResponse, error) {
+	var response pb.LookupURLResponse
+
+	if in.Url == "" {
+		return &response, status.Error(codes.InvalidArgument, codes.InvalidArgument.String())
+	}
+
+	pair, err := s.us.LookupURL(ctx, in.Url)
+	if err != nil {
+		if urlcheck.IsInvalid(err) {
+			return &response, status.Error(codes.InvalidArgument, codes.InvalidArgument.String())
+		}
+		if errors.Is(err, storage.ErrTokenNotFound) {
+			return &response, status.Error(codes.NotFound, codes.NotFound.String())
+		}
+		return &response, status.Error(codes.Internal, codes.Internal.String())
+	}
+
+	response.ShortUrl = pair.ShortURL
+	response.Status = string(pair.Status)
+	response.Expire = pair.Expire.Unix()
+
+	return &response, nil
+}
@@ -0,0 +1,63 @@
+package grpcserver
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alrund/yp-1/internal/app"
+	"github.com/alrund/yp-1/internal/app/config"
+	"github.com/alrund/yp-1/internal/app/encryption"
+	"github.com/alrund/yp-1/internal/app/storage"
+	tkn "github.com/alrund/yp-1/internal/app/token"
+	pb "github.com/alrund/yp-1/internal/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+func TestLookupURL(t *testing.T) {
+	testConfig := &config.Config{
+		GrpcServerAddress: "localhost:9090",
+		BaseURL:           "http://localhost:8080/",
+		CipherPass:        "PASS",
+	}
+	testEncryptor := encryption.NewEncryption(testConfig.CipherPass)
+	expire := time.Now().Add(-time.Hour).Unix()
+	preparedStorage := storage.NewMap()
+	require.NoError(t, preparedStorage.Set("XXX-YYY-ZZZ", "http://ya.ru", &tkn.Token{
+		Value: "qwerty", Expire: time.Unix(expire, 0), Removed: true,
+	}))
+	us := &app.URLShortener{
+		Config:         testConfig,
+		Storage:        preparedStorage,
+		TokenGenerator: new(TestGenerator),
+	}
+
+	conn, err := grpc.DialContext(
+		context.Background(),
+		testConfig.GrpcServerAddress,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(dialer(us)),
+	)
+	require.NoError(t, err)
+	defer conn.Close()
+	client := pb.NewAppClient(conn)
+
+	ctx := getContextWithUserID("AAA-BBB-CCC", testEncryptor)
+
+	_, err = client.LookupURL(ctx, &pb.LookupURLRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.LookupURL(ctx, &pb.LookupURLRequest{Url: "http://lost.ru"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	found, err := client.LookupURL(ctx, &pb.LookupURLRequest{Url: "http://ya.ru"})
+	require.NoError(t, err)
+	assert.Equal(t, "http://localhost:8080/qwerty", found.ShortUrl)
+	assert.Equal(t, "removed", found.Status)
+	assert.Equal(t, expire, found.Expire)
+}
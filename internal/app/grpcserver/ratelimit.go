@@ -0,0 +1,87 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+
+	"github.com/alrund/yp-1/internal/app/ratelimit"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// RetryAfterKey the metadata key of the seconds to wait after a rejected call.
+const RetryAfterKey = "retry-after"
+
+// MethodClasses the route classes of the rate limited methods.
+var MethodClasses = map[string]ratelimit.Class{
+	"/app.App/Add":         ratelimit.ClassCreate,
+	"/app.App/AddBatch":    ratelimit.ClassCreate,
+	"/app.App/Get":         ratelimit.ClassRedirect,
+	"/app.App/GetBatch":    ratelimit.ClassRedirect,
+	"/app.App/LookupURL":   ratelimit.ClassRedirect,
+	"/app.App/GetUserURLs": ratelimit.ClassList,
+	"/app.App/SearchURLs":  ratelimit.ClassList,
+	"/app.App/DeleteURLs":  ratelimit.ClassDelete,
+}
+
+// RateLimitInterceptor limits the calls of the classified methods by the user ID and by the peer IP.
+// It must run after the auth interceptor to see the user ID. A nil limiter lets everything pass.
+func RateLimitInterceptor(limiter *ratelimit.Limiter, classes map[string]ratelimit.Class) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		class, ok := classes[info.FullMethod]
+		if limiter == nil || !ok {
+			return handler(ctx, req)
+		}
+
+		if err := limiter.Allow(class, rateLimitKeys(ctx)...); err != nil {
+			return nil, rateLimitError(ctx, err)
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// rateLimitError returns ResourceExhausted with the retry-after header for an exceeded limit.
+func rateLimitError(ctx context.Context, err error) error {
+	if !errors.Is(err, ratelimit.ErrExceeded) {
+		return status.Error(codes.Internal, codes.Internal.String())
+	}
+
+	_ = grpc.SetHeader(ctx, metadata.Pairs(RetryAfterKey, strconv.Itoa(ratelimit.RetryAfter(err))))
+	return status.Error(codes.ResourceExhausted, codes.ResourceExhausted.String())
+}
+
+func rateLimitKeys(ctx context.Context) []string {
+	keys := make([]string, 0, 2)
+	if userID, ok := ctx.Value(UserIDContextKey).(string); ok && userID != "" {
+		keys = append(keys, "user:"+userID)
+	}
+	if ip := peerIP(ctx); ip != "" {
+		keys = append(keys, "ip:"+ip)
+	}
+	return keys
+}
+
+// peerIP returns the IP of the caller, empty if it is unknown.
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
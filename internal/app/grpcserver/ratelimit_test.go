@@ -0,0 +1,97 @@
+package grpcserver
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/alrund/yp-1/internal/app"
+	"github.com/alrund/yp-1/internal/app/config"
+	"github.com/alrund/yp-1/internal/app/encryption"
+	"github.com/alrund/yp-1/internal/app/ratelimit"
+	"github.com/alrund/yp-1/internal/app/storage"
+	pb "github.com/alrund/yp-1/internal/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func TestRateLimitInterceptor(t *testing.T) {
+	limiter := ratelimit.New(storage.NewMap(), map[ratelimit.Class]ratelimit.Limit{
+		ratelimit.ClassRedirect: {Rate: 1, Burst: 1},
+	}, 0)
+	interceptor := RateLimitInterceptor(limiter, MethodClasses)
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	}
+	call := func(method, userID, ip string) error {
+		ctx := context.WithValue(context.Background(), UserIDContextKey, userID)
+		ctx = peer.NewContext(ctx, &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP(ip), Port: 1234}})
+		_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method}, handler)
+		return err
+	}
+
+	require.NoError(t, call("/app.App/Get", "user-1", "10.0.0.1"))
+	assert.Equal(t, codes.ResourceExhausted, status.Code(call("/app.App/Get", "user-1", "10.0.0.2")))
+	assert.Equal(t, codes.ResourceExhausted, status.Code(call("/app.App/Get", "user-2", "10.0.0.1")))
+	assert.NoError(t, call("/app.App/Get", "user-2", "10.0.0.2"))
+	assert.NoError(t, call("/app.App/Ping", "user-1", "10.0.0.1"), "an unclassified method is unlimited")
+}
+
+func TestCreateQuota(t *testing.T) {
+	testConfig := &config.Config{
+		GrpcServerAddress: "localhost:9090",
+		BaseURL:           "http://localhost:8080/",
+		CipherPass:        "PASS",
+	}
+	us := &app.URLShortener{
+		Config:         testConfig,
+		Storage:        storage.NewMap(),
+		TokenGenerator: new(TestGenerator),
+		RateLimiter:    ratelimit.New(storage.NewMap(), nil, 2),
+	}
+
+	conn, err := grpc.DialContext(
+		context.Background(),
+		testConfig.GrpcServerAddress,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(dialer(us)),
+	)
+	require.NoError(t, err)
+	defer conn.Close()
+	client := pb.NewAppClient(conn)
+	ctx := getContextWithUserID("XXX-YYY-ZZZ", encryption.NewEncryption(testConfig.CipherPass))
+
+	_, err = client.AddBatch(ctx, &pb.AddBatchRequest{Urls: []*pb.AddBatchRequest_Url{
+		{CorrelationId: "1", OriginalUrl: "http://ya.ru"},
+		{CorrelationId: "2", OriginalUrl: "http://ya2.ru"},
+		{CorrelationId: "3", OriginalUrl: "http://ya3.ru"},
+	}})
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	// The same URL makes a single link, the other one is given back to the quota.
+	_, err = client.AddBatch(ctx, &pb.AddBatchRequest{Urls: []*pb.AddBatchRequest_Url{
+		{CorrelationId: "1", OriginalUrl: "http://ya.ru"},
+		{CorrelationId: "2", OriginalUrl: "http://YA.ru"},
+	}})
+	require.NoError(t, err)
+
+	// The rejected and the existing links are given back to the quota.
+	_, err = client.Add(ctx, &pb.AddRequest{Url: ""})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = client.Add(ctx, &pb.AddRequest{Url: "http://ya.ru"})
+	require.NoError(t, err)
+
+	var header metadata.MD
+	_, err = client.Add(ctx, &pb.AddRequest{Url: "http://ya2.ru"}, grpc.Header(&header))
+	require.NoError(t, err)
+
+	_, err = client.Add(ctx, &pb.AddRequest{Url: "http://ya3.ru"}, grpc.Header(&header))
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Len(t, header.Get(RetryAfterKey), 1)
+}
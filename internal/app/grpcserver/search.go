@@ -0,0 +1,35 @@
+package grpcserver
+
+import (
+	"context"
+
+	"github.com/alrund/yp-1/internal/app/search"
+	pb "github.com/alrund/yp-1/internal/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// SearchURLs returns the user's links matching the query, from the most relevant.
+func (s *Server) SearchURLs(ctx context.Context, in *pb.SearchURLsRequest) (*pb.SearchURLsResponse, error) {
+	var response pb.SearchURLsResponse
+
+	contextUserID := ctx.Value(UserIDContextKey)
+	userID, ok := contextUserID.(string)
+	if !ok {
+		return &response, status.Error(codes.Internal, codes.Internal.String())
+	}
+
+	urls, err := s.us.SearchURLs(ctx, userID, search.Query{Text: in.Q, Limit: int(in.Limit)})
+	if err != nil {
+		if search.IsInvalid(err) {
+			return &response, status.Error(codes.InvalidArgument, codes.InvalidArgument.String())
+		}
+		return &response, status.Error(codes.Internal, codes.Internal.String())
+	}
+
+	for _, url := range urls {
+		response.Urls = append(response.Urls, newURL(url))
+	}
+
+	return &response, nil
+}
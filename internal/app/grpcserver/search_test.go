@@ -0,0 +1,64 @@
+package grpcserver
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alrund/yp-1/internal/app"
+	"github.com/alrund/yp-1/internal/app/config"
+	"github.com/alrund/yp-1/internal/app/encryption"
+	"github.com/alrund/yp-1/internal/app/storage"
+	pb "github.com/alrund/yp-1/internal/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+func TestSearchURLs(t *testing.T) {
+	testConfig := &config.Config{
+		GrpcServerAddress: "localhost:9090",
+		BaseURL:           "http://localhost:8080/",
+		CipherPass:        "PASS",
+	}
+	testEncryptor := encryption.NewEncryption(testConfig.CipherPass)
+	us := &app.URLShortener{
+		Config:         testConfig,
+		Storage:        storage.NewMap(),
+		TokenGenerator: new(TestGenerator),
+	}
+
+	conn, err := grpc.DialContext(
+		context.Background(),
+		testConfig.GrpcServerAddress,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(dialer(us)),
+	)
+	require.NoError(t, err)
+	defer conn.Close()
+	client := pb.NewAppClient(conn)
+
+	ctx := getContextWithUserID("XXX-YYY-ZZZ", testEncryptor)
+
+	_, err = client.Add(ctx, &pb.AddRequest{Url: "http://ya.ru", Title: "Yandex", Tags: []string{"search"}})
+	require.NoError(t, err)
+
+	_, err = client.SearchURLs(ctx, &pb.SearchURLsRequest{Q: " "})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.SearchURLs(ctx, &pb.SearchURLsRequest{Q: "ya", Limit: -1})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	found, err := client.SearchURLs(ctx, &pb.SearchURLsRequest{Q: "yandex sea"})
+	require.NoError(t, err)
+	require.Len(t, found.Urls, 1)
+	assert.Equal(t, "http://ya.ru", found.Urls[0].OriginalUrl)
+	assert.Equal(t, "http://localhost:8080/qwerty", found.Urls[0].ShortUrl)
+	assert.Equal(t, []string{"search"}, found.Urls[0].Tags)
+
+	found, err = client.SearchURLs(getContextWithUserID("AAA-BBB-CCC", testEncryptor), &pb.SearchURLsRequest{Q: "ya"})
+	require.NoError(t, err)
+	assert.Empty(t, found.Urls)
+}
@@ -0,0 +1,68 @@
+package grpcserver
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+const (
+	ServiceContextKey ContextKey = "service"
+
+	// PermissionStats allows Stats without the trusted subnet check.
+	PermissionStats = "stats"
+)
+
+// ServiceIdentity an internal caller authenticated by its client certificate.
+type ServiceIdentity struct {
+	Name        string
+	Permissions map[string]bool
+}
+
+// Can reports whether the service has the permission.
+func (si *ServiceIdentity) Can(permission string) bool {
+	return si != nil && si.Permissions[permission]
+}
+
+// ServiceFromContext returns the calling service, nil for regular users.
+func ServiceFromContext(ctx context.Context) *ServiceIdentity {
+	si, _ := ctx.Value(ServiceContextKey).(*ServiceIdentity)
+	return si
+}
+
+// ServiceIdentityInterceptor identifies the caller by the subject common name of its verified client certificate.
+// services maps the common names to their permissions, unknown services get no permissions.
+func ServiceIdentityInterceptor(services map[string][]string) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		if name, ok := clientCertName(ctx); ok {
+			si := &ServiceIdentity{Name: name, Permissions: make(map[string]bool)}
+			for _, permission := range services[name] {
+				si.Permissions[permission] = true
+			}
+			ctx = context.WithValue(ctx, ServiceContextKey, si)
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+func clientCertName(ctx context.Context) (string, bool) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return "", false
+	}
+
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(tlsInfo.State.VerifiedChains) == 0 || len(tlsInfo.State.VerifiedChains[0]) == 0 {
+		return "", false
+	}
+
+	return tlsInfo.State.VerifiedChains[0][0].Subject.CommonName, true
+}
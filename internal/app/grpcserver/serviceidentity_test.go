@@ -0,0 +1,113 @@
+package grpcserver
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"testing"
+	"time"
+
+	"github.com/alrund/yp-1/internal/app"
+	"github.com/alrund/yp-1/internal/app/config"
+	"github.com/alrund/yp-1/internal/app/storage"
+	tkn "github.com/alrund/yp-1/internal/app/token"
+	pb "github.com/alrund/yp-1/internal/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func getContextWithClientCert(commonName string) context.Context {
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: commonName}}
+	return peer.NewContext(context.Background(), &peer.Peer{
+		AuthInfo: credentials.TLSInfo{
+			State: tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}},
+		},
+	})
+}
+
+func TestServiceIdentityInterceptor(t *testing.T) {
+	services := map[string][]string{"collector": {PermissionStats}}
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want *ServiceIdentity
+	}{
+		{
+			name: "no client certificate",
+			ctx:  context.Background(),
+			want: nil,
+		},
+		{
+			name: "unverified client certificate",
+			ctx: peer.NewContext(context.Background(), &peer.Peer{
+				AuthInfo: credentials.TLSInfo{},
+			}),
+			want: nil,
+		},
+		{
+			name: "known service",
+			ctx:  getContextWithClientCert("collector"),
+			want: &ServiceIdentity{Name: "collector", Permissions: map[string]bool{PermissionStats: true}},
+		},
+		{
+			name: "unknown service",
+			ctx:  getContextWithClientCert("stranger"),
+			want: &ServiceIdentity{Name: "stranger", Permissions: map[string]bool{}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got *ServiceIdentity
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				got = ServiceFromContext(ctx)
+				return nil, nil
+			}
+
+			_, err := ServiceIdentityInterceptor(services)(tt.ctx, nil, &grpc.UnaryServerInfo{}, handler)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestStatsServiceIdentity(t *testing.T) {
+	testStorage := storage.NewMap()
+	_ = testStorage.Set(
+		"XXX-YYY-ZZZ",
+		"https://ya.ru",
+		&tkn.Token{Value: "qwerty", Expire: time.Now().Add(tkn.LifeTime)},
+	)
+
+	us := &app.URLShortener{
+		Config: &config.Config{
+			GrpcServerAddress: "localhost:9090",
+			BaseURL:           "http://localhost:8080/",
+		},
+		Storage:        testStorage,
+		TokenGenerator: new(TestGenerator),
+	}
+	s := New(us)
+
+	ctx := context.WithValue(context.Background(), ServiceContextKey, &ServiceIdentity{
+		Name:        "collector",
+		Permissions: map[string]bool{PermissionStats: true},
+	})
+	resp, err := s.Stats(ctx, &pb.StatsRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), resp.Users)
+	assert.Equal(t, int32(1), resp.Urls)
+
+	ctx = context.WithValue(context.Background(), ServiceContextKey, &ServiceIdentity{
+		Name:        "stranger",
+		Permissions: map[string]bool{},
+	})
+	_, err = s.Stats(ctx, &pb.StatsRequest{})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
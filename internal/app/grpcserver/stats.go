@@ -13,30 +13,13 @@ import (
 func (s *Server) Stats(ctx context.Context, in *pb.StatsRequest) (*pb.StatsResponse, error) {
 	var response pb.StatsResponse
 
-	cfg := s.us.GetConfig()
-	if cfg.TrustedSubnet == "" {
-		return &response, status.Error(codes.PermissionDenied, codes.PermissionDenied.String())
-	}
-
-	if s.us.TrustedSubnet == nil {
-		_, ipnet, err := net.ParseCIDR(cfg.TrustedSubnet)
-		if err != nil {
-			return &response, status.Error(codes.Internal, codes.Internal.String())
+	if !ServiceFromContext(ctx).Can(PermissionStats) {
+		if err := s.checkTrustedSubnet(in.XRealIP); err != nil {
+			return &response, err
 		}
-		s.us.TrustedSubnet = ipnet
 	}
 
-	if in.XRealIP == "" {
-		return &response, status.Error(codes.PermissionDenied, codes.PermissionDenied.String())
-	}
-
-	realIP := net.ParseIP(in.XRealIP)
-
-	if !s.us.TrustedSubnet.Contains(realIP) {
-		return &response, status.Error(codes.PermissionDenied, codes.PermissionDenied.String())
-	}
-
-	stat, err := s.us.GetStats()
+	stat, err := s.us.GetStats(ctx)
 	if err != nil {
 		return &response, status.Error(codes.PermissionDenied, err.Error())
 	}
@@ -46,3 +29,25 @@ func (s *Server) Stats(ctx context.Context, in *pb.StatsRequest) (*pb.StatsRespo
 
 	return &response, nil
 }
+
+func (s *Server) checkTrustedSubnet(xRealIP string) error {
+	trustedSubnet, err := s.us.GetTrustedSubnet()
+	if err != nil {
+		return status.Error(codes.Internal, codes.Internal.String())
+	}
+	if trustedSubnet == nil {
+		return status.Error(codes.PermissionDenied, codes.PermissionDenied.String())
+	}
+
+	if xRealIP == "" {
+		return status.Error(codes.PermissionDenied, codes.PermissionDenied.String())
+	}
+
+	realIP := net.ParseIP(xRealIP)
+
+	if !trustedSubnet.Contains(realIP) {
+		return status.Error(codes.PermissionDenied, codes.PermissionDenied.String())
+	}
+
+	return nil
+}
@@ -0,0 +1,90 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+
+	"github.com/alrund/yp-1/internal/app/linkmeta"
+	"github.com/alrund/yp-1/internal/app/storage"
+	pb "github.com/alrund/yp-1/internal/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// UpdateURL changes the title, the notes or the tags of a link, the absent fields are kept.
+func (s *Server) UpdateURL(ctx context.Context, in *pb.UpdateURLRequest) (*pb.UpdateURLResponse, error) {
+	var response pb.UpdateURLResponse
+
+	contextUserID := ctx.Value(UserIDContextKey)
+	userID, ok := contextUserID.(string)
+	if !ok {
+		return &response, status.Error(codes.Internal, codes.Internal.String())
+	}
+
+	update := linkmeta.Update{Title: in.Title, Notes: in.Notes}
+	if in.Tags != nil {
+		tags := in.Tags.Values
+		update.Tags = &tags
+	}
+
+	m, err := s.us.UpdateURL(ctx, userID, in.Token, update)
+	if err != nil {
+		return &response, tagError(err)
+	}
+
+	response.Title = m.Title
+	response.Notes = m.Notes
+	response.Tags = m.Tags
+
+	return &response, nil
+}
+
+// ListTags returns the tags of the user's links with the link counts.
+func (s *Server) ListTags(ctx context.Context, in *pb.ListTagsRequest) (*pb.ListTagsResponse, error) {
+	var response pb.ListTagsResponse
+
+	contextUserID := ctx.Value(UserIDContextKey)
+	userID, ok := contextUserID.(string)
+	if !ok {
+		return &response, status.Error(codes.Internal, codes.Internal.String())
+	}
+
+	tags, err := s.us.GetTags(ctx, userID)
+	if err != nil {
+		return &response, status.Error(codes.Internal, codes.Internal.String())
+	}
+
+	for _, tag := range tags {
+		response.Tags = append(response.Tags, &pb.ListTagsResponse_Tag{Tag: tag.Tag, Count: int32(tag.Count)})
+	}
+
+	return &response, nil
+}
+
+// RenameTag renames a tag on the user's links.
+func (s *Server) RenameTag(ctx context.Context, in *pb.RenameTagRequest) (*pb.RenameTagResponse, error) {
+	var response pb.RenameTagResponse
+
+	contextUserID := ctx.Value(UserIDContextKey)
+	userID, ok := contextUserID.(string)
+	if !ok {
+		return &response, status.Error(codes.Internal, codes.Internal.String())
+	}
+
+	if err := s.us.RenameTag(ctx, userID, in.From, in.To); err != nil {
+		return &response, tagError(err)
+	}
+
+	return &response, nil
+}
+
+func tagError(err error) error {
+	switch {
+	case linkmeta.IsInvalid(err):
+		return status.Error(codes.InvalidArgument, codes.InvalidArgument.String())
+	case errors.Is(err, storage.ErrTokenNotFound), errors.Is(err, storage.ErrTagNotFound):
+		return status.Error(codes.NotFound, codes.NotFound.String())
+	default:
+		return status.Error(codes.Internal, codes.Internal.String())
+	}
+}
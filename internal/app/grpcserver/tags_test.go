@@ -0,0 +1,93 @@
+package grpcserver
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alrund/yp-1/internal/app"
+	"github.com/alrund/yp-1/internal/app/config"
+	"github.com/alrund/yp-1/internal/app/encryption"
+	"github.com/alrund/yp-1/internal/app/storage"
+	pb "github.com/alrund/yp-1/internal/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+func TestTags(t *testing.T) {
+	testConfig := &config.Config{
+		GrpcServerAddress: "localhost:9090",
+		BaseURL:           "http://localhost:8080/",
+		CipherPass:        "PASS",
+	}
+	testEncryptor := encryption.NewEncryption(testConfig.CipherPass)
+	us := &app.URLShortener{
+		Config:         testConfig,
+		Storage:        storage.NewMap(),
+		TokenGenerator: new(TestGenerator),
+	}
+
+	conn, err := grpc.DialContext(
+		context.Background(),
+		testConfig.GrpcServerAddress,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(dialer(us)),
+	)
+	require.NoError(t, err)
+	defer conn.Close()
+	client := pb.NewAppClient(conn)
+
+	ctx := getContextWithUserID("XXX-YYY-ZZZ", testEncryptor)
+	otherCtx := getContextWithUserID("AAA-BBB-CCC", testEncryptor)
+
+	_, err = client.Add(ctx, &pb.AddRequest{Url: "http://ya.ru", Tags: []string{""}})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.Add(ctx, &pb.AddRequest{Url: "http://ya.ru", Title: "Yandex", Tags: []string{"Search", "RU"}})
+	require.NoError(t, err)
+
+	urls, err := client.GetUserURLs(ctx, &pb.GetUserURLsRequest{Tag: "ru"})
+	require.NoError(t, err)
+	require.Len(t, urls.Urls, 1)
+	assert.Equal(t, "Yandex", urls.Urls[0].Title)
+	assert.Equal(t, []string{"ru", "search"}, urls.Urls[0].Tags)
+
+	_, err = client.UpdateURL(otherCtx, &pb.UpdateURLRequest{Token: "qwerty"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	notes := "docs"
+	_, err = client.UpdateURL(ctx, &pb.UpdateURLRequest{Token: "qwerty", Tags: &pb.Tags{Values: []string{"a,b"}}})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	updated, err := client.UpdateURL(ctx, &pb.UpdateURLRequest{Token: "qwerty", Notes: &notes})
+	require.NoError(t, err)
+	assert.Equal(t, "Yandex", updated.Title)
+	assert.Equal(t, "docs", updated.Notes)
+	assert.Equal(t, []string{"ru", "search"}, updated.Tags)
+
+	updated, err = client.UpdateURL(ctx, &pb.UpdateURLRequest{Token: "qwerty", Tags: &pb.Tags{}})
+	require.NoError(t, err)
+	assert.Empty(t, updated.Tags)
+
+	_, err = client.UpdateURL(ctx, &pb.UpdateURLRequest{Token: "qwerty", Tags: &pb.Tags{Values: []string{"ru"}}})
+	require.NoError(t, err)
+
+	_, err = client.RenameTag(ctx, &pb.RenameTagRequest{From: "search", To: "web"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = client.RenameTag(ctx, &pb.RenameTagRequest{From: "ru", To: "Web"})
+	require.NoError(t, err)
+
+	tags, err := client.ListTags(ctx, &pb.ListTagsRequest{})
+	require.NoError(t, err)
+	require.Len(t, tags.Tags, 1)
+	assert.Equal(t, "web", tags.Tags[0].Tag)
+	assert.Equal(t, int32(1), tags.Tags[0].Count)
+
+	tags, err = client.ListTags(otherCtx, &pb.ListTagsRequest{})
+	require.NoError(t, err)
+	assert.Empty(t, tags.Tags)
+}
@@ -0,0 +1,163 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+
+	"github.com/alrund/yp-1/internal/app"
+	"github.com/alrund/yp-1/internal/app/storage"
+	"github.com/alrund/yp-1/internal/app/workspace"
+	pb "github.com/alrund/yp-1/internal/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// CreateWorkspace creates a workspace with the user as its admin.
+func (s *Server) CreateWorkspace(
+	ctx context.Context,
+	in *pb.CreateWorkspaceRequest,
+) (*pb.CreateWorkspaceResponse, error) {
+	var response pb.CreateWorkspaceResponse
+
+	contextUserID := ctx.Value(UserIDContextKey)
+	userID, ok := contextUserID.(string)
+	if !ok {
+		return &response, status.Error(codes.Internal, codes.Internal.String())
+	}
+
+	membership, err := s.us.CreateWorkspace(ctx, userID, in.Name)
+	if err != nil {
+		if errors.Is(err, app.ErrEmptyWorkspaceName) || errors.Is(err, app.ErrLongWorkspaceName) {
+			return &response, status.Error(codes.InvalidArgument, codes.InvalidArgument.String())
+		}
+		return &response, status.Error(codes.Internal, codes.Internal.String())
+	}
+
+	response.Workspace = workspaceMessage(membership)
+
+	return &response, nil
+}
+
+// ListWorkspaces returns the workspaces of the user with the user's role.
+func (s *Server) ListWorkspaces(ctx context.Context, in *pb.ListWorkspacesRequest) (*pb.ListWorkspacesResponse, error) {
+	var response pb.ListWorkspacesResponse
+
+	contextUserID := ctx.Value(UserIDContextKey)
+	userID, ok := contextUserID.(string)
+	if !ok {
+		return &response, status.Error(codes.Internal, codes.Internal.String())
+	}
+
+	memberships, err := s.us.GetWorkspaces(ctx, userID)
+	if err != nil {
+		return &response, status.Error(codes.Internal, codes.Internal.String())
+	}
+
+	for _, m := range memberships {
+		response.Workspaces = append(response.Workspaces, workspaceMessage(m))
+	}
+
+	return &response, nil
+}
+
+// ListWorkspaceMembers returns the members of the workspace to any of its members.
+func (s *Server) ListWorkspaceMembers(
+	ctx context.Context,
+	in *pb.ListWorkspaceMembersRequest,
+) (*pb.ListWorkspaceMembersResponse, error) {
+	var response pb.ListWorkspaceMembersResponse
+
+	contextUserID := ctx.Value(UserIDContextKey)
+	userID, ok := contextUserID.(string)
+	if !ok {
+		return &response, status.Error(codes.Internal, codes.Internal.String())
+	}
+
+	members, err := s.us.GetWorkspaceMembers(ctx, userID, in.WorkspaceId)
+	if err != nil {
+		return &response, workspaceError(err)
+	}
+
+	for _, m := range members {
+		response.Members = append(response.Members, &pb.WorkspaceMember{UserId: m.UserID, Role: string(m.Role)})
+	}
+
+	return &response, nil
+}
+
+// SetWorkspaceMember adds a member to the workspace or changes the role, only an admin can do it.
+func (s *Server) SetWorkspaceMember(
+	ctx context.Context,
+	in *pb.SetWorkspaceMemberRequest,
+) (*pb.SetWorkspaceMemberResponse, error) {
+	var response pb.SetWorkspaceMemberResponse
+
+	contextUserID := ctx.Value(UserIDContextKey)
+	userID, ok := contextUserID.(string)
+	if !ok {
+		return &response, status.Error(codes.Internal, codes.Internal.String())
+	}
+
+	err := s.us.SetWorkspaceMember(ctx, userID, in.WorkspaceId, in.UserId, in.Role)
+	if err != nil {
+		return &response, workspaceError(err)
+	}
+
+	return &response, nil
+}
+
+// RemoveWorkspaceMember removes a member from the workspace.
+func (s *Server) RemoveWorkspaceMember(
+	ctx context.Context,
+	in *pb.RemoveWorkspaceMemberRequest,
+) (*pb.RemoveWorkspaceMemberResponse, error) {
+	var response pb.RemoveWorkspaceMemberResponse
+
+	contextUserID := ctx.Value(UserIDContextKey)
+	userID, ok := contextUserID.(string)
+	if !ok {
+		return &response, status.Error(codes.Internal, codes.Internal.String())
+	}
+
+	err := s.us.RemoveWorkspaceMember(ctx, userID, in.WorkspaceId, in.UserId)
+	if err != nil {
+		return &response, workspaceError(err)
+	}
+
+	return &response, nil
+}
+
+// linkOwner returns the owner of the new links, the user or the selected workspace.
+func linkOwner(ctx context.Context, us *app.URLShortener, userID, workspaceID string) (string, error) {
+	owner, err := us.LinkOwner(ctx, userID, workspaceID)
+	if err != nil {
+		return "", workspaceError(err)
+	}
+
+	return owner, nil
+}
+
+func workspaceError(err error) error {
+	switch {
+	case errors.Is(err, workspace.ErrInvalidRole):
+		return status.Error(codes.InvalidArgument, codes.InvalidArgument.String())
+	case errors.Is(err, app.ErrForbidden):
+		return status.Error(codes.PermissionDenied, codes.PermissionDenied.String())
+	case errors.Is(err, storage.ErrWorkspaceNotFound), errors.Is(err, storage.ErrMemberNotFound),
+		errors.Is(err, app.ErrUnknownUser):
+		return status.Error(codes.NotFound, codes.NotFound.String())
+	case errors.Is(err, storage.ErrLastAdmin):
+		return status.Error(codes.FailedPrecondition, codes.FailedPrecondition.String())
+	default:
+		return status.Error(codes.Internal, codes.Internal.String())
+	}
+}
+
+func workspaceMessage(m *workspace.Membership) *pb.Workspace {
+	return &pb.Workspace{
+		Id:      m.Workspace.ID,
+		Name:    m.Workspace.Name,
+		Role:    string(m.Role),
+		Created: m.Workspace.Created.Unix(),
+	}
+}
@@ -0,0 +1,100 @@
+package grpcserver
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alrund/yp-1/internal/app"
+	"github.com/alrund/yp-1/internal/app/account"
+	"github.com/alrund/yp-1/internal/app/config"
+	"github.com/alrund/yp-1/internal/app/encryption"
+	"github.com/alrund/yp-1/internal/app/storage"
+	pb "github.com/alrund/yp-1/internal/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+func TestWorkspaces(t *testing.T) {
+	testConfig := &config.Config{
+		GrpcServerAddress: "localhost:9090",
+		BaseURL:           "http://localhost:8080/",
+		CipherPass:        "PASS",
+	}
+	testEncryptor := encryption.NewEncryption(testConfig.CipherPass)
+	us := &app.URLShortener{
+		Config:         testConfig,
+		Storage:        storage.NewMap(),
+		TokenGenerator: new(TestGenerator),
+	}
+
+	conn, err := grpc.DialContext(
+		context.Background(),
+		testConfig.GrpcServerAddress,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(dialer(us)),
+	)
+	require.NoError(t, err)
+	defer conn.Close()
+	client := pb.NewAppClient(conn)
+
+	adminCtx := getContextWithUserID("admin", testEncryptor)
+	viewerCtx := getContextWithUserID("viewer", testEncryptor)
+
+	_, err = client.CreateWorkspace(adminCtx, &pb.CreateWorkspaceRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	created, err := client.CreateWorkspace(adminCtx, &pb.CreateWorkspaceRequest{Name: "team"})
+	require.NoError(t, err)
+	assert.Equal(t, "admin", created.Workspace.Role)
+	workspaceID := created.Workspace.Id
+
+	_, err = client.SetWorkspaceMember(viewerCtx, &pb.SetWorkspaceMemberRequest{
+		WorkspaceId: workspaceID, UserId: "viewer", Role: "admin",
+	})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = client.SetWorkspaceMember(adminCtx, &pb.SetWorkspaceMemberRequest{
+		WorkspaceId: workspaceID, UserId: "viewer", Role: "viewer",
+	})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	require.NoError(t, us.Storage.SetAccount(&account.Account{ID: "viewer", Login: "viewer"}))
+	_, err = client.SetWorkspaceMember(adminCtx, &pb.SetWorkspaceMemberRequest{
+		WorkspaceId: workspaceID, UserId: "viewer", Role: "viewer",
+	})
+	require.NoError(t, err)
+
+	_, err = client.Add(viewerCtx, &pb.AddRequest{Url: "http://viewer.ru", WorkspaceId: workspaceID})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = client.Add(adminCtx, &pb.AddRequest{Url: "http://team.ru", WorkspaceId: workspaceID})
+	require.NoError(t, err)
+
+	urls, err := client.GetUserURLs(viewerCtx, &pb.GetUserURLsRequest{})
+	require.NoError(t, err)
+	require.Len(t, urls.Urls, 1)
+	assert.Equal(t, "http://team.ru", urls.Urls[0].OriginalUrl)
+	assert.Equal(t, workspaceID, urls.Urls[0].WorkspaceId)
+
+	members, err := client.ListWorkspaceMembers(viewerCtx, &pb.ListWorkspaceMembersRequest{WorkspaceId: workspaceID})
+	require.NoError(t, err)
+	assert.Len(t, members.Members, 2)
+
+	_, err = client.RemoveWorkspaceMember(adminCtx, &pb.RemoveWorkspaceMemberRequest{
+		WorkspaceId: workspaceID, UserId: "admin",
+	})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	_, err = client.RemoveWorkspaceMember(viewerCtx, &pb.RemoveWorkspaceMemberRequest{
+		WorkspaceId: workspaceID, UserId: "viewer",
+	})
+	require.NoError(t, err)
+
+	workspaces, err := client.ListWorkspaces(viewerCtx, &pb.ListWorkspacesRequest{})
+	require.NoError(t, err)
+	assert.Empty(t, workspaces.Workspaces)
+}
@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/alrund/yp-1/internal/app/account"
+	"github.com/alrund/yp-1/internal/app/middleware"
+	"github.com/alrund/yp-1/internal/app/storage"
+)
+
+type CredentialsRequest struct {
+	Login    string `json:"login"`
+	Password string `json:"password"`
+}
+
+type SessionResponse struct {
+	UserID string `json:"user_id"`
+	// Token the session token, the same as in the cookie, for the clients which send it in a header.
+	Token string `json:"token"`
+}
+
+// Register creates an account, the links of the current user are moved to it.
+func (hc *Collection) Register() func(w http.ResponseWriter, r *http.Request) {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		userID, credentials, ok := readCredentials(w, r)
+		if !ok {
+			return
+		}
+
+		a, err := hc.us.Register(r.Context(), userID, credentials.Login, credentials.Password)
+		if err != nil {
+			switch {
+			case errors.Is(err, storage.ErrAccountExists):
+				http.Error(w, "409 Conflict.", http.StatusConflict)
+			case errors.Is(err, account.ErrEmptyLogin),
+				errors.Is(err, account.ErrLongLogin),
+				errors.Is(err, account.ErrShortPassword),
+				errors.Is(err, account.ErrLongPassword):
+				http.Error(w, "400 Bad Request.", http.StatusBadRequest)
+			default:
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			}
+			return
+		}
+
+		hc.startSession(w, a.ID, http.StatusCreated)
+	}
+	return fn
+}
+
+// Login signs the user in, the links of the current anonymous user are moved to the account.
+func (hc *Collection) Login() func(w http.ResponseWriter, r *http.Request) {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		userID, credentials, ok := readCredentials(w, r)
+		if !ok {
+			return
+		}
+
+		a, err := hc.us.Login(r.Context(), userID, credentials.Login, credentials.Password)
+		if err != nil {
+			if errors.Is(err, account.ErrInvalidCredentials) {
+				http.Error(w, "401 Unauthorized.", http.StatusUnauthorized)
+				return
+			}
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		hc.startSession(w, a.ID, http.StatusOK)
+	}
+	return fn
+}
+
+// readCredentials returns the current user ID and the credentials, it writes the error response if not ok.
+func readCredentials(w http.ResponseWriter, r *http.Request) (string, CredentialsRequest, bool) {
+	credentials := CredentialsRequest{}
+
+	if !hasContentType(r, "application/json") {
+		http.Error(w, "415 Unsupported Media Type.", http.StatusUnsupportedMediaType)
+		return "", credentials, false
+	}
+
+	contextUserID := r.Context().Value(middleware.UserIDContextKey)
+	userID, ok := contextUserID.(string)
+	if !ok {
+		http.Error(w, "500 Internal Server Error.", http.StatusInternalServerError)
+		return "", credentials, false
+	}
+
+	b, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return "", credentials, false
+	}
+
+	err = json.Unmarshal(b, &credentials)
+	if err != nil {
+		http.Error(w, "400 Bad Request.", http.StatusBadRequest)
+		return "", credentials, false
+	}
+
+	return userID, credentials, true
+}
+
+// startSession sends a new session of the account in the cookie and the response.
+func (hc *Collection) startSession(w http.ResponseWriter, accountID string, code int) {
+	token, err := hc.us.Sessions.Issue(accountID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	middleware.SetCookie(w, token)
+	writeJSON(w, code, SessionResponse{UserID: accountID, Token: token})
+}
@@ -0,0 +1,185 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alrund/yp-1/internal/app"
+	"github.com/alrund/yp-1/internal/app/config"
+	"github.com/alrund/yp-1/internal/app/encryption"
+	"github.com/alrund/yp-1/internal/app/linkmeta"
+	"github.com/alrund/yp-1/internal/app/middleware"
+	"github.com/alrund/yp-1/internal/app/session"
+	"github.com/alrund/yp-1/internal/app/storage"
+	"github.com/alrund/yp-1/internal/app/token/generator"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccounts(t *testing.T) {
+	us := &app.URLShortener{
+		Config: &config.Config{
+			ServerAddress: "localhost:8080",
+			BaseURL:       "http://localhost:8080/",
+		},
+		Storage:        storage.NewMap(),
+		TokenGenerator: generator.NewSimple(),
+		Sessions:       session.NewEncrypted(encryption.NewEncryption("PASS")),
+	}
+	hc := NewCollection(us)
+
+	serve := func(h http.HandlerFunc, userID, body string) (*http.Response, SessionResponse) {
+		request := getNewRequestWithUserID(http.MethodPost, "/api/user/login", userID, 0, strings.NewReader(body))
+		request.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		h(w, request)
+		res := w.Result()
+		defer res.Body.Close()
+
+		var response SessionResponse
+		b, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		if res.StatusCode < http.StatusBadRequest {
+			require.NoError(t, json.Unmarshal(b, &response))
+		}
+		return res, response
+	}
+
+	tests := []struct {
+		name     string
+		handler  http.HandlerFunc
+		userID   string
+		body     string
+		wantCode int
+	}{
+		{
+			name:     "register with short password",
+			handler:  hc.Register(),
+			userID:   "XXX-YYY-ZZZ",
+			body:     `{"login": "alice", "password": "short"}`,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "register",
+			handler:  hc.Register(),
+			userID:   "XXX-YYY-ZZZ",
+			body:     `{"login": "alice", "password": "correct horse"}`,
+			wantCode: http.StatusCreated,
+		},
+		{
+			name:     "register taken login",
+			handler:  hc.Register(),
+			userID:   "AAA-BBB-CCC",
+			body:     `{"login": "alice", "password": "correct horse"}`,
+			wantCode: http.StatusConflict,
+		},
+		{
+			name:     "login with wrong password",
+			handler:  hc.Login(),
+			userID:   "AAA-BBB-CCC",
+			body:     `{"login": "alice", "password": "wrong password"}`,
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "login unknown user",
+			handler:  hc.Login(),
+			userID:   "AAA-BBB-CCC",
+			body:     `{"login": "bob", "password": "correct horse"}`,
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "login",
+			handler:  hc.Login(),
+			userID:   "AAA-BBB-CCC",
+			body:     `{"login": "alice", "password": "correct horse"}`,
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "bad json",
+			handler:  hc.Login(),
+			userID:   "AAA-BBB-CCC",
+			body:     `{"login": `,
+			wantCode: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, response := serve(tt.handler, tt.userID, tt.body)
+			assert.Equal(t, tt.wantCode, res.StatusCode)
+			if tt.wantCode >= http.StatusBadRequest {
+				return
+			}
+
+			cookies := res.Cookies()
+			require.Len(t, cookies, 1)
+			assert.Equal(t, string(middleware.UserIDContextKey), cookies[0].Name)
+			assert.Equal(t, response.Token, cookies[0].Value)
+
+			userID, _, err := us.Sessions.Parse(response.Token)
+			require.NoError(t, err)
+			assert.Equal(t, response.UserID, userID)
+		})
+	}
+}
+
+func TestAccountsMergeAnonymousLinks(t *testing.T) {
+	us := &app.URLShortener{
+		Config: &config.Config{
+			ServerAddress: "localhost:8080",
+			BaseURL:       "http://localhost:8080/",
+		},
+		Storage:        storage.NewMap(),
+		TokenGenerator: generator.NewSimple(),
+		Sessions:       session.NewEncrypted(encryption.NewEncryption("PASS")),
+	}
+	hc := NewCollection(us)
+
+	serve := func(h http.HandlerFunc, userID, body string) SessionResponse {
+		request := getNewRequestWithUserID(http.MethodPost, "/api/user/login", userID, 0, strings.NewReader(body))
+		request.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		h(w, request)
+		res := w.Result()
+		defer res.Body.Close()
+		require.Less(t, res.StatusCode, http.StatusBadRequest)
+
+		var response SessionResponse
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&response))
+		return response
+	}
+	add := func(userID, url string) {
+		_, err := us.Add(context.Background(), userID, url, linkmeta.Meta{})
+		require.NoError(t, err)
+	}
+
+	add("first-anonymous", "http://first.ru")
+	alice := serve(hc.Register(), "first-anonymous", `{"login": "alice", "password": "correct horse"}`)
+	add("second-anonymous", "http://second.ru")
+	serve(hc.Login(), "second-anonymous", `{"login": "alice", "password": "correct horse"}`)
+
+	add("bob-anonymous", "http://bob.ru")
+	bob := serve(hc.Register(), "bob-anonymous", `{"login": "bob", "password": "correct horse"}`)
+	// Bob signs in as Alice in the same browser, his links stay with him.
+	serve(hc.Login(), bob.UserID, `{"login": "alice", "password": "correct horse"}`)
+
+	urls, err := us.GetURLsByUserID(alice.UserID)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"http://first.ru", "http://second.ru"}, originalURLs(urls))
+
+	urls, err = us.GetURLsByUserID(bob.UserID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"http://bob.ru"}, originalURLs(urls))
+}
+
+func originalURLs(pairs []storage.URLpairs) []string {
+	urls := make([]string, 0, len(pairs))
+	for _, pair := range pairs {
+		urls = append(urls, pair.OriginalURL)
+	}
+	return urls
+}
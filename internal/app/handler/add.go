@@ -7,12 +7,19 @@ import (
 	"mime"
 	"net/http"
 
+	"github.com/alrund/yp-1/internal/app/linkmeta"
 	"github.com/alrund/yp-1/internal/app/middleware"
+	"github.com/alrund/yp-1/internal/app/ratelimit"
+	"github.com/alrund/yp-1/internal/app/screening"
 	"github.com/alrund/yp-1/internal/app/storage"
+	"github.com/alrund/yp-1/internal/app/urlcheck"
 )
 
 type JSONRequest struct {
-	URL string `json:"url"`
+	URL   string   `json:"url"`
+	Title string   `json:"title,omitempty"`
+	Notes string   `json:"notes,omitempty"`
+	Tags  []string `json:"tags,omitempty"`
 }
 
 type JSONResponse struct {
@@ -31,14 +38,34 @@ func (hc *Collection) Add() func(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		owner, ok := hc.linkOwner(w, r, userID)
+		if !ok {
+			return
+		}
+
 		b, err := io.ReadAll(r.Body)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 
-		token, err := hc.us.Add(userID, string(b))
+		refund, err := hc.us.UseCreateQuota(userID, 1)
+		if err != nil {
+			writeQuotaError(w, err)
+			return
+		}
+
+		token, err := hc.us.Add(r.Context(), owner, string(b), linkmeta.Meta{})
 		if err != nil {
+			refund(1)
+			if urlcheck.IsInvalid(err) {
+				http.Error(w, "400 Bad Request.", http.StatusBadRequest)
+				return
+			}
+			if errors.Is(err, screening.ErrBlocked) {
+				http.Error(w, "403 Forbidden.", http.StatusForbidden)
+				return
+			}
 			if !errors.Is(err, storage.ErrURLAlreadyExists) {
 				http.Error(w, err.Error(), http.StatusInternalServerError)
 				return
@@ -74,6 +101,11 @@ func (hc *Collection) AddJSON() func(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		owner, ok := hc.linkOwner(w, r, userID)
+		if !ok {
+			return
+		}
+
 		b, err := io.ReadAll(r.Body)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -87,8 +119,29 @@ func (hc *Collection) AddJSON() func(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		token, err := hc.us.Add(userID, jsonRequest.URL)
+		m, err := linkmeta.New(jsonRequest.Title, jsonRequest.Notes, jsonRequest.Tags)
 		if err != nil {
+			http.Error(w, "400 Bad Request.", http.StatusBadRequest)
+			return
+		}
+
+		refund, err := hc.us.UseCreateQuota(userID, 1)
+		if err != nil {
+			writeQuotaError(w, err)
+			return
+		}
+
+		token, err := hc.us.Add(r.Context(), owner, jsonRequest.URL, m)
+		if err != nil {
+			refund(1)
+			if urlcheck.IsInvalid(err) {
+				http.Error(w, "400 Bad Request.", http.StatusBadRequest)
+				return
+			}
+			if errors.Is(err, screening.ErrBlocked) {
+				http.Error(w, "403 Forbidden.", http.StatusForbidden)
+				return
+			}
 			if !errors.Is(err, storage.ErrURLAlreadyExists) {
 				http.Error(w, err.Error(), http.StatusInternalServerError)
 				return
@@ -114,6 +167,15 @@ func (hc *Collection) AddJSON() func(w http.ResponseWriter, r *http.Request) {
 	return fn
 }
 
+// writeQuotaError writes 429 for an exceeded daily quota.
+func writeQuotaError(w http.ResponseWriter, err error) {
+	if errors.Is(err, ratelimit.ErrExceeded) {
+		middleware.WriteRateLimited(w, err)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
+
 func hasContentType(r *http.Request, mimetype string) bool {
 	contentType := r.Header.Get("Content-type")
 	t, _, err := mime.ParseMediaType(contentType)
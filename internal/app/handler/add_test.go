@@ -2,6 +2,7 @@ package handler
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"net/http"
@@ -12,9 +13,13 @@ import (
 
 	"github.com/alrund/yp-1/internal/app"
 	"github.com/alrund/yp-1/internal/app/config"
+	"github.com/alrund/yp-1/internal/app/listing"
+	"github.com/alrund/yp-1/internal/app/ratelimit"
 	"github.com/alrund/yp-1/internal/app/storage"
 	tkn "github.com/alrund/yp-1/internal/app/token"
+	"github.com/alrund/yp-1/internal/app/token/generator"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 type TestGenerator struct{}
@@ -27,7 +32,7 @@ func TestAdd(t *testing.T) {
 	preparedStorage := storage.NewMap()
 	_ = preparedStorage.Set(
 		"XXX-YYY-ZZZ",
-		"existsurl",
+		"http://exists.ru",
 		&tkn.Token{Value: "qwerty", Expire: time.Now().Add(tkn.LifeTime)},
 	)
 
@@ -61,7 +66,7 @@ func TestAdd(t *testing.T) {
 				method: http.MethodPost,
 				target: "/",
 				userID: "XXX-YYY-ZZZ",
-				body:   "url",
+				body:   "http://url.ru",
 			},
 			want: want{
 				code:        http.StatusCreated,
@@ -75,7 +80,7 @@ func TestAdd(t *testing.T) {
 				method: http.MethodPost,
 				target: "/",
 				userID: "XXX-YYY-ZZZ",
-				body:   "existsurl",
+				body:   "http://exists.ru",
 			},
 			want: want{
 				code:        http.StatusConflict,
@@ -90,7 +95,7 @@ func TestAdd(t *testing.T) {
 				target:        "/",
 				userID:        "",
 				errTypeUserID: 666,
-				body:          "url",
+				body:          "http://url.ru",
 			},
 			want: want{
 				code:        http.StatusInternalServerError,
@@ -137,7 +142,7 @@ func TestAddJSON(t *testing.T) {
 	preparedStorage := storage.NewMap()
 	_ = preparedStorage.Set(
 		"XXX-YYY-ZZZ",
-		"existsurl",
+		"http://exists.ru",
 		&tkn.Token{Value: "qwerty", Expire: time.Now().Add(tkn.LifeTime)},
 	)
 	testConfig := &config.Config{
@@ -235,7 +240,7 @@ func TestAddJSONFail(t *testing.T) {
 	preparedStorage := storage.NewMap()
 	_ = preparedStorage.Set(
 		"XXX-YYY-ZZZ",
-		"existsurl",
+		"http://exists.ru",
 		&tkn.Token{Value: "qwerty", Expire: time.Now().Add(tkn.LifeTime)},
 	)
 	testConfig := &config.Config{
@@ -269,7 +274,7 @@ func TestAddJSONFail(t *testing.T) {
 				method:      http.MethodPost,
 				target:      "/api/shorten",
 				userID:      "XXX-YYY-ZZZ",
-				body:        `{"url": "existsurl"}`,
+				body:        `{"url": "http://exists.ru"}`,
 				contentType: "application/json; charset=utf-8",
 			},
 			want: want{
@@ -405,3 +410,85 @@ func ExampleCollection_AddJSON() {
 	fmt.Println(string(buf))
 	// {"result":"http://localhost:8080/oTHlXx"}
 }
+
+func TestAddQuota(t *testing.T) {
+	us := &app.URLShortener{
+		Config: &config.Config{
+			ServerAddress: "localhost:8080",
+			BaseURL:       "http://localhost:8080/",
+		},
+		Storage:        storage.NewMap(),
+		TokenGenerator: generator.NewSimple(),
+		RateLimiter:    ratelimit.New(storage.NewMap(), nil, 1),
+	}
+	hc := NewCollection(us)
+
+	serve := func(body string) *http.Response {
+		request := getNewRequestWithUserID(http.MethodPost, "/", "XXX-YYY-ZZZ", 0, strings.NewReader(body))
+		w := httptest.NewRecorder()
+		hc.Add()(w, request)
+		res := w.Result()
+		defer res.Body.Close()
+		return res
+	}
+
+	assert.Equal(t, http.StatusCreated, serve("http://ya.ru").StatusCode)
+
+	res := serve("http://ya2.ru")
+	assert.Equal(t, http.StatusTooManyRequests, res.StatusCode)
+	assert.NotEmpty(t, res.Header.Get("Retry-After"))
+}
+
+func TestAddCanonicalURL(t *testing.T) {
+	us := &app.URLShortener{
+		Config: &config.Config{
+			ServerAddress:       "localhost:8080",
+			BaseURL:             "http://localhost:8080/",
+			URLDenyPrivateHosts: true,
+			URLStripParams:      []string{"utm_*"},
+		},
+		Storage:        storage.NewMap(),
+		TokenGenerator: generator.NewSimple(),
+	}
+	hc := NewCollection(us)
+
+	serve := func(h http.HandlerFunc, target, contentType, body string) (int, string) {
+		request := getNewRequestWithUserID(http.MethodPost, target, "XXX-YYY-ZZZ", 0, strings.NewReader(body))
+		request.Header.Set("Content-Type", contentType)
+		w := httptest.NewRecorder()
+		h(w, request)
+		res := w.Result()
+		defer res.Body.Close()
+
+		b, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		return res.StatusCode, string(b)
+	}
+
+	for _, body := range []string{"", "  ", "url", "javascript:alert(1)", "http://127.0.0.1/admin",
+		"http://ya.ru/" + strings.Repeat("x", 255)} {
+		code, _ := serve(hc.Add(), "/", "text/plain", body)
+		assert.Equal(t, http.StatusBadRequest, code, body)
+	}
+	code, _ := serve(hc.AddJSON(), "/api/shorten", "application/json", `{"url": "ftp://ya.ru"}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = serve(hc.AddBatchJSON(), "/api/shorten/batch", "application/json",
+		`[{"correlation_id": "1", "original_url": "http://ya.ru"}, {"correlation_id": "2", "original_url": "ya.ru"}]`)
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	code, shortURL := serve(hc.Add(), "/", "text/plain", " HTTP://Ya.RU:80/%7euser?q=1&utm_source=mail\n")
+	require.Equal(t, http.StatusCreated, code)
+	code, sameURL := serve(hc.Add(), "/", "text/plain", "http://ya.ru/~user?q=1")
+	assert.Equal(t, http.StatusConflict, code)
+	assert.Equal(t, shortURL, sameURL)
+
+	urls, _, err := us.GetUserURLs(context.Background(), "XXX-YYY-ZZZ", listing.Query{})
+	require.NoError(t, err)
+	require.Len(t, urls, 1)
+	assert.Equal(t, "http://ya.ru/~user?q=1", urls[0].OriginalURL)
+
+	code, b := serve(hc.AddBatchJSON(), "/api/shorten/batch", "application/json",
+		`[{"correlation_id": "1", "original_url": "http://YA.ru/~user?q=1"}]`)
+	assert.Equal(t, http.StatusConflict, code)
+	assert.JSONEq(t, `[{"correlation_id": "1", "short_url": "`+shortURL+`"}]`, b)
+}
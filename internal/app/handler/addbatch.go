@@ -6,13 +6,19 @@ import (
 	"io"
 	"net/http"
 
+	"github.com/alrund/yp-1/internal/app/linkmeta"
 	"github.com/alrund/yp-1/internal/app/middleware"
+	"github.com/alrund/yp-1/internal/app/screening"
 	"github.com/alrund/yp-1/internal/app/storage"
+	"github.com/alrund/yp-1/internal/app/urlcheck"
 )
 
 type JSONBatchRequestRow struct {
-	CorrelationID string `json:"correlation_id"`
-	OriginalURL   string `json:"original_url"`
+	CorrelationID string   `json:"correlation_id"`
+	OriginalURL   string   `json:"original_url"`
+	Title         string   `json:"title,omitempty"`
+	Notes         string   `json:"notes,omitempty"`
+	Tags          []string `json:"tags,omitempty"`
 }
 
 type JSONBatchResponseRow struct {
@@ -37,6 +43,11 @@ func (hc *Collection) AddBatchJSON() func(w http.ResponseWriter, r *http.Request
 			return
 		}
 
+		owner, ok := hc.linkOwner(w, r, userID)
+		if !ok {
+			return
+		}
+
 		b, err := io.ReadAll(r.Body)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -52,8 +63,32 @@ func (hc *Collection) AddBatchJSON() func(w http.ResponseWriter, r *http.Request
 
 		jsonResponse := make([]JSONBatchResponseRow, 0)
 		URLs, URL2Row := getURL2Row(jsonRequests)
-		tokens, err := hc.us.AddBatch(userID, URLs)
+		metas := make(map[string]linkmeta.Meta, len(URL2Row))
+		for URL, row := range URL2Row {
+			metas[URL], err = linkmeta.New(row.Title, row.Notes, row.Tags)
+			if err != nil {
+				http.Error(w, "400 Bad Request.", http.StatusBadRequest)
+				return
+			}
+		}
+
+		refund, err := hc.us.UseCreateQuota(userID, len(URLs))
 		if err != nil {
+			writeQuotaError(w, err)
+			return
+		}
+
+		tokens, added, err := hc.us.AddBatch(r.Context(), owner, URLs, metas)
+		refund(len(URLs) - added)
+		if err != nil {
+			if urlcheck.IsInvalid(err) {
+				http.Error(w, "400 Bad Request.", http.StatusBadRequest)
+				return
+			}
+			if errors.Is(err, screening.ErrBlocked) {
+				http.Error(w, "403 Forbidden.", http.StatusForbidden)
+				return
+			}
 			if !errors.Is(err, storage.ErrURLAlreadyExists) {
 				http.Error(w, err.Error(), http.StatusInternalServerError)
 				return
@@ -173,7 +173,7 @@ func TestAddBatchJSONFail(t *testing.T) {
 	preparedStorage := storage.NewMap()
 	_ = preparedStorage.Set(
 		"XXX-YYY-ZZZ",
-		"existsurl",
+		"http://exists.ru",
 		&tkn.Token{Value: "qwerty", Expire: time.Now().Add(tkn.LifeTime)},
 	)
 
@@ -307,7 +307,7 @@ func TestAddBatchJSONConflict(t *testing.T) {
 	preparedStorage := storage.NewMap()
 	_ = preparedStorage.Set(
 		"XXX-YYY-ZZZ",
-		"existsurl",
+		"http://exists.ru",
 		&tkn.Token{Value: "qwerty", Expire: time.Now().Add(tkn.LifeTime)},
 	)
 
@@ -338,7 +338,7 @@ func TestAddBatchJSONConflict(t *testing.T) {
 				body: `[
 					{
 						"correlation_id":"222",
-						"original_url":"existsurl"
+						"original_url":"http://exists.ru"
 					}
 				]`,
 				contentType: "application/json; charset=utf-8",
@@ -0,0 +1,156 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/alrund/yp-1/internal/app"
+	"github.com/alrund/yp-1/internal/app/apikey"
+	"github.com/alrund/yp-1/internal/app/middleware"
+	"github.com/alrund/yp-1/internal/app/storage"
+)
+
+type APIKeyRequest struct {
+	Name string `json:"name"`
+}
+
+type APIKeyResponse struct {
+	ID      string    `json:"id"`
+	Name    string    `json:"name"`
+	Hint    string    `json:"hint"`
+	Created time.Time `json:"created"`
+	Revoked bool      `json:"revoked"`
+	// Key the secret, it is returned only on creation.
+	Key string `json:"key,omitempty"`
+}
+
+// CreateAPIKey creates a named API key of the user, the key is returned only once.
+func (hc *Collection) CreateAPIKey() func(w http.ResponseWriter, r *http.Request) {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		if !hasContentType(r, "application/json") {
+			http.Error(w, "415 Unsupported Media Type.", http.StatusUnsupportedMediaType)
+			return
+		}
+
+		contextUserID := r.Context().Value(middleware.UserIDContextKey)
+		userID, ok := contextUserID.(string)
+		if !ok {
+			http.Error(w, "500 Internal Server Error.", http.StatusInternalServerError)
+			return
+		}
+
+		b, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		jsonRequest := APIKeyRequest{}
+		err = json.Unmarshal(b, &jsonRequest)
+		if err != nil {
+			http.Error(w, "400 Bad Request.", http.StatusBadRequest)
+			return
+		}
+
+		key, secret, err := hc.us.CreateAPIKey(r.Context(), userID, jsonRequest.Name)
+		if err != nil {
+			if errors.Is(err, app.ErrEmptyAPIKeyName) || errors.Is(err, app.ErrLongAPIKeyName) {
+				http.Error(w, "400 Bad Request.", http.StatusBadRequest)
+				return
+			}
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		jsonResponse := newAPIKeyResponse(key)
+		jsonResponse.Key = secret
+		writeJSON(w, http.StatusCreated, jsonResponse)
+	}
+	return fn
+}
+
+// GetAPIKeys returns the API keys of the user.
+func (hc *Collection) GetAPIKeys() func(w http.ResponseWriter, r *http.Request) {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		contextUserID := r.Context().Value(middleware.UserIDContextKey)
+		userID, ok := contextUserID.(string)
+		if !ok {
+			http.Error(w, "500 Internal Server Error.", http.StatusInternalServerError)
+			return
+		}
+
+		keys, err := hc.us.GetAPIKeys(r.Context(), userID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if len(keys) == 0 {
+			http.Error(w, "204 No Content.", http.StatusNoContent)
+			return
+		}
+
+		jsonResponse := make([]APIKeyResponse, 0, len(keys))
+		for _, key := range keys {
+			jsonResponse = append(jsonResponse, newAPIKeyResponse(key))
+		}
+		writeJSON(w, http.StatusOK, jsonResponse)
+	}
+	return fn
+}
+
+// RevokeAPIKey revokes the API key of the user given by the last path element.
+func (hc *Collection) RevokeAPIKey() func(w http.ResponseWriter, r *http.Request) {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		contextUserID := r.Context().Value(middleware.UserIDContextKey)
+		userID, ok := contextUserID.(string)
+		if !ok {
+			http.Error(w, "500 Internal Server Error.", http.StatusInternalServerError)
+			return
+		}
+
+		id := path.Base(r.URL.Path)
+		err := hc.us.RevokeAPIKey(r.Context(), userID, id)
+		if err != nil {
+			if errors.Is(err, storage.ErrAPIKeyNotFound) {
+				http.Error(w, "404 Not Found.", http.StatusNotFound)
+				return
+			}
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+	return fn
+}
+
+func newAPIKeyResponse(key *apikey.Key) APIKeyResponse {
+	return APIKeyResponse{
+		ID:      key.ID,
+		Name:    key.Name,
+		Hint:    key.Hint,
+		Created: key.Created,
+		Revoked: key.Revoked,
+	}
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	result, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	_, err = w.Write(result)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
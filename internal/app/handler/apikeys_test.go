@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alrund/yp-1/internal/app"
+	"github.com/alrund/yp-1/internal/app/config"
+	"github.com/alrund/yp-1/internal/app/storage"
+	"github.com/alrund/yp-1/internal/app/token/generator"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKeys(t *testing.T) {
+	us := &app.URLShortener{
+		Config: &config.Config{
+			ServerAddress: "localhost:8080",
+			BaseURL:       "http://localhost:8080/",
+		},
+		Storage:        storage.NewMap(),
+		TokenGenerator: generator.NewSimple(),
+	}
+	hc := NewCollection(us)
+
+	serve := func(h http.HandlerFunc, request *http.Request) (*http.Response, []byte) {
+		w := httptest.NewRecorder()
+		h(w, request)
+		res := w.Result()
+		defer res.Body.Close()
+		body, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		return res, body
+	}
+
+	// Empty list.
+	res, _ := serve(hc.GetAPIKeys(), getNewRequestWithUserID(http.MethodGet, "/api/user/keys", "XXX-YYY-ZZZ", 0, nil))
+	assert.Equal(t, http.StatusNoContent, res.StatusCode)
+
+	// Create.
+	request := getNewRequestWithUserID(http.MethodPost, "/api/user/keys", "XXX-YYY-ZZZ", 0, strings.NewReader(`{"name": "ci"}`))
+	request.Header.Set("Content-Type", "application/json")
+	res, body := serve(hc.CreateAPIKey(), request)
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+
+	var created APIKeyResponse
+	require.NoError(t, json.Unmarshal(body, &created))
+	assert.Equal(t, "ci", created.Name)
+	assert.NotEmpty(t, created.Key)
+	assert.True(t, strings.HasPrefix(created.Key, created.Hint))
+
+	userID, err := us.AuthenticateAPIKey(request.Context(), created.Key)
+	require.NoError(t, err)
+	assert.Equal(t, "XXX-YYY-ZZZ", userID)
+
+	// Create without a name.
+	request = getNewRequestWithUserID(http.MethodPost, "/api/user/keys", "XXX-YYY-ZZZ", 0, strings.NewReader(`{}`))
+	request.Header.Set("Content-Type", "application/json")
+	res, _ = serve(hc.CreateAPIKey(), request)
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+
+	// List, the secret is not shown again.
+	res, body = serve(hc.GetAPIKeys(), getNewRequestWithUserID(http.MethodGet, "/api/user/keys", "XXX-YYY-ZZZ", 0, nil))
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	assert.NotContains(t, string(body), created.Key)
+
+	var listed []APIKeyResponse
+	require.NoError(t, json.Unmarshal(body, &listed))
+	require.Len(t, listed, 1)
+	assert.Equal(t, created.ID, listed[0].ID)
+	assert.False(t, listed[0].Revoked)
+
+	// Revoke by another user.
+	res, _ = serve(hc.RevokeAPIKey(), getNewRequestWithUserID(
+		http.MethodDelete, "/api/user/keys/"+created.ID, "AAA-BBB-CCC", 0, nil,
+	))
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
+
+	// Revoke.
+	res, _ = serve(hc.RevokeAPIKey(), getNewRequestWithUserID(
+		http.MethodDelete, "/api/user/keys/"+created.ID, "XXX-YYY-ZZZ", 0, nil,
+	))
+	assert.Equal(t, http.StatusNoContent, res.StatusCode)
+
+	_, err = us.AuthenticateAPIKey(request.Context(), created.Key)
+	assert.Error(t, err)
+
+	// Wrong user ID type.
+	res, _ = serve(hc.GetAPIKeys(), getNewRequestWithUserID(http.MethodGet, "/api/user/keys", "", 1, nil))
+	assert.Equal(t, http.StatusInternalServerError, res.StatusCode)
+}
@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/alrund/yp-1/internal/app/audit"
+)
+
+// Audit returns the audit events of a token or a user to the trusted subnet.
+// The query parameters are token, user_id and limit.
+func (hc *Collection) Audit() func(w http.ResponseWriter, r *http.Request) {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		if !hc.fromTrustedSubnet(w, r) {
+			return
+		}
+
+		hc.writeAudit(w, r)
+	}
+	return fn
+}
+
+// AdminAudit returns the audit events without the trusted subnet check.
+// It is meant for the admin listener, which guards the access by itself.
+func (hc *Collection) AdminAudit() func(w http.ResponseWriter, r *http.Request) {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		hc.writeAudit(w, r)
+	}
+	return fn
+}
+
+func (hc *Collection) writeAudit(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := audit.Filter{Token: query.Get("token"), UserID: query.Get("user_id")}
+	if filter.Token == "" && filter.UserID == "" {
+		http.Error(w, "400 Bad Request.", http.StatusBadRequest)
+		return
+	}
+	if limit := query.Get("limit"); limit != "" {
+		var err error
+		filter.Limit, err = strconv.Atoi(limit)
+		if err != nil || filter.Limit < 1 {
+			http.Error(w, "400 Bad Request.", http.StatusBadRequest)
+			return
+		}
+	}
+
+	events, err := hc.us.GetAuditEvents(r.Context(), filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, events)
+}
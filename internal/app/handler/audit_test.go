@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alrund/yp-1/internal/app"
+	"github.com/alrund/yp-1/internal/app/audit"
+	"github.com/alrund/yp-1/internal/app/config"
+	"github.com/alrund/yp-1/internal/app/linkmeta"
+	"github.com/alrund/yp-1/internal/app/storage"
+	"github.com/alrund/yp-1/internal/app/token/generator"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAudit(t *testing.T) {
+	us := &app.URLShortener{
+		Config: &config.Config{
+			ServerAddress: "localhost:8080",
+			BaseURL:       "http://localhost:8080/",
+			TrustedSubnet: "216.58.192.64/24",
+		},
+		Storage:        storage.NewMap(),
+		TokenGenerator: generator.NewSimple(),
+	}
+	hc := NewCollection(us)
+
+	ctx := audit.WithActor(context.Background(), audit.Actor{
+		UserID:    "XXX-YYY-ZZZ",
+		Source:    audit.SourceHTTP,
+		ClientIP:  "10.0.0.1",
+		RequestID: "req-1",
+	})
+	token, err := us.Add(ctx, "XXX-YYY-ZZZ", "http://ya.ru", linkmeta.Meta{})
+	require.NoError(t, err)
+	require.NoError(t, us.RemoveTokens(ctx, []string{token.Value, "unknown"}, "XXX-YYY-ZZZ"))
+	_, err = us.Add(context.Background(), "AAA-BBB-CCC", "http://ya2.ru", linkmeta.Meta{})
+	require.NoError(t, err)
+
+	serve := func(target, realIP string) (int, []*audit.Event) {
+		request := httptest.NewRequest(http.MethodGet, target, nil)
+		request.Header.Set("X-Real-IP", realIP)
+		w := httptest.NewRecorder()
+		hc.Audit()(w, request)
+		res := w.Result()
+		defer res.Body.Close()
+
+		var events []*audit.Event
+		if res.StatusCode == http.StatusOK {
+			require.NoError(t, json.NewDecoder(res.Body).Decode(&events))
+		}
+		return res.StatusCode, events
+	}
+
+	code, _ := serve("/api/internal/audit?token="+token.Value, "10.0.0.1")
+	assert.Equal(t, http.StatusForbidden, code)
+
+	code, _ = serve("/api/internal/audit", "216.58.192.1")
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	code, _ = serve("/api/internal/audit?user_id=XXX-YYY-ZZZ&limit=none", "216.58.192.1")
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	code, events := serve("/api/internal/audit?token="+token.Value, "216.58.192.1")
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, events, 2, "only the removed token is recorded")
+	assert.Equal(t, audit.ActionDelete, events[0].Action)
+	assert.Equal(t, audit.ActionCreate, events[1].Action)
+	assert.Equal(t, "http://ya.ru", events[1].URL)
+	assert.Equal(t, "XXX-YYY-ZZZ", events[1].ActorID)
+	assert.Equal(t, audit.SourceHTTP, events[1].Source)
+	assert.Equal(t, "10.0.0.1", events[1].ClientIP)
+	assert.Equal(t, "req-1", events[1].RequestID)
+
+	code, events = serve("/api/internal/audit?user_id=AAA-BBB-CCC", "216.58.192.1")
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, events, 1)
+	assert.Equal(t, "http://ya2.ru", events[0].URL)
+
+	code, events = serve("/api/internal/audit?user_id=XXX-YYY-ZZZ&limit=1", "216.58.192.1")
+	require.Equal(t, http.StatusOK, code)
+	assert.Len(t, events, 1)
+}
@@ -7,9 +7,18 @@ import (
 	"net/http/httptest"
 	"time"
 
+	"github.com/alrund/yp-1/internal/app/account"
+	"github.com/alrund/yp-1/internal/app/apikey"
+	"github.com/alrund/yp-1/internal/app/audit"
+	"github.com/alrund/yp-1/internal/app/linkcheck"
+	"github.com/alrund/yp-1/internal/app/linkmeta"
+	"github.com/alrund/yp-1/internal/app/listing"
 	"github.com/alrund/yp-1/internal/app/middleware"
+	"github.com/alrund/yp-1/internal/app/ratelimit"
+	"github.com/alrund/yp-1/internal/app/search"
 	"github.com/alrund/yp-1/internal/app/storage"
 	tkn "github.com/alrund/yp-1/internal/app/token"
+	"github.com/alrund/yp-1/internal/app/workspace"
 )
 
 type TestStorage struct{}
@@ -51,6 +60,13 @@ func (st *TestStorage) GetURLsByUserID(userID string) ([]storage.URLpairs, error
 	}
 }
 
+func (st *TestStorage) GetURLsPage(ownerIDs []string, query listing.Query) ([]listing.Item, string, error) {
+	if ownerIDs[0] == "empty" {
+		return []listing.Item{}, "", nil
+	}
+	return query.Page([]listing.Item{{Token: "shorturl", URL: "url", OwnerID: ownerIDs[0]}}, time.Now())
+}
+
 func (st *TestStorage) GetURL(string) (string, error)                                 { return "https://ya.ru", nil }
 func (st *TestStorage) GetTokensByUserID(string) ([]*tkn.Token, error)                { return nil, nil }
 func (st *TestStorage) GetTokenByURL(string) (*tkn.Token, error)                      { return nil, nil }
@@ -61,6 +77,59 @@ func (st *TestStorage) Ping(ctx context.Context) error
 func (st *TestStorage) RemoveTokens(tokenValues []string, userID string) error        { return nil }
 func (st *TestStorage) GetURLCount() (int, error)                                     { return 2, nil }
 func (st *TestStorage) GetUserIDCount() (int, error)                                  { return 3, nil }
+func (st *TestStorage) SetAPIKey(*apikey.Key) error                                   { return nil }
+func (st *TestStorage) GetAPIKey(string) (*apikey.Key, error)                         { return nil, storage.ErrAPIKeyNotFound }
+func (st *TestStorage) GetAPIKeysByUserID(string) ([]*apikey.Key, error)              { return nil, nil }
+func (st *TestStorage) RevokeAPIKey(string, string) error                             { return nil }
+func (st *TestStorage) SetAccount(*account.Account) error                             { return nil }
+func (st *TestStorage) ReassignUser(string, string) error                             { return nil }
+func (st *TestStorage) HasUser(string) (bool, error)                                  { return true, nil }
+func (st *TestStorage) SetWorkspace(*workspace.Workspace) error                       { return nil }
+func (st *TestStorage) SetMember(*workspace.Member) error                             { return nil }
+func (st *TestStorage) GetMembers(string) ([]*workspace.Member, error)                { return nil, nil }
+func (st *TestStorage) GetMembershipsByUserID(string) ([]*workspace.Member, error)    { return nil, nil }
+func (st *TestStorage) RemoveMember(string, string) error                             { return nil }
+func (st *TestStorage) SetRateBucket(string, ratelimit.Bucket) error                  { return nil }
+func (st *TestStorage) AddQuotaUsage(string, string, int) (int, error)                { return 0, nil }
+func (st *TestStorage) PruneRateLimits(time.Time, string) error                       { return nil }
+func (st *TestStorage) AddAuditEvents([]*audit.Event) error                           { return nil }
+func (st *TestStorage) GetAuditEvents(audit.Filter) ([]*audit.Event, error)           { return nil, nil }
+func (st *TestStorage) AddClick(string) error                                         { return nil }
+func (st *TestStorage) FlushClicks() error                                            { return nil }
+func (st *TestStorage) SetLinkMeta(string, linkmeta.Meta) error                       { return nil }
+func (st *TestStorage) GetLinkMeta(string) (linkmeta.Meta, error)                     { return linkmeta.Meta{}, nil }
+func (st *TestStorage) GetTags([]string) ([]linkmeta.TagCount, error)                 { return nil, nil }
+func (st *TestStorage) RenameTag([]string, string, string) (int, error)               { return 0, nil }
+func (st *TestStorage) SearchURLs([]string, search.Query) ([]listing.Item, error)     { return nil, nil }
+func (st *TestStorage) GetURLsByTokens([]string) ([]listing.Item, error)              { return nil, nil }
+func (st *TestStorage) GetLinksToCheck(time.Time, int) ([]listing.Item, error)        { return nil, nil }
+func (st *TestStorage) SetLinkHealth(map[string]linkcheck.Health) error               { return nil }
+func (st *TestStorage) SetLinkWebhook(string, string) error                           { return nil }
+func (st *TestStorage) GetLinkWebhook(string) (string, error)                         { return "", nil }
+
+func (st *TestStorage) UpdateLinkMeta([]string, string, linkmeta.Update) (linkmeta.Meta, error) {
+	return linkmeta.Meta{}, nil
+}
+
+func (st *TestStorage) GetAccountByLogin(string) (*account.Account, error) {
+	return nil, storage.ErrAccountNotFound
+}
+
+func (st *TestStorage) GetAccountByID(string) (*account.Account, error) {
+	return nil, storage.ErrAccountNotFound
+}
+
+func (st *TestStorage) GetWorkspace(string) (*workspace.Workspace, error) {
+	return nil, storage.ErrWorkspaceNotFound
+}
+
+func (st *TestStorage) GetMember(string, string) (*workspace.Member, error) {
+	return nil, storage.ErrMemberNotFound
+}
+
+func (st *TestStorage) GetRateBucket(string) (ratelimit.Bucket, error) {
+	return ratelimit.Bucket{}, nil
+}
 
 func getNewRequestWithUserID(method, target, userID string, errTypeUserID int, body io.Reader) *http.Request {
 	request := httptest.NewRequest(method, target, body)
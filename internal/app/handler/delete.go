@@ -2,9 +2,11 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 
+	"github.com/alrund/yp-1/internal/app"
 	"github.com/alrund/yp-1/internal/app/middleware"
 )
 
@@ -36,11 +38,17 @@ func (hc *Collection) DeleteURLs() func(w http.ResponseWriter, r *http.Request)
 			return
 		}
 
-		w.WriteHeader(http.StatusAccepted)
+		err = hc.us.RemoveTokensAsync(r.Context(), tokens, userID)
+		if err != nil {
+			if errors.Is(err, app.ErrRemoveQueueFull) || errors.Is(err, app.ErrRemoveQueueClosed) {
+				http.Error(w, "503 Service Unavailable.", http.StatusServiceUnavailable)
+				return
+			}
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
 
-		go func() {
-			_ = hc.us.RemoveTokens(tokens, userID)
-		}()
+		w.WriteHeader(http.StatusAccepted)
 	}
 	return fn
 }
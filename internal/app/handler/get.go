@@ -4,8 +4,12 @@ import (
 	"encoding/json"
 	"errors"
 	"net/http"
+	"strconv"
 
+	"github.com/alrund/yp-1/internal/app/linkmeta"
+	"github.com/alrund/yp-1/internal/app/listing"
 	"github.com/alrund/yp-1/internal/app/middleware"
+	"github.com/alrund/yp-1/internal/app/screening"
 	"github.com/alrund/yp-1/internal/app/storage"
 	"github.com/alrund/yp-1/internal/app/token"
 )
@@ -18,7 +22,7 @@ func (hc *Collection) Get() func(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "400 Bad Request.", http.StatusBadRequest)
 			return
 		}
-		url, err := hc.us.Get(id)
+		url, err := hc.us.Get(r.Context(), id)
 		if err != nil {
 			if errors.Is(err, storage.ErrTokenNotFound) {
 				http.Error(w, "404 Not Found.", http.StatusNotFound)
@@ -35,6 +39,11 @@ func (hc *Collection) Get() func(w http.ResponseWriter, r *http.Request) {
 				return
 			}
 
+			if errors.Is(err, screening.ErrBlocked) {
+				writeInterstitial(w, url, err)
+				return
+			}
+
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
@@ -50,7 +59,9 @@ func (hc *Collection) Get() func(w http.ResponseWriter, r *http.Request) {
 	return fn
 }
 
-// GetUserURLs returns a URL by user ID.
+// GetUserURLs returns a page of the user's URLs.
+// The query parameters limit, cursor, sort, status, contains and tag select the page,
+// the Link header refers to the next page.
 func (hc *Collection) GetUserURLs() func(w http.ResponseWriter, r *http.Request) {
 	fn := func(w http.ResponseWriter, r *http.Request) {
 		contextUserID := r.Context().Value(middleware.UserIDContextKey)
@@ -60,13 +71,24 @@ func (hc *Collection) GetUserURLs() func(w http.ResponseWriter, r *http.Request)
 			return
 		}
 
-		urls, err := hc.us.GetUserURLs(userID)
+		query, err := parseURLQuery(r)
+		if err != nil {
+			http.Error(w, "400 Bad Request.", http.StatusBadRequest)
+			return
+		}
+
+		urls, next, err := hc.us.GetUserURLs(r.Context(), userID, query)
 		if err != nil {
 			if errors.Is(err, storage.ErrTokenNotFound) {
 				http.Error(w, "204 No Content.", http.StatusNoContent)
 				return
 			}
 
+			if listing.IsInvalid(err) || linkmeta.IsInvalid(err) {
+				http.Error(w, "400 Bad Request.", http.StatusBadRequest)
+				return
+			}
+
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
@@ -77,6 +99,13 @@ func (hc *Collection) GetUserURLs() func(w http.ResponseWriter, r *http.Request)
 			return
 		}
 
+		if next != "" {
+			nextURL := *r.URL
+			params := nextURL.Query()
+			params.Set("cursor", next)
+			nextURL.RawQuery = params.Encode()
+			w.Header().Set("Link", "<"+nextURL.RequestURI()+">; rel=\"next\"")
+		}
 		w.Header().Set("Content-Type", "application/json; charset=utf-8")
 		_, err = w.Write(result)
 		if err != nil {
@@ -86,3 +115,25 @@ func (hc *Collection) GetUserURLs() func(w http.ResponseWriter, r *http.Request)
 	}
 	return fn
 }
+
+// parseURLQuery reads the listing query from the query parameters.
+func parseURLQuery(r *http.Request) (listing.Query, error) {
+	params := r.URL.Query()
+	query := listing.Query{
+		Sort:     listing.Sort(params.Get("sort")),
+		Status:   listing.Status(params.Get("status")),
+		Contains: params.Get("contains"),
+		Tag:      params.Get("tag"),
+		Cursor:   params.Get("cursor"),
+	}
+
+	if limit := params.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil {
+			return listing.Query{}, listing.ErrInvalidLimit
+		}
+		query.Limit = n
+	}
+
+	return query, nil
+}
@@ -2,18 +2,23 @@ package handler
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/alrund/yp-1/internal/app"
 	"github.com/alrund/yp-1/internal/app/config"
 	"github.com/alrund/yp-1/internal/app/helper"
+	"github.com/alrund/yp-1/internal/app/linkmeta"
 	"github.com/alrund/yp-1/internal/app/middleware"
+	"github.com/alrund/yp-1/internal/app/storage"
 	"github.com/alrund/yp-1/internal/app/token/generator"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestGet(t *testing.T) {
@@ -190,6 +195,32 @@ func TestGetUserURLs(t *testing.T) {
 				contentType: "text/plain; charset=utf-8",
 			},
 		},
+		{
+			name: "bad sort",
+			request: request{
+				method: http.MethodGet,
+				target: "/api/user/urls?sort=url",
+				userID: "XXX-YYY-ZZZ",
+			},
+			want: want{
+				code:        http.StatusBadRequest,
+				response:    "400 Bad Request.\n",
+				contentType: "text/plain; charset=utf-8",
+			},
+		},
+		{
+			name: "bad limit",
+			request: request{
+				method: http.MethodGet,
+				target: "/api/user/urls?limit=many",
+				userID: "XXX-YYY-ZZZ",
+			},
+			want: want{
+				code:        http.StatusBadRequest,
+				response:    "400 Bad Request.\n",
+				contentType: "text/plain; charset=utf-8",
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -262,6 +293,97 @@ func ExampleCollection_GetUserURLs() {
 
 	fmt.Println(string(buf))
 	// [
-	//  {"short_url": "http://localhost:8080/koRTZS", "original_url": "https://google.ru"}
+	//  {
+	//   "short_url": "http://localhost:8080/koRTZS", "original_url": "https://google.ru",
+	//   "created": "2022-12-10T12:00:00Z", "expire": "2022-12-11T12:00:00Z",
+	//   "removed": false, "clicks": 3, "status": "active"
+	//  }
 	// ]
 }
+
+func TestGetUserURLsPages(t *testing.T) {
+	us := &app.URLShortener{
+		Config: &config.Config{
+			ServerAddress: "localhost:8080",
+			BaseURL:       "http://localhost:8080/",
+		},
+		Storage:        storage.NewMap(),
+		TokenGenerator: generator.NewSimple(),
+	}
+	hc := NewCollection(us)
+
+	tokens := make([]string, 0, 3)
+	for _, url := range []string{"http://a.ru", "http://b.ru", "http://c.com"} {
+		token, err := us.Add(context.Background(), "XXX-YYY-ZZZ", url, linkmeta.Meta{})
+		require.NoError(t, err)
+		tokens = append(tokens, token.Value)
+	}
+	_, err := us.Get(context.Background(), tokens[0])
+	require.NoError(t, err)
+	require.NoError(t, us.RemoveTokens(context.Background(), []string{tokens[1]}, "XXX-YYY-ZZZ"))
+
+	serve := func(target string) (int, string, []string) {
+		request := getNewRequestWithUserID(http.MethodGet, target, "XXX-YYY-ZZZ", 0, nil)
+		w := httptest.NewRecorder()
+		hc.GetUserURLs()(w, request)
+		res := w.Result()
+		defer res.Body.Close()
+
+		urls := make([]string, 0)
+		if res.StatusCode == http.StatusOK {
+			var pairs []storage.URLpairs
+			require.NoError(t, json.NewDecoder(res.Body).Decode(&pairs))
+			for _, p := range pairs {
+				urls = append(urls, p.OriginalURL)
+			}
+		}
+		return res.StatusCode, res.Header.Get("Link"), urls
+	}
+
+	// The newest first, two a page.
+	code, link, urls := serve("/api/user/urls?limit=2")
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []string{"http://c.com", "http://b.ru"}, urls)
+	require.True(t, strings.HasPrefix(link, "</api/user/urls?"))
+	next := strings.TrimSuffix(strings.TrimPrefix(link, "<"), `>; rel="next"`)
+
+	code, link, urls = serve(next)
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []string{"http://a.ru"}, urls)
+	assert.Empty(t, link)
+
+	_, _, urls = serve("/api/user/urls?sort=-clicks&limit=1")
+	assert.Equal(t, []string{"http://a.ru"}, urls)
+
+	_, _, urls = serve("/api/user/urls?status=active&contains=.RU")
+	assert.Equal(t, []string{"http://a.ru"}, urls)
+
+	_, _, urls = serve("/api/user/urls?status=removed")
+	assert.Equal(t, []string{"http://b.ru"}, urls)
+
+	code, _, _ = serve("/api/user/urls?status=expired")
+	assert.Equal(t, http.StatusNoContent, code)
+
+	code, _, _ = serve("/api/user/urls?sort=clicks&" + strings.SplitN(next, "?", 2)[1])
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	// The links carry their metadata.
+	metadata := func(target string) map[string]interface{} {
+		request := getNewRequestWithUserID(http.MethodGet, target, "XXX-YYY-ZZZ", 0, nil)
+		w := httptest.NewRecorder()
+		hc.GetUserURLs()(w, request)
+		var pairs []map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &pairs))
+		require.Len(t, pairs, 1)
+		return pairs[0]
+	}
+	clicked := metadata("/api/user/urls?sort=-clicks&limit=1")
+	assert.Equal(t, float64(1), clicked["clicks"])
+	assert.Equal(t, "active", clicked["status"])
+	assert.Equal(t, false, clicked["removed"])
+	assert.NotEmpty(t, clicked["created"])
+	assert.NotEmpty(t, clicked["expire"])
+	removed := metadata("/api/user/urls?status=removed")
+	assert.Equal(t, "removed", removed["status"])
+	assert.Equal(t, true, removed["removed"])
+}
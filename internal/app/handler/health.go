@@ -0,0 +1,45 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/alrund/yp-1/internal/app/health"
+)
+
+// Healthz reports that the process is alive.
+func (hc *Collection) Healthz() func(w http.ResponseWriter, r *http.Request) {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		writeHealthReport(w, &health.Report{Status: health.StatusUp})
+	}
+	return fn
+}
+
+// Readyz reports whether the application is able to serve requests.
+func (hc *Collection) Readyz() func(w http.ResponseWriter, r *http.Request) {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		writeHealthReport(w, hc.us.Readiness(r.Context()))
+	}
+	return fn
+}
+
+func writeHealthReport(w http.ResponseWriter, report *health.Report) {
+	result, err := json.Marshal(report)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	httpCode := http.StatusOK
+	if !report.IsUp() {
+		httpCode = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(httpCode)
+	_, err = w.Write(result)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alrund/yp-1/internal/app"
+	"github.com/alrund/yp-1/internal/app/config"
+	"github.com/alrund/yp-1/internal/app/token/generator"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealth(t *testing.T) {
+	fullQueue := app.NewRemoveQueue(1, 1)
+	_ = fullQueue.Push(context.Background(), []string{"qwerty"}, "XXX-YYY-ZZZ")
+
+	type want struct {
+		code     int
+		response string
+	}
+	tests := []struct {
+		name        string
+		handler     func(hc *Collection) func(w http.ResponseWriter, r *http.Request)
+		removeQueue *app.RemoveQueue
+		want        want
+	}{
+		{
+			name:    "healthz",
+			handler: (*Collection).Healthz,
+			want: want{
+				code:     http.StatusOK,
+				response: `{"status":"up"}`,
+			},
+		},
+		{
+			name:        "readyz",
+			handler:     (*Collection).Readyz,
+			removeQueue: app.NewRemoveQueue(10, 1),
+			want: want{
+				code:     http.StatusOK,
+				response: `{"status":"up","components":{"storage":{"status":"up"},"remove_queue":{"status":"up"}}}`,
+			},
+		},
+		{
+			name:        "readyz - remove queue is full",
+			handler:     (*Collection).Readyz,
+			removeQueue: fullQueue,
+			want: want{
+				code: http.StatusServiceUnavailable,
+				response: `{"status":"down","components":{"storage":{"status":"up"},` +
+					`"remove_queue":{"status":"down","error":"remove queue is full: 1 tasks"}}}`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			us := &app.URLShortener{
+				Config: &config.Config{
+					ServerAddress: "localhost:8080",
+					BaseURL:       "http://localhost:8080/",
+				},
+				Storage:        new(TestStorage),
+				TokenGenerator: generator.NewSimple(),
+				RemoveQueue:    tt.removeQueue,
+			}
+			hc := NewCollection(us)
+			request := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+			w := httptest.NewRecorder()
+			h := http.HandlerFunc(tt.handler(hc))
+			h.ServeHTTP(w, request)
+			res := w.Result()
+			defer res.Body.Close()
+
+			assert.Equal(t, tt.want.code, res.StatusCode)
+			assert.Equal(t, "application/json; charset=utf-8", res.Header.Get("Content-Type"))
+			assert.JSONEq(t, tt.want.response, w.Body.String())
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package handler
+
+import (
+	"errors"
+	"html/template"
+	"net/http"
+
+	"github.com/alrund/yp-1/internal/app/screening"
+)
+
+var interstitialTemplate = template.Must(template.New("interstitial").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="robots" content="noindex">
+<title>Warning: suspicious link</title>
+</head>
+<body>
+<h1>Warning: suspicious link</h1>
+<p>The link leads to a site which is suspected of phishing or malware{{if .Reason}} ({{.Reason}}){{end}}.</p>
+<p>Continue only if you trust it: <a href="{{.URL}}" rel="noopener noreferrer nofollow">{{.URL}}</a></p>
+</body>
+</html>
+`))
+
+// writeInterstitial writes the warning page shown instead of redirecting to a blocklisted URL.
+func writeInterstitial(w http.ResponseWriter, url string, err error) {
+	data := struct {
+		URL    string
+		Reason string
+	}{URL: url}
+	var blocked *screening.BlockedError
+	if errors.As(err, &blocked) {
+		data.Reason = blocked.Reason
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	if err := interstitialTemplate.Execute(w, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
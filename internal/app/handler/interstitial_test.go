@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alrund/yp-1/internal/app"
+	"github.com/alrund/yp-1/internal/app/config"
+	"github.com/alrund/yp-1/internal/app/screening"
+	"github.com/alrund/yp-1/internal/app/storage"
+	"github.com/alrund/yp-1/internal/app/token/generator"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInterstitial(t *testing.T) {
+	domainsFile := filepath.Join(t.TempDir(), "domains.txt")
+	require.NoError(t, os.WriteFile(domainsFile, []byte("evil.com\n"), 0o600))
+	blocklist, err := screening.LoadBlocklist(domainsFile, "")
+	require.NoError(t, err)
+
+	us := &app.URLShortener{
+		Config: &config.Config{
+			ServerAddress: "localhost:8080",
+			BaseURL:       "http://localhost:8080/",
+		},
+		Storage:        storage.NewMap(),
+		TokenGenerator: generator.NewSimple(),
+		Screener: &screening.Screener{
+			Blocklist: blocklist,
+			Reputation: screening.CheckerFunc(func(ctx context.Context, url string) (screening.Verdict, error) {
+				return screening.Verdict{Blocked: strings.Contains(url, "phish")}, nil
+			}),
+		},
+	}
+	hc := NewCollection(us)
+
+	add := func(body string) (int, string) {
+		request := getNewRequestWithUserID(http.MethodPost, "/", "XXX-YYY-ZZZ", 0, strings.NewReader(body))
+		w := httptest.NewRecorder()
+		hc.Add()(w, request)
+		res := w.Result()
+		defer res.Body.Close()
+
+		b, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		return res.StatusCode, string(b)
+	}
+
+	code, _ := add("http://www.evil.com/login")
+	assert.Equal(t, http.StatusForbidden, code)
+	code, _ = add("http://phish.ru/")
+	assert.Equal(t, http.StatusForbidden, code)
+
+	request := getNewRequestWithUserID(http.MethodPost, "/api/shorten/batch", "XXX-YYY-ZZZ", 0, strings.NewReader(
+		`[{"correlation_id": "1", "original_url": "http://ya.ru"}, {"correlation_id": "2", "original_url": "http://evil.com"}]`,
+	))
+	request.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	hc.AddBatchJSON()(w, request)
+	res := w.Result()
+	res.Body.Close()
+	assert.Equal(t, http.StatusForbidden, res.StatusCode)
+
+	code, shortURL := add("http://ya.ru/?q=<b>")
+	require.Equal(t, http.StatusCreated, code)
+	target := strings.TrimPrefix(shortURL, "http://localhost:8080")
+
+	get := func() *http.Response {
+		w := httptest.NewRecorder()
+		hc.Get()(w, httptest.NewRequest(http.MethodGet, target, nil))
+		return w.Result()
+	}
+
+	res = get()
+	res.Body.Close()
+	assert.Equal(t, http.StatusTemporaryRedirect, res.StatusCode)
+
+	require.NoError(t, os.WriteFile(domainsFile, []byte("evil.com\nya.ru\n"), 0o600))
+	require.NoError(t, blocklist.Load(domainsFile, ""))
+
+	res = get()
+	defer res.Body.Close()
+	b, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Empty(t, res.Header.Get("Location"))
+	assert.Equal(t, "text/html; charset=utf-8", res.Header.Get("Content-Type"))
+	assert.Contains(t, string(b), "the domain ya.ru is blocklisted")
+	assert.Contains(t, string(b), `href="http://ya.ru/?q=%3cb%3e"`)
+
+	resolved, err := us.GetBatch(context.Background(), []string{strings.TrimPrefix(target, "/")})
+	require.NoError(t, err)
+	assert.Equal(t, app.ResolveBlocked, resolved[0].Status)
+	assert.Equal(t, "http://ya.ru/?q=<b>", resolved[0].URL)
+}
@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/alrund/yp-1/internal/app"
+	"github.com/alrund/yp-1/internal/app/middleware"
+)
+
+// LinkWebhookJSON the webhook the changes of the reachability of the user's links are posted to.
+type LinkWebhookJSON struct {
+	URL string `json:"url"`
+}
+
+// GetLinkWebhook returns the user's link check webhook.
+func (hc *Collection) GetLinkWebhook() func(w http.ResponseWriter, r *http.Request) {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		contextUserID := r.Context().Value(middleware.UserIDContextKey)
+		userID, ok := contextUserID.(string)
+		if !ok {
+			http.Error(w, "500 Internal Server Error.", http.StatusInternalServerError)
+			return
+		}
+
+		webhookURL, err := hc.us.GetLinkWebhook(r.Context(), userID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if webhookURL == "" {
+			http.Error(w, "404 Not Found.", http.StatusNotFound)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, LinkWebhookJSON{URL: webhookURL})
+	}
+	return fn
+}
+
+// SetLinkWebhook registers the user's link check webhook, replacing the former one.
+func (hc *Collection) SetLinkWebhook() func(w http.ResponseWriter, r *http.Request) {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		if !hasContentType(r, "application/json") {
+			http.Error(w, "415 Unsupported Media Type.", http.StatusUnsupportedMediaType)
+			return
+		}
+
+		contextUserID := r.Context().Value(middleware.UserIDContextKey)
+		userID, ok := contextUserID.(string)
+		if !ok {
+			http.Error(w, "500 Internal Server Error.", http.StatusInternalServerError)
+			return
+		}
+
+		b, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		jsonRequest := LinkWebhookJSON{}
+		err = json.Unmarshal(b, &jsonRequest)
+		if err != nil {
+			http.Error(w, "400 Bad Request.", http.StatusBadRequest)
+			return
+		}
+
+		err = hc.us.SetLinkWebhook(r.Context(), userID, jsonRequest.URL)
+		if err != nil {
+			if errors.Is(err, app.ErrInvalidWebhookURL) {
+				http.Error(w, "400 Bad Request.", http.StatusBadRequest)
+				return
+			}
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+	return fn
+}
+
+// RemoveLinkWebhook removes the user's link check webhook.
+func (hc *Collection) RemoveLinkWebhook() func(w http.ResponseWriter, r *http.Request) {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		contextUserID := r.Context().Value(middleware.UserIDContextKey)
+		userID, ok := contextUserID.(string)
+		if !ok {
+			http.Error(w, "500 Internal Server Error.", http.StatusInternalServerError)
+			return
+		}
+
+		err := hc.us.RemoveLinkWebhook(r.Context(), userID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+	return fn
+}
@@ -0,0 +1,169 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alrund/yp-1/internal/app"
+	"github.com/alrund/yp-1/internal/app/config"
+	"github.com/alrund/yp-1/internal/app/linkcheck"
+	"github.com/alrund/yp-1/internal/app/linkmeta"
+	"github.com/alrund/yp-1/internal/app/storage"
+	"github.com/alrund/yp-1/internal/app/token/generator"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLinkHealth(t *testing.T) {
+	destinations := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/gone" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer destinations.Close()
+
+	var (
+		mx     sync.Mutex
+		events = map[string][]linkcheck.Event{} // webhook path: events
+	)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var batch []linkcheck.Event
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&batch))
+		mx.Lock()
+		events[r.URL.Path] = append(events[r.URL.Path], batch...)
+		mx.Unlock()
+	}))
+	defer webhook.Close()
+
+	us := &app.URLShortener{
+		Config: &config.Config{
+			ServerAddress: "localhost:8080",
+			BaseURL:       "http://localhost:8080/",
+		},
+		Storage:        storage.NewMap(),
+		TokenGenerator: generator.NewSimple(),
+	}
+	hc := NewCollection(us)
+
+	_, err := us.Add(context.Background(), "XXX-YYY-ZZZ", destinations.URL+"/ok", linkmeta.Meta{})
+	require.NoError(t, err)
+	gone, err := us.Add(context.Background(), "XXX-YYY-ZZZ", destinations.URL+"/gone", linkmeta.Meta{})
+	require.NoError(t, err)
+	othersGone, err := us.Add(context.Background(), "AAA-BBB-CCC", destinations.URL+"/gone?other", linkmeta.Meta{})
+	require.NoError(t, err)
+	_, err = us.Add(context.Background(), "DDD-EEE-FFF", destinations.URL+"/gone?silent", linkmeta.Meta{})
+	require.NoError(t, err)
+
+	// Every owner registers a webhook of their own, the last one has none.
+	for userID, path := range map[string]string{"XXX-YYY-ZZZ": "/first", "AAA-BBB-CCC": "/second"} {
+		body := strings.NewReader(`{"url": "` + webhook.URL + path + `"}`)
+		request := getNewRequestWithUserID(http.MethodPut, "/api/user/webhook", userID, 0, body)
+		request.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		hc.SetLinkWebhook()(w, request)
+		require.Equal(t, http.StatusNoContent, w.Code)
+	}
+
+	checker := linkcheck.NewChecker(time.Second, 2, 0)
+	checker.Client = destinations.Client() // the test destinations are on the loopback
+	notifier := linkcheck.NewWebhook(time.Second)
+	notifier.Client.Transport = webhook.Client().Transport // so are the webhooks
+	lc := &app.LinkChecker{
+		Checker: checker,
+		Webhook: notifier,
+	}
+	checked, err := us.CheckLinks(context.Background(), lc)
+	require.NoError(t, err)
+	assert.Equal(t, 4, checked)
+
+	// Only the broken links are reported, each to the webhook of its owner.
+	require.Len(t, events, 2)
+	require.Len(t, events["/first"], 1)
+	assert.Equal(t, gone.Value, events["/first"][0].Token)
+	assert.Equal(t, "http://localhost:8080/"+gone.Value, events["/first"][0].ShortURL)
+	assert.Equal(t, "XXX-YYY-ZZZ", events["/first"][0].OwnerID)
+	assert.False(t, events["/first"][0].Reachable)
+	assert.Equal(t, http.StatusNotFound, events["/first"][0].StatusCode)
+	require.Len(t, events["/second"], 1)
+	assert.Equal(t, othersGone.Value, events["/second"][0].Token)
+	assert.Equal(t, "AAA-BBB-CCC", events["/second"][0].OwnerID)
+
+	// The owner sees the health of the links.
+	request := getNewRequestWithUserID(http.MethodGet, "/api/user/urls", "XXX-YYY-ZZZ", 0, nil)
+	w := httptest.NewRecorder()
+	hc.GetUserURLs()(w, request)
+	require.Equal(t, http.StatusOK, w.Code)
+	var pairs []storage.URLpairs
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &pairs))
+	require.Len(t, pairs, 2)
+	health := map[string]*linkcheck.Health{}
+	for _, pair := range pairs {
+		health[pair.OriginalURL] = pair.Health
+	}
+	require.NotNil(t, health[destinations.URL+"/ok"])
+	assert.True(t, health[destinations.URL+"/ok"].Reachable)
+	assert.Equal(t, http.StatusOK, health[destinations.URL+"/ok"].StatusCode)
+	require.NotNil(t, health[destinations.URL+"/gone"])
+	assert.False(t, health[destinations.URL+"/gone"].Reachable)
+	assert.Equal(t, http.StatusNotFound, health[destinations.URL+"/gone"].StatusCode)
+
+	// The links checked within the interval wait, the unchanged ones are not reported again.
+	lc.Interval = time.Hour
+	checked, err = us.CheckLinks(context.Background(), lc)
+	require.NoError(t, err)
+	assert.Equal(t, 0, checked)
+	lc.Interval = 0
+	_, err = us.CheckLinks(context.Background(), lc)
+	require.NoError(t, err)
+	assert.Len(t, events["/first"], 1)
+	assert.Len(t, events["/second"], 1)
+}
+
+func TestLinkWebhook(t *testing.T) {
+	us := &app.URLShortener{
+		Config:  &config.Config{},
+		Storage: storage.NewMap(),
+	}
+	hc := NewCollection(us)
+
+	get := func(userID string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		hc.GetLinkWebhook()(w, getNewRequestWithUserID(http.MethodGet, "/api/user/webhook", userID, 0, nil))
+		return w
+	}
+	set := func(userID, body string) int {
+		request := getNewRequestWithUserID(http.MethodPut, "/api/user/webhook", userID, 0, strings.NewReader(body))
+		request.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		hc.SetLinkWebhook()(w, request)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusNotFound, get("XXX-YYY-ZZZ").Code)
+
+	assert.Equal(t, http.StatusBadRequest, set("XXX-YYY-ZZZ", `{"url": "hooks.example.com"}`))
+	assert.Equal(t, http.StatusBadRequest, set("XXX-YYY-ZZZ", `{"url": "ftp://hooks.example.com"}`))
+	assert.Equal(t, http.StatusBadRequest, set("XXX-YYY-ZZZ", `{"url": ""}`))
+	assert.Equal(t, http.StatusBadRequest, set("XXX-YYY-ZZZ", `{"url":`))
+	assert.Equal(t, http.StatusNoContent, set("XXX-YYY-ZZZ", `{"url": "https://hooks.example.com/links"}`))
+
+	w := get("XXX-YYY-ZZZ")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"url": "https://hooks.example.com/links"}`, w.Body.String())
+	assert.Equal(t, http.StatusNotFound, get("AAA-BBB-CCC").Code, "the webhook is the user's own")
+
+	w = httptest.NewRecorder()
+	hc.RemoveLinkWebhook()(w, getNewRequestWithUserID(http.MethodDelete, "/api/user/webhook", "XXX-YYY-ZZZ", 0, nil))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, http.StatusNotFound, get("XXX-YYY-ZZZ").Code)
+
+	w = httptest.NewRecorder()
+	hc.GetLinkWebhook()(w, getNewRequestWithUserID(http.MethodGet, "/api/user/webhook", "", 1, nil))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
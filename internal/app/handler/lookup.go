@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/alrund/yp-1/internal/app/listing"
+	"github.com/alrund/yp-1/internal/app/storage"
+	"github.com/alrund/yp-1/internal/app/urlcheck"
+)
+
+type LookupResponse struct {
+	ShortURL    string         `json:"short_url"`
+	OriginalURL string         `json:"original_url"`
+	Status      listing.Status `json:"status"`
+	Expire      time.Time      `json:"expire"`
+}
+
+// LookupURL returns the short URL of the original URL given by the url parameter.
+// It neither creates a link nor refreshes an expired one.
+func (hc *Collection) LookupURL() func(w http.ResponseWriter, r *http.Request) {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		url := r.URL.Query().Get("url")
+		if url == "" {
+			http.Error(w, "400 Bad Request.", http.StatusBadRequest)
+			return
+		}
+
+		pair, err := hc.us.LookupURL(r.Context(), url)
+		if err != nil {
+			if urlcheck.IsInvalid(err) {
+				http.Error(w, "400 Bad Request.", http.StatusBadRequest)
+				return
+			}
+			if errors.Is(err, storage.ErrTokenNotFound) {
+				http.Error(w, "404 Not Found.", http.StatusNotFound)
+				return
+			}
+
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, LookupResponse{
+			ShortURL:    pair.ShortURL,
+			OriginalURL: pair.OriginalURL,
+			Status:      pair.Status,
+			Expire:      pair.Expire,
+		})
+	}
+	return fn
+}
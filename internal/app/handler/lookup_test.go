@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/alrund/yp-1/internal/app"
+	"github.com/alrund/yp-1/internal/app/config"
+	"github.com/alrund/yp-1/internal/app/listing"
+	"github.com/alrund/yp-1/internal/app/storage"
+	tkn "github.com/alrund/yp-1/internal/app/token"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookupURL(t *testing.T) {
+	expired := time.Unix(time.Now().Add(-time.Hour).Unix(), 0)
+	preparedStorage := storage.NewMap()
+	require.NoError(t, preparedStorage.Set("XXX-YYY-ZZZ", "http://ya.ru/?q=1", &tkn.Token{
+		Value: "qwerty", Expire: time.Now().Add(tkn.LifeTime),
+	}))
+	require.NoError(t, preparedStorage.Set("XXX-YYY-ZZZ", "http://old.ru", &tkn.Token{Value: "asdfgh", Expire: expired}))
+
+	us := &app.URLShortener{
+		Config: &config.Config{
+			ServerAddress: "localhost:8080",
+			BaseURL:       "http://localhost:8080/",
+		},
+		Storage: preparedStorage,
+	}
+	hc := NewCollection(us)
+
+	tests := []struct {
+		name       string
+		target     string
+		wantCode   int
+		wantShort  string
+		wantStatus listing.Status
+	}{
+		{"no url", "/api/lookup", http.StatusBadRequest, "", ""},
+		{"invalid url", "/api/lookup?url=" + url.QueryEscape("javascript:alert(1)"), http.StatusBadRequest, "", ""},
+		{"other form", "/api/lookup?url=" + url.QueryEscape("HTTP://Ya.RU:80/?q=1"), http.StatusOK,
+			"http://localhost:8080/qwerty", listing.StatusActive},
+		{"unknown", "/api/lookup?url=" + url.QueryEscape("http://lost.ru"), http.StatusNotFound, "", ""},
+		{"active", "/api/lookup?url=" + url.QueryEscape("http://ya.ru/?q=1"), http.StatusOK,
+			"http://localhost:8080/qwerty", listing.StatusActive},
+		{"expired", "/api/lookup?url=" + url.QueryEscape("http://old.ru"), http.StatusOK,
+			"http://localhost:8080/asdfgh", listing.StatusExpired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			w := httptest.NewRecorder()
+			hc.LookupURL()(w, request)
+			res := w.Result()
+			defer res.Body.Close()
+
+			require.Equal(t, tt.wantCode, res.StatusCode)
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+			var response LookupResponse
+			require.NoError(t, json.NewDecoder(res.Body).Decode(&response))
+			assert.Equal(t, tt.wantShort, response.ShortURL)
+			assert.Equal(t, tt.wantStatus, response.Status)
+		})
+	}
+
+	token, err := us.GetToken("asdfgh")
+	require.NoError(t, err)
+	assert.True(t, token.Expire.Equal(expired), "the lookup does not refresh the link")
+
+	_, err = us.LookupURL(context.Background(), "http://lost.ru")
+	assert.ErrorIs(t, err, storage.ErrTokenNotFound)
+	has, err := us.HasURL("http://lost.ru")
+	require.NoError(t, err)
+	assert.False(t, has, "the lookup does not create a link")
+}
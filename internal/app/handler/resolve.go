@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/alrund/yp-1/internal/app"
+)
+
+type ResolveResponseRow struct {
+	Token       string            `json:"token"`
+	OriginalURL string            `json:"original_url,omitempty"`
+	Status      app.ResolveStatus `json:"status"`
+}
+
+// ResolveBatch resolves multiple tokens at once, the request is a JSON array of the tokens.
+// Each token gets its original URL or the reason it cannot be resolved.
+func (hc *Collection) ResolveBatch() func(w http.ResponseWriter, r *http.Request) {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		if !hasContentType(r, "application/json") {
+			http.Error(w, "415 Unsupported Media Type.", http.StatusUnsupportedMediaType)
+			return
+		}
+
+		b, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		tokenValues := make([]string, 0)
+		err = json.Unmarshal(b, &tokenValues)
+		if err != nil {
+			http.Error(w, "400 Bad Request.", http.StatusBadRequest)
+			return
+		}
+
+		resolved, err := hc.us.GetBatch(r.Context(), tokenValues)
+		if err != nil {
+			if errors.Is(err, app.ErrEmptyResolveBatch) || errors.Is(err, app.ErrLongResolveBatch) {
+				http.Error(w, "400 Bad Request.", http.StatusBadRequest)
+				return
+			}
+
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		rows := make([]ResolveResponseRow, 0, len(resolved))
+		for _, r := range resolved {
+			rows = append(rows, ResolveResponseRow{Token: r.Token, OriginalURL: r.URL, Status: r.Status})
+		}
+
+		writeJSON(w, http.StatusOK, rows)
+	}
+	return fn
+}
@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alrund/yp-1/internal/app"
+	"github.com/alrund/yp-1/internal/app/config"
+	"github.com/alrund/yp-1/internal/app/listing"
+	"github.com/alrund/yp-1/internal/app/storage"
+	tkn "github.com/alrund/yp-1/internal/app/token"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveBatch(t *testing.T) {
+	preparedStorage := storage.NewMap()
+	require.NoError(t, preparedStorage.Set("XXX-YYY-ZZZ", "http://ya.ru", &tkn.Token{
+		Value: "qwerty", Expire: time.Now().Add(tkn.LifeTime),
+	}))
+	require.NoError(t, preparedStorage.Set("XXX-YYY-ZZZ", "http://old.ru", &tkn.Token{
+		Value: "asdfgh", Expire: time.Now().Add(-time.Hour),
+	}))
+	require.NoError(t, preparedStorage.Set("XXX-YYY-ZZZ", "http://go.dev", &tkn.Token{
+		Value: "zxcvbn", Expire: time.Now().Add(tkn.LifeTime), Removed: true,
+	}))
+
+	us := &app.URLShortener{
+		Config: &config.Config{
+			ServerAddress: "localhost:8080",
+			BaseURL:       "http://localhost:8080/",
+		},
+		Storage: preparedStorage,
+	}
+	hc := NewCollection(us)
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantCode    int
+		want        string
+	}{
+		{"wrong content type", "text/plain", `["qwerty"]`, http.StatusUnsupportedMediaType, ""},
+		{"bad json", "application/json", `{"token": "qwerty"}`, http.StatusBadRequest, ""},
+		{"empty", "application/json", `[]`, http.StatusBadRequest, ""},
+		{"too many", "application/json",
+			`[` + strings.Repeat(`"qwerty",`, app.MaxResolveBatch) + `"qwerty"]`, http.StatusBadRequest, ""},
+		{"statuses", "application/json", `["zxcvbn", "unknown", "qwerty", "asdfgh"]`, http.StatusOK, `[
+			{"token": "zxcvbn", "status": "removed"},
+			{"token": "unknown", "status": "not_found"},
+			{"token": "qwerty", "original_url": "http://ya.ru", "status": "ok"},
+			{"token": "asdfgh", "status": "expired"}
+		]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPost, "/api/resolve/batch", strings.NewReader(tt.body))
+			request.Header.Set("Content-Type", tt.contentType)
+			w := httptest.NewRecorder()
+			hc.ResolveBatch()(w, request)
+			res := w.Result()
+			defer res.Body.Close()
+
+			require.Equal(t, tt.wantCode, res.StatusCode)
+			if tt.want == "" {
+				return
+			}
+			var rows []ResolveResponseRow
+			require.NoError(t, json.NewDecoder(res.Body).Decode(&rows))
+			b, err := json.Marshal(rows)
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.want, string(b))
+		})
+	}
+
+	items, _, err := preparedStorage.GetURLsPage([]string{"XXX-YYY-ZZZ"}, listing.Query{})
+	require.NoError(t, err)
+	for _, item := range items {
+		assert.Zero(t, item.Clicks, "the resolve does not count the clicks")
+	}
+}
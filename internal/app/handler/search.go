@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/alrund/yp-1/internal/app/middleware"
+	"github.com/alrund/yp-1/internal/app/search"
+)
+
+// SearchURLs returns the user's links matching the q parameter, from the most relevant.
+// It matches the original URL, the short code, the title and the tags.
+func (hc *Collection) SearchURLs() func(w http.ResponseWriter, r *http.Request) {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		contextUserID := r.Context().Value(middleware.UserIDContextKey)
+		userID, ok := contextUserID.(string)
+		if !ok {
+			http.Error(w, "500 Internal Server Error.", http.StatusInternalServerError)
+			return
+		}
+
+		params := r.URL.Query()
+		query := search.Query{Text: params.Get("q")}
+		if limit := params.Get("limit"); limit != "" {
+			n, err := strconv.Atoi(limit)
+			if err != nil {
+				http.Error(w, "400 Bad Request.", http.StatusBadRequest)
+				return
+			}
+			query.Limit = n
+		}
+
+		urls, err := hc.us.SearchURLs(r.Context(), userID, query)
+		if err != nil {
+			if search.IsInvalid(err) {
+				http.Error(w, "400 Bad Request.", http.StatusBadRequest)
+				return
+			}
+
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if len(urls) == 0 {
+			http.Error(w, "204 No Content.", http.StatusNoContent)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, urls)
+	}
+	return fn
+}
@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/alrund/yp-1/internal/app"
+	"github.com/alrund/yp-1/internal/app/config"
+	"github.com/alrund/yp-1/internal/app/linkmeta"
+	"github.com/alrund/yp-1/internal/app/storage"
+	"github.com/alrund/yp-1/internal/app/token/generator"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchURLs(t *testing.T) {
+	us := &app.URLShortener{
+		Config: &config.Config{
+			ServerAddress: "localhost:8080",
+			BaseURL:       "http://localhost:8080/",
+		},
+		Storage:        storage.NewMap(),
+		TokenGenerator: generator.NewSimple(),
+	}
+	hc := NewCollection(us)
+
+	ctx := context.Background()
+	ya, err := us.Add(ctx, "XXX-YYY-ZZZ", "http://ya.ru/news", linkmeta.Meta{Title: "Yandex"})
+	require.NoError(t, err)
+	_, err = us.Add(ctx, "XXX-YYY-ZZZ", "http://go.dev", linkmeta.Meta{Tags: []string{"news"}})
+	require.NoError(t, err)
+	_, err = us.Add(ctx, "AAA-BBB-CCC", "http://news.ru", linkmeta.Meta{})
+	require.NoError(t, err)
+
+	serve := func(target, userID string) (int, []storage.URLpairs) {
+		request := getNewRequestWithUserID(http.MethodGet, target, userID, 0, nil)
+		w := httptest.NewRecorder()
+		hc.SearchURLs()(w, request)
+		res := w.Result()
+		defer res.Body.Close()
+
+		b, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		var urls []storage.URLpairs
+		if res.StatusCode == http.StatusOK {
+			require.NoError(t, json.Unmarshal(b, &urls))
+		}
+		return res.StatusCode, urls
+	}
+
+	tests := []struct {
+		name     string
+		target   string
+		userID   string
+		wantCode int
+		want     []string
+	}{
+		{"no query", "/api/user/urls/search", "XXX-YYY-ZZZ", http.StatusBadRequest, nil},
+		{"no terms", "/api/user/urls/search?q=" + url.QueryEscape("://"), "XXX-YYY-ZZZ", http.StatusBadRequest, nil},
+		{"bad limit", "/api/user/urls/search?q=ya&limit=x", "XXX-YYY-ZZZ", http.StatusBadRequest, nil},
+		{"big limit", "/api/user/urls/search?q=ya&limit=1000", "XXX-YYY-ZZZ", http.StatusBadRequest, nil},
+		{"not found", "/api/user/urls/search?q=lost", "XXX-YYY-ZZZ", http.StatusNoContent, nil},
+		{"ranked", "/api/user/urls/search?q=NEWS", "XXX-YYY-ZZZ", http.StatusOK, []string{"http://go.dev", "http://ya.ru/news"}},
+		{"title prefix", "/api/user/urls/search?q=yand", "XXX-YYY-ZZZ", http.StatusOK, []string{"http://ya.ru/news"}},
+		{"short code", "/api/user/urls/search?q=" + ya.Value, "XXX-YYY-ZZZ", http.StatusOK, []string{"http://ya.ru/news"}},
+		{"limit", "/api/user/urls/search?q=news&limit=1", "XXX-YYY-ZZZ", http.StatusOK, []string{"http://go.dev"}},
+		{"other user", "/api/user/urls/search?q=news", "AAA-BBB-CCC", http.StatusOK, []string{"http://news.ru"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, urls := serve(tt.target, tt.userID)
+			require.Equal(t, tt.wantCode, code)
+			if tt.want == nil {
+				return
+			}
+			found := make([]string, 0, len(urls))
+			for _, u := range urls {
+				found = append(found, u.OriginalURL)
+			}
+			assert.Equal(t, tt.want, found)
+		})
+	}
+
+	require.NoError(t, us.RemoveTokens(ctx, []string{ya.Value}, "XXX-YYY-ZZZ"))
+	code, _ := serve("/api/user/urls/search?q=yandex", "XXX-YYY-ZZZ")
+	assert.Equal(t, http.StatusNoContent, code)
+}
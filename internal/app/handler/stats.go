@@ -9,52 +9,70 @@ import (
 // Stats get statistic information.
 func (hc *Collection) Stats() func(w http.ResponseWriter, r *http.Request) {
 	fn := func(w http.ResponseWriter, r *http.Request) {
-		cfg := hc.us.GetConfig()
-		if cfg.TrustedSubnet == "" {
-			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		if !hc.fromTrustedSubnet(w, r) {
 			return
 		}
 
-		if hc.us.TrustedSubnet == nil {
-			_, ipnet, err := net.ParseCIDR(cfg.TrustedSubnet)
-			if err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-				return
-			}
-			hc.us.TrustedSubnet = ipnet
-		}
+		hc.writeStats(w, r)
+	}
+	return fn
+}
 
-		realIPHeader := r.Header.Get("X-Real-IP")
-		if realIPHeader == "" {
-			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
-			return
-		}
+// fromTrustedSubnet checks that the X-Real-IP of the request is in the trusted subnet.
+// It writes the error response itself.
+func (hc *Collection) fromTrustedSubnet(w http.ResponseWriter, r *http.Request) bool {
+	trustedSubnet, err := hc.us.GetTrustedSubnet()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return false
+	}
+	if trustedSubnet == nil {
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return false
+	}
 
-		realIP := net.ParseIP(realIPHeader)
+	realIPHeader := r.Header.Get("X-Real-IP")
+	if realIPHeader == "" {
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return false
+	}
 
-		if !hc.us.TrustedSubnet.Contains(realIP) {
-			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
-			return
-		}
+	realIP := net.ParseIP(realIPHeader)
 
-		stat, err := hc.us.GetStats()
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
+	if !trustedSubnet.Contains(realIP) {
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return false
+	}
 
-		result, err := json.Marshal(stat)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
+	return true
+}
 
-		w.Header().Set("Content-Type", "application/json; charset=utf-8")
-		_, err = w.Write(result)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
+// AdminStats get statistic information without the trusted subnet check.
+// It is meant for the admin listener, which guards the access by itself.
+func (hc *Collection) AdminStats() func(w http.ResponseWriter, r *http.Request) {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		hc.writeStats(w, r)
 	}
 	return fn
 }
+
+func (hc *Collection) writeStats(w http.ResponseWriter, r *http.Request) {
+	stat, err := hc.us.GetStats(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	result, err := json.Marshal(stat)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	_, err = w.Write(result)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
@@ -143,3 +143,22 @@ func TestStats(t *testing.T) {
 		})
 	}
 }
+
+func TestAdminStats(t *testing.T) {
+	us := &app.URLShortener{
+		Config:         &config.Config{ServerAddress: "localhost:8080", BaseURL: "http://localhost:8080/"},
+		Storage:        new(TestStorage),
+		TokenGenerator: generator.NewSimple(),
+	}
+	hc := NewCollection(us)
+	request := httptest.NewRequest(http.MethodGet, "/stats", nil)
+	w := httptest.NewRecorder()
+	h := http.HandlerFunc(hc.AdminStats())
+	h.ServeHTTP(w, request)
+	res := w.Result()
+	defer res.Body.Close()
+
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "application/json; charset=utf-8", res.Header.Get("Content-Type"))
+	assert.JSONEq(t, `{"Urls":2,"Users":3}`, w.Body.String())
+}
@@ -0,0 +1,151 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"path"
+
+	"github.com/alrund/yp-1/internal/app/linkmeta"
+	"github.com/alrund/yp-1/internal/app/middleware"
+	"github.com/alrund/yp-1/internal/app/storage"
+)
+
+// URLMetaRequest the fields to change, an absent field is kept.
+type URLMetaRequest struct {
+	Title *string   `json:"title"`
+	Notes *string   `json:"notes"`
+	Tags  *[]string `json:"tags"`
+}
+
+type URLMetaResponse struct {
+	Title string   `json:"title"`
+	Notes string   `json:"notes"`
+	Tags  []string `json:"tags"`
+}
+
+type RenameTagRequest struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// UpdateURL changes the title, the notes or the tags of the link given by the path /api/user/urls/{token}.
+func (hc *Collection) UpdateURL() func(w http.ResponseWriter, r *http.Request) {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		if !hasContentType(r, "application/json") {
+			http.Error(w, "415 Unsupported Media Type.", http.StatusUnsupportedMediaType)
+			return
+		}
+
+		contextUserID := r.Context().Value(middleware.UserIDContextKey)
+		userID, ok := contextUserID.(string)
+		if !ok {
+			http.Error(w, "500 Internal Server Error.", http.StatusInternalServerError)
+			return
+		}
+
+		b, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		jsonRequest := URLMetaRequest{}
+		err = json.Unmarshal(b, &jsonRequest)
+		if err != nil {
+			http.Error(w, "400 Bad Request.", http.StatusBadRequest)
+			return
+		}
+
+		update := linkmeta.Update{Title: jsonRequest.Title, Notes: jsonRequest.Notes, Tags: jsonRequest.Tags}
+		m, err := hc.us.UpdateURL(r.Context(), userID, path.Base(r.URL.Path), update)
+		if err != nil {
+			writeTagError(w, err)
+			return
+		}
+
+		tags := m.Tags
+		if tags == nil {
+			tags = []string{}
+		}
+		writeJSON(w, http.StatusOK, URLMetaResponse{Title: m.Title, Notes: m.Notes, Tags: tags})
+	}
+	return fn
+}
+
+// GetTags returns the tags of the user's links with the link counts.
+func (hc *Collection) GetTags() func(w http.ResponseWriter, r *http.Request) {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		contextUserID := r.Context().Value(middleware.UserIDContextKey)
+		userID, ok := contextUserID.(string)
+		if !ok {
+			http.Error(w, "500 Internal Server Error.", http.StatusInternalServerError)
+			return
+		}
+
+		tags, err := hc.us.GetTags(r.Context(), userID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if len(tags) == 0 {
+			http.Error(w, "204 No Content.", http.StatusNoContent)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, tags)
+	}
+	return fn
+}
+
+// RenameTag renames a tag on the user's links, a link having both tags keeps one.
+func (hc *Collection) RenameTag() func(w http.ResponseWriter, r *http.Request) {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		if !hasContentType(r, "application/json") {
+			http.Error(w, "415 Unsupported Media Type.", http.StatusUnsupportedMediaType)
+			return
+		}
+
+		contextUserID := r.Context().Value(middleware.UserIDContextKey)
+		userID, ok := contextUserID.(string)
+		if !ok {
+			http.Error(w, "500 Internal Server Error.", http.StatusInternalServerError)
+			return
+		}
+
+		b, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		jsonRequest := RenameTagRequest{}
+		err = json.Unmarshal(b, &jsonRequest)
+		if err != nil {
+			http.Error(w, "400 Bad Request.", http.StatusBadRequest)
+			return
+		}
+
+		err = hc.us.RenameTag(r.Context(), userID, jsonRequest.From, jsonRequest.To)
+		if err != nil {
+			writeTagError(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+	return fn
+}
+
+func writeTagError(w http.ResponseWriter, err error) {
+	switch {
+	case linkmeta.IsInvalid(err):
+		http.Error(w, "400 Bad Request.", http.StatusBadRequest)
+	case errors.Is(err, storage.ErrTokenNotFound), errors.Is(err, storage.ErrTagNotFound):
+		http.Error(w, "404 Not Found.", http.StatusNotFound)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/alrund/yp-1/internal/app"
+	"github.com/alrund/yp-1/internal/app/config"
+	"github.com/alrund/yp-1/internal/app/linkmeta"
+	"github.com/alrund/yp-1/internal/app/storage"
+	"github.com/alrund/yp-1/internal/app/token/generator"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTags(t *testing.T) {
+	us := &app.URLShortener{
+		Config: &config.Config{
+			ServerAddress: "localhost:8080",
+			BaseURL:       "http://localhost:8080/",
+		},
+		Storage:        storage.NewMap(),
+		TokenGenerator: generator.NewSimple(),
+	}
+	hc := NewCollection(us)
+
+	serve := func(h http.HandlerFunc, method, target, userID, body string) (int, []byte) {
+		request := getNewRequestWithUserID(method, target, userID, 0, strings.NewReader(body))
+		request.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		h(w, request)
+		res := w.Result()
+		defer res.Body.Close()
+
+		b, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		return res.StatusCode, b
+	}
+	add := func(body string) string {
+		code, b := serve(hc.AddJSON(), http.MethodPost, "/api/shorten", "XXX-YYY-ZZZ", body)
+		require.Equal(t, http.StatusCreated, code, string(b))
+		var response JSONResponse
+		require.NoError(t, json.Unmarshal(b, &response))
+		return path.Base(response.Result)
+	}
+	list := func(target string) []storage.URLpairs {
+		code, b := serve(hc.GetUserURLs(), http.MethodGet, target, "XXX-YYY-ZZZ", "")
+		if code == http.StatusNoContent {
+			return nil
+		}
+		require.Equal(t, http.StatusOK, code)
+		var urls []storage.URLpairs
+		require.NoError(t, json.Unmarshal(b, &urls))
+		return urls
+	}
+
+	code, _ := serve(hc.AddJSON(), http.MethodPost, "/api/shorten", "XXX-YYY-ZZZ",
+		`{"url": "http://ya.ru", "tags": ["a,b"]}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	ya := add(`{"url": "http://ya.ru", "title": " Yandex ", "tags": ["Search", "ru", "search"]}`)
+	goDev := add(`{"url": "http://go.dev", "tags": ["Go"]}`)
+
+	urls := list("/api/user/urls?tag=SEARCH")
+	require.Len(t, urls, 1)
+	assert.Equal(t, "http://ya.ru", urls[0].OriginalURL)
+	assert.Equal(t, "Yandex", urls[0].Title)
+	assert.Equal(t, []string{"ru", "search"}, urls[0].Tags)
+
+	code, _ = serve(hc.GetUserURLs(), http.MethodGet, "/api/user/urls?tag=a,b", "XXX-YYY-ZZZ", "")
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	tests := []struct {
+		name     string
+		target   string
+		userID   string
+		body     string
+		wantCode int
+		want     string
+	}{
+		{"bad json", "/api/user/urls/" + goDev, "XXX-YYY-ZZZ", `{`, http.StatusBadRequest, ""},
+		{"bad tag", "/api/user/urls/" + goDev, "XXX-YYY-ZZZ", `{"tags": [""]}`, http.StatusBadRequest, ""},
+		{"long title", "/api/user/urls/" + goDev, "XXX-YYY-ZZZ",
+			`{"title": "` + strings.Repeat("x", linkmeta.MaxTitleLength+1) + `"}`, http.StatusBadRequest, ""},
+		{"unknown token", "/api/user/urls/unknown", "XXX-YYY-ZZZ", `{"title": "Go"}`, http.StatusNotFound, ""},
+		{"other user", "/api/user/urls/" + goDev, "AAA-BBB-CCC", `{"title": "Go"}`, http.StatusNotFound, ""},
+		{"notes kept", "/api/user/urls/" + goDev, "XXX-YYY-ZZZ", `{"notes": "docs"}`, http.StatusOK,
+			`{"title": "", "notes": "docs", "tags": ["go"]}`},
+		{"title and tags", "/api/user/urls/" + goDev, "XXX-YYY-ZZZ", `{"title": "Go", "tags": ["lang", "ru"]}`,
+			http.StatusOK, `{"title": "Go", "notes": "docs", "tags": ["lang", "ru"]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, b := serve(hc.UpdateURL(), http.MethodPatch, tt.target, tt.userID, tt.body)
+			assert.Equal(t, tt.wantCode, code)
+			if tt.want != "" {
+				assert.JSONEq(t, tt.want, string(b))
+			}
+		})
+	}
+
+	code, b := serve(hc.GetTags(), http.MethodGet, "/api/user/tags", "XXX-YYY-ZZZ", "")
+	require.Equal(t, http.StatusOK, code)
+	assert.JSONEq(t, `[{"tag": "lang", "count": 1}, {"tag": "ru", "count": 2}, {"tag": "search", "count": 1}]`, string(b))
+
+	code, _ = serve(hc.GetTags(), http.MethodGet, "/api/user/tags", "AAA-BBB-CCC", "")
+	assert.Equal(t, http.StatusNoContent, code)
+
+	code, _ = serve(hc.RenameTag(), http.MethodPost, "/api/user/tags/rename", "XXX-YYY-ZZZ",
+		`{"from": "unknown", "to": "other"}`)
+	assert.Equal(t, http.StatusNotFound, code)
+
+	code, _ = serve(hc.RenameTag(), http.MethodPost, "/api/user/tags/rename", "XXX-YYY-ZZZ",
+		`{"from": "ru", "to": ""}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	code, _ = serve(hc.RenameTag(), http.MethodPost, "/api/user/tags/rename", "XXX-YYY-ZZZ",
+		`{"from": "RU", "to": "search"}`)
+	require.Equal(t, http.StatusNoContent, code)
+
+	urls = list("/api/user/urls?tag=search")
+	require.Len(t, urls, 2)
+	assert.ElementsMatch(t, []string{"http://ya.ru", "http://go.dev"}, []string{urls[0].OriginalURL, urls[1].OriginalURL})
+	assert.Empty(t, list("/api/user/urls?tag=ru"))
+
+	code, b = serve(hc.GetTags(), http.MethodGet, "/api/user/tags", "XXX-YYY-ZZZ", "")
+	require.Equal(t, http.StatusOK, code)
+	assert.JSONEq(t, `[{"tag": "lang", "count": 1}, {"tag": "search", "count": 2}]`, string(b))
+
+	require.NoError(t, us.RemoveTokens(context.Background(), []string{ya}, "XXX-YYY-ZZZ"))
+	code, b = serve(hc.GetTags(), http.MethodGet, "/api/user/tags", "XXX-YYY-ZZZ", "")
+	require.Equal(t, http.StatusOK, code)
+	assert.JSONEq(t, `[{"tag": "lang", "count": 1}, {"tag": "search", "count": 1}]`, string(b),
+		"the removed links are not counted")
+}
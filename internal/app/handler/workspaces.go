@@ -0,0 +1,241 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/alrund/yp-1/internal/app"
+	"github.com/alrund/yp-1/internal/app/middleware"
+	"github.com/alrund/yp-1/internal/app/storage"
+	"github.com/alrund/yp-1/internal/app/workspace"
+)
+
+// workspaceQueryParam selects the workspace the new links are added to.
+const workspaceQueryParam = "workspace"
+
+type WorkspaceRequest struct {
+	Name string `json:"name"`
+}
+
+type WorkspaceResponse struct {
+	ID      string    `json:"id"`
+	Name    string    `json:"name"`
+	Role    string    `json:"role"`
+	Created time.Time `json:"created"`
+}
+
+type MemberRequest struct {
+	Role string `json:"role"`
+}
+
+type MemberResponse struct {
+	UserID string `json:"user_id"`
+	Role   string `json:"role"`
+}
+
+// CreateWorkspace creates a workspace with the user as its admin.
+func (hc *Collection) CreateWorkspace() func(w http.ResponseWriter, r *http.Request) {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		if !hasContentType(r, "application/json") {
+			http.Error(w, "415 Unsupported Media Type.", http.StatusUnsupportedMediaType)
+			return
+		}
+
+		contextUserID := r.Context().Value(middleware.UserIDContextKey)
+		userID, ok := contextUserID.(string)
+		if !ok {
+			http.Error(w, "500 Internal Server Error.", http.StatusInternalServerError)
+			return
+		}
+
+		b, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		jsonRequest := WorkspaceRequest{}
+		err = json.Unmarshal(b, &jsonRequest)
+		if err != nil {
+			http.Error(w, "400 Bad Request.", http.StatusBadRequest)
+			return
+		}
+
+		membership, err := hc.us.CreateWorkspace(r.Context(), userID, jsonRequest.Name)
+		if err != nil {
+			if errors.Is(err, app.ErrEmptyWorkspaceName) || errors.Is(err, app.ErrLongWorkspaceName) {
+				http.Error(w, "400 Bad Request.", http.StatusBadRequest)
+				return
+			}
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		writeJSON(w, http.StatusCreated, newWorkspaceResponse(membership))
+	}
+	return fn
+}
+
+// GetWorkspaces returns the workspaces of the user with the user's role.
+func (hc *Collection) GetWorkspaces() func(w http.ResponseWriter, r *http.Request) {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		contextUserID := r.Context().Value(middleware.UserIDContextKey)
+		userID, ok := contextUserID.(string)
+		if !ok {
+			http.Error(w, "500 Internal Server Error.", http.StatusInternalServerError)
+			return
+		}
+
+		memberships, err := hc.us.GetWorkspaces(r.Context(), userID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if len(memberships) == 0 {
+			http.Error(w, "204 No Content.", http.StatusNoContent)
+			return
+		}
+
+		jsonResponse := make([]WorkspaceResponse, 0, len(memberships))
+		for _, m := range memberships {
+			jsonResponse = append(jsonResponse, newWorkspaceResponse(m))
+		}
+		writeJSON(w, http.StatusOK, jsonResponse)
+	}
+	return fn
+}
+
+// GetWorkspaceMembers returns the members of the workspace given by the path /api/workspaces/{id}/members.
+func (hc *Collection) GetWorkspaceMembers() func(w http.ResponseWriter, r *http.Request) {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		contextUserID := r.Context().Value(middleware.UserIDContextKey)
+		userID, ok := contextUserID.(string)
+		if !ok {
+			http.Error(w, "500 Internal Server Error.", http.StatusInternalServerError)
+			return
+		}
+
+		workspaceID := path.Base(path.Dir(r.URL.Path))
+		members, err := hc.us.GetWorkspaceMembers(r.Context(), userID, workspaceID)
+		if err != nil {
+			writeWorkspaceError(w, err)
+			return
+		}
+
+		jsonResponse := make([]MemberResponse, 0, len(members))
+		for _, m := range members {
+			jsonResponse = append(jsonResponse, MemberResponse{UserID: m.UserID, Role: string(m.Role)})
+		}
+		writeJSON(w, http.StatusOK, jsonResponse)
+	}
+	return fn
+}
+
+// SetWorkspaceMember adds a member or changes the role,
+// the path is /api/workspaces/{id}/members/{user_id}.
+func (hc *Collection) SetWorkspaceMember() func(w http.ResponseWriter, r *http.Request) {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		if !hasContentType(r, "application/json") {
+			http.Error(w, "415 Unsupported Media Type.", http.StatusUnsupportedMediaType)
+			return
+		}
+
+		contextUserID := r.Context().Value(middleware.UserIDContextKey)
+		userID, ok := contextUserID.(string)
+		if !ok {
+			http.Error(w, "500 Internal Server Error.", http.StatusInternalServerError)
+			return
+		}
+
+		b, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		jsonRequest := MemberRequest{}
+		err = json.Unmarshal(b, &jsonRequest)
+		if err != nil {
+			http.Error(w, "400 Bad Request.", http.StatusBadRequest)
+			return
+		}
+
+		workspaceID, memberID := memberPath(r)
+		err = hc.us.SetWorkspaceMember(r.Context(), userID, workspaceID, memberID, jsonRequest.Role)
+		if err != nil {
+			writeWorkspaceError(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+	return fn
+}
+
+// RemoveWorkspaceMember removes a member, the path is /api/workspaces/{id}/members/{user_id}.
+func (hc *Collection) RemoveWorkspaceMember() func(w http.ResponseWriter, r *http.Request) {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		contextUserID := r.Context().Value(middleware.UserIDContextKey)
+		userID, ok := contextUserID.(string)
+		if !ok {
+			http.Error(w, "500 Internal Server Error.", http.StatusInternalServerError)
+			return
+		}
+
+		workspaceID, memberID := memberPath(r)
+		err := hc.us.RemoveWorkspaceMember(r.Context(), userID, workspaceID, memberID)
+		if err != nil {
+			writeWorkspaceError(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+	return fn
+}
+
+// linkOwner returns the owner of the new links, the workspace may be selected by the query parameter.
+// It writes the error response itself.
+func (hc *Collection) linkOwner(w http.ResponseWriter, r *http.Request, userID string) (string, bool) {
+	owner, err := hc.us.LinkOwner(r.Context(), userID, r.URL.Query().Get(workspaceQueryParam))
+	if err != nil {
+		writeWorkspaceError(w, err)
+		return "", false
+	}
+
+	return owner, true
+}
+
+func memberPath(r *http.Request) (workspaceID, memberID string) {
+	return path.Base(path.Dir(path.Dir(r.URL.Path))), path.Base(r.URL.Path)
+}
+
+func writeWorkspaceError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, workspace.ErrInvalidRole):
+		http.Error(w, "400 Bad Request.", http.StatusBadRequest)
+	case errors.Is(err, app.ErrForbidden):
+		http.Error(w, "403 Forbidden.", http.StatusForbidden)
+	case errors.Is(err, storage.ErrWorkspaceNotFound), errors.Is(err, storage.ErrMemberNotFound),
+		errors.Is(err, app.ErrUnknownUser):
+		http.Error(w, "404 Not Found.", http.StatusNotFound)
+	case errors.Is(err, storage.ErrLastAdmin):
+		http.Error(w, "409 Conflict.", http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func newWorkspaceResponse(m *workspace.Membership) WorkspaceResponse {
+	return WorkspaceResponse{
+		ID:      m.Workspace.ID,
+		Name:    m.Workspace.Name,
+		Role:    string(m.Role),
+		Created: m.Workspace.Created,
+	}
+}
@@ -0,0 +1,214 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alrund/yp-1/internal/app"
+	"github.com/alrund/yp-1/internal/app/account"
+	"github.com/alrund/yp-1/internal/app/config"
+	"github.com/alrund/yp-1/internal/app/listing"
+	"github.com/alrund/yp-1/internal/app/storage"
+	tkn "github.com/alrund/yp-1/internal/app/token"
+	"github.com/alrund/yp-1/internal/app/token/generator"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkspaces(t *testing.T) {
+	us := &app.URLShortener{
+		Config: &config.Config{
+			ServerAddress: "localhost:8080",
+			BaseURL:       "http://localhost:8080/",
+		},
+		Storage:        storage.NewMap(),
+		TokenGenerator: generator.NewSimple(),
+	}
+	hc := NewCollection(us)
+
+	serve := func(h http.HandlerFunc, method, target, userID, body string) (int, []byte) {
+		request := getNewRequestWithUserID(method, target, userID, 0, strings.NewReader(body))
+		request.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		h(w, request)
+		res := w.Result()
+		defer res.Body.Close()
+
+		b, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		return res.StatusCode, b
+	}
+
+	code, _ := serve(hc.CreateWorkspace(), http.MethodPost, "/api/workspaces", "admin", `{"name": ""}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	code, b := serve(hc.CreateWorkspace(), http.MethodPost, "/api/workspaces", "admin", `{"name": "team"}`)
+	require.Equal(t, http.StatusCreated, code)
+	var created WorkspaceResponse
+	require.NoError(t, json.Unmarshal(b, &created))
+	assert.Equal(t, "team", created.Name)
+	assert.Equal(t, "admin", created.Role)
+
+	// Only the known users can be added.
+	for _, userID := range []string{"editor", "viewer"} {
+		require.NoError(t, us.Storage.SetAccount(&account.Account{ID: userID, Login: userID}))
+	}
+
+	members := "/api/workspaces/" + created.ID + "/members"
+	tests := []struct {
+		name     string
+		handler  http.HandlerFunc
+		method   string
+		target   string
+		userID   string
+		body     string
+		wantCode int
+	}{
+		{
+			name:     "invalid role",
+			handler:  hc.SetWorkspaceMember(),
+			method:   http.MethodPut,
+			target:   members + "/editor",
+			userID:   "admin",
+			body:     `{"role": "owner"}`,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "unknown user",
+			handler:  hc.SetWorkspaceMember(),
+			method:   http.MethodPut,
+			target:   members + "/stranger",
+			userID:   "admin",
+			body:     `{"role": "viewer"}`,
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "add editor",
+			handler:  hc.SetWorkspaceMember(),
+			method:   http.MethodPut,
+			target:   members + "/editor",
+			userID:   "admin",
+			body:     `{"role": "editor"}`,
+			wantCode: http.StatusNoContent,
+		},
+		{
+			name:     "add viewer",
+			handler:  hc.SetWorkspaceMember(),
+			method:   http.MethodPut,
+			target:   members + "/viewer",
+			userID:   "admin",
+			body:     `{"role": "viewer"}`,
+			wantCode: http.StatusNoContent,
+		},
+		{
+			name:     "editor can not manage members",
+			handler:  hc.SetWorkspaceMember(),
+			method:   http.MethodPut,
+			target:   members + "/viewer",
+			userID:   "editor",
+			body:     `{"role": "admin"}`,
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:     "stranger does not see the workspace",
+			handler:  hc.GetWorkspaceMembers(),
+			method:   http.MethodGet,
+			target:   members,
+			userID:   "stranger",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "last admin can not leave",
+			handler:  hc.RemoveWorkspaceMember(),
+			method:   http.MethodDelete,
+			target:   members + "/admin",
+			userID:   "admin",
+			wantCode: http.StatusConflict,
+		},
+		{
+			name:     "last admin can not step down",
+			handler:  hc.SetWorkspaceMember(),
+			method:   http.MethodPut,
+			target:   members + "/admin",
+			userID:   "admin",
+			body:     `{"role": "editor"}`,
+			wantCode: http.StatusConflict,
+		},
+		{
+			name:     "viewer can not add links",
+			handler:  hc.AddJSON(),
+			method:   http.MethodPost,
+			target:   "/api/shorten?workspace=" + created.ID,
+			userID:   "viewer",
+			body:     `{"url": "http://viewer.ru"}`,
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:     "editor adds a shared link",
+			handler:  hc.AddJSON(),
+			method:   http.MethodPost,
+			target:   "/api/shorten?workspace=" + created.ID,
+			userID:   "editor",
+			body:     `{"url": "http://team.ru"}`,
+			wantCode: http.StatusCreated,
+		},
+		{
+			name:     "stranger can not add links",
+			handler:  hc.Add(),
+			method:   http.MethodPost,
+			target:   "/?workspace=" + created.ID,
+			userID:   "stranger",
+			body:     "http://stranger.ru",
+			wantCode: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _ := serve(tt.handler, tt.method, tt.target, tt.userID, tt.body)
+			assert.Equal(t, tt.wantCode, code)
+		})
+	}
+
+	code, b = serve(hc.GetWorkspaceMembers(), http.MethodGet, members, "viewer", "")
+	require.Equal(t, http.StatusOK, code)
+	var memberList []MemberResponse
+	require.NoError(t, json.Unmarshal(b, &memberList))
+	assert.Equal(t, []MemberResponse{
+		{UserID: "admin", Role: "admin"},
+		{UserID: "editor", Role: "editor"},
+		{UserID: "viewer", Role: "viewer"},
+	}, memberList)
+
+	// Every member sees the shared link.
+	for _, userID := range []string{"admin", "editor", "viewer"} {
+		urls, _, err := us.GetUserURLs(context.Background(), userID, listing.Query{})
+		require.NoError(t, err)
+		require.Len(t, urls, 1)
+		assert.Equal(t, "http://team.ru", urls[0].OriginalURL)
+		assert.Equal(t, created.ID, urls[0].WorkspaceID)
+	}
+	_, _, err := us.GetUserURLs(context.Background(), "stranger", listing.Query{})
+	assert.ErrorIs(t, err, storage.ErrTokenNotFound)
+
+	token, err := us.GetTokenByURL("http://team.ru")
+	require.NoError(t, err)
+
+	// A viewer can not delete the shared link, an editor can.
+	require.NoError(t, us.RemoveTokens(context.Background(), []string{token.Value}, "viewer"))
+	_, err = us.Get(context.Background(), token.Value)
+	require.NoError(t, err)
+
+	require.NoError(t, us.RemoveTokens(context.Background(), []string{token.Value}, "editor"))
+	_, err = us.Get(context.Background(), token.Value)
+	assert.ErrorIs(t, err, tkn.ErrTokenRemovedError)
+
+	code, _ = serve(hc.RemoveWorkspaceMember(), http.MethodDelete, members+"/viewer", "viewer", "")
+	assert.Equal(t, http.StatusNoContent, code)
+	code, _ = serve(hc.GetWorkspaces(), http.MethodGet, "/api/workspaces", "viewer", "")
+	assert.Equal(t, http.StatusNoContent, code)
+}
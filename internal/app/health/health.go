@@ -0,0 +1,65 @@
+package health
+
+import (
+	"context"
+	"sync"
+)
+
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+// Check checks a single component, nil means the component is healthy.
+type Check func(ctx context.Context) error
+
+// Component the state of a single component.
+type Component struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Report the aggregated state of the components.
+type Report struct {
+	Status     string               `json:"status"`
+	Components map[string]Component `json:"components,omitempty"`
+}
+
+// Run runs the checks concurrently and aggregates them into a report.
+func Run(ctx context.Context, checks map[string]Check) *Report {
+	report := &Report{
+		Status:     StatusUp,
+		Components: make(map[string]Component, len(checks)),
+	}
+
+	var (
+		mx sync.Mutex
+		wg sync.WaitGroup
+	)
+	wg.Add(len(checks))
+	for name, check := range checks {
+		go func(name string, check Check) {
+			defer wg.Done()
+
+			component := Component{Status: StatusUp}
+			if err := check(ctx); err != nil {
+				component = Component{Status: StatusDown, Error: err.Error()}
+			}
+
+			mx.Lock()
+			defer mx.Unlock()
+			report.Components[name] = component
+			if component.Status == StatusDown {
+				report.Status = StatusDown
+			}
+		}(name, check)
+	}
+	wg.Wait()
+
+	return report
+}
+
+// IsUp reports whether all components are healthy.
+func (r *Report) IsUp() bool {
+	return r.Status == StatusUp
+}
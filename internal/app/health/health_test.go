@@ -0,0 +1,49 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRun(t *testing.T) {
+	up := func(context.Context) error { return nil }
+	down := func(context.Context) error { return errors.New("connection refused") }
+
+	tests := []struct {
+		name   string
+		checks map[string]Check
+		want   *Report
+	}{
+		{
+			name:   "success - no checks",
+			checks: map[string]Check{},
+			want:   &Report{Status: StatusUp, Components: map[string]Component{}},
+		},
+		{
+			name:   "success",
+			checks: map[string]Check{"storage": up, "queue": up},
+			want: &Report{Status: StatusUp, Components: map[string]Component{
+				"storage": {Status: StatusUp},
+				"queue":   {Status: StatusUp},
+			}},
+		},
+		{
+			name:   "fail - one component is down",
+			checks: map[string]Check{"storage": down, "queue": up},
+			want: &Report{Status: StatusDown, Components: map[string]Component{
+				"storage": {Status: StatusDown, Error: "connection refused"},
+				"queue":   {Status: StatusUp},
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Run(context.Background(), tt.checks)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.want.Status == StatusUp, got.IsUp())
+		})
+	}
+}
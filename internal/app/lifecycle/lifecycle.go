@@ -0,0 +1,171 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alrund/yp-1/internal/app/logging"
+)
+
+// Component a part of the service with its own lifetime.
+type Component struct {
+	Name string
+	// Start prepares the component, it must not block.
+	Start func(ctx context.Context) error
+	// Run serves until the component is stopped, a returned error brings the whole service down.
+	Run func() error
+	// Stop releases the component within the deadline of ctx.
+	Stop func(ctx context.Context) error
+}
+
+// Manager starts the components in order and stops them in reverse order.
+type Manager struct {
+	components  []Component
+	stopTimeout time.Duration
+}
+
+func NewManager(stopTimeout time.Duration) *Manager {
+	return &Manager{stopTimeout: stopTimeout}
+}
+
+// Add appends the component to the start order.
+func (m *Manager) Add(c Component) {
+	m.components = append(m.components, c)
+}
+
+// Run starts the components and blocks until ctx is done or a component fails.
+// Then the started components are stopped and their Run calls are awaited within the stop timeout.
+func (m *Manager) Run(ctx context.Context) error {
+	runErrCh := make(chan error, len(m.components))
+	var running sync.WaitGroup
+
+	started := 0
+	var startErr error
+	for _, c := range m.components {
+		if c.Start != nil {
+			if err := c.Start(ctx); err != nil {
+				startErr = fmt.Errorf("%s start: %w", c.Name, err)
+				break
+			}
+		}
+		started++
+
+		if c.Run != nil {
+			running.Add(1)
+			go func(c Component) {
+				defer running.Done()
+				if err := c.Run(); err != nil {
+					runErrCh <- fmt.Errorf("%s: %w", c.Name, err)
+				}
+			}(c)
+		}
+		logging.Infof("%s started", c.Name)
+	}
+
+	var runErr error
+	if startErr == nil {
+		select {
+		case <-ctx.Done():
+		case runErr = <-runErrCh:
+		}
+	}
+
+	stopCtx, cancel := context.WithTimeout(context.Background(), m.stopTimeout)
+	defer cancel()
+
+	stopErr := m.stop(stopCtx, started)
+	waitErr := wait(stopCtx, &running)
+
+	return joinErrors(startErr, runErr, stopErr, waitErr)
+}
+
+func (m *Manager) stop(ctx context.Context, started int) error {
+	var errs []error
+	for i := started - 1; i >= 0; i-- {
+		c := m.components[i]
+		if c.Stop == nil {
+			continue
+		}
+		if err := c.Stop(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("%s stop: %w", c.Name, err))
+			continue
+		}
+		logging.Infof("%s stopped", c.Name)
+	}
+
+	return joinErrors(errs...)
+}
+
+// wait waits for the Run calls to return within the deadline of ctx.
+func wait(ctx context.Context, running *sync.WaitGroup) error {
+	done := make(chan struct{})
+	go func() {
+		running.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("run: %w", ctx.Err())
+	}
+}
+
+// Errors several errors which occurred during the same run.
+type Errors []error
+
+func (e Errors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Is reports whether any of the errors matches the target.
+// Errors does not rely on Unwrap() []error, errors.Is understands it only since Go 1.20.
+func (e Errors) Is(target error) bool {
+	for _, err := range e {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first of the errors which matches the target.
+func (e Errors) As(target interface{}) bool {
+	for _, err := range e {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func joinErrors(errs ...error) error {
+	var joined Errors
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+		if nested, ok := err.(Errors); ok { //nolint:errorlint // only the own flat list is merged
+			joined = append(joined, nested...)
+			continue
+		}
+		joined = append(joined, err)
+	}
+
+	switch len(joined) {
+	case 0:
+		return nil
+	case 1:
+		return joined[0]
+	}
+	return joined
+}
@@ -0,0 +1,172 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManagerRun(t *testing.T) {
+	errStart := errors.New("address already in use")
+	errRun := errors.New("accept failed")
+
+	tests := []struct {
+		name      string
+		failStart string
+		failRun   string
+		wantCalls []string
+		wantErr   error
+	}{
+		{
+			name:      "success",
+			wantCalls: []string{"start a", "start b", "start c", "stop c", "stop b", "stop a"},
+		},
+		{
+			name:      "fail - start",
+			failStart: "b",
+			wantCalls: []string{"start a", "start b", "stop a"},
+			wantErr:   errStart,
+		},
+		{
+			name:      "fail - run",
+			failRun:   "c",
+			wantCalls: []string{"start a", "start b", "start c", "stop c", "stop b", "stop a"},
+			wantErr:   errRun,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				mx    sync.Mutex
+				calls []string
+			)
+			call := func(name string) {
+				mx.Lock()
+				defer mx.Unlock()
+				calls = append(calls, name)
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			m := NewManager(time.Second)
+			for _, name := range []string{"a", "b", "c"} {
+				name := name
+				stopped := make(chan struct{})
+				m.Add(Component{
+					Name: name,
+					Start: func(ctx context.Context) error {
+						call("start " + name)
+						if name == tt.failStart {
+							return errStart
+						}
+						return nil
+					},
+					Run: func() error {
+						if name == tt.failRun {
+							return errRun
+						}
+						<-stopped
+						return nil
+					},
+					Stop: func(ctx context.Context) error {
+						call("stop " + name)
+						close(stopped)
+						return nil
+					},
+				})
+			}
+
+			if tt.failStart == "" && tt.failRun == "" {
+				time.AfterFunc(10*time.Millisecond, cancel)
+			}
+
+			err := m.Run(ctx)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.Nil(t, err)
+			}
+			mx.Lock()
+			defer mx.Unlock()
+			assert.Equal(t, tt.wantCalls, calls)
+		})
+	}
+}
+
+func TestManagerStopTimeout(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	m := NewManager(10 * time.Millisecond)
+	m.Add(Component{
+		Name: "slow",
+		Stop: func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	})
+
+	err := m.Run(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestManagerWaitsForRun(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	returned := make(chan struct{})
+	m := NewManager(time.Second)
+	m.Add(Component{
+		Name: "slow run",
+		Run: func() error {
+			time.Sleep(10 * time.Millisecond)
+			close(returned)
+			return nil
+		},
+	})
+
+	assert.Nil(t, m.Run(ctx))
+	select {
+	case <-returned:
+	default:
+		t.Error("Run returned before the component stopped running")
+	}
+
+	release := make(chan struct{})
+	defer close(release)
+	m = NewManager(10 * time.Millisecond)
+	m.Add(Component{
+		Name: "stuck run",
+		Run: func() error {
+			<-release
+			return nil
+		},
+	})
+	assert.ErrorIs(t, m.Run(ctx), context.DeadlineExceeded)
+}
+
+func TestErrors(t *testing.T) {
+	errStop := errors.New("stop failed")
+	err := joinErrors(
+		fmt.Errorf("a: %w", context.Canceled),
+		&net.OpError{Op: "accept", Err: errStop},
+	)
+
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.True(t, errors.Is(err, errStop))
+	assert.False(t, errors.Is(err, context.DeadlineExceeded))
+
+	var opErr *net.OpError
+	assert.True(t, errors.As(err, &opErr))
+	assert.Equal(t, "accept", opErr.Op)
+	var pathErr *os.PathError
+	assert.False(t, errors.As(err, &pathErr))
+}
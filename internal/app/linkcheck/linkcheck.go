@@ -0,0 +1,291 @@
+package linkcheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Defaults of the checker.
+const (
+	DefaultTimeout     = 10 * time.Second
+	DefaultConcurrency = 8
+	DefaultHostDelay   = time.Second
+	UserAgent          = "yp-1-linkcheck/1.0"
+	maxRedirects       = 10
+)
+
+var (
+	ErrPrivateAddress   = errors.New("the destination address is not public")
+	ErrTooManyRedirects = errors.New("too many redirects")
+)
+
+// deniedPrefixes the special-purpose ranges of the IANA registries, no public destination is there.
+var deniedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),       // this network
+	netip.MustParsePrefix("10.0.0.0/8"),      // private
+	netip.MustParsePrefix("100.64.0.0/10"),   // carrier-grade NAT
+	netip.MustParsePrefix("127.0.0.0/8"),     // loopback
+	netip.MustParsePrefix("169.254.0.0/16"),  // link-local, the cloud metadata
+	netip.MustParsePrefix("172.16.0.0/12"),   // private
+	netip.MustParsePrefix("192.0.0.0/24"),    // IETF protocol assignments
+	netip.MustParsePrefix("192.0.2.0/24"),    // documentation
+	netip.MustParsePrefix("192.88.99.0/24"),  // 6to4 relay anycast
+	netip.MustParsePrefix("192.168.0.0/16"),  // private
+	netip.MustParsePrefix("198.18.0.0/15"),   // benchmarking
+	netip.MustParsePrefix("198.51.100.0/24"), // documentation
+	netip.MustParsePrefix("203.0.113.0/24"),  // documentation
+	netip.MustParsePrefix("224.0.0.0/4"),     // multicast
+	netip.MustParsePrefix("240.0.0.0/4"),     // reserved and broadcast
+	netip.MustParsePrefix("::/96"),           // unspecified, loopback and IPv4-compatible
+	netip.MustParsePrefix("64:ff9b:1::/48"),  // local-use IPv4/IPv6 translation
+	netip.MustParsePrefix("100::/64"),        // discard-only
+	netip.MustParsePrefix("2001::/23"),       // IETF protocol assignments, Teredo
+	netip.MustParsePrefix("2001:db8::/32"),   // documentation
+	netip.MustParsePrefix("2002::/16"),       // 6to4
+	netip.MustParsePrefix("3fff::/20"),       // documentation
+	netip.MustParsePrefix("fc00::/7"),        // unique local
+	netip.MustParsePrefix("fe80::/10"),       // link-local
+	netip.MustParsePrefix("fec0::/10"),       // site-local
+	netip.MustParsePrefix("ff00::/8"),        // multicast
+}
+
+// nat64Prefix the well-known NAT64 prefix, the IPv4 address is in the last 32 bits.
+var nat64Prefix = netip.MustParsePrefix("64:ff9b::/96")
+
+// Health the last check of a link destination.
+type Health struct {
+	Checked    time.Time `json:"checked"`
+	StatusCode int       `json:"status_code,omitempty"` // 0 when no response came
+	Reachable  bool      `json:"reachable"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// IsZero reports whether the link was never checked.
+func (h Health) IsZero() bool {
+	return h.Checked.IsZero()
+}
+
+// Target a link to check.
+type Target struct {
+	Token string
+	URL   string
+}
+
+// Checker checks the destinations with HEAD requests, falling back to GET when HEAD is refused.
+// A destination is reachable when the final response, after the redirects, is not an error.
+// The hosts are checked concurrently, the requests to the same host are sent one by one with a delay.
+// The client of NewChecker connects to the public addresses only, the host names are checked once resolved.
+type Checker struct {
+	Client      *http.Client
+	Blocked     func(rawURL string) error // refuses a destination or a redirect, nil refuses nothing
+	Timeout     time.Duration             // of a request with its fallback
+	Concurrency int                       // the most hosts checked at once
+	HostDelay   time.Duration             // between the requests to the same host
+}
+
+func NewChecker(timeout time.Duration, concurrency int, hostDelay time.Duration) *Checker {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	if concurrency < 1 {
+		concurrency = DefaultConcurrency
+	}
+	if hostDelay < 0 {
+		hostDelay = 0
+	}
+	c := &Checker{
+		Timeout:     timeout,
+		Concurrency: concurrency,
+		HostDelay:   hostDelay,
+	}
+	c.Client = c.newClient(denyPrivate)
+
+	return c
+}
+
+// newClient returns a client dialing through the control and checking every redirect with Blocked.
+// It ignores the proxy settings, so the addresses it connects to are the ones checked.
+func (c *Checker) newClient(control func(network, address string, conn syscall.RawConn) error) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = (&net.Dialer{
+		Timeout:   c.Timeout,
+		KeepAlive: 30 * time.Second,
+		Control:   control,
+	}).DialContext
+
+	return &http.Client{
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return ErrTooManyRedirects
+			}
+			return c.blocked(req.URL.String())
+		},
+	}
+}
+
+func (c *Checker) blocked(rawURL string) error {
+	if c.Blocked == nil {
+		return nil
+	}
+	return c.Blocked(rawURL)
+}
+
+// denyPrivate refuses the connections to the addresses which are not public.
+func denyPrivate(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	addr, err := netip.ParseAddr(host)
+	if err != nil || !isPublic(addr) {
+		return fmt.Errorf("%w: %s", ErrPrivateAddress, host)
+	}
+	return nil
+}
+
+// isPublic reports whether the address is out of the special-purpose ranges.
+// The IPv4 addresses mapped to IPv6 or behind the well-known NAT64 prefix are checked as IPv4.
+func isPublic(addr netip.Addr) bool {
+	addr = addr.WithZone("").Unmap()
+	if nat64Prefix.Contains(addr) {
+		b := addr.As16()
+		addr = netip.AddrFrom4([4]byte{b[12], b[13], b[14], b[15]})
+	}
+
+	for _, prefix := range deniedPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// Check checks the targets and returns their health by the token.
+// The targets left when ctx is done are not in the result.
+func (c *Checker) Check(ctx context.Context, targets []Target) map[string]Health {
+	hosts := make([]string, 0)
+	byHost := make(map[string][]Target)
+	for _, target := range targets {
+		host := ""
+		if u, err := url.Parse(target.URL); err == nil {
+			host = strings.ToLower(u.Host)
+		}
+		if _, ok := byHost[host]; !ok {
+			hosts = append(hosts, host)
+		}
+		byHost[host] = append(byHost[host], target)
+	}
+
+	queue := make(chan []Target)
+	go func() {
+		defer close(queue)
+		for _, host := range hosts {
+			select {
+			case queue <- byHost[host]:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	results := make(map[string]Health, len(targets))
+	var mx sync.Mutex
+	var wg sync.WaitGroup
+	wg.Add(c.Concurrency)
+	for i := 0; i < c.Concurrency; i++ {
+		go func() {
+			defer wg.Done()
+			for hostTargets := range queue {
+				for j, target := range hostTargets {
+					if j > 0 && !sleep(ctx, c.HostDelay) {
+						break
+					}
+					health := c.CheckURL(ctx, target.URL)
+					if ctx.Err() != nil {
+						break
+					}
+					mx.Lock()
+					results[target.Token] = health
+					mx.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	return results
+}
+
+// CheckURL checks a single destination, a blocked one is not requested.
+func (c *Checker) CheckURL(ctx context.Context, rawURL string) Health {
+	if err := c.blocked(rawURL); err != nil {
+		return Health{Checked: time.Now(), Error: err.Error()}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	statusCode, err := c.request(ctx, http.MethodHead, rawURL)
+	if err == nil && statusCode >= http.StatusBadRequest {
+		statusCode, err = c.request(ctx, http.MethodGet, rawURL)
+	}
+
+	health := Health{Checked: time.Now(), StatusCode: statusCode}
+	switch {
+	case errors.Is(err, ErrPrivateAddress):
+		// The resolved address is not shown to the owner.
+		health.Error = ErrPrivateAddress.Error()
+		return health
+	case err != nil:
+		health.Error = err.Error()
+		return health
+	}
+	health.Reachable = statusCode < http.StatusBadRequest
+	return health
+}
+
+func (c *Checker) request(ctx context.Context, method, rawURL string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("User-Agent", UserAgent)
+
+	res, err := c.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	// The body is not read, the connection may be lost for reuse but a huge page is not downloaded.
+	res.Body.Close()
+
+	return res.StatusCode, nil
+}
+
+// sleep waits for the duration, it returns false when ctx is done first.
+func sleep(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,222 @@
+package linkcheck
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChecker(t *testing.T) {
+	var mx sync.Mutex
+	methods := make(map[string][]string)
+	var times []time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mx.Lock()
+		methods[r.URL.Path] = append(methods[r.URL.Path], r.Method)
+		times = append(times, time.Now())
+		mx.Unlock()
+
+		switch r.URL.Path {
+		case "/ok":
+		case "/moved":
+			http.Redirect(w, r, "/ok", http.StatusFound)
+		case "/moved-to-blocked":
+			http.Redirect(w, r, "/blocked", http.StatusFound)
+		case "/no-head":
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+			}
+		case "/slow":
+			time.Sleep(200 * time.Millisecond)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	checker := NewChecker(100*time.Millisecond, 2, 20*time.Millisecond)
+	checker.Client = checker.newClient(nil) // the test server is on the loopback
+	checker.Blocked = func(rawURL string) error {
+		if strings.HasSuffix(rawURL, "/blocked") {
+			return errors.New("blocked")
+		}
+		return nil
+	}
+	results := checker.Check(context.Background(), []Target{
+		{Token: "ok", URL: server.URL + "/ok"},
+		{Token: "moved", URL: server.URL + "/moved"},
+		{Token: "no-head", URL: server.URL + "/no-head"},
+		{Token: "gone", URL: server.URL + "/gone"},
+		{Token: "slow", URL: server.URL + "/slow"},
+		{Token: "blocked", URL: server.URL + "/blocked"},
+		{Token: "moved-to-blocked", URL: server.URL + "/moved-to-blocked"},
+		{Token: "refused", URL: "http://127.0.0.1:1/"},
+	})
+	require.Len(t, results, 8)
+
+	assert.True(t, results["ok"].Reachable)
+	assert.Equal(t, http.StatusOK, results["ok"].StatusCode)
+	assert.False(t, results["ok"].Checked.IsZero())
+	assert.True(t, results["moved"].Reachable)
+	assert.True(t, results["no-head"].Reachable)
+
+	assert.False(t, results["gone"].Reachable)
+	assert.Equal(t, http.StatusNotFound, results["gone"].StatusCode)
+	assert.False(t, results["slow"].Reachable)
+	assert.NotEmpty(t, results["slow"].Error, "the request timed out")
+	assert.False(t, results["refused"].Reachable)
+	assert.Zero(t, results["refused"].StatusCode)
+	assert.NotEmpty(t, results["refused"].Error)
+	assert.False(t, results["blocked"].Reachable)
+	assert.Equal(t, "blocked", results["blocked"].Error)
+	assert.False(t, results["moved-to-blocked"].Reachable)
+	assert.Contains(t, results["moved-to-blocked"].Error, "blocked")
+
+	// The handler of the timed out request may still be running.
+	mx.Lock()
+	defer mx.Unlock()
+	assert.Equal(t, []string{http.MethodHead, http.MethodGet}, methods["/no-head"])
+	assert.Equal(t, []string{http.MethodHead}, methods["/ok"][:1])
+	assert.Empty(t, methods["/blocked"], "the blocked destinations and redirects are not requested")
+	assert.GreaterOrEqual(t, times[len(times)-1].Sub(times[0]), 4*20*time.Millisecond,
+		"the requests to the same host are delayed")
+}
+
+func TestCheckerPrivate(t *testing.T) {
+	requested := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = true
+	}))
+	defer server.Close()
+
+	health := NewChecker(time.Second, 1, 0).CheckURL(context.Background(), server.URL+"/ok")
+	assert.False(t, health.Reachable)
+	assert.Equal(t, ErrPrivateAddress.Error(), health.Error, "the resolved address is not shown")
+	assert.False(t, requested)
+}
+
+func TestDenyPrivate(t *testing.T) {
+	tests := []struct {
+		name    string
+		address string
+		denied  bool
+	}{
+		{"this network", "0.1.2.3:80", true},
+		{"private 10/8", "10.0.0.1:80", true},
+		{"carrier-grade NAT", "100.64.0.1:80", true},
+		{"loopback", "127.0.0.1:80", true},
+		{"link-local", "169.254.169.254:80", true},
+		{"private 172.16/12", "172.16.0.1:80", true},
+		{"IETF protocol assignments", "192.0.0.1:80", true},
+		{"TEST-NET-1", "192.0.2.1:80", true},
+		{"6to4 relay anycast", "192.88.99.1:80", true},
+		{"private 192.168/16", "192.168.1.1:443", true},
+		{"benchmarking", "198.19.0.1:80", true},
+		{"TEST-NET-2", "198.51.100.1:80", true},
+		{"TEST-NET-3", "203.0.113.1:80", true},
+		{"multicast", "224.0.0.1:80", true},
+		{"reserved", "240.0.0.1:80", true},
+		{"broadcast", "255.255.255.255:80", true},
+		{"unspecified IPv6", "[::]:80", true},
+		{"loopback IPv6", "[::1]:80", true},
+		{"IPv4-compatible", "[::a00:1]:80", true},
+		{"IPv4-mapped private", "[::ffff:10.0.0.1]:80", true},
+		{"IPv4-mapped loopback", "[::ffff:127.0.0.1]:80", true},
+		{"NAT64 private", "[64:ff9b::a9fe:a9fe]:80", true},
+		{"local-use NAT64", "[64:ff9b:1::1]:80", true},
+		{"discard-only", "[100::1]:80", true},
+		{"Teredo", "[2001::1]:80", true},
+		{"documentation IPv6", "[2001:db8::1]:80", true},
+		{"6to4", "[2002:a00:1::1]:80", true},
+		{"documentation IPv6 3fff", "[3fff::1]:80", true},
+		{"unique local", "[fd00::1]:80", true},
+		{"link-local IPv6", "[fe80::1]:80", true},
+		{"link-local IPv6 with zone", "[fe80::1%eth0]:80", true},
+		{"site-local", "[fec0::1]:80", true},
+		{"multicast IPv6", "[ff02::1]:80", true},
+		{"not an IP", "localhost:80", true},
+		{"public", "93.184.216.34:443", false},
+		{"public IPv6", "[2606:4700:4700::1111]:443", false},
+		{"IPv4-mapped public", "[::ffff:93.184.216.34]:443", false},
+		{"NAT64 public", "[64:ff9b::5db8:d822]:443", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := denyPrivate("tcp", tt.address, nil)
+			if tt.denied {
+				assert.ErrorIs(t, err, ErrPrivateAddress)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestCheckerCanceled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	results := NewChecker(time.Second, 1, time.Second).Check(ctx, []Target{
+		{Token: "a", URL: server.URL + "/a"},
+		{Token: "b", URL: server.URL + "/b"},
+	})
+	assert.Empty(t, results)
+}
+
+func TestChanged(t *testing.T) {
+	now := time.Now()
+	up := Health{Checked: now, Reachable: true}
+	down := Health{Checked: now}
+
+	assert.False(t, Changed(Health{}, up))
+	assert.True(t, Changed(Health{}, down))
+	assert.True(t, Changed(up, down))
+	assert.True(t, Changed(down, up))
+	assert.False(t, Changed(down, down))
+}
+
+func TestWebhook(t *testing.T) {
+	var received []Event
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/moved" {
+			http.Redirect(w, r, "/", http.StatusFound)
+			return
+		}
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		if received[0].Token == "fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	events := []Event{{Token: "qwerty", OriginalURL: "http://ya.ru", OwnerID: "XXX-YYY-ZZZ", StatusCode: 404}}
+
+	wh := NewWebhook(time.Second)
+	assert.ErrorIs(t, wh.Notify(context.Background(), server.URL, events), ErrPrivateAddress)
+
+	wh.Client.Transport = server.Client().Transport // the test server is on the loopback
+	require.NoError(t, wh.Notify(context.Background(), server.URL, nil))
+	assert.Nil(t, received, "nothing is sent without events")
+
+	require.NoError(t, wh.Notify(context.Background(), server.URL, events))
+	assert.Equal(t, events[0].Token, received[0].Token)
+	assert.Equal(t, 404, received[0].StatusCode)
+
+	assert.Error(t, wh.Notify(context.Background(), server.URL, []Event{{Token: "fail"}}))
+
+	received = nil
+	assert.Error(t, wh.Notify(context.Background(), server.URL+"/moved", events), "the redirects are not followed")
+	assert.Nil(t, received)
+}
@@ -0,0 +1,90 @@
+package linkcheck
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Event a change of the reachability of a link destination, sent to the webhook.
+type Event struct {
+	Token       string    `json:"token"`
+	ShortURL    string    `json:"short_url"`
+	OriginalURL string    `json:"original_url"`
+	OwnerID     string    `json:"owner_id"`
+	Reachable   bool      `json:"reachable"`
+	StatusCode  int       `json:"status_code,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	Checked     time.Time `json:"checked"`
+}
+
+// Changed reports whether the next health is worth an event: the reachability changed
+// or the first check found the destination broken.
+func Changed(prev, next Health) bool {
+	if prev.IsZero() {
+		return !next.Reachable
+	}
+	return prev.Reachable != next.Reachable
+}
+
+// Webhook posts the events as JSON arrays to the webhooks the owners registered for their links.
+// The client of NewWebhook connects to the public addresses only and does not follow the redirects,
+// so an owner's webhook does not reach the internal services.
+type Webhook struct {
+	Client *http.Client
+}
+
+func NewWebhook(timeout time.Duration) *Webhook {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = (&net.Dialer{
+		Timeout:   timeout,
+		KeepAlive: 30 * time.Second,
+		Control:   denyPrivate,
+	}).DialContext
+
+	return &Webhook{Client: &http.Client{
+		Transport: transport,
+		Timeout:   timeout,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}}
+}
+
+// Notify posts the events to the URL at once, nothing is sent without events.
+func (wh *Webhook) Notify(ctx context.Context, url string, events []Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	body, err := json.Marshal(events)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", UserAgent)
+
+	res, err := wh.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("webhook responded %s", res.Status)
+	}
+	return nil
+}
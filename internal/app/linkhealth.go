@@ -0,0 +1,168 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"time"
+
+	"github.com/alrund/yp-1/internal/app/linkcheck"
+	"github.com/alrund/yp-1/internal/app/listing"
+	"github.com/alrund/yp-1/internal/app/logging"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// DefaultLinkCheckBatch the most links checked in a round when the batch size is not set.
+const DefaultLinkCheckBatch = 500
+
+const maxWebhookURLLength = 2048
+
+var ErrInvalidWebhookURL = errors.New("webhook url is not an absolute http(s) url")
+
+// LinkChecker the settings of the background checks of the link destinations.
+type LinkChecker struct {
+	Checker   *linkcheck.Checker
+	Webhook   *linkcheck.Webhook // posts the events to the webhooks of the owners, nil sends none
+	Interval  time.Duration      // a link is checked again after the interval
+	BatchSize int                // the most links checked in a round
+}
+
+// CheckLinks runs a round of the checks: the active links not checked within the interval are checked
+// and their health is stored. The changes of the reachability of the links are posted to the webhook
+// of their owner, the owners without a webhook are not notified and a failed post is logged.
+// It returns the number of the checked links.
+func (us *URLShortener) CheckLinks(ctx context.Context, lc *LinkChecker) (_ int, err error) {
+	ctx, span := startSpan(ctx, "URLShortener.CheckLinks")
+	defer func() { endSpan(span, err) }()
+
+	batchSize := lc.BatchSize
+	if batchSize < 1 {
+		batchSize = DefaultLinkCheckBatch
+	}
+
+	s := us.storage(ctx)
+	items, err := s.GetLinksToCheck(time.Now().Add(-lc.Interval), batchSize)
+	if err != nil {
+		return 0, err
+	}
+	span.SetAttributes(attribute.Int("links.count", len(items)))
+	if len(items) == 0 {
+		return 0, nil
+	}
+
+	targets := make([]linkcheck.Target, 0, len(items))
+	for _, item := range items {
+		targets = append(targets, linkcheck.Target{Token: item.Token, URL: item.URL})
+	}
+
+	health := lc.Checker.Check(ctx, targets)
+	if len(health) == 0 {
+		return 0, nil
+	}
+	if err = s.SetLinkHealth(health); err != nil {
+		return 0, err
+	}
+
+	if lc.Webhook != nil {
+		us.notifyOwners(ctx, lc.Webhook, items, health)
+	}
+
+	return len(health), nil
+}
+
+// notifyOwners posts the changes of the reachability to the webhook of every owner,
+// an owner gets the events of their own links only.
+func (us *URLShortener) notifyOwners(
+	ctx context.Context,
+	webhook *linkcheck.Webhook,
+	items []listing.Item,
+	health map[string]linkcheck.Health,
+) {
+	baseURL := us.GetBaseURL()
+	owners := make([]string, 0)
+	events := make(map[string][]linkcheck.Event)
+	for _, item := range items {
+		h, ok := health[item.Token]
+		if !ok || !linkcheck.Changed(item.Health, h) {
+			continue
+		}
+		if _, ok := events[item.OwnerID]; !ok {
+			owners = append(owners, item.OwnerID)
+		}
+		events[item.OwnerID] = append(events[item.OwnerID], linkcheck.Event{
+			Token:       item.Token,
+			ShortURL:    baseURL + item.Token,
+			OriginalURL: item.URL,
+			OwnerID:     item.OwnerID,
+			Reachable:   h.Reachable,
+			StatusCode:  h.StatusCode,
+			Error:       h.Error,
+			Checked:     h.Checked,
+		})
+	}
+
+	s := us.storage(ctx)
+	for _, ownerID := range owners {
+		webhookURL, err := s.GetLinkWebhook(ownerID)
+		if err != nil {
+			logging.Errorf("Link check webhook of %s: %v", ownerID, err)
+			continue
+		}
+		if webhookURL == "" {
+			continue
+		}
+		if err := webhook.Notify(ctx, webhookURL, events[ownerID]); err != nil {
+			logging.Errorf("Link check webhook of %s: %v", ownerID, err)
+		}
+	}
+}
+
+// SetLinkWebhook registers the URL the changes of the reachability of the user's links are posted to.
+func (us *URLShortener) SetLinkWebhook(ctx context.Context, userID, rawURL string) (err error) {
+	ctx, span := startSpan(ctx, "URLShortener.SetLinkWebhook")
+	defer func() { endSpan(span, err) }()
+
+	if len(rawURL) > maxWebhookURLLength {
+		return ErrInvalidWebhookURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ErrInvalidWebhookURL
+	}
+
+	return us.storage(ctx).SetLinkWebhook(userID, rawURL)
+}
+
+// GetLinkWebhook returns the user's link check webhook URL, empty if the user has none.
+func (us *URLShortener) GetLinkWebhook(ctx context.Context, userID string) (_ string, err error) {
+	ctx, span := startSpan(ctx, "URLShortener.GetLinkWebhook")
+	defer func() { endSpan(span, err) }()
+
+	return us.storage(ctx).GetLinkWebhook(userID)
+}
+
+// RemoveLinkWebhook removes the user's link check webhook.
+func (us *URLShortener) RemoveLinkWebhook(ctx context.Context, userID string) (err error) {
+	ctx, span := startSpan(ctx, "URLShortener.RemoveLinkWebhook")
+	defer func() { endSpan(span, err) }()
+
+	return us.storage(ctx).SetLinkWebhook(userID, "")
+}
+
+// WatchLinks runs the rounds of the checks every poll interval until ctx is done.
+func (us *URLShortener) WatchLinks(ctx context.Context, lc *LinkChecker, poll time.Duration) {
+	ticker := time.NewTicker(poll)
+	defer ticker.Stop()
+
+	for {
+		if _, err := us.CheckLinks(ctx, lc); err != nil && ctx.Err() == nil {
+			logging.Errorf("Link check: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
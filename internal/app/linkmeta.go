@@ -0,0 +1,76 @@
+package app
+
+import (
+	"context"
+
+	"github.com/alrund/yp-1/internal/app/linkmeta"
+	"github.com/alrund/yp-1/internal/app/storage"
+	"github.com/alrund/yp-1/internal/app/workspace"
+)
+
+// UpdateURL changes the title, the notes or the tags of a link of the user or of a workspace the user can edit.
+// Other links are reported as not found.
+func (us *URLShortener) UpdateURL(
+	ctx context.Context,
+	userID, tokenValue string,
+	update linkmeta.Update,
+) (_ linkmeta.Meta, err error) {
+	ctx, span := startSpan(ctx, "URLShortener.UpdateURL")
+	defer func() { endSpan(span, err) }()
+
+	s := us.storage(ctx)
+	owners, err := linkOwners(s, userID, workspace.Role.CanEdit)
+	if err != nil {
+		return linkmeta.Meta{}, err
+	}
+
+	return s.UpdateLinkMeta(owners, tokenValue, update)
+}
+
+// GetTags returns the tags of the links of the user and of the user's workspaces with the link counts.
+func (us *URLShortener) GetTags(ctx context.Context, userID string) (_ []linkmeta.TagCount, err error) {
+	ctx, span := startSpan(ctx, "URLShortener.GetTags")
+	defer func() { endSpan(span, err) }()
+
+	s := us.storage(ctx)
+	owners, err := linkOwners(s, userID, func(workspace.Role) bool { return true })
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetTags(owners)
+}
+
+// RenameTag renames the tag on the links of the user and of the workspaces the user can edit.
+// A link having both tags keeps one.
+func (us *URLShortener) RenameTag(ctx context.Context, userID, from, to string) (err error) {
+	ctx, span := startSpan(ctx, "URLShortener.RenameTag")
+	defer func() { endSpan(span, err) }()
+
+	if from, err = linkmeta.NormalizeTag(from); err != nil {
+		return err
+	}
+	if to, err = linkmeta.NormalizeTag(to); err != nil {
+		return err
+	}
+
+	s := us.storage(ctx)
+	owners, err := linkOwners(s, userID, workspace.Role.CanEdit)
+	if err != nil {
+		return err
+	}
+
+	if from == to {
+		return nil
+	}
+
+	renamed, err := s.RenameTag(owners, from, to)
+	if err != nil {
+		return err
+	}
+	if renamed == 0 {
+		return storage.ErrTagNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,161 @@
+package linkmeta
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	MaxTitleLength = 255
+	MaxNotesLength = 4096
+	MaxTagLength   = 64
+	MaxTags        = 20
+)
+
+var (
+	ErrLongTitle   = errors.New("the title is too long")
+	ErrLongNotes   = errors.New("the notes are too long")
+	ErrInvalidTag  = errors.New("invalid tag")
+	ErrTooManyTags = errors.New("too many tags")
+)
+
+// Meta the title, the notes and the tags of a link.
+type Meta struct {
+	Title string   `json:"title"`
+	Notes string   `json:"notes"`
+	Tags  []string `json:"tags"` // normalized, unique and sorted
+}
+
+// Update the fields to change, nil keeps the field.
+type Update struct {
+	Title *string
+	Notes *string
+	Tags  *[]string
+}
+
+// TagCount a tag with the number of the links having it.
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
+
+// New returns the checked meta with the tags normalized.
+func New(title, notes string, tags []string) (Meta, error) {
+	title = strings.TrimSpace(title)
+	if utf8.RuneCountInString(title) > MaxTitleLength {
+		return Meta{}, fmt.Errorf("%w, the maximum is %d", ErrLongTitle, MaxTitleLength)
+	}
+	if utf8.RuneCountInString(notes) > MaxNotesLength {
+		return Meta{}, fmt.Errorf("%w, the maximum is %d", ErrLongNotes, MaxNotesLength)
+	}
+
+	unique := make(map[string]struct{}, len(tags))
+	for _, tag := range tags {
+		normalized, err := NormalizeTag(tag)
+		if err != nil {
+			return Meta{}, err
+		}
+		unique[normalized] = struct{}{}
+	}
+	if len(unique) > MaxTags {
+		return Meta{}, fmt.Errorf("%w, the maximum is %d", ErrTooManyTags, MaxTags)
+	}
+
+	m := Meta{Title: title, Notes: notes}
+	for tag := range unique {
+		m.Tags = append(m.Tags, tag)
+	}
+	sort.Strings(m.Tags)
+
+	return m, nil
+}
+
+// NormalizeTag trims and lowercases the tag.
+// A tag is not empty, is at most MaxTagLength long and has no commas and control characters.
+func NormalizeTag(tag string) (string, error) {
+	normalized := strings.ToLower(strings.TrimSpace(tag))
+	if normalized == "" || utf8.RuneCountInString(normalized) > MaxTagLength {
+		return "", fmt.Errorf("%w %q", ErrInvalidTag, tag)
+	}
+	if strings.IndexFunc(normalized, func(r rune) bool { return r == ',' || unicode.IsControl(r) }) >= 0 {
+		return "", fmt.Errorf("%w %q", ErrInvalidTag, tag)
+	}
+	return normalized, nil
+}
+
+// IsInvalid reports whether the error is caused by invalid meta.
+func IsInvalid(err error) bool {
+	return errors.Is(err, ErrLongTitle) ||
+		errors.Is(err, ErrLongNotes) ||
+		errors.Is(err, ErrInvalidTag) ||
+		errors.Is(err, ErrTooManyTags)
+}
+
+// IsZero reports whether the meta is empty.
+func (m Meta) IsZero() bool {
+	return m.Title == "" && m.Notes == "" && len(m.Tags) == 0
+}
+
+// HasTag reports whether the meta has the normalized tag.
+func (m Meta) HasTag(tag string) bool {
+	i := sort.SearchStrings(m.Tags, tag)
+	return i < len(m.Tags) && m.Tags[i] == tag
+}
+
+// Apply returns the meta changed by the update.
+func (u Update) Apply(m Meta) (Meta, error) {
+	if u.Title != nil {
+		m.Title = *u.Title
+	}
+	if u.Notes != nil {
+		m.Notes = *u.Notes
+	}
+	if u.Tags != nil {
+		m.Tags = *u.Tags
+	}
+	return New(m.Title, m.Notes, m.Tags)
+}
+
+// RenameTag returns the meta with the normalized tag renamed, merged if it has the new tag already.
+// It reports whether the meta had the tag.
+func (m Meta) RenameTag(from, to string) (Meta, bool) {
+	if !m.HasTag(from) {
+		return m, false
+	}
+
+	tags := make([]string, 0, len(m.Tags))
+	for _, tag := range m.Tags {
+		if tag != from && tag != to {
+			tags = append(tags, tag)
+		}
+	}
+	tags = append(tags, to)
+	sort.Strings(tags)
+	m.Tags = tags
+
+	return m, true
+}
+
+// CountTags returns the tags of the metas with the link counts, sorted by the tag.
+func CountTags(metas []Meta) []TagCount {
+	counts := make(map[string]int)
+	for _, m := range metas {
+		for _, tag := range m.Tags {
+			counts[tag]++
+		}
+	}
+
+	tags := make([]TagCount, 0, len(counts))
+	for tag, count := range counts {
+		tags = append(tags, TagCount{Tag: tag, Count: count})
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		return tags[i].Tag < tags[j].Tag
+	})
+
+	return tags
+}
@@ -0,0 +1,86 @@
+package linkmeta
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		title   string
+		notes   string
+		tags    []string
+		want    Meta
+		wantErr error
+	}{
+		{"zero", "", "", nil, Meta{}, nil},
+		{"normalized", " Go ", "docs", []string{"Lang", " go ", "lang"}, Meta{Title: "Go", Notes: "docs", Tags: []string{"go", "lang"}}, nil},
+		{"long title", strings.Repeat("я", MaxTitleLength+1), "", nil, Meta{}, ErrLongTitle},
+		{"long notes", "", strings.Repeat("x", MaxNotesLength+1), nil, Meta{}, ErrLongNotes},
+		{"empty tag", "", "", []string{" "}, Meta{}, ErrInvalidTag},
+		{"comma", "", "", []string{"a,b"}, Meta{}, ErrInvalidTag},
+		{"control character", "", "", []string{"a\tb"}, Meta{}, ErrInvalidTag},
+		{"long tag", "", "", []string{strings.Repeat("x", MaxTagLength+1)}, Meta{}, ErrInvalidTag},
+		{"too many tags", "", "", strings.Split("a b c d e f g h i j k l m n o p q r s t u", " "), Meta{}, ErrTooManyTags},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := New(tt.title, tt.notes, tt.tags)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.True(t, IsInvalid(err))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, m)
+		})
+	}
+}
+
+func TestUpdateApply(t *testing.T) {
+	m := Meta{Title: "Go", Notes: "docs", Tags: []string{"go"}}
+	title := " Golang "
+	tags := []string{"Lang"}
+
+	updated, err := Update{Title: &title}.Apply(m)
+	require.NoError(t, err)
+	assert.Equal(t, Meta{Title: "Golang", Notes: "docs", Tags: []string{"go"}}, updated)
+
+	updated, err = Update{Tags: &tags}.Apply(m)
+	require.NoError(t, err)
+	assert.Equal(t, Meta{Title: "Go", Notes: "docs", Tags: []string{"lang"}}, updated)
+
+	bad := []string{""}
+	_, err = Update{Tags: &bad}.Apply(m)
+	assert.ErrorIs(t, err, ErrInvalidTag)
+}
+
+func TestRenameTag(t *testing.T) {
+	m := Meta{Tags: []string{"go", "lang", "web"}}
+
+	renamed, ok := m.RenameTag("go", "golang")
+	assert.True(t, ok)
+	assert.Equal(t, []string{"golang", "lang", "web"}, renamed.Tags)
+
+	merged, ok := m.RenameTag("go", "web")
+	assert.True(t, ok)
+	assert.Equal(t, []string{"lang", "web"}, merged.Tags)
+
+	same, ok := m.RenameTag("ru", "web")
+	assert.False(t, ok)
+	assert.Equal(t, m, same)
+}
+
+func TestCountTags(t *testing.T) {
+	counts := CountTags([]Meta{
+		{Tags: []string{"go", "web"}},
+		{Tags: []string{"web"}},
+		{},
+	})
+	assert.Equal(t, []TagCount{{Tag: "go", Count: 1}, {Tag: "web", Count: 2}}, counts)
+	assert.Empty(t, CountTags(nil))
+}
@@ -0,0 +1,273 @@
+package listing
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alrund/yp-1/internal/app/linkcheck"
+)
+
+// Sort the order of the listing, a leading minus sorts in descending order.
+type Sort string
+
+const (
+	SortCreated     Sort = "created"
+	SortCreatedDesc Sort = "-created"
+	SortClicks      Sort = "clicks"
+	SortClicksDesc  Sort = "-clicks"
+)
+
+// Status of the link.
+type Status string
+
+const (
+	StatusActive  Status = "active"
+	StatusExpired Status = "expired"
+	StatusRemoved Status = "removed"
+)
+
+// DefaultLimit the page size when the query does not set it, MaxLimit the largest page.
+const (
+	DefaultLimit = 100
+	MaxLimit     = 1000
+)
+
+var (
+	ErrInvalidSort   = errors.New("invalid sort")
+	ErrInvalidStatus = errors.New("invalid status")
+	ErrInvalidLimit  = errors.New("invalid limit")
+	ErrInvalidCursor = errors.New("invalid cursor")
+)
+
+// IsInvalid reports whether the error is caused by an invalid query.
+func IsInvalid(err error) bool {
+	return errors.Is(err, ErrInvalidSort) ||
+		errors.Is(err, ErrInvalidStatus) ||
+		errors.Is(err, ErrInvalidLimit) ||
+		errors.Is(err, ErrInvalidCursor)
+}
+
+// Query selects a page of the links.
+// The zero query returns the first page of every link from the newest.
+type Query struct {
+	Sort     Sort
+	Status   Status
+	Contains string // a case-insensitive substring of the original URL
+	Tag      string // a normalized tag
+	Limit    int
+	Cursor   string // the cursor of the previous page
+}
+
+// Item a link in the listing.
+type Item struct {
+	Token   string
+	URL     string
+	OwnerID string
+	Created time.Time
+	Expire  time.Time
+	Removed bool
+	Clicks  int64
+	Title   string
+	Notes   string
+	Tags    []string
+	Health  linkcheck.Health // the last check of the destination
+}
+
+// Status returns the status of the link at the time.
+func (item Item) Status(now time.Time) Status {
+	switch {
+	case item.Removed:
+		return StatusRemoved
+	case item.Expire.After(now):
+		return StatusActive
+	default:
+		return StatusExpired
+	}
+}
+
+// Cursor the position after the last item of a page.
+type Cursor struct {
+	Key   int64 // the sort key of the item
+	Token string
+}
+
+// ParseSort parses the sort, empty means the newest first.
+func ParseSort(s string) (Sort, error) {
+	switch by := Sort(s); by {
+	case "":
+		return SortCreatedDesc, nil
+	case SortCreated, SortCreatedDesc, SortClicks, SortClicksDesc:
+		return by, nil
+	}
+	return "", fmt.Errorf("%w %q", ErrInvalidSort, s)
+}
+
+// ParseStatus parses the status, empty means any.
+func ParseStatus(s string) (Status, error) {
+	switch status := Status(s); status {
+	case "", StatusActive, StatusExpired, StatusRemoved:
+		return status, nil
+	}
+	return "", fmt.Errorf("%w %q", ErrInvalidStatus, s)
+}
+
+// Validate checks the query, so the storage may trust it.
+func (q Query) Validate() error {
+	if _, err := ParseSort(string(q.Sort)); err != nil {
+		return err
+	}
+	if _, err := ParseStatus(string(q.Status)); err != nil {
+		return err
+	}
+	if q.Limit < 0 || q.Limit > MaxLimit {
+		return fmt.Errorf("%w %d, the maximum is %d", ErrInvalidLimit, q.Limit, MaxLimit)
+	}
+	_, err := q.ParseCursor()
+	return err
+}
+
+// PageSize returns the number of the items of the page.
+func (q Query) PageSize() int {
+	if q.Limit <= 0 {
+		return DefaultLimit
+	}
+	return q.Limit
+}
+
+// SortBy returns the sort, the default for the empty one.
+func (q Query) SortBy() Sort {
+	if q.Sort == "" {
+		return SortCreatedDesc
+	}
+	return q.Sort
+}
+
+// Descending reports whether the items are sorted in descending order.
+func (q Query) Descending() bool {
+	return strings.HasPrefix(string(q.SortBy()), "-")
+}
+
+// Key returns the sort key of the item, the links created before the time was kept have the key 0.
+func (q Query) Key(item Item) int64 {
+	switch q.SortBy() {
+	case SortClicks, SortClicksDesc:
+		return item.Clicks
+	default:
+		if item.Created.IsZero() {
+			return 0
+		}
+		return item.Created.UnixNano()
+	}
+}
+
+// Match reports whether the item passes the filters, the cursor aside.
+func (q Query) Match(item Item, now time.Time) bool {
+	if q.Status != "" && item.Status(now) != q.Status {
+		return false
+	}
+
+	if q.Contains != "" && !strings.Contains(strings.ToLower(item.URL), strings.ToLower(q.Contains)) {
+		return false
+	}
+
+	if q.Tag != "" && !hasTag(item.Tags, q.Tag) {
+		return false
+	}
+
+	return true
+}
+
+// Page filters and sorts the items and returns the page after the cursor with the cursor of the next page.
+// The next cursor is empty on the last page.
+func (q Query) Page(items []Item, now time.Time) ([]Item, string, error) {
+	cursor, err := q.ParseCursor()
+	if err != nil {
+		return nil, "", err
+	}
+
+	found := make([]Item, 0)
+	for _, item := range items {
+		if q.Match(item, now) && (cursor == nil || q.after(item, *cursor)) {
+			found = append(found, item)
+		}
+	}
+
+	sort.Slice(found, func(i, j int) bool {
+		return q.before(found[i], found[j])
+	})
+
+	size := q.PageSize()
+	if len(found) <= size {
+		return found, "", nil
+	}
+
+	return found[:size], q.NextCursor(found[size-1]), nil
+}
+
+// NextCursor returns the cursor of the page after the item.
+func (q Query) NextCursor(last Item) string {
+	raw := string(q.SortBy()) + "\n" + strconv.FormatInt(q.Key(last), 10) + "\n" + last.Token
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// ParseCursor returns the cursor of the query, nil for the first page.
+// The cursor must be made by a query with the same sort.
+func (q Query) ParseCursor() (*Cursor, error) {
+	if q.Cursor == "" {
+		return nil, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(q.Cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	parts := strings.SplitN(string(raw), "\n", 3)
+	if len(parts) != 3 || Sort(parts[0]) != q.SortBy() {
+		return nil, ErrInvalidCursor
+	}
+
+	key, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return &Cursor{Key: key, Token: parts[2]}, nil
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// before reports whether a comes before b, the token breaks the ties.
+func (q Query) before(a, b Item) bool {
+	return q.less(q.Key(a), a.Token, q.Key(b), b.Token)
+}
+
+// after reports whether the item comes after the cursor.
+func (q Query) after(item Item, cursor Cursor) bool {
+	return q.less(cursor.Key, cursor.Token, q.Key(item), item.Token)
+}
+
+func (q Query) less(keyA int64, tokenA string, keyB int64, tokenB string) bool {
+	if keyA == keyB {
+		if q.Descending() {
+			return tokenA > tokenB
+		}
+		return tokenA < tokenB
+	}
+	if q.Descending() {
+		return keyA > keyB
+	}
+	return keyA < keyB
+}
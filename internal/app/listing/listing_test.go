@@ -0,0 +1,93 @@
+package listing
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   Query
+		wantErr error
+	}{
+		{"zero", Query{}, nil},
+		{"full", Query{Sort: SortClicksDesc, Status: StatusExpired, Contains: "ya", Limit: MaxLimit}, nil},
+		{"sort", Query{Sort: "url"}, ErrInvalidSort},
+		{"status", Query{Status: "lost"}, ErrInvalidStatus},
+		{"negative limit", Query{Limit: -1}, ErrInvalidLimit},
+		{"big limit", Query{Limit: MaxLimit + 1}, ErrInvalidLimit},
+		{"cursor", Query{Cursor: "!"}, ErrInvalidCursor},
+		{"cursor of another sort", Query{Sort: SortClicks, Cursor: Query{}.NextCursor(Item{Token: "a"})}, ErrInvalidCursor},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.query.Validate()
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.True(t, IsInvalid(err))
+		})
+	}
+}
+
+func TestQueryPage(t *testing.T) {
+	now := time.Now()
+	items := []Item{
+		{Token: "a", URL: "http://ya.ru", Created: now.Add(-3 * time.Hour), Expire: now.Add(time.Hour), Clicks: 5, Tags: []string{"promo", "ru"}},
+		{Token: "b", URL: "http://go.dev", Created: now.Add(-2 * time.Hour), Expire: now.Add(-time.Hour), Clicks: 1},
+		{Token: "c", URL: "http://YA.ru/c", Created: now.Add(-time.Hour), Expire: now.Add(time.Hour), Removed: true, Tags: []string{"ru"}},
+		{Token: "d", URL: "http://old.ru", Expire: now.Add(time.Hour), Clicks: 1},
+	}
+	tokens := func(items []Item) []string {
+		values := make([]string, 0, len(items))
+		for _, item := range items {
+			values = append(values, item.Token)
+		}
+		return values
+	}
+
+	tests := []struct {
+		name  string
+		query Query
+		want  []string
+	}{
+		{"newest first", Query{}, []string{"c", "b", "a", "d"}},
+		{"oldest first", Query{Sort: SortCreated}, []string{"d", "a", "b", "c"}},
+		{"most clicked first", Query{Sort: SortClicksDesc}, []string{"a", "d", "b", "c"}},
+		{"least clicked first", Query{Sort: SortClicks}, []string{"c", "b", "d", "a"}},
+		{"active", Query{Status: StatusActive}, []string{"a", "d"}},
+		{"expired", Query{Status: StatusExpired}, []string{"b"}},
+		{"removed", Query{Status: StatusRemoved}, []string{"c"}},
+		{"contains", Query{Contains: "ya.RU"}, []string{"c", "a"}},
+		{"tag", Query{Tag: "ru"}, []string{"c", "a"}},
+		{"tag and status", Query{Tag: "ru", Status: StatusActive}, []string{"a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, next, err := tt.query.Page(items, now)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, tokens(page))
+			assert.Empty(t, next)
+		})
+	}
+
+	t.Run("pages", func(t *testing.T) {
+		query := Query{Sort: SortClicksDesc, Limit: 3}
+		page, next, err := query.Page(items, now)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"a", "d", "b"}, tokens(page))
+		require.NotEmpty(t, next)
+
+		query.Cursor = next
+		page, next, err = query.Page(items, now)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"c"}, tokens(page))
+		assert.Empty(t, next)
+	})
+}
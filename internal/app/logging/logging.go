@@ -0,0 +1,54 @@
+package logging
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync/atomic"
+)
+
+// Level the least severity of the written messages.
+type Level int32
+
+const (
+	LevelInfo Level = iota
+	LevelError
+)
+
+// ErrLevel the level is unknown.
+var ErrLevel = errors.New("unknown log level")
+
+var level int32 // Level
+
+// ParseLevel returns the level by its name, an empty name is info.
+func ParseLevel(name string) (Level, error) {
+	switch name {
+	case "", "info":
+		return LevelInfo, nil
+	case "error":
+		return LevelError, nil
+	}
+	return LevelInfo, fmt.Errorf("%w %q, expected info or error", ErrLevel, name)
+}
+
+// SetLevel sets the least severity of the written messages, it is safe to call at any time.
+func SetLevel(l Level) {
+	atomic.StoreInt32(&level, int32(l))
+}
+
+// GetLevel returns the least severity of the written messages.
+func GetLevel() Level {
+	return Level(atomic.LoadInt32(&level))
+}
+
+// Infof writes a message about the normal work of the service.
+func Infof(format string, v ...interface{}) {
+	if GetLevel() <= LevelInfo {
+		log.Printf(format, v...)
+	}
+}
+
+// Errorf writes a message about a failure, it is written at every level.
+func Errorf(format string, v ...interface{}) {
+	log.Printf(format, v...)
+}
@@ -0,0 +1,50 @@
+package logging
+
+import (
+	"bytes"
+	"log"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    Level
+		wantErr bool
+	}{
+		{name: "", want: LevelInfo},
+		{name: "info", want: LevelInfo},
+		{name: "error", want: LevelError},
+		{name: "debug", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseLevel(tt.name)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrLevel)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestLevel(t *testing.T) {
+	var buf bytes.Buffer
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(&buf)
+	defer SetLevel(GetLevel())
+
+	SetLevel(LevelError)
+	Infof("started")
+	Errorf("failed")
+	assert.NotContains(t, buf.String(), "started")
+	assert.Contains(t, buf.String(), "failed")
+
+	SetLevel(LevelInfo)
+	Infof("started")
+	assert.Contains(t, buf.String(), "started")
+}
@@ -0,0 +1,36 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	"github.com/alrund/yp-1/internal/app/listing"
+	"github.com/alrund/yp-1/internal/app/storage"
+)
+
+// LookupURL returns the short URL of the original URL with its status.
+// Unlike Add, it neither creates a link nor refreshes an expired one.
+// The URL is canonicalized as Add does, so any form of a stored URL is found.
+func (us *URLShortener) LookupURL(ctx context.Context, url string) (_ storage.URLpairs, err error) {
+	ctx, span := startSpan(ctx, "URLShortener.LookupURL")
+	defer func() { endSpan(span, err) }()
+
+	url, err = us.GetURLPolicy().Canonicalize(url)
+	if err != nil {
+		return storage.URLpairs{}, err
+	}
+
+	token, err := us.storage(ctx).GetTokenByURL(url)
+	if err != nil {
+		return storage.URLpairs{}, err
+	}
+
+	item := listing.Item{Expire: token.Expire, Removed: token.Removed}
+	return storage.URLpairs{
+		ShortURL:    us.GetBaseURL() + token.Value,
+		OriginalURL: url,
+		Expire:      token.Expire,
+		Removed:     token.Removed,
+		Status:      item.Status(time.Now()),
+	}, nil
+}
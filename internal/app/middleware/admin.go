@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// AdminAccess restricts access to the admin listener.
+// A request passes if it carries the bearer token or comes from the trusted subnet.
+// When neither restriction is configured every request is denied.
+// The trusted subnet is looked up on every request, so it follows configuration reloads.
+func AdminAccess(token string, trustedSubnet func() *net.IPNet) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if token != "" && hasBearerToken(r, token) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if trustedSubnet != nil {
+				if ipnet := trustedSubnet(); ipnet != nil && ipnet.Contains(remoteIP(r)) {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		})
+	}
+}
+
+func hasBearerToken(r *http.Request, token string) bool {
+	value, ok := bearerToken(r)
+	if !ok {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(value), []byte(token)) == 1
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	authorization := r.Header.Get("Authorization")
+	if !strings.HasPrefix(authorization, "Bearer ") {
+		return "", false
+	}
+	return strings.TrimPrefix(authorization, "Bearer "), true
+}
+
+func remoteIP(r *http.Request) net.IP {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return net.ParseIP(host)
+}
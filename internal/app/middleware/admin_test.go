@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdminAccess(t *testing.T) {
+	_, ipnet, _ := net.ParseCIDR("192.168.1.0/24")
+	trustedSubnet := func() *net.IPNet { return ipnet }
+
+	tests := []struct {
+		name          string
+		token         string
+		trustedSubnet func() *net.IPNet
+		authorization string
+		remoteAddr    string
+		wantCode      int
+	}{
+		{
+			name:          "success - bearer token",
+			token:         "secret",
+			authorization: "Bearer secret",
+			remoteAddr:    "10.0.0.1:1234",
+			wantCode:      http.StatusOK,
+		},
+		{
+			name:          "success - trusted subnet",
+			token:         "secret",
+			trustedSubnet: trustedSubnet,
+			remoteAddr:    "192.168.1.10:1234",
+			wantCode:      http.StatusOK,
+		},
+		{
+			name:       "fail - no restrictions",
+			remoteAddr: "10.0.0.1:1234",
+			wantCode:   http.StatusForbidden,
+		},
+		{
+			name:          "fail - wrong token",
+			token:         "secret",
+			authorization: "Bearer wrong",
+			remoteAddr:    "10.0.0.1:1234",
+			wantCode:      http.StatusForbidden,
+		},
+		{
+			name:          "fail - trusted subnet is not configured",
+			trustedSubnet: func() *net.IPNet { return nil },
+			remoteAddr:    "192.168.1.10:1234",
+			wantCode:      http.StatusForbidden,
+		},
+		{
+			name:          "fail - not in trusted subnet",
+			trustedSubnet: trustedSubnet,
+			remoteAddr:    "10.0.0.1:1234",
+			wantCode:      http.StatusForbidden,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+			request := httptest.NewRequest(http.MethodGet, "/debug/pprof/", nil)
+			request.RemoteAddr = tt.remoteAddr
+			if tt.authorization != "" {
+				request.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+			AdminAccess(tt.token, tt.trustedSubnet)(next).ServeHTTP(w, request)
+			res := w.Result()
+			defer res.Body.Close()
+
+			assert.Equal(t, tt.wantCode, res.StatusCode)
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/alrund/yp-1/internal/app/audit"
+	"github.com/google/uuid"
+)
+
+// RequestIDHeader carries the request ID, a missing one is generated and sent back.
+const RequestIDHeader = "X-Request-ID"
+
+// Audit puts the actor of the request into the context for the audit log.
+// It must run after Auth to see the user ID.
+func Audit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(RequestIDHeader)
+		if requestID == "" {
+			requestID = uuid.NewString()
+		}
+		w.Header().Set(RequestIDHeader, requestID)
+
+		actor := audit.Actor{Source: audit.SourceHTTP, RequestID: requestID}
+		actor.UserID, _ = r.Context().Value(UserIDContextKey).(string)
+		if ip := remoteIP(r); ip != nil {
+			actor.ClientIP = ip.String()
+		}
+
+		next.ServeHTTP(w, r.WithContext(audit.WithActor(r.Context(), actor)))
+	})
+}
@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alrund/yp-1/internal/app/audit"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAudit(t *testing.T) {
+	var actor audit.Actor
+	h := Audit(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actor = audit.ActorFromContext(r.Context())
+	}))
+
+	request := httptest.NewRequest(http.MethodPost, "/", nil)
+	request.RemoteAddr = "10.0.0.1:1234"
+	request.Header.Set(RequestIDHeader, "req-1")
+	request = request.WithContext(context.WithValue(request.Context(), UserIDContextKey, "XXX-YYY-ZZZ"))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, request)
+
+	assert.Equal(t, audit.Actor{
+		UserID:    "XXX-YYY-ZZZ",
+		Source:    audit.SourceHTTP,
+		ClientIP:  "10.0.0.1",
+		RequestID: "req-1",
+	}, actor)
+	assert.Equal(t, "req-1", w.Header().Get(RequestIDHeader))
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", nil))
+	assert.NotEmpty(t, actor.RequestID)
+	assert.Equal(t, actor.RequestID, w.Header().Get(RequestIDHeader))
+}
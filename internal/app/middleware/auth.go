@@ -6,7 +6,8 @@ import (
 	"net/http"
 	"time"
 
-	"github.com/alrund/yp-1/internal/app/encryption"
+	"github.com/alrund/yp-1/internal/app/apikey"
+	"github.com/alrund/yp-1/internal/app/session"
 	"github.com/google/uuid"
 )
 
@@ -14,11 +15,55 @@ type ContextKey string
 
 const UserIDContextKey ContextKey = "userID"
 
-// Auth authenticates the user.
-func Auth(enc *encryption.Encryption) func(next http.Handler) http.Handler {
+// ScopesContextKey the scopes granted to the request, missing when the credentials are not limited to scopes.
+const ScopesContextKey ContextKey = "scopes"
+
+// APIKeyAuthenticator resolves an API key into the ID of its owner.
+type APIKeyAuthenticator interface {
+	AuthenticateAPIKey(ctx context.Context, secret string) (string, error)
+}
+
+// Auth authenticates the user by the "Authorization: Bearer <API key or session token>" header or the cookie.
+// The scopes of a scoped session token are put into the context for RequireScope, the API keys are not scoped.
+// apiKeys may be nil, then the API keys are not accepted.
+func Auth(sessions session.Codec, apiKeys APIKeyAuthenticator) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			userID, err := getCookie(r, enc)
+			token, ok := bearerToken(r)
+			if ok && !apikey.IsKey(token) {
+				userID, _, err := sessions.Parse(token)
+				if err != nil {
+					http.Error(w, "401 Unauthorized.", http.StatusUnauthorized)
+					return
+				}
+				ctx, err := withScopes(r.Context(), sessions, token)
+				if err != nil {
+					http.Error(w, "401 Unauthorized.", http.StatusUnauthorized)
+					return
+				}
+
+				ctx = context.WithValue(ctx, UserIDContextKey, userID)
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return
+			}
+
+			if ok && apiKeys != nil {
+				userID, err := apiKeys.AuthenticateAPIKey(r.Context(), token)
+				if err != nil {
+					if errors.Is(err, apikey.ErrInvalid) {
+						http.Error(w, "401 Unauthorized.", http.StatusUnauthorized)
+						return
+					}
+					http.Error(w, "500 Internal Server Error.", http.StatusInternalServerError)
+					return
+				}
+
+				ctx := context.WithValue(r.Context(), UserIDContextKey, userID)
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return
+			}
+
+			token, userID, current, err := getCookie(r, sessions)
 			if err != nil && !errors.Is(err, http.ErrNoCookie) {
 				http.Error(w, "500 Internal Server Error.", http.StatusInternalServerError)
 				return
@@ -26,50 +71,87 @@ func Auth(enc *encryption.Encryption) func(next http.Handler) http.Handler {
 
 			if userID == "" {
 				userID = uuid.New().String()
-				err = addCookie(userID, w, enc)
+			}
+
+			// A new user or a cookie issued with an old key or close to the expiry gets a fresh cookie.
+			if !current {
+				token, err = sessions.Issue(userID)
 				if err != nil {
 					http.Error(w, "500 Internal Server Error.", http.StatusInternalServerError)
 					return
 				}
+				SetCookie(w, token)
+			}
+
+			ctx, err := withScopes(r.Context(), sessions, token)
+			if err != nil {
+				http.Error(w, "500 Internal Server Error.", http.StatusInternalServerError)
+				return
 			}
 
-			ctx := context.WithValue(r.Context(), UserIDContextKey, userID)
+			ctx = context.WithValue(ctx, UserIDContextKey, userID)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
 
-func addCookie(userID string, w http.ResponseWriter, enc *encryption.Encryption) error {
-	encrypted, err := enc.Encrypt(userID)
+// RequireScope lets through the requests granted the scope, the others get 403 Forbidden.
+// It must run after Auth to see the scopes.
+func RequireScope(scope string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if scopes, ok := r.Context().Value(ScopesContextKey).([]string); ok && !session.HasScope(scopes, scope) {
+				http.Error(w, "403 Forbidden.", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// withScopes puts the scopes granted by the session token into the context, the tokens of the other codecs
+// are not limited.
+func withScopes(ctx context.Context, sessions session.Codec, token string) (context.Context, error) {
+	scoped, ok := sessions.(session.Scoped)
+	if !ok {
+		return ctx, nil
+	}
+
+	scopes, err := scoped.ParseScopes(token)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	return context.WithValue(ctx, ScopesContextKey, scopes), nil
+}
 
+// SetCookie sends the session token in the cookie.
+func SetCookie(w http.ResponseWriter, token string) {
 	http.SetCookie(w, &http.Cookie{
 		Name:     string(UserIDContextKey),
-		Value:    encrypted,
+		Value:    token,
 		Path:     "/",
 		Expires:  time.Now().Add(365 * 24 * time.Hour),
 		HttpOnly: true,
 	})
-
-	return nil
 }
 
-func getCookie(r *http.Request, enc *encryption.Encryption) (string, error) {
+// getCookie returns the session token, the user ID and whether the cookie is up to date.
+// A cookie which can not be read, e.g. made with a removed key or expired, is treated as missing.
+func getCookie(r *http.Request, sessions session.Codec) (string, string, bool, error) {
 	userCookie, err := r.Cookie(string(UserIDContextKey))
 	if err != nil {
-		return "", err
+		return "", "", false, err
 	}
 
 	if userCookie.Value == "" {
-		return "", nil
+		return "", "", false, nil
 	}
 
-	userID, err := enc.Decrypt(userCookie.Value)
+	userID, current, err := sessions.Parse(userCookie.Value)
 	if err != nil {
-		return "", err
+		return "", "", false, nil //nolint:nilerr // the user gets a new cookie
 	}
 
-	return userID, nil
+	return userCookie.Value, userID, current, nil
 }
@@ -0,0 +1,320 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alrund/yp-1/internal/app/apikey"
+	"github.com/alrund/yp-1/internal/app/encryption"
+	"github.com/alrund/yp-1/internal/app/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuth(t *testing.T) {
+	const userID = "user-1"
+
+	enc := encryption.NewEncryption("NEWPASS", "OLDPASS")
+	current, err := enc.Encrypt(userID)
+	require.NoError(t, err)
+	old, err := encryption.NewEncryption("OLDPASS").Encrypt(userID)
+	require.NoError(t, err)
+	removed, err := encryption.NewEncryption("REMOVED").Encrypt(userID)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name          string
+		cookie        string
+		wantUserID    string
+		wantNewCookie bool
+	}{
+		{
+			name:          "new user",
+			wantNewCookie: true,
+		},
+		{
+			name:       "current key",
+			cookie:     current,
+			wantUserID: userID,
+		},
+		{
+			name:          "old key is rotated",
+			cookie:        old,
+			wantUserID:    userID,
+			wantNewCookie: true,
+		},
+		{
+			name:          "removed key",
+			cookie:        removed,
+			wantNewCookie: true,
+		},
+		{
+			name:          "garbage",
+			cookie:        "garbage",
+			wantNewCookie: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotUserID string
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotUserID, _ = r.Context().Value(UserIDContextKey).(string)
+			})
+
+			request := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.cookie != "" {
+				request.AddCookie(&http.Cookie{Name: string(UserIDContextKey), Value: tt.cookie})
+			}
+			w := httptest.NewRecorder()
+			Auth(session.NewEncrypted(enc), nil)(next).ServeHTTP(w, request)
+
+			res := w.Result()
+			defer res.Body.Close()
+
+			require.Equal(t, http.StatusOK, res.StatusCode)
+			require.NotEmpty(t, gotUserID)
+			if tt.wantUserID != "" {
+				assert.Equal(t, tt.wantUserID, gotUserID)
+			} else {
+				assert.NotEqual(t, userID, gotUserID)
+			}
+
+			cookies := res.Cookies()
+			if !tt.wantNewCookie {
+				assert.Empty(t, cookies)
+				return
+			}
+
+			require.Len(t, cookies, 1)
+			assert.True(t, enc.IsCurrent(cookies[0].Value))
+			decrypted, err := enc.Decrypt(cookies[0].Value)
+			require.NoError(t, err)
+			assert.Equal(t, gotUserID, decrypted)
+		})
+	}
+}
+
+type testAPIKeys map[string]string
+
+func (k testAPIKeys) AuthenticateAPIKey(ctx context.Context, secret string) (string, error) {
+	if secret == "sk_broken" {
+		return "", errors.New("storage is down")
+	}
+	userID, ok := k[secret]
+	if !ok {
+		return "", apikey.ErrInvalid
+	}
+	return userID, nil
+}
+
+func TestAuthAPIKey(t *testing.T) {
+	enc := encryption.NewEncryption("PASS")
+	apiKeys := testAPIKeys{"sk_valid": "key-owner"}
+
+	tests := []struct {
+		name          string
+		apiKeys       APIKeyAuthenticator
+		authorization string
+		wantCode      int
+		wantUserID    string
+	}{
+		{
+			name:          "valid key",
+			apiKeys:       apiKeys,
+			authorization: "Bearer sk_valid",
+			wantCode:      http.StatusOK,
+			wantUserID:    "key-owner",
+		},
+		{
+			name:          "invalid key",
+			apiKeys:       apiKeys,
+			authorization: "Bearer sk_revoked",
+			wantCode:      http.StatusUnauthorized,
+		},
+		{
+			name:          "storage error",
+			apiKeys:       apiKeys,
+			authorization: "Bearer sk_broken",
+			wantCode:      http.StatusInternalServerError,
+		},
+		{
+			name:          "api keys are off",
+			authorization: "Bearer sk_valid",
+			wantCode:      http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotUserID string
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotUserID, _ = r.Context().Value(UserIDContextKey).(string)
+			})
+
+			request := httptest.NewRequest(http.MethodGet, "/", nil)
+			request.Header.Set("Authorization", tt.authorization)
+			w := httptest.NewRecorder()
+			Auth(session.NewEncrypted(enc), tt.apiKeys)(next).ServeHTTP(w, request)
+
+			res := w.Result()
+			defer res.Body.Close()
+
+			assert.Equal(t, tt.wantCode, res.StatusCode)
+			if tt.wantUserID != "" {
+				assert.Equal(t, tt.wantUserID, gotUserID)
+				assert.Empty(t, res.Cookies())
+			}
+		})
+	}
+}
+
+func TestAuthJWT(t *testing.T) {
+	sessions, err := session.NewHS256([]byte("0123456789abcdef0123456789abcdef"), time.Hour)
+	require.NoError(t, err)
+	token, err := sessions.Issue("user-1")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name          string
+		cookie        string
+		authorization string
+		wantCode      int
+		wantUserID    string
+		wantNewCookie bool
+	}{
+		{
+			name:          "new user",
+			wantCode:      http.StatusOK,
+			wantNewCookie: true,
+		},
+		{
+			name:       "cookie",
+			cookie:     token,
+			wantCode:   http.StatusOK,
+			wantUserID: "user-1",
+		},
+		{
+			name:          "bearer token",
+			authorization: "Bearer " + token,
+			wantCode:      http.StatusOK,
+			wantUserID:    "user-1",
+		},
+		{
+			name:          "invalid bearer token",
+			authorization: "Bearer " + token + "x",
+			wantCode:      http.StatusUnauthorized,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotUserID string
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotUserID, _ = r.Context().Value(UserIDContextKey).(string)
+			})
+
+			request := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.cookie != "" {
+				request.AddCookie(&http.Cookie{Name: string(UserIDContextKey), Value: tt.cookie})
+			}
+			if tt.authorization != "" {
+				request.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+			Auth(sessions, nil)(next).ServeHTTP(w, request)
+
+			res := w.Result()
+			defer res.Body.Close()
+
+			require.Equal(t, tt.wantCode, res.StatusCode)
+			if tt.wantUserID != "" {
+				assert.Equal(t, tt.wantUserID, gotUserID)
+			}
+			assert.Equal(t, tt.wantNewCookie, len(res.Cookies()) > 0)
+			if tt.wantNewCookie {
+				userID, current, err := sessions.Parse(res.Cookies()[0].Value)
+				require.NoError(t, err)
+				assert.True(t, current)
+				assert.Equal(t, gotUserID, userID)
+			}
+		})
+	}
+}
+
+func TestRequireScope(t *testing.T) {
+	jwt, err := session.NewHS256([]byte("0123456789abcdef0123456789abcdef"), time.Hour)
+	require.NoError(t, err)
+	jwt.Scopes = []string{session.ScopeURLs}
+	token, err := jwt.Issue("user-1")
+	require.NoError(t, err)
+
+	encrypted := session.NewEncrypted(encryption.NewEncryption("J53RPX6"))
+	encryptedToken, err := encrypted.Issue("user-1")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name          string
+		sessions      session.Codec
+		cookie        string
+		authorization string
+		scope         string
+		wantCode      int
+	}{
+		{
+			name:          "bearer token with the scope",
+			sessions:      jwt,
+			authorization: "Bearer " + token,
+			scope:         session.ScopeURLs,
+			wantCode:      http.StatusOK,
+		},
+		{
+			name:          "bearer token without the scope",
+			sessions:      jwt,
+			authorization: "Bearer " + token,
+			scope:         session.ScopeKeys,
+			wantCode:      http.StatusForbidden,
+		},
+		{
+			name:     "cookie without the scope",
+			sessions: jwt,
+			cookie:   token,
+			scope:    session.ScopeWorkspaces,
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:     "new user gets the configured scopes",
+			sessions: jwt,
+			scope:    session.ScopeKeys,
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:     "encrypted cookie is not scoped",
+			sessions: encrypted,
+			cookie:   encryptedToken,
+			scope:    session.ScopeKeys,
+			wantCode: http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+			request := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.cookie != "" {
+				request.AddCookie(&http.Cookie{Name: string(UserIDContextKey), Value: tt.cookie})
+			}
+			if tt.authorization != "" {
+				request.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+			Auth(tt.sessions, nil)(RequireScope(tt.scope)(next)).ServeHTTP(w, request)
+
+			res := w.Result()
+			defer res.Body.Close()
+
+			assert.Equal(t, tt.wantCode, res.StatusCode)
+		})
+	}
+}
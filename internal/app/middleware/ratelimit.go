@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/alrund/yp-1/internal/app/ratelimit"
+)
+
+// RateLimit limits the requests of the route class by the user ID and by the client IP.
+// A rejected request gets 429 with the Retry-After header. A nil limiter lets everything pass.
+func RateLimit(limiter *ratelimit.Limiter, class ratelimit.Class) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if limiter == nil {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			err := limiter.Allow(class, rateLimitKeys(r)...)
+			if err != nil {
+				if errors.Is(err, ratelimit.ErrExceeded) {
+					WriteRateLimited(w, err)
+					return
+				}
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// WriteRateLimited writes 429 with the Retry-After header of the *ratelimit.ExceededError.
+func WriteRateLimited(w http.ResponseWriter, err error) {
+	w.Header().Set("Retry-After", strconv.Itoa(ratelimit.RetryAfter(err)))
+	http.Error(w, "429 Too Many Requests.", http.StatusTooManyRequests)
+}
+
+func rateLimitKeys(r *http.Request) []string {
+	keys := make([]string, 0, 2)
+	if userID, ok := r.Context().Value(UserIDContextKey).(string); ok && userID != "" {
+		keys = append(keys, "user:"+userID)
+	}
+	if ip := remoteIP(r); ip != nil {
+		keys = append(keys, "ip:"+ip.String())
+	}
+	return keys
+}
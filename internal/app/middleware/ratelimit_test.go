@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alrund/yp-1/internal/app/ratelimit"
+	"github.com/alrund/yp-1/internal/app/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimit(t *testing.T) {
+	limiter := ratelimit.New(storage.NewMap(), map[ratelimit.Class]ratelimit.Limit{
+		ratelimit.ClassCreate: {Rate: 0.1, Burst: 1},
+	}, 0)
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	serve := func(h http.Handler, userID, remoteAddr string) *http.Response {
+		request := httptest.NewRequest(http.MethodPost, "/api/shorten", nil)
+		request.RemoteAddr = remoteAddr
+		request = request.WithContext(context.WithValue(request.Context(), UserIDContextKey, userID))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, request)
+		res := w.Result()
+		defer res.Body.Close()
+		return res
+	}
+
+	h := RateLimit(limiter, ratelimit.ClassCreate)(next)
+	assert.Equal(t, http.StatusOK, serve(h, "user-1", "10.0.0.1:1234").StatusCode)
+
+	res := serve(h, "user-1", "10.0.0.2:1234")
+	assert.Equal(t, http.StatusTooManyRequests, res.StatusCode, "limited by the user ID")
+	assert.Equal(t, "10", res.Header.Get("Retry-After"))
+
+	res = serve(h, "user-2", "10.0.0.1:1234")
+	assert.Equal(t, http.StatusTooManyRequests, res.StatusCode, "limited by the IP")
+
+	assert.Equal(t, http.StatusOK, serve(h, "user-2", "10.0.0.2:1234").StatusCode)
+
+	unlimited := RateLimit(limiter, ratelimit.ClassRedirect)(next)
+	assert.Equal(t, http.StatusOK, serve(unlimited, "user-1", "10.0.0.1:1234").StatusCode)
+	assert.Equal(t, http.StatusOK, serve(RateLimit(nil, ratelimit.ClassCreate)(next), "user-1", "10.0.0.1:1234").StatusCode)
+}
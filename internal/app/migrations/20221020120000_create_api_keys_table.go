@@ -0,0 +1,37 @@
+package migrations
+
+import "database/sql"
+
+func UpAPIKeys(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS api_keys
+		(
+			id UUID NOT NULL PRIMARY KEY,
+			user_id UUID NOT NULL,
+			name VARCHAR(255) NOT NULL,
+			key_hash CHAR(64) NOT NULL CONSTRAINT api_keys_key_hash_uindex UNIQUE,
+			hint VARCHAR(16) NOT NULL,
+			created BIGINT NOT NULL,
+			revoked BOOLEAN NOT NULL DEFAULT false
+		);`,
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`CREATE INDEX IF NOT EXISTS api_keys_user_id_index ON api_keys (user_id);`)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func DownAPIKeys(tx *sql.Tx) error {
+	_, err := tx.Exec("DROP TABLE IF EXISTS api_keys;")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
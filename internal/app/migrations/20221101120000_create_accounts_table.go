@@ -0,0 +1,29 @@
+package migrations
+
+import "database/sql"
+
+func UpAccounts(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS accounts
+		(
+			id UUID NOT NULL PRIMARY KEY,
+			login VARCHAR(255) NOT NULL CONSTRAINT accounts_login_uindex UNIQUE,
+			password_hash VARCHAR(255) NOT NULL,
+			created BIGINT NOT NULL
+		);`,
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func DownAccounts(tx *sql.Tx) error {
+	_, err := tx.Exec("DROP TABLE IF EXISTS accounts;")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
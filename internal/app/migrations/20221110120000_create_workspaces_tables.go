@@ -0,0 +1,51 @@
+package migrations
+
+import "database/sql"
+
+func UpWorkspaces(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS workspaces
+		(
+			id UUID NOT NULL PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			created BIGINT NOT NULL
+		);`,
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		CREATE TABLE IF NOT EXISTS workspace_members
+		(
+			workspace_id UUID NOT NULL REFERENCES workspaces (id) ON DELETE CASCADE,
+			user_id UUID NOT NULL,
+			role VARCHAR(16) NOT NULL,
+			PRIMARY KEY (workspace_id, user_id)
+		);`,
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`CREATE INDEX IF NOT EXISTS workspace_members_user_id_index ON workspace_members (user_id);`)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func DownWorkspaces(tx *sql.Tx) error {
+	_, err := tx.Exec("DROP TABLE IF EXISTS workspace_members;")
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("DROP TABLE IF EXISTS workspaces;")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
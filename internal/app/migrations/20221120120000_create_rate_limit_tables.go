@@ -0,0 +1,46 @@
+package migrations
+
+import "database/sql"
+
+func UpRateLimits(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS rate_buckets
+		(
+			key VARCHAR(255) NOT NULL PRIMARY KEY,
+			tokens DOUBLE PRECISION NOT NULL,
+			updated BIGINT NOT NULL
+		);`,
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		CREATE TABLE IF NOT EXISTS quota_usage
+		(
+			key VARCHAR(255) NOT NULL,
+			day DATE NOT NULL,
+			used INT NOT NULL,
+			PRIMARY KEY (key, day)
+		);`,
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func DownRateLimits(tx *sql.Tx) error {
+	_, err := tx.Exec("DROP TABLE IF EXISTS quota_usage;")
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("DROP TABLE IF EXISTS rate_buckets;")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,42 @@
+package migrations
+
+import "database/sql"
+
+func UpAuditEvents(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS audit_events
+		(
+			id BIGSERIAL NOT NULL PRIMARY KEY,
+			created BIGINT NOT NULL,
+			action VARCHAR(16) NOT NULL,
+			token VARCHAR(255) NOT NULL,
+			url TEXT NOT NULL,
+			owner_id VARCHAR(255) NOT NULL,
+			actor_id VARCHAR(255) NOT NULL,
+			source VARCHAR(16) NOT NULL,
+			client_ip VARCHAR(64) NOT NULL,
+			request_id VARCHAR(255) NOT NULL
+		);`,
+	)
+	if err != nil {
+		return err
+	}
+
+	for _, column := range []string{"token", "owner_id", "actor_id"} {
+		_, err = tx.Exec("CREATE INDEX IF NOT EXISTS audit_events_" + column + "_index ON audit_events (" + column + ");")
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func DownAuditEvents(tx *sql.Tx) error {
+	_, err := tx.Exec("DROP TABLE IF EXISTS audit_events;")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,44 @@
+package migrations
+
+import "database/sql"
+
+func UpListingColumns(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		ALTER TABLE tokens
+			ADD IF NOT EXISTS created BIGINT DEFAULT 0 NOT NULL,
+			ADD IF NOT EXISTS clicks BIGINT DEFAULT 0 NOT NULL;`,
+	)
+	if err != nil {
+		return err
+	}
+
+	indexes := map[string]string{
+		"urls_user_id_index":   "urls (user_id)",
+		"tokens_created_index": "tokens (created, token)",
+		"tokens_clicks_index":  "tokens (clicks, token)",
+	}
+	for name, columns := range indexes {
+		_, err = tx.Exec("CREATE INDEX IF NOT EXISTS " + name + " ON " + columns + ";")
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func DownListingColumns(tx *sql.Tx) error {
+	for _, name := range []string{"urls_user_id_index", "tokens_created_index", "tokens_clicks_index"} {
+		_, err := tx.Exec("DROP INDEX IF EXISTS " + name + ";")
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err := tx.Exec("ALTER TABLE tokens DROP COLUMN IF EXISTS created, DROP COLUMN IF EXISTS clicks;")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,45 @@
+package migrations
+
+import "database/sql"
+
+func UpLinkMeta(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS link_meta
+		(
+			token VARCHAR(255) NOT NULL PRIMARY KEY,
+			title VARCHAR(255) NOT NULL,
+			notes TEXT NOT NULL
+		);`,
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		CREATE TABLE IF NOT EXISTS link_tags
+		(
+			token VARCHAR(255) NOT NULL,
+			tag VARCHAR(64) NOT NULL,
+			PRIMARY KEY (token, tag)
+		);`,
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("CREATE INDEX IF NOT EXISTS link_tags_tag_index ON link_tags (tag);")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func DownLinkMeta(tx *sql.Tx) error {
+	_, err := tx.Exec("DROP TABLE IF EXISTS link_tags, link_meta;")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,128 @@
+package migrations
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// linkSearchDocument builds the search vector of the link u with the meta m.
+// The weights follow the search package: the short code, the tags, the title and the URL.
+// Everything but letters and digits splits the words, as the in-memory index does.
+const linkSearchDocument = `
+	setweight(to_tsvector('simple', u.token), 'A') ||
+	setweight(to_tsvector('simple', regexp_replace(
+		COALESCE((SELECT string_agg(lt.tag, ' ') FROM link_tags lt WHERE lt.token = u.token), ''),
+		'[^[:alnum:]]+', ' ', 'g'
+	)), 'B') ||
+	setweight(to_tsvector('simple', regexp_replace(COALESCE(m.title, ''), '[^[:alnum:]]+', ' ', 'g')), 'C') ||
+	setweight(to_tsvector('simple', regexp_replace(u.url, '[^[:alnum:]]+', ' ', 'g')), 'D')`
+
+// UpLinkSearch creates the search vectors of the links, the triggers keep them in sync with the links and their meta.
+func UpLinkSearch(tx *sql.Tx) error {
+	var exists bool
+	err := tx.QueryRow("SELECT to_regclass('link_search') IS NOT NULL;").Scan(&exists)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		CREATE TABLE IF NOT EXISTS link_search
+		(
+			token VARCHAR(255) NOT NULL PRIMARY KEY,
+			document TSVECTOR NOT NULL
+		);`,
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("CREATE INDEX IF NOT EXISTS link_search_document_index ON link_search USING GIN (document);")
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		CREATE OR REPLACE FUNCTION refresh_link_search(link_token VARCHAR) RETURNS VOID AS $$
+		BEGIN
+			INSERT INTO link_search(token, document)
+			SELECT u.token, ` + linkSearchDocument + `
+			FROM urls u LEFT JOIN link_meta m ON m.token = u.token
+			WHERE u.token = link_token
+			ON CONFLICT (token) DO UPDATE SET document = EXCLUDED.document;
+		END;
+		$$ LANGUAGE plpgsql;`,
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		CREATE OR REPLACE FUNCTION link_search_trigger() RETURNS TRIGGER AS $$
+		BEGIN
+			IF TG_OP = 'DELETE' THEN
+				PERFORM refresh_link_search(OLD.token);
+				RETURN OLD;
+			END IF;
+			PERFORM refresh_link_search(NEW.token);
+			RETURN NEW;
+		END;
+		$$ LANGUAGE plpgsql;`,
+	)
+	if err != nil {
+		return err
+	}
+
+	triggers := []struct {
+		name, table, events string
+	}{
+		{"urls_search", "urls", "INSERT OR UPDATE OF url, token"},
+		{"link_meta_search", "link_meta", "INSERT OR UPDATE"},
+		{"link_tags_search", "link_tags", "INSERT OR DELETE"},
+	}
+	for _, trigger := range triggers {
+		_, err = tx.Exec(fmt.Sprintf("DROP TRIGGER IF EXISTS %s ON %s;", trigger.name, trigger.table))
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(fmt.Sprintf(
+			"CREATE TRIGGER %s AFTER %s ON %s FOR EACH ROW EXECUTE PROCEDURE link_search_trigger();",
+			trigger.name, trigger.events, trigger.table,
+		))
+		if err != nil {
+			return err
+		}
+	}
+
+	if exists {
+		return nil
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO link_search(token, document)
+		SELECT u.token, ` + linkSearchDocument + `
+		FROM urls u LEFT JOIN link_meta m ON m.token = u.token
+		ON CONFLICT (token) DO NOTHING;`,
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func DownLinkSearch(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		DROP TRIGGER IF EXISTS urls_search ON urls;
+		DROP TRIGGER IF EXISTS link_meta_search ON link_meta;
+		DROP TRIGGER IF EXISTS link_tags_search ON link_tags;
+		DROP FUNCTION IF EXISTS link_search_trigger();
+		DROP FUNCTION IF EXISTS refresh_link_search(VARCHAR);
+		DROP TABLE IF EXISTS link_search;`,
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,35 @@
+package migrations
+
+import "database/sql"
+
+func UpLinkHealth(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS link_health
+		(
+			token VARCHAR(255) NOT NULL PRIMARY KEY,
+			checked BIGINT NOT NULL,
+			status_code INTEGER NOT NULL,
+			reachable BOOLEAN NOT NULL,
+			error TEXT NOT NULL
+		);`,
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("CREATE INDEX IF NOT EXISTS link_health_checked_index ON link_health (checked);")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func DownLinkHealth(tx *sql.Tx) error {
+	_, err := tx.Exec("DROP TABLE IF EXISTS link_health;")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,27 @@
+package migrations
+
+import "database/sql"
+
+func UpLinkWebhooks(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS link_webhooks
+		(
+			user_id VARCHAR(255) NOT NULL PRIMARY KEY,
+			url TEXT NOT NULL
+		);`,
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func DownLinkWebhooks(tx *sql.Tx) error {
+	_, err := tx.Exec("DROP TABLE IF EXISTS link_webhooks;")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,49 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	"github.com/alrund/yp-1/internal/app/logging"
+)
+
+// UseCreateQuota counts the links the user creates against the daily quota.
+// The returned refund takes back the links which are not created after all, like the rejected or existing ones.
+func (us *URLShortener) UseCreateQuota(userID string, n int) (refund func(n int), err error) {
+	if us.RateLimiter == nil {
+		return func(int) {}, nil
+	}
+
+	refundQuota, err := us.RateLimiter.UseQuota(userID, n)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(n int) {
+		if err := refundQuota(n); err != nil {
+			logging.Errorf("Quota refund: %v", err)
+		}
+	}, nil
+}
+
+// WatchRateLimits prunes the full buckets and the past quota usage every interval till the context is done.
+func (us *URLShortener) WatchRateLimits(ctx context.Context, interval time.Duration) {
+	if us.RateLimiter == nil {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := us.RateLimiter.Prune(); err != nil {
+			logging.Errorf("Rate limit pruning: %v", err)
+		}
+	}
+}
@@ -0,0 +1,335 @@
+package ratelimit
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Class a group of routes which share a limit.
+type Class string
+
+const (
+	ClassCreate   Class = "create"
+	ClassRedirect Class = "redirect"
+	ClassList     Class = "list"
+	ClassDelete   Class = "delete"
+)
+
+// dayLayout the format of the quota days.
+const dayLayout = "2006-01-02"
+
+// Classes every route class in the order of the docs.
+var Classes = []Class{ClassCreate, ClassRedirect, ClassList, ClassDelete}
+
+var (
+	ErrExceeded     = errors.New("rate limit exceeded")
+	ErrUnknownClass = errors.New("unknown route class")
+	ErrInvalidLimit = errors.New("invalid rate limit")
+)
+
+// ExceededError a rejected request, it can be retried after RetryAfter.
+type ExceededError struct {
+	RetryAfter time.Duration
+}
+
+func (e *ExceededError) Error() string {
+	return fmt.Sprintf("%s, retry after %s", ErrExceeded, e.RetryAfter)
+}
+
+func (e *ExceededError) Is(target error) bool {
+	return target == ErrExceeded
+}
+
+// RetryAfter returns the whole seconds to wait, for the Retry-After header.
+func RetryAfter(err error) int {
+	var exceeded *ExceededError
+	if !errors.As(err, &exceeded) {
+		return 0
+	}
+	return int(math.Ceil(exceeded.RetryAfter.Seconds()))
+}
+
+// Limit a token bucket which refills Rate tokens per second up to Burst.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// ParseLimit parses a limit like "10/s 20": 10 requests per second with the burst of 20.
+// The unit is s, m or h, the burst defaults to the request count.
+func ParseLimit(s string) (Limit, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 || len(fields) > 2 {
+		return Limit{}, fmt.Errorf("%w %q, expected like \"10/s 20\"", ErrInvalidLimit, s)
+	}
+
+	parts := strings.SplitN(fields[0], "/", 2)
+	count, err := strconv.Atoi(parts[0])
+	if err != nil || count < 1 || len(parts) != 2 {
+		return Limit{}, fmt.Errorf("%w %q, expected like \"10/s 20\"", ErrInvalidLimit, s)
+	}
+
+	var per time.Duration
+	switch parts[1] {
+	case "s":
+		per = time.Second
+	case "m":
+		per = time.Minute
+	case "h":
+		per = time.Hour
+	default:
+		return Limit{}, fmt.Errorf("%w %q, the unit is s, m or h", ErrInvalidLimit, s)
+	}
+
+	limit := Limit{Rate: float64(count) / per.Seconds(), Burst: count}
+	if len(fields) == 2 {
+		limit.Burst, err = strconv.Atoi(fields[1])
+		if err != nil || limit.Burst < 1 {
+			return Limit{}, fmt.Errorf("%w %q, the burst must be positive", ErrInvalidLimit, s)
+		}
+	}
+
+	return limit, nil
+}
+
+// ParseLimits parses the limits of the route classes.
+func ParseLimits(limits map[string]string) (map[Class]Limit, error) {
+	parsed := make(map[Class]Limit, len(limits))
+	for name, s := range limits {
+		class := Class(name)
+		if !class.valid() {
+			return nil, fmt.Errorf("%w %q", ErrUnknownClass, name)
+		}
+		limit, err := ParseLimit(s)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		parsed[class] = limit
+	}
+
+	return parsed, nil
+}
+
+func (c Class) valid() bool {
+	for _, class := range Classes {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+// Bucket the state of a token bucket, the zero bucket is full.
+type Bucket struct {
+	Tokens  float64
+	Updated time.Time
+}
+
+// Store keeps the buckets and the quota usage.
+type Store interface {
+	// GetRateBucket returns the bucket, the zero bucket for an unknown key.
+	GetRateBucket(key string) (Bucket, error)
+	SetRateBucket(key string, b Bucket) error
+	// AddQuotaUsage adds n to the usage of the key on the day and returns the new usage.
+	// The usage of the other days may be dropped.
+	AddQuotaUsage(key, day string, n int) (int, error)
+	// PruneRateLimits drops the buckets not updated since updatedBefore and the usage of the days before day.
+	PruneRateLimits(updatedBefore time.Time, day string) error
+}
+
+// keyLocks the number of the locks the keys are spread over.
+const keyLocks = 64
+
+// Limiter limits the requests of each route class by keys like the user ID and the client IP.
+// The read-modify-write of a bucket is serialized per key within the process only,
+// so instances sharing a store may let a few extra requests through.
+type Limiter struct {
+	store      Store
+	limits     map[Class]Limit
+	dailyQuota int
+	now        func() time.Time
+	mx         sync.RWMutex // guards the limits and the quota
+	keyMx      [keyLocks]sync.Mutex
+}
+
+// New creates a limiter, a class without a limit and a zero quota are unlimited.
+func New(store Store, limits map[Class]Limit, dailyQuota int) *Limiter {
+	return &Limiter{
+		store:      store,
+		limits:     limits,
+		dailyQuota: dailyQuota,
+		now:        time.Now,
+	}
+}
+
+// SetLimits replaces the limits and the quota, e.g. on a config reload.
+func (l *Limiter) SetLimits(limits map[Class]Limit, dailyQuota int) {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+	l.limits = limits
+	l.dailyQuota = dailyQuota
+}
+
+// Allow takes a token from the bucket of each key or returns an *ExceededError taking none.
+func (l *Limiter) Allow(class Class, keys ...string) error {
+	l.mx.RLock()
+	limit, ok := l.limits[class]
+	l.mx.RUnlock()
+	if !ok {
+		return nil
+	}
+
+	bucketKeys := make([]string, len(keys))
+	for i, key := range keys {
+		bucketKeys[i] = bucketKey(class, key)
+	}
+	defer l.lockKeys(bucketKeys)()
+
+	now := l.now()
+	buckets := make([]Bucket, len(keys))
+	var retryAfter time.Duration
+	for i, key := range bucketKeys {
+		b, err := l.store.GetRateBucket(key)
+		if err != nil {
+			return err
+		}
+		b = refill(b, limit, now)
+		if b.Tokens < 1 {
+			wait := time.Duration((1 - b.Tokens) / limit.Rate * float64(time.Second))
+			if wait > retryAfter {
+				retryAfter = wait
+			}
+		}
+		buckets[i] = b
+	}
+	if retryAfter > 0 {
+		return &ExceededError{RetryAfter: retryAfter}
+	}
+
+	for i, key := range bucketKeys {
+		buckets[i].Tokens--
+		if err := l.store.SetRateBucket(key, buckets[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// UseQuota counts n created links of the key against the daily quota
+// and returns the refund, which takes back the links not created after all.
+// Over the quota nothing is counted and an *ExceededError lasting till the next UTC day is returned.
+func (l *Limiter) UseQuota(key string, n int) (refund func(n int) error, err error) {
+	l.mx.RLock()
+	dailyQuota := l.dailyQuota
+	l.mx.RUnlock()
+	if dailyQuota <= 0 || n <= 0 {
+		return noRefund, nil
+	}
+
+	quotaKey := "quota:" + key
+	defer l.lockKeys([]string{quotaKey})()
+
+	now := l.now().UTC()
+	day := now.Format(dayLayout)
+	used, err := l.store.AddQuotaUsage(key, day, n)
+	if err != nil {
+		return nil, err
+	}
+	if used <= dailyQuota {
+		charged := n
+		return func(n int) error {
+			if n > charged {
+				n = charged
+			}
+			// The usage of a past day is not kept, nothing is left to refund.
+			if n <= 0 || l.now().UTC().Format(dayLayout) != day {
+				return nil
+			}
+			charged -= n
+
+			defer l.lockKeys([]string{quotaKey})()
+			_, err := l.store.AddQuotaUsage(key, day, -n)
+			return err
+		}, nil
+	}
+
+	if _, err := l.store.AddQuotaUsage(key, day, -n); err != nil {
+		return nil, err
+	}
+	tomorrow := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+	return nil, &ExceededError{RetryAfter: tomorrow.Sub(now)}
+}
+
+func noRefund(int) error {
+	return nil
+}
+
+// Prune drops the buckets which are full again and the quota usage of the past days,
+// so the store does not grow with every user and IP ever seen.
+// A full bucket is the same as a missing one, and a bucket refills in Burst/Rate seconds at most.
+func (l *Limiter) Prune() error {
+	l.mx.RLock()
+	var fullAfter time.Duration
+	for _, limit := range l.limits {
+		if d := time.Duration(float64(limit.Burst) / limit.Rate * float64(time.Second)); d > fullAfter {
+			fullAfter = d
+		}
+	}
+	now := l.now()
+	l.mx.RUnlock()
+
+	return l.store.PruneRateLimits(now.Add(-fullAfter), now.UTC().Format(dayLayout))
+}
+
+// lockKeys locks the keys and returns the unlock.
+// The locks are taken in their order, so the requests sharing some keys can not deadlock.
+func (l *Limiter) lockKeys(keys []string) func() {
+	locks := make([]int, 0, len(keys))
+	for _, key := range keys {
+		h := fnv.New32a()
+		_, _ = h.Write([]byte(key))
+		locks = append(locks, int(h.Sum32()%keyLocks))
+	}
+	sort.Ints(locks)
+
+	taken := locks[:0]
+	for _, lock := range locks {
+		if len(taken) > 0 && lock == taken[len(taken)-1] {
+			continue
+		}
+		l.keyMx[lock].Lock()
+		taken = append(taken, lock)
+	}
+
+	return func() {
+		for _, lock := range taken {
+			l.keyMx[lock].Unlock()
+		}
+	}
+}
+
+func refill(b Bucket, limit Limit, now time.Time) Bucket {
+	burst := float64(limit.Burst)
+	if b.Updated.IsZero() {
+		return Bucket{Tokens: burst, Updated: now}
+	}
+
+	if elapsed := now.Sub(b.Updated); elapsed > 0 {
+		b.Tokens = math.Min(burst, b.Tokens+elapsed.Seconds()*limit.Rate)
+		b.Updated = now
+	}
+
+	return b
+}
+
+func bucketKey(class Class, key string) string {
+	return string(class) + ":" + key
+}
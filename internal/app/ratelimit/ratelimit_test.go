@@ -0,0 +1,235 @@
+package ratelimit
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testStore struct {
+	buckets map[string]Bucket
+	usage   map[string]int
+	pruned  []string
+	mx      sync.Mutex
+}
+
+func newTestStore() *testStore {
+	return &testStore{buckets: make(map[string]Bucket), usage: make(map[string]int)}
+}
+
+func (s *testStore) GetRateBucket(key string) (Bucket, error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	return s.buckets[key], nil
+}
+
+func (s *testStore) SetRateBucket(key string, b Bucket) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	s.buckets[key] = b
+	return nil
+}
+
+func (s *testStore) AddQuotaUsage(key, day string, n int) (int, error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	s.usage[day+":"+key] += n
+	return s.usage[day+":"+key], nil
+}
+
+func (s *testStore) PruneRateLimits(updatedBefore time.Time, day string) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	for key, b := range s.buckets {
+		if b.Updated.Before(updatedBefore) {
+			delete(s.buckets, key)
+			s.pruned = append(s.pruned, key)
+		}
+	}
+	for key := range s.usage {
+		if key[:len(day)] < day {
+			delete(s.usage, key)
+			s.pruned = append(s.pruned, key)
+		}
+	}
+	return nil
+}
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    Limit
+		wantErr bool
+	}{
+		{value: "10/s", want: Limit{Rate: 10, Burst: 10}},
+		{value: "60/m 5", want: Limit{Rate: 1, Burst: 5}},
+		{value: "3600/h", want: Limit{Rate: 1, Burst: 3600}},
+		{value: "", wantErr: true},
+		{value: "10", wantErr: true},
+		{value: "0/s", wantErr: true},
+		{value: "10/d", wantErr: true},
+		{value: "10/s 0", wantErr: true},
+		{value: "10/s 5 5", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseLimit(tt.value)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidLimit)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	_, err := ParseLimits(map[string]string{"upload": "10/s"})
+	assert.ErrorIs(t, err, ErrUnknownClass)
+}
+
+func TestLimiterAllow(t *testing.T) {
+	now := time.Date(2022, 11, 20, 12, 0, 0, 0, time.UTC)
+	l := New(newTestStore(), map[Class]Limit{ClassCreate: {Rate: 1, Burst: 2}}, 0)
+	l.now = func() time.Time { return now }
+
+	assert.NoError(t, l.Allow(ClassRedirect, "user:1"), "a class without a limit is unlimited")
+
+	require.NoError(t, l.Allow(ClassCreate, "user:1", "ip:1"))
+	require.NoError(t, l.Allow(ClassCreate, "user:1", "ip:1"))
+
+	err := l.Allow(ClassCreate, "user:1", "ip:1")
+	require.ErrorIs(t, err, ErrExceeded)
+	assert.Equal(t, 1, RetryAfter(err))
+
+	// Another user behind the same IP is limited too, and takes no token of its own.
+	assert.ErrorIs(t, l.Allow(ClassCreate, "user:2", "ip:1"), ErrExceeded)
+	require.NoError(t, l.Allow(ClassCreate, "user:2", "ip:2"))
+	require.NoError(t, l.Allow(ClassCreate, "user:2", "ip:2"))
+
+	now = now.Add(time.Second)
+	assert.NoError(t, l.Allow(ClassCreate, "user:1", "ip:1"))
+	assert.ErrorIs(t, l.Allow(ClassCreate, "user:1", "ip:1"), ErrExceeded)
+
+	l.SetLimits(nil, 0)
+	assert.NoError(t, l.Allow(ClassCreate, "user:1", "ip:1"))
+}
+
+func TestLimiterAllowConcurrent(t *testing.T) {
+	l := New(newTestStore(), map[Class]Limit{ClassCreate: {Rate: 0.001, Burst: 10}}, 0)
+
+	var (
+		wg    sync.WaitGroup
+		mx    sync.Mutex
+		count int
+	)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if l.Allow(ClassCreate, "user:1", "ip:"+strconv.Itoa(i%3)) == nil {
+				mx.Lock()
+				count++
+				mx.Unlock()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, 10, count, "the shared key lets the burst through only")
+}
+
+func TestLimiterUseQuota(t *testing.T) {
+	now := time.Date(2022, 11, 20, 23, 0, 0, 0, time.UTC)
+	l := New(newTestStore(), nil, 3)
+	l.now = func() time.Time { return now }
+
+	_, err := l.UseQuota("user:1", 2)
+	require.NoError(t, err)
+
+	_, err = l.UseQuota("user:1", 2)
+	require.ErrorIs(t, err, ErrExceeded)
+	assert.Equal(t, int(time.Hour.Seconds()), RetryAfter(err))
+
+	_, err = l.UseQuota("user:1", 1)
+	require.NoError(t, err, "the rejected links are not counted")
+	_, err = l.UseQuota("user:1", 1)
+	assert.ErrorIs(t, err, ErrExceeded)
+	_, err = l.UseQuota("user:2", 3)
+	require.NoError(t, err)
+
+	now = now.Add(time.Hour)
+	_, err = l.UseQuota("user:1", 3)
+	assert.NoError(t, err)
+}
+
+func TestLimiterRefundQuota(t *testing.T) {
+	now := time.Date(2022, 11, 20, 12, 0, 0, 0, time.UTC)
+	l := New(newTestStore(), nil, 3)
+	l.now = func() time.Time { return now }
+
+	refund, err := l.UseQuota("user:1", 3)
+	require.NoError(t, err)
+	require.NoError(t, refund(2))
+	require.NoError(t, refund(5), "no more than the charged links are refunded")
+
+	_, err = l.UseQuota("user:1", 3)
+	require.NoError(t, err)
+	_, err = l.UseQuota("user:1", 1)
+	assert.ErrorIs(t, err, ErrExceeded)
+
+	refund, err = New(newTestStore(), nil, 0).UseQuota("user:1", 1)
+	require.NoError(t, err)
+	assert.NoError(t, refund(1), "nothing to refund without a quota")
+}
+
+func TestLimiterRefundQuotaNextDay(t *testing.T) {
+	now := time.Date(2022, 11, 20, 23, 59, 0, 0, time.UTC)
+	store := newTestStore()
+	l := New(store, nil, 3)
+	l.now = func() time.Time { return now }
+
+	refund, err := l.UseQuota("user:1", 2)
+	require.NoError(t, err)
+
+	now = now.Add(time.Minute)
+	_, err = l.UseQuota("user:1", 1)
+	require.NoError(t, err)
+
+	require.NoError(t, refund(2))
+	assert.Equal(t, 2, store.usage["2022-11-20:user:1"], "the past day is not refunded")
+	assert.Equal(t, 1, store.usage["2022-11-21:user:1"], "the current day is not changed")
+}
+
+func TestLimiterPrune(t *testing.T) {
+	now := time.Date(2022, 11, 20, 23, 0, 0, 0, time.UTC)
+	store := newTestStore()
+	l := New(store, map[Class]Limit{ClassCreate: {Rate: 1, Burst: 2}, ClassList: {Rate: 0.5, Burst: 5}}, 3)
+	l.now = func() time.Time { return now }
+
+	require.NoError(t, l.Allow(ClassCreate, "user:1"))
+	_, err := l.UseQuota("user:1", 1)
+	require.NoError(t, err)
+	now = now.Add(5 * time.Second)
+	require.NoError(t, l.Allow(ClassCreate, "user:2"))
+
+	// The slowest class refills in 10 seconds.
+	now = now.Add(5 * time.Second)
+	require.NoError(t, l.Prune())
+	assert.Empty(t, store.pruned)
+
+	now = now.Add(time.Second)
+	require.NoError(t, l.Prune())
+	assert.Equal(t, []string{"create:user:1"}, store.pruned)
+
+	now = now.Add(time.Hour)
+	require.NoError(t, l.Prune())
+	assert.ElementsMatch(t, []string{"create:user:1", "create:user:2", "2022-11-20:user:1"}, store.pruned)
+}
@@ -0,0 +1,35 @@
+package app
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alrund/yp-1/internal/app/health"
+)
+
+// MigrationChecker is implemented by storages with a schema to migrate.
+type MigrationChecker interface {
+	CheckMigrations(ctx context.Context) error
+}
+
+// Readiness checks whether the application is able to serve requests.
+func (us *URLShortener) Readiness(ctx context.Context) *health.Report {
+	checks := map[string]health.Check{
+		"storage": us.Ping,
+	}
+
+	if mc, ok := us.Storage.(MigrationChecker); ok {
+		checks["migrations"] = mc.CheckMigrations
+	}
+
+	if us.RemoveQueue != nil {
+		checks["remove_queue"] = func(context.Context) error {
+			if us.RemoveQueue.Len() >= us.RemoveQueue.Cap() {
+				return fmt.Errorf("%w: %d tasks", ErrRemoveQueueFull, us.RemoveQueue.Len())
+			}
+			return nil
+		}
+	}
+
+	return health.Run(ctx, checks)
+}
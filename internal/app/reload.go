@@ -0,0 +1,34 @@
+package app
+
+import (
+	"github.com/alrund/yp-1/internal/app/config"
+)
+
+// ReloadConfig validates the next configuration and applies its reloadable settings.
+// It returns the names of the changed settings which require a restart.
+// On error the current configuration is kept.
+func (us *URLShortener) ReloadConfig(next *config.Config) ([]string, error) {
+	if err := next.Validate(); err != nil {
+		return nil, err
+	}
+
+	us.configMx.Lock()
+	merged, restartRequired := config.Merge(us.Config, next)
+	us.Config = merged
+	us.trustedSubnet = nil
+	listeners := us.reloadListeners
+	us.configMx.Unlock()
+
+	for _, listener := range listeners {
+		listener(merged)
+	}
+
+	return restartRequired, nil
+}
+
+// OnConfigReload registers fn to be called with the new configuration after each reload.
+func (us *URLShortener) OnConfigReload(fn func(cfg *config.Config)) {
+	us.configMx.Lock()
+	defer us.configMx.Unlock()
+	us.reloadListeners = append(us.reloadListeners, fn)
+}
@@ -0,0 +1,105 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/alrund/yp-1/internal/app/logging"
+)
+
+var (
+	ErrRemoveQueueFull   = errors.New("remove queue is full")
+	ErrRemoveQueueClosed = errors.New("remove queue is closed")
+)
+
+type removeTask struct {
+	ctx         context.Context
+	tokenValues []string
+	userID      string
+}
+
+// RemoveQueue removes user tokens in the background with a fixed number of workers.
+type RemoveQueue struct {
+	tasks   chan removeTask
+	workers int
+	wg      sync.WaitGroup
+	closed  bool
+	mx      sync.RWMutex
+}
+
+func NewRemoveQueue(size, workers int) *RemoveQueue {
+	if workers < 1 {
+		workers = 1
+	}
+	return &RemoveQueue{
+		tasks:   make(chan removeTask, size),
+		workers: workers,
+	}
+}
+
+// Start starts the workers which remove tokens through us.
+func (q *RemoveQueue) Start(us *URLShortener) {
+	q.wg.Add(q.workers)
+	for i := 0; i < q.workers; i++ {
+		go func() {
+			defer q.wg.Done()
+			for task := range q.tasks {
+				if err := us.RemoveTokens(task.ctx, task.tokenValues, task.userID); err != nil {
+					logging.Errorf("Remove tokens: %v", err)
+				}
+			}
+		}()
+	}
+}
+
+// Push queues the tokens for removal without blocking.
+func (q *RemoveQueue) Push(ctx context.Context, tokenValues []string, userID string) error {
+	q.mx.RLock()
+	defer q.mx.RUnlock()
+
+	if q.closed {
+		return ErrRemoveQueueClosed
+	}
+
+	select {
+	case q.tasks <- removeTask{ctx: ctx, tokenValues: tokenValues, userID: userID}:
+		return nil
+	default:
+		return ErrRemoveQueueFull
+	}
+}
+
+// Stop stops accepting new tasks and waits until the queued ones are done or ctx is done.
+func (q *RemoveQueue) Stop(ctx context.Context) error {
+	q.mx.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.tasks)
+	}
+	q.mx.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("%d tasks left: %w", q.Len(), ctx.Err())
+	}
+}
+
+// Len returns the number of queued tasks.
+func (q *RemoveQueue) Len() int {
+	return len(q.tasks)
+}
+
+// Cap returns the queue capacity.
+func (q *RemoveQueue) Cap() int {
+	return cap(q.tasks)
+}
@@ -0,0 +1,82 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// MaxResolveBatch the most tokens resolved at once.
+const MaxResolveBatch = 10000
+
+var (
+	ErrEmptyResolveBatch = errors.New("no tokens to resolve")
+	ErrLongResolveBatch  = errors.New("too many tokens to resolve")
+)
+
+// ResolveStatus the outcome of resolving a token.
+type ResolveStatus string
+
+const (
+	ResolveOK       ResolveStatus = "ok"
+	ResolveNotFound ResolveStatus = "not_found"
+	ResolveExpired  ResolveStatus = "expired"
+	ResolveRemoved  ResolveStatus = "removed"
+	ResolveBlocked  ResolveStatus = "blocked" // the destination is blocklisted, the URL is given for a warning
+)
+
+// Resolved a token with its original URL, the URL is empty unless the status is ok or blocked.
+type Resolved struct {
+	Token  string
+	URL    string
+	Status ResolveStatus
+}
+
+// GetBatch resolves the tokens at once, the results follow the order of the tokens.
+// Unlike Get, it does not count the clicks.
+func (us *URLShortener) GetBatch(ctx context.Context, tokenValues []string) (_ []Resolved, err error) {
+	ctx, span := startSpan(ctx, "URLShortener.GetBatch")
+	span.SetAttributes(attribute.Int("tokens.count", len(tokenValues)))
+	defer func() { endSpan(span, err) }()
+
+	if len(tokenValues) == 0 {
+		return nil, ErrEmptyResolveBatch
+	}
+	if len(tokenValues) > MaxResolveBatch {
+		return nil, fmt.Errorf("%w, the maximum is %d", ErrLongResolveBatch, MaxResolveBatch)
+	}
+
+	items, err := us.storage(ctx).GetURLsByTokens(tokenValues)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	found := make(map[string]Resolved, len(items))
+	for _, item := range items {
+		switch {
+		case item.Removed:
+			found[item.Token] = Resolved{Token: item.Token, Status: ResolveRemoved}
+		case item.Expire.Before(now):
+			found[item.Token] = Resolved{Token: item.Token, Status: ResolveExpired}
+		case us.Screener.Blocked(item.URL) != nil:
+			found[item.Token] = Resolved{Token: item.Token, URL: item.URL, Status: ResolveBlocked}
+		default:
+			found[item.Token] = Resolved{Token: item.Token, URL: item.URL, Status: ResolveOK}
+		}
+	}
+
+	resolved := make([]Resolved, 0, len(tokenValues))
+	for _, tokenValue := range tokenValues {
+		r, ok := found[tokenValue]
+		if !ok {
+			r = Resolved{Token: tokenValue, Status: ResolveNotFound}
+		}
+		resolved = append(resolved, r)
+	}
+
+	return resolved, nil
+}
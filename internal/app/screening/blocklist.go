@@ -0,0 +1,124 @@
+package screening
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Blocklist the blocked domains and URL patterns. The lists are read from files,
+// one entry a line, the empty lines and the lines starting with # are skipped.
+// A domain blocks its subdomains too, a pattern is a regular expression matched against the whole URL.
+type Blocklist struct {
+	domains  map[string]struct{}
+	patterns []*regexp.Regexp
+	mx       sync.RWMutex
+}
+
+// LoadBlocklist reads the blocklist from the files, an empty path is an empty list.
+func LoadBlocklist(domainsFile, patternsFile string) (*Blocklist, error) {
+	b := &Blocklist{}
+	if err := b.Load(domainsFile, patternsFile); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Load replaces the lists with the ones read from the files. On error the current lists are kept.
+func (b *Blocklist) Load(domainsFile, patternsFile string) error {
+	domains := make(map[string]struct{})
+	err := readLines(domainsFile, func(line string) error {
+		domain := strings.TrimPrefix(strings.TrimPrefix(strings.ToLower(line), "*"), ".")
+		if domain == "" {
+			return fmt.Errorf("invalid domain %q", line)
+		}
+		domains[domain] = struct{}{}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	patterns := make([]*regexp.Regexp, 0)
+	err = readLines(patternsFile, func(line string) error {
+		pattern, err := regexp.Compile(line)
+		if err != nil {
+			return err
+		}
+		patterns = append(patterns, pattern)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	b.mx.Lock()
+	defer b.mx.Unlock()
+	b.domains, b.patterns = domains, patterns
+
+	return nil
+}
+
+// Check implements the Checker, so the blocklist can be used wherever a checker is.
+func (b *Blocklist) Check(_ context.Context, url string) (Verdict, error) {
+	return b.Match(url), nil
+}
+
+// Match checks the host of the URL with its parent domains and the whole URL with the patterns.
+func (b *Blocklist) Match(rawURL string) Verdict {
+	b.mx.RLock()
+	defer b.mx.RUnlock()
+
+	if u, err := url.Parse(rawURL); err == nil {
+		host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+		for domain := host; domain != ""; {
+			if _, ok := b.domains[domain]; ok {
+				return Verdict{Blocked: true, Reason: "the domain " + domain + " is blocklisted"}
+			}
+			i := strings.IndexByte(domain, '.')
+			if i < 0 {
+				break
+			}
+			domain = domain[i+1:]
+		}
+	}
+
+	for _, pattern := range b.patterns {
+		if pattern.MatchString(rawURL) {
+			return Verdict{Blocked: true, Reason: "the URL matches a blocklisted pattern"}
+		}
+	}
+
+	return Verdict{}
+}
+
+// readLines calls fn with the trimmed entries of the file, the errors name the file and the line.
+func readLines(path string, fn func(line string) error) error {
+	if path == "" {
+		return nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if err := fn(line); err != nil {
+			return fmt.Errorf("%s:%d: %w", path, n, err)
+		}
+	}
+
+	return scanner.Err()
+}
@@ -0,0 +1,78 @@
+package screening
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+var ErrBlocked = errors.New("the URL is blocked")
+
+// BlockedError a URL refused by a checker with the reason.
+type BlockedError struct {
+	Reason string
+}
+
+func (e *BlockedError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrBlocked, e.Reason)
+}
+
+func (e *BlockedError) Unwrap() error {
+	return ErrBlocked
+}
+
+// Verdict the result of checking a URL, the reason tells why it is blocked.
+type Verdict struct {
+	Blocked bool
+	Reason  string
+}
+
+// Checker checks a URL, an external reputation service is plugged in by implementing it.
+type Checker interface {
+	Check(ctx context.Context, url string) (Verdict, error)
+}
+
+// CheckerFunc adapts a function to the Checker.
+type CheckerFunc func(ctx context.Context, url string) (Verdict, error)
+
+func (f CheckerFunc) Check(ctx context.Context, url string) (Verdict, error) {
+	return f(ctx, url)
+}
+
+// Screener the screening of the URLs to shorten. A nil Screener lets every URL pass.
+type Screener struct {
+	Blocklist  *Blocklist // nil blocks nothing
+	Reputation Checker    // nil skips the reputation check
+}
+
+// Screen checks a new destination with the blocklist and then with the reputation checker.
+// A blocked URL gets a *BlockedError, a failed check gets its error, so nothing passes unchecked.
+func (s *Screener) Screen(ctx context.Context, url string) error {
+	if err := s.Blocked(url); err != nil {
+		return err
+	}
+	if s == nil || s.Reputation == nil {
+		return nil
+	}
+
+	verdict, err := s.Reputation.Check(ctx, url)
+	if err != nil {
+		return fmt.Errorf("reputation check: %w", err)
+	}
+	if verdict.Blocked {
+		return &BlockedError{Reason: verdict.Reason}
+	}
+	return nil
+}
+
+// Blocked checks a stored destination with the blocklist only, it is cheap enough for every redirect.
+func (s *Screener) Blocked(url string) error {
+	if s == nil || s.Blocklist == nil {
+		return nil
+	}
+
+	if verdict := s.Blocklist.Match(url); verdict.Blocked {
+		return &BlockedError{Reason: verdict.Reason}
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package screening
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestBlocklist(t *testing.T) {
+	domainsFile := writeFile(t, "domains.txt", "# phishing\nEvil.com\n\n*.bad.org\n")
+	patternsFile := writeFile(t, "patterns.txt", `^https?://[^/]+/login\.php`+"\n")
+
+	b, err := LoadBlocklist(domainsFile, patternsFile)
+	require.NoError(t, err)
+
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{"http://evil.com/", true},
+		{"http://www.EVIL.com./path", true},
+		{"http://notevil.com/", false},
+		{"http://bad.org/", true},
+		{"http://a.b.bad.org/", true},
+		{"http://ya.ru/login.php?u=1", true},
+		{"http://ya.ru/app/login.php", false},
+		{"http://ya.ru/", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			verdict := b.Match(tt.url)
+			assert.Equal(t, tt.want, verdict.Blocked)
+			if tt.want {
+				assert.NotEmpty(t, verdict.Reason)
+			}
+		})
+	}
+
+	require.NoError(t, os.WriteFile(domainsFile, []byte("ya.ru\n"), 0o600))
+	require.NoError(t, b.Load(domainsFile, ""))
+	assert.True(t, b.Match("http://ya.ru/").Blocked)
+	assert.False(t, b.Match("http://evil.com/").Blocked)
+	assert.False(t, b.Match("http://other.ru/login.php").Blocked, "the patterns are replaced too")
+
+	brokenFile := writeFile(t, "broken.txt", "ok\n(unclosed\n")
+	err = b.Load("", brokenFile)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "broken.txt:2")
+	assert.True(t, b.Match("http://ya.ru/").Blocked, "a failed load keeps the lists")
+
+	_, err = LoadBlocklist(filepath.Join(t.TempDir(), "missing.txt"), "")
+	assert.Error(t, err)
+}
+
+func TestScreener(t *testing.T) {
+	var nilScreener *Screener
+	assert.NoError(t, nilScreener.Screen(context.Background(), "http://evil.com/"))
+	assert.NoError(t, nilScreener.Blocked("http://evil.com/"))
+
+	b, err := LoadBlocklist(writeFile(t, "domains.txt", "evil.com\n"), "")
+	require.NoError(t, err)
+
+	checked := 0
+	s := &Screener{
+		Blocklist: b,
+		Reputation: CheckerFunc(func(ctx context.Context, url string) (Verdict, error) {
+			checked++
+			switch url {
+			case "http://phish.ru/":
+				return Verdict{Blocked: true, Reason: "phishing"}, nil
+			case "http://down.ru/":
+				return Verdict{}, errors.New("timeout")
+			}
+			return Verdict{}, nil
+		}),
+	}
+
+	err = s.Screen(context.Background(), "http://evil.com/")
+	assert.ErrorIs(t, err, ErrBlocked)
+	assert.Equal(t, 0, checked, "the blocklist goes first")
+
+	err = s.Screen(context.Background(), "http://phish.ru/")
+	var blocked *BlockedError
+	require.ErrorAs(t, err, &blocked)
+	assert.Equal(t, "phishing", blocked.Reason)
+
+	err = s.Screen(context.Background(), "http://down.ru/")
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, ErrBlocked)
+
+	assert.NoError(t, s.Screen(context.Background(), "http://ya.ru/"))
+	assert.NoError(t, s.Blocked("http://phish.ru/"), "the redirects check the blocklist only")
+}
@@ -0,0 +1,37 @@
+package app
+
+import (
+	"context"
+
+	"github.com/alrund/yp-1/internal/app/search"
+	"github.com/alrund/yp-1/internal/app/storage"
+	"github.com/alrund/yp-1/internal/app/workspace"
+)
+
+// SearchURLs returns the links of the user and of the user's workspaces matching the query, from the most relevant.
+// The removed links are not searched.
+func (us *URLShortener) SearchURLs(
+	ctx context.Context,
+	userID string,
+	query search.Query,
+) (_ []storage.URLpairs, err error) {
+	ctx, span := startSpan(ctx, "URLShortener.SearchURLs")
+	defer func() { endSpan(span, err) }()
+
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+
+	s := us.storage(ctx)
+	owners, err := linkOwners(s, userID, func(workspace.Role) bool { return true })
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := s.SearchURLs(owners, query)
+	if err != nil {
+		return nil, err
+	}
+
+	return us.urlPairs(userID, items), nil
+}
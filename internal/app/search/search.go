@@ -0,0 +1,232 @@
+package search
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DefaultLimit the number of the results when the query does not set it, MaxLimit the most results.
+const (
+	DefaultLimit   = 20
+	MaxLimit       = 100
+	MaxQueryLength = 256
+)
+
+// The weights of the fields, a match of the short code ranks first and a match of the URL last.
+const (
+	WeightToken = 8
+	WeightTag   = 4
+	WeightTitle = 2
+	WeightURL   = 1
+)
+
+var (
+	ErrEmptyQuery   = errors.New("empty search query")
+	ErrLongQuery    = errors.New("the search query is too long")
+	ErrInvalidLimit = errors.New("invalid limit")
+)
+
+// IsInvalid reports whether the error is caused by an invalid query.
+func IsInvalid(err error) bool {
+	return errors.Is(err, ErrEmptyQuery) ||
+		errors.Is(err, ErrLongQuery) ||
+		errors.Is(err, ErrInvalidLimit)
+}
+
+// Query a search over the links. Every term of the text must match a word of the link or its beginning.
+type Query struct {
+	Text  string
+	Limit int
+}
+
+// Validate checks the query, so the storage may trust it.
+func (q Query) Validate() error {
+	if utf8.RuneCountInString(q.Text) > MaxQueryLength {
+		return fmt.Errorf("%w, the maximum is %d", ErrLongQuery, MaxQueryLength)
+	}
+	if len(q.Terms()) == 0 {
+		return ErrEmptyQuery
+	}
+	if q.Limit < 0 || q.Limit > MaxLimit {
+		return fmt.Errorf("%w %d, the maximum is %d", ErrInvalidLimit, q.Limit, MaxLimit)
+	}
+	return nil
+}
+
+// Terms returns the unique terms of the query text.
+func (q Query) Terms() []string {
+	return Terms(q.Text)
+}
+
+// PageSize returns the number of the results.
+func (q Query) PageSize() int {
+	if q.Limit <= 0 {
+		return DefaultLimit
+	}
+	return q.Limit
+}
+
+// Terms splits the text into unique lowercase words of letters and digits.
+func Terms(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	terms := make([]string, 0, len(words))
+	seen := make(map[string]struct{}, len(words))
+	for _, word := range words {
+		if _, ok := seen[word]; !ok {
+			seen[word] = struct{}{}
+			terms = append(terms, word)
+		}
+	}
+	return terms
+}
+
+// Document the searchable fields of a link.
+type Document struct {
+	Token string
+	URL   string
+	Title string
+	Tags  []string
+}
+
+// weights returns the terms of the document with the sum of the weights of the fields having them.
+func (doc Document) weights() map[string]int {
+	weights := make(map[string]int)
+	add := func(text string, weight int) {
+		for _, term := range Terms(text) {
+			weights[term] += weight
+		}
+	}
+
+	add(doc.Token, WeightToken)
+	add(strings.Join(doc.Tags, " "), WeightTag)
+	add(doc.Title, WeightTitle)
+	add(doc.URL, WeightURL)
+
+	return weights
+}
+
+// Hit a found link with its relevance.
+type Hit struct {
+	Token string
+	Score int
+}
+
+// Index an in-memory inverted index of the documents.
+type Index struct {
+	postings map[string]map[string]int // term: token: weight
+	terms    map[string][]string       // token: terms
+	mx       sync.RWMutex
+}
+
+func NewIndex() *Index {
+	return &Index{
+		postings: make(map[string]map[string]int),
+		terms:    make(map[string][]string),
+	}
+}
+
+// Put adds the document or replaces the one with the same token.
+func (ix *Index) Put(doc Document) {
+	ix.mx.Lock()
+	defer ix.mx.Unlock()
+
+	ix.remove(doc.Token)
+
+	weights := doc.weights()
+	terms := make([]string, 0, len(weights))
+	for term, weight := range weights {
+		if ix.postings[term] == nil {
+			ix.postings[term] = make(map[string]int)
+		}
+		ix.postings[term][doc.Token] = weight
+		terms = append(terms, term)
+	}
+	ix.terms[doc.Token] = terms
+}
+
+// Remove removes the document of the token.
+func (ix *Index) Remove(token string) {
+	ix.mx.Lock()
+	defer ix.mx.Unlock()
+
+	ix.remove(token)
+}
+
+func (ix *Index) remove(token string) {
+	for _, term := range ix.terms[token] {
+		delete(ix.postings[term], token)
+		if len(ix.postings[term]) == 0 {
+			delete(ix.postings, term)
+		}
+	}
+	delete(ix.terms, token)
+}
+
+// Search returns the documents matching every term from the most relevant, the token breaks the ties.
+// A whole word scores twice its weight, a word beginning with the term scores its weight.
+func (ix *Index) Search(terms []string) []Hit {
+	ix.mx.RLock()
+	defer ix.mx.RUnlock()
+
+	if len(terms) == 0 {
+		return []Hit{}
+	}
+
+	var scores map[string]int
+	for _, term := range terms {
+		termScores := ix.match(term)
+		if scores == nil {
+			scores = termScores
+			continue
+		}
+		for token := range scores {
+			if score, ok := termScores[token]; ok {
+				scores[token] += score
+			} else {
+				delete(scores, token)
+			}
+		}
+	}
+
+	hits := make([]Hit, 0, len(scores))
+	for token, score := range scores {
+		hits = append(hits, Hit{Token: token, Score: score})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score == hits[j].Score {
+			return hits[i].Token < hits[j].Token
+		}
+		return hits[i].Score > hits[j].Score
+	})
+
+	return hits
+}
+
+// match returns the best score of the term by the token.
+// The prefixes are found by scanning the terms, which is fast enough for the in-memory storages.
+func (ix *Index) match(term string) map[string]int {
+	scores := make(map[string]int)
+	for indexed, postings := range ix.postings {
+		factor := 1
+		if indexed == term {
+			factor = 2
+		} else if !strings.HasPrefix(indexed, term) {
+			continue
+		}
+
+		for token, weight := range postings {
+			if score := weight * factor; score > scores[token] {
+				scores[token] = score
+			}
+		}
+	}
+	return scores
+}
@@ -0,0 +1,87 @@
+package search
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTerms(t *testing.T) {
+	assert.Equal(t, []string{"https", "ya", "ru", "search", "q", "go"}, Terms("https://Ya.ru/search?q=go&q=GO"))
+	assert.Equal(t, []string{"привет", "мир"}, Terms("Привет, мир!"))
+	assert.Empty(t, Terms(" -/. "))
+}
+
+func TestQueryValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   Query
+		wantErr error
+	}{
+		{"valid", Query{Text: "ya.ru", Limit: MaxLimit}, nil},
+		{"empty", Query{}, ErrEmptyQuery},
+		{"no terms", Query{Text: "://"}, ErrEmptyQuery},
+		{"long", Query{Text: strings.Repeat("x", MaxQueryLength+1)}, ErrLongQuery},
+		{"negative limit", Query{Text: "ya", Limit: -1}, ErrInvalidLimit},
+		{"big limit", Query{Text: "ya", Limit: MaxLimit + 1}, ErrInvalidLimit},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.query.Validate()
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.True(t, IsInvalid(err))
+		})
+	}
+	assert.Equal(t, DefaultLimit, Query{}.PageSize())
+}
+
+func TestIndex(t *testing.T) {
+	ix := NewIndex()
+	ix.Put(Document{Token: "qwerty", URL: "http://ya.ru/news", Title: "Yandex news"})
+	ix.Put(Document{Token: "asdfgh", URL: "http://go.dev/doc", Tags: []string{"news"}})
+	ix.Put(Document{Token: "news", URL: "http://other.ru"})
+
+	tokens := func(hits []Hit) []string {
+		values := make([]string, 0, len(hits))
+		for _, hit := range hits {
+			values = append(values, hit.Token)
+		}
+		return values
+	}
+
+	tests := []struct {
+		name  string
+		terms []string
+		want  []string
+	}{
+		{"ranked by the field", []string{"news"}, []string{"news", "asdfgh", "qwerty"}},
+		{"every term", []string{"news", "ya"}, []string{"qwerty"}},
+		{"prefix", []string{"yan"}, []string{"qwerty"}},
+		{"ties by the token", []string{"ru"}, []string{"news", "qwerty"}},
+		{"nothing", []string{"lost"}, []string{}},
+		{"no terms", nil, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tokens(ix.Search(tt.terms)))
+		})
+	}
+
+	t.Run("replace", func(t *testing.T) {
+		ix.Put(Document{Token: "qwerty", URL: "http://ya.ru"})
+		assert.Empty(t, ix.Search([]string{"yandex"}))
+		assert.Equal(t, []string{"qwerty"}, tokens(ix.Search([]string{"ya"})))
+	})
+
+	t.Run("remove", func(t *testing.T) {
+		ix.Remove("qwerty")
+		ix.Remove("unknown")
+		assert.Empty(t, ix.Search([]string{"ya"}))
+		assert.NotContains(t, ix.postings, "ya", "the empty postings are dropped")
+	})
+}
@@ -0,0 +1,249 @@
+package session
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	AlgHS256 = "HS256"
+	AlgEdDSA = "EdDSA"
+
+	// minHS256KeySize the shortest HMAC secret accepted, RFC 7518 requires at least the hash size.
+	minHS256KeySize = 32
+)
+
+var (
+	ErrExpired          = errors.New("session token expired")
+	ErrUnknownAlgorithm = errors.New("unknown JWT algorithm")
+	ErrWeakKey          = errors.New("JWT key is too short")
+)
+
+// Claims the payload of the session JWT.
+type Claims struct {
+	Subject   string `json:"sub"`
+	Issuer    string `json:"iss,omitempty"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+	Scope     string `json:"scope,omitempty"` // space separated
+}
+
+// Scopes returns the scopes granted by the token.
+func (c *Claims) Scopes() []string {
+	return strings.Fields(c.Scope)
+}
+
+type header struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ"`
+	Kid string `json:"kid"`
+}
+
+// JWT a codec which issues signed JSON Web Tokens, so other services can verify the user
+// with the HMAC secret or the Ed25519 public key and without the AES password.
+type JWT struct {
+	alg    string
+	kid    string
+	sign   func(data []byte) []byte
+	verify func(data, sig []byte) bool
+	// TTL the lifetime of a token, it is issued again when less than half of it is left.
+	TTL time.Duration
+	// Issuer the "iss" claim, optional.
+	Issuer string
+	// Scopes granted to the issued tokens.
+	Scopes []string
+	now    func() time.Time
+}
+
+// NewHS256 returns a codec which signs tokens with HMAC SHA-256.
+func NewHS256(secret []byte, ttl time.Duration) (*JWT, error) {
+	if len(secret) < minHS256KeySize {
+		return nil, fmt.Errorf("%w: %d bytes, at least %d required", ErrWeakKey, len(secret), minHS256KeySize)
+	}
+
+	return &JWT{
+		alg: AlgHS256,
+		kid: keyID(secret),
+		sign: func(data []byte) []byte {
+			mac := hmac.New(sha256.New, secret)
+			mac.Write(data)
+			return mac.Sum(nil)
+		},
+		verify: func(data, sig []byte) bool {
+			mac := hmac.New(sha256.New, secret)
+			mac.Write(data)
+			return hmac.Equal(sig, mac.Sum(nil))
+		},
+		TTL: ttl,
+		now: time.Now,
+	}, nil
+}
+
+// NewEd25519 returns a codec which signs tokens with the Ed25519 private key.
+func NewEd25519(key ed25519.PrivateKey, ttl time.Duration) *JWT {
+	public := key.Public().(ed25519.PublicKey)
+
+	return &JWT{
+		alg: AlgEdDSA,
+		kid: keyID(public),
+		sign: func(data []byte) []byte {
+			return ed25519.Sign(key, data)
+		},
+		verify: func(data, sig []byte) bool {
+			return ed25519.Verify(public, data, sig)
+		},
+		TTL: ttl,
+		now: time.Now,
+	}
+}
+
+// LoadJWT reads the key file of the algorithm: a raw secret for HS256
+// or a PEM encoded PKCS #8 private key for EdDSA.
+func LoadJWT(alg, keyFile string, ttl time.Duration) (*JWT, error) {
+	data, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	switch alg {
+	case AlgHS256:
+		return NewHS256([]byte(strings.TrimSpace(string(data))), ttl)
+	case AlgEdDSA:
+		block, _ := pem.Decode(data)
+		if block == nil {
+			return nil, fmt.Errorf("%s: no PEM data", keyFile)
+		}
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		edKey, ok := key.(ed25519.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("%s: not an Ed25519 private key", keyFile)
+		}
+		return NewEd25519(edKey, ttl), nil
+	default:
+		return nil, fmt.Errorf("%w %q", ErrUnknownAlgorithm, alg)
+	}
+}
+
+func (j *JWT) Issue(userID string) (string, error) {
+	now := j.now()
+	return j.IssueClaims(&Claims{
+		Subject:   userID,
+		Issuer:    j.Issuer,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(j.TTL).Unix(),
+		Scope:     strings.Join(j.Scopes, " "),
+	})
+}
+
+// IssueClaims signs the claims as they are.
+func (j *JWT) IssueClaims(claims *Claims) (string, error) {
+	h, err := json.Marshal(header{Alg: j.alg, Typ: "JWT", Kid: j.kid})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signed := encode(h) + "." + encode(payload)
+	return signed + "." + encode(j.sign([]byte(signed))), nil
+}
+
+func (j *JWT) Parse(token string) (string, bool, error) {
+	claims, current, err := j.ParseClaims(token)
+	if err != nil {
+		return "", false, err
+	}
+	return claims.Subject, current, nil
+}
+
+// ParseScopes returns the scopes of the "scope" claim.
+func (j *JWT) ParseScopes(token string) ([]string, error) {
+	claims, _, err := j.ParseClaims(token)
+	if err != nil {
+		return nil, err
+	}
+	return claims.Scopes(), nil
+}
+
+// ParseClaims verifies the token and returns its claims
+// and whether it was signed with the current key and is far from the expiry.
+func (j *JWT) ParseClaims(token string) (*Claims, bool, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, false, ErrInvalid
+	}
+
+	var h header
+	if err := decode(parts[0], &h); err != nil {
+		return nil, false, err
+	}
+	// The algorithm is fixed by the configuration, never taken from the token.
+	if h.Alg != j.alg {
+		return nil, false, ErrInvalid
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, false, ErrInvalid
+	}
+	if !j.verify([]byte(parts[0]+"."+parts[1]), sig) {
+		return nil, false, ErrInvalid
+	}
+
+	var claims Claims
+	if err := decode(parts[1], &claims); err != nil {
+		return nil, false, err
+	}
+	if claims.Subject == "" {
+		return nil, false, ErrInvalid
+	}
+
+	now := j.now()
+	expiresAt := time.Unix(claims.ExpiresAt, 0)
+	if !now.Before(expiresAt) {
+		return nil, false, ErrExpired
+	}
+	if j.Issuer != "" && claims.Issuer != j.Issuer {
+		return nil, false, ErrInvalid
+	}
+
+	current := h.Kid == j.kid && expiresAt.Sub(now) > j.TTL/2
+
+	return &claims, current, nil
+}
+
+func keyID(key []byte) string {
+	sum := sha256.Sum256(append([]byte("key id:"), key...))
+	return hex.EncodeToString(sum[:4])
+}
+
+func encode(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decode(part string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return ErrInvalid
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return ErrInvalid
+	}
+	return nil
+}
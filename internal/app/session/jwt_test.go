@@ -0,0 +1,148 @@
+package session
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+func TestJWT(t *testing.T) {
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	hs, err := NewHS256([]byte(testSecret), time.Hour)
+	require.NoError(t, err)
+
+	for _, codec := range []*JWT{hs, NewEd25519(edKey, time.Hour)} {
+		t.Run(codec.alg, func(t *testing.T) {
+			codec.Issuer = "shortener"
+			codec.Scopes = []string{"urls", "keys"}
+
+			token, err := codec.Issue("user-1")
+			require.NoError(t, err)
+
+			claims, current, err := codec.ParseClaims(token)
+			require.NoError(t, err)
+			assert.True(t, current)
+			assert.Equal(t, "user-1", claims.Subject)
+			assert.Equal(t, "shortener", claims.Issuer)
+			assert.Equal(t, []string{"urls", "keys"}, claims.Scopes())
+			assert.Equal(t, time.Hour, time.Duration(claims.ExpiresAt-claims.IssuedAt)*time.Second)
+
+			parts := strings.Split(token, ".")
+			forged := parts[0] + "." + base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"admin","exp":9999999999}`)) + "." + parts[2]
+			_, _, err = codec.Parse(forged)
+			assert.ErrorIs(t, err, ErrInvalid)
+		})
+	}
+}
+
+func TestJWTParse(t *testing.T) {
+	codec, err := NewHS256([]byte(testSecret), time.Hour)
+	require.NoError(t, err)
+	other, err := NewHS256([]byte(strings.Repeat("x", 32)), time.Hour)
+	require.NoError(t, err)
+
+	now := time.Now()
+	issue := func(codec *JWT, issuedAt time.Time) string {
+		codec.now = func() time.Time { return issuedAt }
+		defer func() { codec.now = time.Now }()
+		token, err := codec.Issue("user-1")
+		require.NoError(t, err)
+		return token
+	}
+
+	tests := []struct {
+		name        string
+		token       string
+		wantErr     error
+		wantCurrent bool
+	}{
+		{
+			name:        "fresh",
+			token:       issue(codec, now),
+			wantCurrent: true,
+		},
+		{
+			name:  "close to the expiry",
+			token: issue(codec, now.Add(-45*time.Minute)),
+		},
+		{
+			name:    "expired",
+			token:   issue(codec, now.Add(-2*time.Hour)),
+			wantErr: ErrExpired,
+		},
+		{
+			name:    "other key",
+			token:   issue(other, now),
+			wantErr: ErrInvalid,
+		},
+		{
+			name:    "algorithm none",
+			token:   "eyJhbGciOiJub25lIiwidHlwIjoiSldUIn0.eyJzdWIiOiJ1c2VyLTEiLCJleHAiOjk5OTk5OTk5OTl9.",
+			wantErr: ErrInvalid,
+		},
+		{
+			name:    "garbage",
+			token:   "garbage",
+			wantErr: ErrInvalid,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userID, current, err := codec.Parse(tt.token)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, "user-1", userID)
+			assert.Equal(t, tt.wantCurrent, current)
+		})
+	}
+}
+
+func TestLoadJWT(t *testing.T) {
+	dir := t.TempDir()
+
+	secretFile := filepath.Join(dir, "secret")
+	require.NoError(t, os.WriteFile(secretFile, []byte(testSecret+"\n"), 0o600))
+	shortFile := filepath.Join(dir, "short")
+	require.NoError(t, os.WriteFile(shortFile, []byte("short"), 0o600))
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(edKey)
+	require.NoError(t, err)
+	edFile := filepath.Join(dir, "ed25519.pem")
+	require.NoError(t, os.WriteFile(edFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600))
+
+	codec, err := LoadJWT(AlgHS256, secretFile, time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, AlgHS256, codec.alg)
+
+	codec, err = LoadJWT(AlgEdDSA, edFile, time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, AlgEdDSA, codec.alg)
+
+	_, err = LoadJWT(AlgHS256, shortFile, time.Hour)
+	assert.ErrorIs(t, err, ErrWeakKey)
+
+	_, err = LoadJWT(AlgEdDSA, secretFile, time.Hour)
+	assert.Error(t, err)
+
+	_, err = LoadJWT("RS256", secretFile, time.Hour)
+	assert.ErrorIs(t, err, ErrUnknownAlgorithm)
+}
@@ -0,0 +1,65 @@
+package session
+
+import (
+	"errors"
+
+	"github.com/alrund/yp-1/internal/app/encryption"
+)
+
+var ErrInvalid = errors.New("invalid session token")
+
+// The scopes a session token may be limited to.
+const (
+	ScopeURLs       = "urls"       // the links of the user
+	ScopeKeys       = "keys"       // the API keys of the user
+	ScopeWorkspaces = "workspaces" // the workspaces and their members
+)
+
+// KnownScopes the scopes the routes require.
+var KnownScopes = []string{ScopeURLs, ScopeKeys, ScopeWorkspaces}
+
+// Codec issues the session tokens which carry the user ID and reads them back.
+type Codec interface {
+	// Issue returns a new token for the user.
+	Issue(userID string) (string, error)
+	// Parse returns the user ID and whether the token is up to date, otherwise it should be issued again.
+	Parse(token string) (string, bool, error)
+}
+
+// Scoped a codec whose tokens are limited to the scopes they carry.
+// The tokens of the other codecs are not limited.
+type Scoped interface {
+	// ParseScopes returns the scopes granted by the token, it is verified like by Parse.
+	ParseScopes(token string) ([]string, error)
+}
+
+// HasScope reports whether the scope is one of the granted ones.
+func HasScope(granted []string, scope string) bool {
+	for _, s := range granted {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Encrypted a codec which keeps the user ID encrypted with the AES keyring.
+type Encrypted struct {
+	enc *encryption.Encryption
+}
+
+func NewEncrypted(enc *encryption.Encryption) *Encrypted {
+	return &Encrypted{enc: enc}
+}
+
+func (e *Encrypted) Issue(userID string) (string, error) {
+	return e.enc.Encrypt(userID)
+}
+
+func (e *Encrypted) Parse(token string) (string, bool, error) {
+	userID, err := e.enc.Decrypt(token)
+	if err != nil {
+		return "", false, err
+	}
+	return userID, e.enc.IsCurrent(token), nil
+}
@@ -5,19 +5,41 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/alrund/yp-1/internal/app/account"
+	"github.com/alrund/yp-1/internal/app/apikey"
+	"github.com/alrund/yp-1/internal/app/audit"
+	"github.com/alrund/yp-1/internal/app/linkcheck"
+	"github.com/alrund/yp-1/internal/app/linkmeta"
+	"github.com/alrund/yp-1/internal/app/listing"
+	"github.com/alrund/yp-1/internal/app/logging"
 	"github.com/alrund/yp-1/internal/app/migrations"
+	"github.com/alrund/yp-1/internal/app/ratelimit"
+	"github.com/alrund/yp-1/internal/app/search"
 	tkn "github.com/alrund/yp-1/internal/app/token"
+	"github.com/alrund/yp-1/internal/app/workspace"
 	"github.com/google/uuid"
+	"github.com/jackc/pgconn"
 	_ "github.com/jackc/pgx/v4/stdlib" // pgx
 )
 
+// uniqueViolation the PostgreSQL error code.
+const uniqueViolation = "23505"
+
+// clicksFlushBatch the tokens a click update takes, PostgreSQL takes up to 65535 parameters a statement.
+const clicksFlushBatch = 1000
+
 // DB database storage.
+// The redirects are counted in memory, the clicks are added to the tokens by FlushClicks.
 type DB struct {
-	db *sql.DB
+	db       *sql.DB
+	clicks   map[string]int64 // the clicks not flushed yet
+	clicksMx sync.Mutex
 }
 
 func NewDB(dsn string) (*DB, error) {
@@ -27,7 +49,8 @@ func NewDB(dsn string) (*DB, error) {
 	}
 
 	newDB := &DB{
-		db: db,
+		db:     db,
+		clicks: make(map[string]int64),
 	}
 
 	err = newDB.migrations()
@@ -66,9 +89,98 @@ func (d *DB) migrations() error {
 		return err
 	}
 
+	err = migrations.UpAPIKeys(tx)
+	if err != nil {
+		return err
+	}
+
+	err = migrations.UpAccounts(tx)
+	if err != nil {
+		return err
+	}
+
+	err = migrations.UpWorkspaces(tx)
+	if err != nil {
+		return err
+	}
+
+	err = migrations.UpRateLimits(tx)
+	if err != nil {
+		return err
+	}
+
+	err = migrations.UpAuditEvents(tx)
+	if err != nil {
+		return err
+	}
+
+	err = migrations.UpListingColumns(tx)
+	if err != nil {
+		return err
+	}
+
+	err = migrations.UpLinkMeta(tx)
+	if err != nil {
+		return err
+	}
+
+	err = migrations.UpLinkSearch(tx)
+	if err != nil {
+		return err
+	}
+
+	err = migrations.UpLinkHealth(tx)
+	if err != nil {
+		return err
+	}
+
+	err = migrations.UpLinkWebhooks(tx)
+	if err != nil {
+		return err
+	}
+
 	return tx.Commit()
 }
 
+// migratedColumns selects every column created by the migrations.
+var migratedColumns = []string{
+	"SELECT token, expire, removed, created, clicks FROM tokens LIMIT 0",
+	"SELECT id, url, token, user_id FROM urls LIMIT 0",
+	"SELECT id, user_id, name, key_hash, hint, created, revoked FROM api_keys LIMIT 0",
+	"SELECT id, login, password_hash, created FROM accounts LIMIT 0",
+	"SELECT id, name, created FROM workspaces LIMIT 0",
+	"SELECT workspace_id, user_id, role FROM workspace_members LIMIT 0",
+	"SELECT key, tokens, updated FROM rate_buckets LIMIT 0",
+	"SELECT key, day, used FROM quota_usage LIMIT 0",
+	"SELECT id, created, action, token, url, owner_id, actor_id, source, client_ip, request_id FROM audit_events LIMIT 0",
+	"SELECT token, title, notes FROM link_meta LIMIT 0",
+	"SELECT token, tag FROM link_tags LIMIT 0",
+	"SELECT token, document FROM link_search LIMIT 0",
+	"SELECT token, checked, status_code, reachable, error FROM link_health LIMIT 0",
+	"SELECT user_id, url FROM link_webhooks LIMIT 0",
+}
+
+// CheckMigrations checks that the schema has every migrated column.
+func (d *DB) CheckMigrations(ctx context.Context) error {
+	for _, query := range migratedColumns {
+		if err := d.checkColumns(ctx, query); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (d *DB) checkColumns(ctx context.Context, query string) error {
+	rows, err := d.db.QueryContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	return rows.Err()
+}
+
 func (d *DB) GetURLCount() (int, error) {
 	var num int
 	err := d.db.QueryRow("SELECT count(DISTINCT token) as num FROM url").Scan(&num)
@@ -103,7 +215,12 @@ func (d *DB) Set(userID, url string, token *tkn.Token) error {
 
 	defer tx.Rollback()
 
-	_, err = d.db.Exec("INSERT INTO tokens(token, expire) VALUES($1, $2)", token.Value, token.Expire.Unix())
+	_, err = d.db.Exec(
+		"INSERT INTO tokens(token, expire, created) VALUES($1, $2, $3)",
+		token.Value,
+		token.Expire.Unix(),
+		unixNano(token.Created),
+	)
 	if err != nil {
 		return err
 	}
@@ -130,7 +247,7 @@ func (d *DB) SetBatch(userID string, url2token map[string]*tkn.Token) error {
 
 	defer tx.Rollback()
 
-	tokensStmt, err := d.db.Prepare("INSERT INTO tokens(token, expire) VALUES($1, $2)")
+	tokensStmt, err := d.db.Prepare("INSERT INTO tokens(token, expire, created) VALUES($1, $2, $3)")
 	if err != nil {
 		return err
 	}
@@ -143,7 +260,7 @@ func (d *DB) SetBatch(userID string, url2token map[string]*tkn.Token) error {
 	defer urlsStmt.Close()
 
 	for url, token := range url2token {
-		_, err = tokensStmt.Exec(token.Value, token.Expire.Unix())
+		_, err = tokensStmt.Exec(token.Value, token.Expire.Unix(), unixNano(token.Created))
 		if err != nil {
 			return err
 		}
@@ -317,39 +434,1094 @@ func (d *DB) GetURLsByUserID(userID string) ([]URLpairs, error) {
 	return pairs, nil
 }
 
-func (d *DB) HasURL(url string) (bool, error) {
-	var u string
-	err := d.db.QueryRow(
-		"SELECT url FROM urls WHERE url = $1", url,
-	).Scan(&u)
+// GetURLsPage returns a page of the URLs of the owners and the cursor of the next page.
+// It fetches one item more than the page to know if there is a next one.
+func (d *DB) GetURLsPage(ownerIDs []string, query listing.Query) ([]listing.Item, string, error) {
+	cursor, err := query.ParseCursor()
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return false, nil
+		return nil, "", err
+	}
+	if len(ownerIDs) == 0 {
+		return []listing.Item{}, "", nil
+	}
+
+	ownerPhs, args := ownerPlaceholders(ownerIDs, 1)
+	conditions := []string{"u.user_id IN (" + ownerPhs + ")"}
+
+	switch query.Status {
+	case listing.StatusActive:
+		args = append(args, time.Now().Unix())
+		conditions = append(conditions, fmt.Sprintf("NOT t.removed AND t.expire > $%d", len(args)))
+	case listing.StatusExpired:
+		args = append(args, time.Now().Unix())
+		conditions = append(conditions, fmt.Sprintf("NOT t.removed AND t.expire <= $%d", len(args)))
+	case listing.StatusRemoved:
+		conditions = append(conditions, "t.removed")
+	}
+
+	if query.Contains != "" {
+		args = append(args, "%"+likeEscaper.Replace(query.Contains)+"%")
+		conditions = append(conditions, fmt.Sprintf("u.url ILIKE $%d", len(args)))
+	}
+
+	if query.Tag != "" {
+		args = append(args, query.Tag)
+		conditions = append(conditions, fmt.Sprintf(
+			"EXISTS (SELECT 1 FROM link_tags lt WHERE lt.token = t.token AND lt.tag = $%d)", len(args),
+		))
+	}
+
+	column, order, cmp := "t.created", "ASC", ">"
+	if query.SortBy() == listing.SortClicks || query.SortBy() == listing.SortClicksDesc {
+		column = "t.clicks"
+	}
+	if query.Descending() {
+		order, cmp = "DESC", "<"
+	}
+
+	if cursor != nil {
+		args = append(args, cursor.Key, cursor.Token)
+		conditions = append(conditions, fmt.Sprintf("(%s, t.token) %s ($%d, $%d)", column, cmp, len(args)-1, len(args)))
+	}
+
+	size := query.PageSize()
+	args = append(args, size+1)
+	rows, err := d.db.Query(fmt.Sprintf(
+		selectListingItems+"WHERE %s ORDER BY %s %s, t.token %s LIMIT $%d",
+		strings.Join(conditions, " AND "), column, order, order, len(args),
+	), args...)
+	if err != nil {
+		return nil, "", err
+	}
+
+	defer rows.Close()
+
+	items, err := scanListingItems(rows)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if len(items) <= size {
+		return items, "", nil
+	}
+
+	return items[:size], query.NextCursor(items[size-1]), nil
+}
+
+// GetURLsByTokens returns the links of the tokens without the clicks and the meta, the unknown tokens are skipped.
+func (d *DB) GetURLsByTokens(tokenValues []string) ([]listing.Item, error) {
+	rows, err := d.db.Query(
+		"SELECT t.token, u.url, u.user_id, t.created, t.expire, t.removed "+
+			"FROM tokens t JOIN urls u ON u.token = t.token WHERE t.token = ANY($1)",
+		tokenValues,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	items := make([]listing.Item, 0, len(tokenValues))
+	for rows.Next() {
+		var item listing.Item
+		var created, expire int64
+		err = rows.Scan(&item.Token, &item.URL, &item.OwnerID, &created, &expire, &item.Removed)
+		if err != nil {
+			return nil, err
 		}
-		return false, err
+		if created != 0 {
+			item.Created = time.Unix(0, created)
+		}
+		item.Expire = time.Unix(expire, 0)
+		items = append(items, item)
 	}
 
-	return u != "", nil
+	return items, rows.Err()
 }
 
-func (d *DB) HasToken(tokenValue string) (bool, error) {
-	var t string
-	err := d.db.QueryRow(
-		"SELECT token FROM tokens WHERE token = $1", tokenValue,
-	).Scan(&t)
+// selectListingItems selects the columns read by scanListingItems
+// from the link t with the URL u, the meta m and the health h.
+const selectListingItems = "SELECT t.token, u.url, u.user_id, t.created, t.expire, t.removed, t.clicks, " +
+	"COALESCE(m.title, ''), COALESCE(m.notes, ''), " +
+	"COALESCE((SELECT string_agg(lt.tag, ',' ORDER BY lt.tag) FROM link_tags lt WHERE lt.token = t.token), ''), " +
+	"COALESCE(h.checked, 0), COALESCE(h.status_code, 0), COALESCE(h.reachable, FALSE), COALESCE(h.error, '') " +
+	"FROM urls u JOIN tokens t ON t.token = u.token LEFT JOIN link_meta m ON m.token = t.token " +
+	"LEFT JOIN link_health h ON h.token = t.token "
+
+func scanListingItems(rows *sql.Rows) ([]listing.Item, error) {
+	items := make([]listing.Item, 0)
+	for rows.Next() {
+		var item listing.Item
+		var created, expire, checked int64
+		var tags string
+		var h linkcheck.Health
+		err := rows.Scan(
+			&item.Token, &item.URL, &item.OwnerID, &created, &expire, &item.Removed, &item.Clicks,
+			&item.Title, &item.Notes, &tags,
+			&checked, &h.StatusCode, &h.Reachable, &h.Error,
+		)
+		if err != nil {
+			return nil, err
+		}
+		item.Tags = splitTags(tags)
+		if checked != 0 {
+			h.Checked = time.Unix(0, checked)
+			item.Health = h
+		}
+		if created != 0 {
+			item.Created = time.Unix(0, created)
+		}
+		item.Expire = time.Unix(expire, 0)
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
+
+// GetLinksToCheck returns the active links checked before the time, the never checked ones first.
+func (d *DB) GetLinksToCheck(checkedBefore time.Time, limit int) ([]listing.Item, error) {
+	rows, err := d.db.Query(
+		selectListingItems+"WHERE NOT t.removed AND t.expire > $1 AND COALESCE(h.checked, 0) < $2 "+
+			"ORDER BY COALESCE(h.checked, 0), t.token LIMIT $3",
+		time.Now().Unix(), checkedBefore.UnixNano(), limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	return scanListingItems(rows)
+}
+
+// SetLinkHealth keeps the results of the checks by the token.
+func (d *DB) SetLinkHealth(health map[string]linkcheck.Health) error {
+	tokenValues := make([]string, 0, len(health))
+	for tokenValue := range health {
+		tokenValues = append(tokenValues, tokenValue)
+	}
+	sort.Strings(tokenValues)
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	for _, tokenValue := range tokenValues {
+		h := health[tokenValue]
+		_, err = tx.Exec(
+			"INSERT INTO link_health(token, checked, status_code, reachable, error) VALUES($1, $2, $3, $4, $5) "+
+				"ON CONFLICT (token) DO UPDATE SET checked = EXCLUDED.checked, status_code = EXCLUDED.status_code, "+
+				"reachable = EXCLUDED.reachable, error = EXCLUDED.error",
+			tokenValue, h.Checked.UnixNano(), h.StatusCode, h.Reachable, h.Error,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// SetLinkWebhook keeps the URL the user's link check events are posted to, an empty URL removes it.
+func (d *DB) SetLinkWebhook(userID, url string) error {
+	if url == "" {
+		_, err := d.db.Exec("DELETE FROM link_webhooks WHERE user_id = $1", userID)
+		return err
+	}
+
+	_, err := d.db.Exec(
+		"INSERT INTO link_webhooks(user_id, url) VALUES($1, $2) "+
+			"ON CONFLICT (user_id) DO UPDATE SET url = EXCLUDED.url",
+		userID,
+		url,
+	)
+
+	return err
+}
+
+// GetLinkWebhook returns the user's link check webhook URL, empty if the user has none.
+func (d *DB) GetLinkWebhook(userID string) (string, error) {
+	var url string
+	err := d.db.QueryRow("SELECT url FROM link_webhooks WHERE user_id = $1", userID).Scan(&url)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return false, nil
+			return "", nil
 		}
-		return false, err
+		return "", err
 	}
 
-	return t != "", nil
+	return url, nil
 }
 
-func (d *DB) Ping(ctx context.Context) error {
-	ctx, cancel := context.WithTimeout(ctx, 1*time.Second)
-	defer cancel()
+// SearchURLs returns the links of the owners which are not removed and match the query, from the most relevant.
+// Every term matches a word of the link or its beginning.
+func (d *DB) SearchURLs(ownerIDs []string, query search.Query) ([]listing.Item, error) {
+	terms := query.Terms()
+	if len(ownerIDs) == 0 || len(terms) == 0 {
+		return []listing.Item{}, nil
+	}
 
-	return d.db.PingContext(ctx)
+	for i, term := range terms {
+		terms[i] = term + ":*"
+	}
+
+	ownerPhs, args := ownerPlaceholders(ownerIDs, 2)
+	args = append([]interface{}{strings.Join(terms, " & ")}, args...)
+	args = append(args, query.PageSize())
+	rows, err := d.db.Query(fmt.Sprintf(
+		selectListingItems+"JOIN link_search s ON s.token = t.token "+
+			"WHERE s.document @@ to_tsquery('simple', $1) AND NOT t.removed AND u.user_id IN (%s) "+
+			"ORDER BY ts_rank(s.document, to_tsquery('simple', $1)) DESC, t.token LIMIT $%d",
+		ownerPhs, len(args),
+	), args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	return scanListingItems(rows)
+}
+
+// AddClick counts a redirect in memory, the click is added to the token by FlushClicks.
+func (d *DB) AddClick(tokenValue string) error {
+	d.clicksMx.Lock()
+	defer d.clicksMx.Unlock()
+
+	if d.clicks == nil {
+		d.clicks = make(map[string]int64)
+	}
+	d.clicks[tokenValue]++
+
+	return nil
+}
+
+// FlushClicks adds the clicks counted since the last flush to the tokens, a batch of the tokens an update.
+// The clicks are kept for the next flush if an update fails.
+func (d *DB) FlushClicks() error {
+	d.clicksMx.Lock()
+	clicks := d.clicks
+	d.clicks = make(map[string]int64)
+	d.clicksMx.Unlock()
+
+	tokenValues := make([]string, 0, len(clicks))
+	for tokenValue := range clicks {
+		tokenValues = append(tokenValues, tokenValue)
+	}
+	sort.Strings(tokenValues)
+
+	for len(tokenValues) > 0 {
+		n := len(tokenValues)
+		if n > clicksFlushBatch {
+			n = clicksFlushBatch
+		}
+		if err := d.addClicks(tokenValues[:n], clicks); err != nil {
+			d.clicksMx.Lock()
+			for _, tokenValue := range tokenValues {
+				d.clicks[tokenValue] += clicks[tokenValue]
+			}
+			d.clicksMx.Unlock()
+			return err
+		}
+		tokenValues = tokenValues[n:]
+	}
+
+	return nil
+}
+
+func (d *DB) addClicks(tokenValues []string, clicks map[string]int64) error {
+	valPhs := ""
+	vals := make([]interface{}, 0, 2*len(tokenValues))
+	for _, tokenValue := range tokenValues {
+		valPhs += fmt.Sprintf("($%d, $%d::bigint),", len(vals)+1, len(vals)+2)
+		vals = append(vals, tokenValue, clicks[tokenValue])
+	}
+
+	_, err := d.db.Exec(fmt.Sprintf(
+		"UPDATE tokens SET clicks = tokens.clicks + tmp.n FROM (VALUES %s) AS tmp (token, n) "+
+			"WHERE tokens.token = tmp.token",
+		strings.TrimRight(valPhs, ",")),
+		vals...,
+	)
+
+	return err
+}
+
+func (d *DB) SetLinkMeta(tokenValue string, m linkmeta.Meta) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	if err = setLinkMeta(tx, tokenValue, m); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// UpdateLinkMeta applies the update to the meta of the link of the owners which is not removed.
+// The token row stays locked until the new meta is written.
+func (d *DB) UpdateLinkMeta(ownerIDs []string, tokenValue string, update linkmeta.Update) (linkmeta.Meta, error) {
+	if len(ownerIDs) == 0 {
+		return linkmeta.Meta{}, ErrTokenNotFound
+	}
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return linkmeta.Meta{}, err
+	}
+
+	defer tx.Rollback()
+
+	ownerPhs, args := ownerPlaceholders(ownerIDs, 2)
+	var token string
+	err = tx.QueryRow(
+		"SELECT t.token FROM tokens t JOIN urls u ON u.token = t.token "+
+			"WHERE t.token = $1 AND u.user_id IN ("+ownerPhs+") AND NOT t.removed FOR UPDATE OF t",
+		append([]interface{}{tokenValue}, args...)...,
+	).Scan(&token)
+	if errors.Is(err, sql.ErrNoRows) {
+		return linkmeta.Meta{}, ErrTokenNotFound
+	}
+	if err != nil {
+		return linkmeta.Meta{}, err
+	}
+
+	m, err := getLinkMeta(tx, tokenValue)
+	if err != nil {
+		return linkmeta.Meta{}, err
+	}
+
+	if m, err = update.Apply(m); err != nil {
+		return linkmeta.Meta{}, err
+	}
+
+	if err = setLinkMeta(tx, tokenValue, m); err != nil {
+		return linkmeta.Meta{}, err
+	}
+
+	return m, tx.Commit()
+}
+
+// GetLinkMeta returns the meta of the link, the zero meta if it has none.
+func (d *DB) GetLinkMeta(tokenValue string) (linkmeta.Meta, error) {
+	return getLinkMeta(d.db, tokenValue)
+}
+
+// querier is implemented by *sql.DB and *sql.Tx.
+type querier interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+func getLinkMeta(q querier, tokenValue string) (linkmeta.Meta, error) {
+	var m linkmeta.Meta
+	err := q.QueryRow("SELECT title, notes FROM link_meta WHERE token = $1", tokenValue).Scan(&m.Title, &m.Notes)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return linkmeta.Meta{}, err
+	}
+
+	rows, err := q.Query("SELECT tag FROM link_tags WHERE token = $1 ORDER BY tag", tokenValue)
+	if err != nil {
+		return linkmeta.Meta{}, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var tag string
+		if err = rows.Scan(&tag); err != nil {
+			return linkmeta.Meta{}, err
+		}
+		m.Tags = append(m.Tags, tag)
+	}
+
+	return m, rows.Err()
+}
+
+func setLinkMeta(tx *sql.Tx, tokenValue string, m linkmeta.Meta) error {
+	_, err := tx.Exec(
+		"INSERT INTO link_meta(token, title, notes) VALUES($1, $2, $3) "+
+			"ON CONFLICT (token) DO UPDATE SET title = EXCLUDED.title, notes = EXCLUDED.notes",
+		tokenValue, m.Title, m.Notes,
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("DELETE FROM link_tags WHERE token = $1", tokenValue)
+	if err != nil {
+		return err
+	}
+
+	for _, tag := range m.Tags {
+		_, err = tx.Exec("INSERT INTO link_tags(token, tag) VALUES($1, $2)", tokenValue, tag)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetTags returns the tags of the links of the owners which are not removed.
+func (d *DB) GetTags(ownerIDs []string) ([]linkmeta.TagCount, error) {
+	tags := make([]linkmeta.TagCount, 0)
+	if len(ownerIDs) == 0 {
+		return tags, nil
+	}
+
+	ownerPhs, args := ownerPlaceholders(ownerIDs, 1)
+	rows, err := d.db.Query(
+		"SELECT lt.tag, count(*) FROM link_tags lt JOIN urls u ON u.token = lt.token JOIN tokens t ON t.token = lt.token "+
+			"WHERE u.user_id IN ("+ownerPhs+") AND NOT t.removed GROUP BY lt.tag ORDER BY lt.tag",
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var tag linkmeta.TagCount
+		if err = rows.Scan(&tag.Tag, &tag.Count); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+
+	return tags, rows.Err()
+}
+
+// RenameTag renames the tag on the links of the owners and returns the number of the links changed.
+// The links having both tags keep one.
+func (d *DB) RenameTag(ownerIDs []string, from, to string) (int, error) {
+	if len(ownerIDs) == 0 {
+		return 0, nil
+	}
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+
+	defer tx.Rollback()
+
+	ownerPhs, args := ownerPlaceholders(ownerIDs, 3)
+	args = append([]interface{}{from, to}, args...)
+	_, err = tx.Exec(
+		"INSERT INTO link_tags(token, tag) SELECT lt.token, $2 FROM link_tags lt JOIN urls u ON u.token = lt.token "+
+			"WHERE lt.tag = $1 AND u.user_id IN ("+ownerPhs+") ON CONFLICT DO NOTHING",
+		args...,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := tx.Exec(
+		"DELETE FROM link_tags lt USING urls u WHERE u.token = lt.token AND lt.tag = $1 AND lt.tag <> $2 "+
+			"AND u.user_id IN ("+ownerPhs+")",
+		args...,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	renamed, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(renamed), tx.Commit()
+}
+
+func (d *DB) HasURL(url string) (bool, error) {
+	var u string
+	err := d.db.QueryRow(
+		"SELECT url FROM urls WHERE url = $1", url,
+	).Scan(&u)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return u != "", nil
+}
+
+func (d *DB) HasToken(tokenValue string) (bool, error) {
+	var t string
+	err := d.db.QueryRow(
+		"SELECT token FROM tokens WHERE token = $1", tokenValue,
+	).Scan(&t)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return t != "", nil
+}
+
+func (d *DB) SetAPIKey(key *apikey.Key) error {
+	_, err := d.db.Exec(
+		"INSERT INTO api_keys(id, user_id, name, key_hash, hint, created, revoked) VALUES($1, $2, $3, $4, $5, $6, $7)",
+		key.ID,
+		key.UserID,
+		key.Name,
+		key.Hash,
+		key.Hint,
+		key.Created.Unix(),
+		key.Revoked,
+	)
+	return err
+}
+
+func (d *DB) GetAPIKey(hash string) (*apikey.Key, error) {
+	row := d.db.QueryRow(
+		"SELECT id, user_id, name, key_hash, hint, created, revoked FROM api_keys WHERE key_hash = $1", hash,
+	)
+
+	key, err := scanAPIKey(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrAPIKeyNotFound
+		}
+		return nil, err
+	}
+
+	return key, nil
+}
+
+func (d *DB) GetAPIKeysByUserID(userID string) ([]*apikey.Key, error) {
+	rows, err := d.db.Query(
+		"SELECT id, user_id, name, key_hash, hint, created, revoked FROM api_keys WHERE user_id = $1 ORDER BY created",
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	keys := make([]*apikey.Key, 0)
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+func (d *DB) RevokeAPIKey(id, userID string) error {
+	result, err := d.db.Exec("UPDATE api_keys SET revoked=true WHERE id = $1 AND user_id = $2", id, userID)
+	if err != nil {
+		return err
+	}
+
+	num, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if num == 0 {
+		return ErrAPIKeyNotFound
+	}
+
+	return nil
+}
+
+func (d *DB) SetAccount(a *account.Account) error {
+	_, err := d.db.Exec(
+		"INSERT INTO accounts(id, login, password_hash, created) VALUES($1, $2, $3, $4)",
+		a.ID,
+		a.Login,
+		a.PasswordHash,
+		a.Created.Unix(),
+	)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+			return ErrAccountExists
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (d *DB) GetAccountByLogin(login string) (*account.Account, error) {
+	return d.getAccount("SELECT id, login, password_hash, created FROM accounts WHERE login = $1", login)
+}
+
+func (d *DB) GetAccountByID(id string) (*account.Account, error) {
+	return d.getAccount("SELECT id, login, password_hash, created FROM accounts WHERE id = $1", id)
+}
+
+func (d *DB) getAccount(query string, arg string) (*account.Account, error) {
+	var a account.Account
+	var created int64
+	err := d.db.QueryRow(query, arg).Scan(&a.ID, &a.Login, &a.PasswordHash, &created)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrAccountNotFound
+		}
+		return nil, err
+	}
+	a.Created = time.Unix(created, 0)
+
+	return &a, nil
+}
+
+func (d *DB) ReassignUser(fromUserID, toUserID string) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	queries := []string{
+		"UPDATE urls SET user_id=$2 WHERE user_id=$1",
+		"UPDATE api_keys SET user_id=$2 WHERE user_id=$1",
+		// Where both are members, the higher role is kept.
+		"INSERT INTO workspace_members(workspace_id, user_id, role) " +
+			"SELECT workspace_id, $2, role FROM workspace_members WHERE user_id=$1 " +
+			"ON CONFLICT (workspace_id, user_id) DO UPDATE SET role = CASE " +
+			"WHEN array_position(ARRAY['viewer', 'editor', 'admin'], EXCLUDED.role::text) > " +
+			"array_position(ARRAY['viewer', 'editor', 'admin'], workspace_members.role::text) " +
+			"THEN EXCLUDED.role ELSE workspace_members.role END",
+		"DELETE FROM workspace_members WHERE user_id=$1",
+		// The target keeps the webhook of their own.
+		"INSERT INTO link_webhooks(user_id, url) SELECT $2, url FROM link_webhooks WHERE user_id=$1 " +
+			"ON CONFLICT (user_id) DO NOTHING",
+		"DELETE FROM link_webhooks WHERE user_id=$1",
+	}
+	for _, query := range queries {
+		if _, err = tx.Exec(query, fromUserID, toUserID); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (d *DB) HasUser(userID string) (bool, error) {
+	var ok bool
+	err := d.db.QueryRow(
+		"SELECT EXISTS(SELECT 1 FROM urls WHERE user_id = $1) "+
+			"OR EXISTS(SELECT 1 FROM accounts WHERE id = $1) "+
+			"OR EXISTS(SELECT 1 FROM api_keys WHERE user_id = $1) "+
+			"OR EXISTS(SELECT 1 FROM workspace_members WHERE user_id = $1)",
+		userID,
+	).Scan(&ok)
+	return ok, err
+}
+
+func (d *DB) SetWorkspace(w *workspace.Workspace) error {
+	_, err := d.db.Exec(
+		"INSERT INTO workspaces(id, name, created) VALUES($1, $2, $3) "+
+			"ON CONFLICT (id) DO UPDATE SET name = EXCLUDED.name",
+		w.ID,
+		w.Name,
+		w.Created.Unix(),
+	)
+	return err
+}
+
+func (d *DB) GetWorkspace(id string) (*workspace.Workspace, error) {
+	var w workspace.Workspace
+	var created int64
+	err := d.db.QueryRow(
+		"SELECT id, name, created FROM workspaces WHERE id = $1", id,
+	).Scan(&w.ID, &w.Name, &created)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrWorkspaceNotFound
+		}
+		return nil, err
+	}
+	w.Created = time.Unix(created, 0)
+
+	return &w, nil
+}
+
+// SetMember and RemoveMember lock the workspace row,
+// so the concurrent changes of the members can not leave the workspace without an admin.
+func (d *DB) SetMember(m *workspace.Member) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	err = lockWorkspace(tx, m.WorkspaceID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(
+		"INSERT INTO workspace_members(workspace_id, user_id, role) VALUES($1, $2, $3) "+
+			"ON CONFLICT (workspace_id, user_id) DO UPDATE SET role = EXCLUDED.role",
+		m.WorkspaceID,
+		m.UserID,
+		string(m.Role),
+	)
+	if err != nil {
+		return err
+	}
+
+	err = checkAdmin(tx, m.WorkspaceID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (d *DB) GetMember(workspaceID, userID string) (*workspace.Member, error) {
+	m, err := scanMember(d.db.QueryRow(
+		"SELECT workspace_id, user_id, role FROM workspace_members WHERE workspace_id = $1 AND user_id = $2",
+		workspaceID,
+		userID,
+	))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrMemberNotFound
+		}
+		return nil, err
+	}
+
+	return m, nil
+}
+
+func (d *DB) GetMembers(workspaceID string) ([]*workspace.Member, error) {
+	return d.getMembers(
+		"SELECT workspace_id, user_id, role FROM workspace_members WHERE workspace_id = $1 ORDER BY user_id",
+		workspaceID,
+	)
+}
+
+func (d *DB) GetMembershipsByUserID(userID string) ([]*workspace.Member, error) {
+	return d.getMembers(
+		"SELECT workspace_id, user_id, role FROM workspace_members WHERE user_id = $1 ORDER BY workspace_id",
+		userID,
+	)
+}
+
+func (d *DB) getMembers(query, arg string) ([]*workspace.Member, error) {
+	rows, err := d.db.Query(query, arg)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	members := make([]*workspace.Member, 0)
+	for rows.Next() {
+		m, err := scanMember(rows)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, m)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return members, nil
+}
+
+func (d *DB) RemoveMember(workspaceID, userID string) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	err = lockWorkspace(tx, workspaceID)
+	if err != nil {
+		if errors.Is(err, ErrWorkspaceNotFound) {
+			return ErrMemberNotFound
+		}
+		return err
+	}
+
+	result, err := tx.Exec(
+		"DELETE FROM workspace_members WHERE workspace_id = $1 AND user_id = $2", workspaceID, userID,
+	)
+	if err != nil {
+		return err
+	}
+
+	num, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if num == 0 {
+		return ErrMemberNotFound
+	}
+
+	err = checkAdmin(tx, workspaceID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func lockWorkspace(tx *sql.Tx, workspaceID string) error {
+	var id string
+	err := tx.QueryRow("SELECT id FROM workspaces WHERE id = $1 FOR UPDATE", workspaceID).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrWorkspaceNotFound
+		}
+		return err
+	}
+
+	return nil
+}
+
+// checkAdmin checks that the workspace keeps an admin after the change of the members.
+func checkAdmin(tx *sql.Tx, workspaceID string) error {
+	var ok bool
+	err := tx.QueryRow(
+		"SELECT EXISTS(SELECT 1 FROM workspace_members WHERE workspace_id = $1 AND role = 'admin')", workspaceID,
+	).Scan(&ok)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrLastAdmin
+	}
+
+	return nil
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanAPIKey(row scanner) (*apikey.Key, error) {
+	var key apikey.Key
+	var created int64
+	err := row.Scan(&key.ID, &key.UserID, &key.Name, &key.Hash, &key.Hint, &created, &key.Revoked)
+	if err != nil {
+		return nil, err
+	}
+	key.Created = time.Unix(created, 0)
+
+	return &key, nil
+}
+
+func scanMember(row scanner) (*workspace.Member, error) {
+	var m workspace.Member
+	var role string
+	err := row.Scan(&m.WorkspaceID, &m.UserID, &role)
+	if err != nil {
+		return nil, err
+	}
+	m.Role = workspace.Role(role)
+
+	return &m, nil
+}
+
+// ownerPlaceholders returns the placeholders of the owner IDs numbered from first and the owner IDs as arguments.
+func ownerPlaceholders(ownerIDs []string, first int) (string, []interface{}) {
+	phs := make([]string, 0, len(ownerIDs))
+	args := make([]interface{}, 0, len(ownerIDs))
+	for i, ownerID := range ownerIDs {
+		phs = append(phs, "$"+strconv.Itoa(first+i))
+		args = append(args, ownerID)
+	}
+	return strings.Join(phs, ", "), args
+}
+
+// splitTags splits the tags aggregated by string_agg, tags have no commas.
+func splitTags(tags string) []string {
+	if tags == "" {
+		return nil
+	}
+	return strings.Split(tags, ",")
+}
+
+// likeEscaper escapes the wildcards of a LIKE pattern, the backslash is the default escape character.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// unixNano returns the nanoseconds of the time, 0 for the zero time.
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+// Close flushes the clicks and closes the database.
+func (d *DB) Close() error {
+	if err := d.FlushClicks(); err != nil {
+		logging.Errorf("Click flush: %v", err)
+	}
+	return d.db.Close()
+}
+
+func (d *DB) Ping(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+
+	return d.db.PingContext(ctx)
+}
+
+func (d *DB) GetRateBucket(key string) (ratelimit.Bucket, error) {
+	var b ratelimit.Bucket
+	var updated int64
+	err := d.db.QueryRow(
+		"SELECT tokens, updated FROM rate_buckets WHERE key = $1", key,
+	).Scan(&b.Tokens, &updated)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ratelimit.Bucket{}, nil
+		}
+		return ratelimit.Bucket{}, err
+	}
+	b.Updated = time.Unix(0, updated)
+
+	return b, nil
+}
+
+func (d *DB) SetRateBucket(key string, b ratelimit.Bucket) error {
+	_, err := d.db.Exec(
+		"INSERT INTO rate_buckets(key, tokens, updated) VALUES($1, $2, $3) "+
+			"ON CONFLICT (key) DO UPDATE SET tokens = EXCLUDED.tokens, updated = EXCLUDED.updated",
+		key,
+		b.Tokens,
+		b.Updated.UnixNano(),
+	)
+
+	return err
+}
+
+func (d *DB) AddQuotaUsage(key, day string, n int) (int, error) {
+	var used int
+	err := d.db.QueryRow(
+		"INSERT INTO quota_usage(key, day, used) VALUES($1, $2, $3) "+
+			"ON CONFLICT (key, day) DO UPDATE SET used = quota_usage.used + EXCLUDED.used RETURNING used",
+		key,
+		day,
+		n,
+	).Scan(&used)
+	if err != nil {
+		return 0, err
+	}
+
+	return used, nil
+}
+
+func (d *DB) PruneRateLimits(updatedBefore time.Time, day string) error {
+	_, err := d.db.Exec("DELETE FROM rate_buckets WHERE updated < $1", updatedBefore.UnixNano())
+	if err != nil {
+		return err
+	}
+
+	_, err = d.db.Exec("DELETE FROM quota_usage WHERE day < $1", day)
+
+	return err
+}
+
+func (d *DB) AddAuditEvents(events []*audit.Event) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(
+		"INSERT INTO audit_events(created, action, token, url, owner_id, actor_id, source, client_ip, request_id) " +
+			"VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id",
+	)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, e := range events {
+		err = stmt.QueryRow(
+			e.Time.UnixNano(), string(e.Action), e.Token, e.URL, e.OwnerID, e.ActorID, e.Source, e.ClientIP, e.RequestID,
+		).Scan(&e.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (d *DB) GetAuditEvents(filter audit.Filter) ([]*audit.Event, error) {
+	query := "SELECT id, created, action, token, url, owner_id, actor_id, source, client_ip, request_id FROM audit_events"
+	conditions := make([]string, 0, 2)
+	args := make([]interface{}, 0, 3)
+	if filter.Token != "" {
+		args = append(args, filter.Token)
+		conditions = append(conditions, fmt.Sprintf("token = $%d", len(args)))
+	}
+	if filter.UserID != "" {
+		args = append(args, filter.UserID)
+		conditions = append(conditions, fmt.Sprintf("(owner_id = $%d OR actor_id = $%d)", len(args), len(args)))
+	}
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	args = append(args, auditLimit(filter))
+	query += fmt.Sprintf(" ORDER BY id DESC LIMIT $%d", len(args))
+
+	rows, err := d.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	events := make([]*audit.Event, 0)
+	for rows.Next() {
+		var e audit.Event
+		var created int64
+		var action string
+		err = rows.Scan(
+			&e.ID, &created, &action, &e.Token, &e.URL, &e.OwnerID, &e.ActorID, &e.Source, &e.ClientIP, &e.RequestID,
+		)
+		if err != nil {
+			return nil, err
+		}
+		e.Time = time.Unix(0, created)
+		e.Action = audit.Action(action)
+		events = append(events, &e)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return events, nil
 }
@@ -3,12 +3,24 @@ package storage
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
+	"errors"
 	"regexp"
 	"testing"
 	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alrund/yp-1/internal/app/account"
+	"github.com/alrund/yp-1/internal/app/apikey"
+	"github.com/alrund/yp-1/internal/app/audit"
+	"github.com/alrund/yp-1/internal/app/linkcheck"
+	"github.com/alrund/yp-1/internal/app/linkmeta"
+	"github.com/alrund/yp-1/internal/app/listing"
+	"github.com/alrund/yp-1/internal/app/ratelimit"
+	"github.com/alrund/yp-1/internal/app/search"
 	tkn "github.com/alrund/yp-1/internal/app/token"
+	"github.com/alrund/yp-1/internal/app/workspace"
+	"github.com/jackc/pgconn"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -794,6 +806,50 @@ func TestDbPing(t *testing.T) {
 	}
 }
 
+func TestDbCheckMigrations(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	for _, query := range migratedColumns {
+		mock.ExpectQuery(regexp.QuoteMeta(query)).WillReturnRows(sqlmock.NewRows(nil))
+	}
+	mock.ExpectQuery(regexp.QuoteMeta(migratedColumns[0])).
+		WillReturnError(errors.New(`column removed does not exist`))
+
+	tests := []struct {
+		name    string
+		wantErr bool
+	}{
+		{
+			"success",
+			false,
+		},
+		{
+			"fail",
+			true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := &DB{db: db}
+			err := storage.CheckMigrations(context.Background())
+			if tt.wantErr {
+				assert.NotNil(t, err)
+			} else {
+				assert.Nil(t, err)
+			}
+		})
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
 func TestDbSet(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
@@ -804,8 +860,8 @@ func TestDbSet(t *testing.T) {
 	expireTime := time.Now().Add(tkn.LifeTime)
 
 	mock.ExpectBegin()
-	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO tokens(token, expire) VALUES($1, $2)")).
-		WithArgs("qwerty", expireTime.Unix()).
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO tokens(token, expire, created) VALUES($1, $2, $3)")).
+		WithArgs("qwerty", expireTime.Unix(), int64(0)).
 		WillReturnResult(sqlmock.NewResult(0, 1))
 	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO urls(id, url, token, user_id) VALUES($1, $2, $3, $4)")).
 		WillReturnResult(sqlmock.NewResult(0, 1))
@@ -857,14 +913,15 @@ func TestDbSetBatch(t *testing.T) {
 	defer db.Close()
 
 	expireTime := time.Now().Add(tkn.LifeTime)
+	createdTime := time.Now()
 
 	mock.ExpectBegin()
-	tokensStmt := mock.ExpectPrepare(regexp.QuoteMeta("INSERT INTO tokens(token, expire) VALUES($1, $2)"))
+	tokensStmt := mock.ExpectPrepare(regexp.QuoteMeta("INSERT INTO tokens(token, expire, created) VALUES($1, $2, $3)"))
 	urlsStmt := mock.ExpectPrepare(
 		regexp.QuoteMeta("INSERT INTO urls(id, url, token, user_id) VALUES($1, $2, $3, $4)"),
 	)
 	tokensStmt.ExpectExec().
-		WithArgs("qwerty", expireTime.Unix()).
+		WithArgs("qwerty", expireTime.Unix(), createdTime.UnixNano()).
 		WillReturnResult(sqlmock.NewResult(0, 1))
 	urlsStmt.ExpectExec().
 		WillReturnResult(sqlmock.NewResult(0, 1))
@@ -885,8 +942,9 @@ func TestDbSetBatch(t *testing.T) {
 				userID: "591c1645-e1bb-4f64-bf8e-7eef7e5bff94",
 				url2token: map[string]*tkn.Token{
 					"http://ya.ru": {
-						Value:  "qwerty",
-						Expire: expireTime,
+						Value:   "qwerty",
+						Expire:  expireTime,
+						Created: createdTime,
 					},
 				},
 			},
@@ -1052,3 +1110,768 @@ func TestDbGetUserIDCountFail(t *testing.T) {
 		})
 	}
 }
+
+func TestDbAPIKeys(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	created := time.Unix(time.Now().Unix(), 0)
+	key := &apikey.Key{ID: "1", UserID: "XXX-YYY-ZZZ", Name: "ci", Hash: "hash1", Hint: "sk_abcdef", Created: created}
+	columns := []string{"id", "user_id", "name", "key_hash", "hint", "created", "revoked"}
+
+	mock.ExpectExec("^INSERT INTO api_keys").
+		WithArgs("1", "XXX-YYY-ZZZ", "ci", "hash1", "sk_abcdef", created.Unix(), false).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("^SELECT (.+) FROM api_keys WHERE key_hash = (.+)").
+		WithArgs("hash1").
+		WillReturnRows(sqlmock.NewRows(columns).AddRow("1", "XXX-YYY-ZZZ", "ci", "hash1", "sk_abcdef", created.Unix(), false))
+	mock.ExpectQuery("^SELECT (.+) FROM api_keys WHERE key_hash = (.+)").
+		WithArgs("unknown").
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectQuery("^SELECT (.+) FROM api_keys WHERE user_id = (.+) ORDER BY created").
+		WithArgs("XXX-YYY-ZZZ").
+		WillReturnRows(sqlmock.NewRows(columns).AddRow("1", "XXX-YYY-ZZZ", "ci", "hash1", "sk_abcdef", created.Unix(), true))
+	mock.ExpectExec("^UPDATE api_keys SET revoked=true WHERE id = (.+) AND user_id = (.+)").
+		WithArgs("1", "XXX-YYY-ZZZ").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("^UPDATE api_keys SET revoked=true WHERE id = (.+) AND user_id = (.+)").
+		WithArgs("1", "AAA-BBB-CCC").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	storage := &DB{db: db}
+
+	require.NoError(t, storage.SetAPIKey(key))
+
+	got, err := storage.GetAPIKey("hash1")
+	require.NoError(t, err)
+	assert.Equal(t, key, got)
+
+	_, err = storage.GetAPIKey("unknown")
+	assert.ErrorIs(t, err, ErrAPIKeyNotFound)
+
+	keys, err := storage.GetAPIKeysByUserID("XXX-YYY-ZZZ")
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	assert.True(t, keys[0].Revoked)
+
+	assert.NoError(t, storage.RevokeAPIKey("1", "XXX-YYY-ZZZ"))
+	assert.ErrorIs(t, storage.RevokeAPIKey("1", "AAA-BBB-CCC"), ErrAPIKeyNotFound)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestDbAccounts(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	created := time.Unix(time.Now().Unix(), 0)
+	alice := &account.Account{ID: "XXX-YYY-ZZZ", Login: "alice", PasswordHash: "hash", Created: created}
+	columns := []string{"id", "login", "password_hash", "created"}
+
+	mock.ExpectExec("^INSERT INTO accounts").
+		WithArgs("XXX-YYY-ZZZ", "alice", "hash", created.Unix()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("^INSERT INTO accounts").
+		WithArgs("AAA-BBB-CCC", "alice", "hash", created.Unix()).
+		WillReturnError(&pgconn.PgError{Code: uniqueViolation})
+	mock.ExpectQuery("^SELECT (.+) FROM accounts WHERE login = (.+)").
+		WithArgs("alice").
+		WillReturnRows(sqlmock.NewRows(columns).AddRow("XXX-YYY-ZZZ", "alice", "hash", created.Unix()))
+	mock.ExpectQuery("^SELECT (.+) FROM accounts WHERE id = (.+)").
+		WithArgs("AAA-BBB-CCC").
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectBegin()
+	mock.ExpectExec("^UPDATE urls SET user_id=(.+) WHERE user_id=(.+)").
+		WithArgs("anonymous", "XXX-YYY-ZZZ").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("^UPDATE api_keys SET user_id=(.+) WHERE user_id=(.+)").
+		WithArgs("anonymous", "XXX-YYY-ZZZ").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(
+		"INSERT INTO workspace_members(workspace_id, user_id, role) "+
+			"SELECT workspace_id, $2, role FROM workspace_members WHERE user_id=$1 "+
+			"ON CONFLICT (workspace_id, user_id) DO UPDATE SET role = CASE "+
+			"WHEN array_position(ARRAY['viewer', 'editor', 'admin'], EXCLUDED.role::text) > "+
+			"array_position(ARRAY['viewer', 'editor', 'admin'], workspace_members.role::text) "+
+			"THEN EXCLUDED.role ELSE workspace_members.role END",
+	)).
+		WithArgs("anonymous", "XXX-YYY-ZZZ").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("^DELETE FROM workspace_members WHERE user_id=(.+)").
+		WithArgs("anonymous", "XXX-YYY-ZZZ").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(
+		"INSERT INTO link_webhooks(user_id, url) SELECT $2, url FROM link_webhooks WHERE user_id=$1 "+
+			"ON CONFLICT (user_id) DO NOTHING",
+	)).
+		WithArgs("anonymous", "XXX-YYY-ZZZ").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("^DELETE FROM link_webhooks WHERE user_id=(.+)").
+		WithArgs("anonymous", "XXX-YYY-ZZZ").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	storage := &DB{db: db}
+
+	require.NoError(t, storage.SetAccount(alice))
+	duplicate := *alice
+	duplicate.ID = "AAA-BBB-CCC"
+	assert.ErrorIs(t, storage.SetAccount(&duplicate), ErrAccountExists)
+
+	got, err := storage.GetAccountByLogin("alice")
+	require.NoError(t, err)
+	assert.Equal(t, alice, got)
+
+	_, err = storage.GetAccountByID("AAA-BBB-CCC")
+	assert.ErrorIs(t, err, ErrAccountNotFound)
+
+	assert.NoError(t, storage.ReassignUser("anonymous", "XXX-YYY-ZZZ"))
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestDbWorkspaces(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	created := time.Unix(time.Now().Unix(), 0)
+	team := &workspace.Workspace{ID: "WWW-WWW-WWW", Name: "team", Created: created}
+	admin := &workspace.Member{WorkspaceID: "WWW-WWW-WWW", UserID: "XXX-YYY-ZZZ", Role: workspace.RoleAdmin}
+	columns := []string{"workspace_id", "user_id", "role"}
+
+	mock.ExpectExec("^INSERT INTO workspaces").
+		WithArgs("WWW-WWW-WWW", "team", created.Unix()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("^SELECT (.+) FROM workspaces WHERE id = (.+)").
+		WithArgs("WWW-WWW-WWW").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "created"}).AddRow("WWW-WWW-WWW", "team", created.Unix()))
+	mock.ExpectQuery("^SELECT (.+) FROM workspaces WHERE id = (.+)").
+		WithArgs("unknown").
+		WillReturnError(sql.ErrNoRows)
+	lockQuery := regexp.QuoteMeta("SELECT id FROM workspaces WHERE id = $1 FOR UPDATE")
+	adminQuery := regexp.QuoteMeta("SELECT EXISTS(SELECT 1 FROM workspace_members WHERE workspace_id = $1 AND role = 'admin')")
+	mock.ExpectBegin()
+	mock.ExpectQuery(lockQuery).
+		WithArgs("WWW-WWW-WWW").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("WWW-WWW-WWW"))
+	mock.ExpectExec("^INSERT INTO workspace_members(.+) ON CONFLICT").
+		WithArgs("WWW-WWW-WWW", "XXX-YYY-ZZZ", "admin").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery(adminQuery).
+		WithArgs("WWW-WWW-WWW").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectQuery(lockQuery).
+		WithArgs("unknown").
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectRollback()
+	mock.ExpectBegin()
+	mock.ExpectQuery(lockQuery).
+		WithArgs("WWW-WWW-WWW").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("WWW-WWW-WWW"))
+	mock.ExpectExec("^INSERT INTO workspace_members(.+) ON CONFLICT").
+		WithArgs("WWW-WWW-WWW", "XXX-YYY-ZZZ", "viewer").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery(adminQuery).
+		WithArgs("WWW-WWW-WWW").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectRollback()
+	mock.ExpectQuery("^SELECT (.+) FROM workspace_members WHERE workspace_id = (.+) AND user_id = (.+)").
+		WithArgs("WWW-WWW-WWW", "AAA-BBB-CCC").
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectQuery("^SELECT (.+) FROM workspace_members WHERE workspace_id = (.+) ORDER BY user_id").
+		WithArgs("WWW-WWW-WWW").
+		WillReturnRows(sqlmock.NewRows(columns).AddRow("WWW-WWW-WWW", "XXX-YYY-ZZZ", "admin"))
+	mock.ExpectQuery("^SELECT (.+) FROM workspace_members WHERE user_id = (.+) ORDER BY workspace_id").
+		WithArgs("XXX-YYY-ZZZ").
+		WillReturnRows(sqlmock.NewRows(columns).AddRow("WWW-WWW-WWW", "XXX-YYY-ZZZ", "admin"))
+	mock.ExpectBegin()
+	mock.ExpectQuery(lockQuery).
+		WithArgs("WWW-WWW-WWW").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("WWW-WWW-WWW"))
+	mock.ExpectExec("^DELETE FROM workspace_members").
+		WithArgs("WWW-WWW-WWW", "AAA-BBB-CCC").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+	mock.ExpectBegin()
+	mock.ExpectQuery(lockQuery).
+		WithArgs("WWW-WWW-WWW").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("WWW-WWW-WWW"))
+	mock.ExpectExec("^DELETE FROM workspace_members").
+		WithArgs("WWW-WWW-WWW", "XXX-YYY-ZZZ").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(adminQuery).
+		WithArgs("WWW-WWW-WWW").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectRollback()
+	mock.ExpectQuery("^SELECT EXISTS(.+) FROM urls WHERE user_id = (.+) OR EXISTS").
+		WithArgs("XXX-YYY-ZZZ").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	storage := &DB{db: db}
+
+	require.NoError(t, storage.SetWorkspace(team))
+
+	got, err := storage.GetWorkspace("WWW-WWW-WWW")
+	require.NoError(t, err)
+	assert.Equal(t, team, got)
+	_, err = storage.GetWorkspace("unknown")
+	assert.ErrorIs(t, err, ErrWorkspaceNotFound)
+
+	require.NoError(t, storage.SetMember(admin))
+	assert.ErrorIs(t, storage.SetMember(&workspace.Member{WorkspaceID: "unknown", UserID: "XXX-YYY-ZZZ", Role: workspace.RoleAdmin}), ErrWorkspaceNotFound)
+	assert.ErrorIs(t, storage.SetMember(&workspace.Member{WorkspaceID: "WWW-WWW-WWW", UserID: "XXX-YYY-ZZZ", Role: workspace.RoleViewer}), ErrLastAdmin)
+
+	_, err = storage.GetMember("WWW-WWW-WWW", "AAA-BBB-CCC")
+	assert.ErrorIs(t, err, ErrMemberNotFound)
+
+	members, err := storage.GetMembers("WWW-WWW-WWW")
+	require.NoError(t, err)
+	assert.Equal(t, []*workspace.Member{admin}, members)
+
+	memberships, err := storage.GetMembershipsByUserID("XXX-YYY-ZZZ")
+	require.NoError(t, err)
+	assert.Equal(t, []*workspace.Member{admin}, memberships)
+
+	assert.ErrorIs(t, storage.RemoveMember("WWW-WWW-WWW", "AAA-BBB-CCC"), ErrMemberNotFound)
+	assert.ErrorIs(t, storage.RemoveMember("WWW-WWW-WWW", "XXX-YYY-ZZZ"), ErrLastAdmin)
+
+	ok, err := storage.HasUser("XXX-YYY-ZZZ")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestDbRateLimits(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	updated := time.Unix(0, time.Now().UnixNano())
+
+	mock.ExpectQuery("^SELECT tokens, updated FROM rate_buckets WHERE key = (.+)").
+		WithArgs("create:user:1").
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectExec("^INSERT INTO rate_buckets(.+) ON CONFLICT").
+		WithArgs("create:user:1", 1.5, updated.UnixNano()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("^SELECT tokens, updated FROM rate_buckets WHERE key = (.+)").
+		WithArgs("create:user:1").
+		WillReturnRows(sqlmock.NewRows([]string{"tokens", "updated"}).AddRow(1.5, updated.UnixNano()))
+	mock.ExpectQuery("^INSERT INTO quota_usage(.+) ON CONFLICT (.+) RETURNING used").
+		WithArgs("user:1", "2022-11-20", 2).
+		WillReturnRows(sqlmock.NewRows([]string{"used"}).AddRow(5))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM rate_buckets WHERE updated < $1")).
+		WithArgs(updated.UnixNano()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM quota_usage WHERE day < $1")).
+		WithArgs("2022-11-21").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	storage := &DB{db: db}
+
+	b, err := storage.GetRateBucket("create:user:1")
+	require.NoError(t, err)
+	assert.Equal(t, ratelimit.Bucket{}, b)
+
+	require.NoError(t, storage.SetRateBucket("create:user:1", ratelimit.Bucket{Tokens: 1.5, Updated: updated}))
+
+	b, err = storage.GetRateBucket("create:user:1")
+	require.NoError(t, err)
+	assert.Equal(t, 1.5, b.Tokens)
+	assert.True(t, b.Updated.Equal(updated))
+
+	used, err := storage.AddQuotaUsage("user:1", "2022-11-20", 2)
+	require.NoError(t, err)
+	assert.Equal(t, 5, used)
+
+	require.NoError(t, storage.PruneRateLimits(updated, "2022-11-21"))
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestDbAuditEvents(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	created := time.Unix(0, time.Now().UnixNano())
+	event := &audit.Event{
+		Time:      created,
+		Action:    audit.ActionCreate,
+		Token:     "qwerty",
+		URL:       "http://ya.ru",
+		OwnerID:   "XXX-YYY-ZZZ",
+		ActorID:   "XXX-YYY-ZZZ",
+		Source:    audit.SourceHTTP,
+		ClientIP:  "10.0.0.1",
+		RequestID: "req",
+	}
+	columns := []string{
+		"id", "created", "action", "token", "url", "owner_id", "actor_id", "source", "client_ip", "request_id",
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectPrepare("^INSERT INTO audit_events").
+		ExpectQuery().
+		WithArgs(created.UnixNano(), "create", "qwerty", "http://ya.ru", "XXX-YYY-ZZZ", "XXX-YYY-ZZZ", "http", "10.0.0.1", "req").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+	mock.ExpectCommit()
+	mock.ExpectQuery(`^SELECT (.+) FROM audit_events WHERE token = \$1 AND \(owner_id = \$2 OR actor_id = \$2\) ORDER BY id DESC LIMIT \$3`).
+		WithArgs("qwerty", "XXX-YYY-ZZZ", audit.DefaultLimit).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(
+			7, created.UnixNano(), "create", "qwerty", "http://ya.ru", "XXX-YYY-ZZZ", "XXX-YYY-ZZZ", "http", "10.0.0.1", "req",
+		))
+
+	storage := &DB{db: db}
+
+	require.NoError(t, storage.AddAuditEvents([]*audit.Event{event}))
+	assert.Equal(t, int64(7), event.ID)
+
+	events, err := storage.GetAuditEvents(audit.Filter{Token: "qwerty", UserID: "XXX-YYY-ZZZ"})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, event.Action, events[0].Action)
+	assert.Equal(t, event.RequestID, events[0].RequestID)
+	assert.True(t, events[0].Time.Equal(created))
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestDbURLsPage(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	created := time.Unix(0, time.Now().UnixNano())
+	expire := time.Unix(time.Now().Add(tkn.LifeTime).Unix(), 0)
+	checked := time.Unix(0, time.Now().UnixNano())
+	columns := []string{
+		"token", "url", "user_id", "created", "expire", "removed", "clicks", "title", "notes", "tags",
+		"checked", "status_code", "reachable", "error",
+	}
+	query := listing.Query{Sort: listing.SortClicksDesc, Contains: "50%", Limit: 1}
+	cursor := query.NextCursor(listing.Item{Token: "asdfgh", Clicks: 3})
+
+	mock.ExpectQuery(regexp.QuoteMeta(
+		"SELECT t.token, u.url, u.user_id, t.created, t.expire, t.removed, t.clicks, "+
+			"COALESCE(m.title, ''), COALESCE(m.notes, ''), "+
+			"COALESCE((SELECT string_agg(lt.tag, ',' ORDER BY lt.tag) FROM link_tags lt WHERE lt.token = t.token), ''), "+
+			"COALESCE(h.checked, 0), COALESCE(h.status_code, 0), COALESCE(h.reachable, FALSE), COALESCE(h.error, '') "+
+			"FROM urls u JOIN tokens t ON t.token = u.token LEFT JOIN link_meta m ON m.token = t.token "+
+			"LEFT JOIN link_health h ON h.token = t.token "+
+			"WHERE u.user_id IN ($1, $2) AND u.url ILIKE $3 ORDER BY t.clicks DESC, t.token DESC LIMIT $4",
+	)).
+		WithArgs("XXX-YYY-ZZZ", "WORKSPACE", `%50\%%`, 2).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(
+				"qwerty", "http://ya.ru/50%", "XXX-YYY-ZZZ", created.UnixNano(), expire.Unix(), false, 5, "Ya", "", "promo,ru",
+				checked.UnixNano(), 404, false, "",
+			).
+			AddRow("asdfgh", "http://go.dev/50%", "WORKSPACE", 0, expire.Unix(), true, 3, "", "", "", 0, 0, false, ""),
+		)
+	mock.ExpectQuery(regexp.QuoteMeta(
+		"WHERE u.user_id IN ($1) AND t.removed "+
+			"AND EXISTS (SELECT 1 FROM link_tags lt WHERE lt.token = t.token AND lt.tag = $2) "+
+			"AND (t.clicks, t.token) < ($3, $4) ORDER BY t.clicks DESC",
+	)).
+		WithArgs("XXX-YYY-ZZZ", "promo", int64(3), "asdfgh", listing.DefaultLimit+1).
+		WillReturnRows(sqlmock.NewRows(columns))
+	clicksQuery := regexp.QuoteMeta(
+		"UPDATE tokens SET clicks = tokens.clicks + tmp.n FROM (VALUES ($1, $2::bigint),($3, $4::bigint)) " +
+			"AS tmp (token, n) WHERE tokens.token = tmp.token",
+	)
+	mock.ExpectExec(clicksQuery).
+		WithArgs("asdfgh", int64(1), "qwerty", int64(2)).
+		WillReturnError(sql.ErrConnDone)
+	mock.ExpectExec(clicksQuery).
+		WithArgs("asdfgh", int64(1), "qwerty", int64(3)).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	storage := &DB{db: db}
+
+	items, next, err := storage.GetURLsPage([]string{"XXX-YYY-ZZZ", "WORKSPACE"}, query)
+	require.NoError(t, err)
+	assert.Equal(t, []listing.Item{{
+		Token: "qwerty", URL: "http://ya.ru/50%", OwnerID: "XXX-YYY-ZZZ", Created: created, Expire: expire, Clicks: 5,
+		Title: "Ya", Tags: []string{"promo", "ru"}, Health: linkcheck.Health{Checked: checked, StatusCode: 404},
+	}}, items)
+	assert.Equal(t, query.NextCursor(items[0]), next)
+
+	items, next, err = storage.GetURLsPage(
+		[]string{"XXX-YYY-ZZZ"},
+		listing.Query{Sort: listing.SortClicksDesc, Status: listing.StatusRemoved, Tag: "promo", Cursor: cursor},
+	)
+	require.NoError(t, err)
+	assert.Empty(t, items)
+	assert.Empty(t, next)
+
+	// The clicks are counted in memory, a failed flush keeps them for the next one.
+	require.NoError(t, storage.AddClick("qwerty"))
+	require.NoError(t, storage.AddClick("qwerty"))
+	require.NoError(t, storage.AddClick("asdfgh"))
+	assert.ErrorIs(t, storage.FlushClicks(), sql.ErrConnDone)
+	require.NoError(t, storage.AddClick("qwerty"))
+	require.NoError(t, storage.FlushClicks())
+	require.NoError(t, storage.FlushClicks(), "nothing to flush")
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestDbLinkMeta(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(
+		"INSERT INTO link_meta(token, title, notes) VALUES($1, $2, $3) "+
+			"ON CONFLICT (token) DO UPDATE SET title = EXCLUDED.title, notes = EXCLUDED.notes",
+	)).
+		WithArgs("qwerty", "Ya", "docs").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM link_tags WHERE token = $1")).
+		WithArgs("qwerty").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO link_tags(token, tag) VALUES($1, $2)")).
+		WithArgs("qwerty", "promo").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO link_tags(token, tag) VALUES($1, $2)")).
+		WithArgs("qwerty", "ru").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT title, notes FROM link_meta WHERE token = $1")).
+		WithArgs("qwerty").
+		WillReturnRows(sqlmock.NewRows([]string{"title", "notes"}).AddRow("Ya", "docs"))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT tag FROM link_tags WHERE token = $1 ORDER BY tag")).
+		WithArgs("qwerty").
+		WillReturnRows(sqlmock.NewRows([]string{"tag"}).AddRow("promo").AddRow("ru"))
+
+	mock.ExpectQuery(regexp.QuoteMeta(
+		"SELECT lt.tag, count(*) FROM link_tags lt JOIN urls u ON u.token = lt.token JOIN tokens t ON t.token = lt.token "+
+			"WHERE u.user_id IN ($1, $2) AND NOT t.removed GROUP BY lt.tag ORDER BY lt.tag",
+	)).
+		WithArgs("XXX-YYY-ZZZ", "WORKSPACE").
+		WillReturnRows(sqlmock.NewRows([]string{"tag", "count"}).AddRow("promo", 1).AddRow("ru", 2))
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(
+		"INSERT INTO link_tags(token, tag) SELECT lt.token, $2 FROM link_tags lt JOIN urls u ON u.token = lt.token "+
+			"WHERE lt.tag = $1 AND u.user_id IN ($3) ON CONFLICT DO NOTHING",
+	)).
+		WithArgs("ru", "web", "XXX-YYY-ZZZ").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(
+		"DELETE FROM link_tags lt USING urls u WHERE u.token = lt.token AND lt.tag = $1 AND lt.tag <> $2 "+
+			"AND u.user_id IN ($3)",
+	)).
+		WithArgs("ru", "web", "XXX-YYY-ZZZ").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	storage := &DB{db: db}
+
+	m := linkmeta.Meta{Title: "Ya", Notes: "docs", Tags: []string{"promo", "ru"}}
+	require.NoError(t, storage.SetLinkMeta("qwerty", m))
+
+	got, err := storage.GetLinkMeta("qwerty")
+	require.NoError(t, err)
+	assert.Equal(t, m, got)
+
+	tags, err := storage.GetTags([]string{"XXX-YYY-ZZZ", "WORKSPACE"})
+	require.NoError(t, err)
+	assert.Equal(t, []linkmeta.TagCount{{Tag: "promo", Count: 1}, {Tag: "ru", Count: 2}}, tags)
+
+	renamed, err := storage.RenameTag([]string{"XXX-YYY-ZZZ"}, "ru", "web")
+	require.NoError(t, err)
+	assert.Equal(t, 2, renamed)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestDbUpdateLinkMeta(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	selectOwned := regexp.QuoteMeta(
+		"SELECT t.token FROM tokens t JOIN urls u ON u.token = t.token " +
+			"WHERE t.token = $1 AND u.user_id IN ($2, $3) AND NOT t.removed FOR UPDATE OF t",
+	)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(selectOwned).
+		WithArgs("qwerty", "XXX-YYY-ZZZ", "WORKSPACE").
+		WillReturnRows(sqlmock.NewRows([]string{"token"}).AddRow("qwerty"))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT title, notes FROM link_meta WHERE token = $1")).
+		WithArgs("qwerty").
+		WillReturnRows(sqlmock.NewRows([]string{"title", "notes"}).AddRow("Ya", ""))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT tag FROM link_tags WHERE token = $1 ORDER BY tag")).
+		WithArgs("qwerty").
+		WillReturnRows(sqlmock.NewRows([]string{"tag"}).AddRow("ru"))
+	mock.ExpectExec(regexp.QuoteMeta(
+		"INSERT INTO link_meta(token, title, notes) VALUES($1, $2, $3) "+
+			"ON CONFLICT (token) DO UPDATE SET title = EXCLUDED.title, notes = EXCLUDED.notes",
+	)).
+		WithArgs("qwerty", "Ya", "docs").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM link_tags WHERE token = $1")).
+		WithArgs("qwerty").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO link_tags(token, tag) VALUES($1, $2)")).
+		WithArgs("qwerty", "ru").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(selectOwned).
+		WithArgs("asdfgh", "XXX-YYY-ZZZ", "WORKSPACE").
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectRollback()
+
+	storage := &DB{db: db}
+
+	notes := "docs"
+	m, err := storage.UpdateLinkMeta([]string{"XXX-YYY-ZZZ", "WORKSPACE"}, "qwerty", linkmeta.Update{Notes: &notes})
+	require.NoError(t, err)
+	assert.Equal(t, linkmeta.Meta{Title: "Ya", Notes: "docs", Tags: []string{"ru"}}, m)
+
+	_, err = storage.UpdateLinkMeta([]string{"XXX-YYY-ZZZ", "WORKSPACE"}, "asdfgh", linkmeta.Update{Notes: &notes})
+	assert.ErrorIs(t, err, ErrTokenNotFound)
+
+	_, err = storage.UpdateLinkMeta(nil, "qwerty", linkmeta.Update{Notes: &notes})
+	assert.ErrorIs(t, err, ErrTokenNotFound)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestDbSearchURLs(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	expire := time.Unix(time.Now().Add(tkn.LifeTime).Unix(), 0)
+	columns := []string{
+		"token", "url", "user_id", "created", "expire", "removed", "clicks", "title", "notes", "tags",
+		"checked", "status_code", "reachable", "error",
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(
+		"SELECT t.token, u.url, u.user_id, t.created, t.expire, t.removed, t.clicks, "+
+			"COALESCE(m.title, ''), COALESCE(m.notes, ''), "+
+			"COALESCE((SELECT string_agg(lt.tag, ',' ORDER BY lt.tag) FROM link_tags lt WHERE lt.token = t.token), ''), "+
+			"COALESCE(h.checked, 0), COALESCE(h.status_code, 0), COALESCE(h.reachable, FALSE), COALESCE(h.error, '') "+
+			"FROM urls u JOIN tokens t ON t.token = u.token LEFT JOIN link_meta m ON m.token = t.token "+
+			"LEFT JOIN link_health h ON h.token = t.token "+
+			"JOIN link_search s ON s.token = t.token "+
+			"WHERE s.document @@ to_tsquery('simple', $1) AND NOT t.removed AND u.user_id IN ($2, $3) "+
+			"ORDER BY ts_rank(s.document, to_tsquery('simple', $1)) DESC, t.token LIMIT $4",
+	)).
+		WithArgs("ya:* & ru:*", "XXX-YYY-ZZZ", "WORKSPACE", 5).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow("qwerty", "http://ya.ru", "XXX-YYY-ZZZ", 0, expire.Unix(), false, 2, "Ya", "", "search", 0, 0, false, ""),
+		)
+
+	storage := &DB{db: db}
+
+	items, err := storage.SearchURLs([]string{"XXX-YYY-ZZZ", "WORKSPACE"}, search.Query{Text: "Ya.ru ya", Limit: 5})
+	require.NoError(t, err)
+	assert.Equal(t, []listing.Item{{
+		Token: "qwerty", URL: "http://ya.ru", OwnerID: "XXX-YYY-ZZZ", Expire: expire, Clicks: 2,
+		Title: "Ya", Tags: []string{"search"},
+	}}, items)
+
+	items, err = storage.SearchURLs(nil, search.Query{Text: "ya"})
+	require.NoError(t, err)
+	assert.Empty(t, items)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+// passStrings lets the string slices reach the mock as pgx takes them for ANY($1).
+type passStrings struct{}
+
+func (passStrings) ConvertValue(v interface{}) (driver.Value, error) {
+	if values, ok := v.([]string); ok {
+		return values, nil
+	}
+	return driver.DefaultParameterConverter.ConvertValue(v)
+}
+
+func TestDbGetURLsByTokens(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.ValueConverterOption(passStrings{}))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	expire := time.Unix(time.Now().Add(tkn.LifeTime).Unix(), 0)
+	tokenValues := []string{"qwerty", "asdfgh", "unknown"}
+
+	mock.ExpectQuery(regexp.QuoteMeta(
+		"SELECT t.token, u.url, u.user_id, t.created, t.expire, t.removed " +
+			"FROM tokens t JOIN urls u ON u.token = t.token WHERE t.token = ANY($1)",
+	)).
+		WithArgs(tokenValues).
+		WillReturnRows(sqlmock.NewRows([]string{"token", "url", "user_id", "created", "expire", "removed"}).
+			AddRow("qwerty", "http://ya.ru", "XXX-YYY-ZZZ", 0, expire.Unix(), false).
+			AddRow("asdfgh", "http://go.dev", "AAA-BBB-CCC", 0, expire.Unix(), true),
+		)
+
+	storage := &DB{db: db}
+
+	items, err := storage.GetURLsByTokens(tokenValues)
+	require.NoError(t, err)
+	assert.Equal(t, []listing.Item{
+		{Token: "qwerty", URL: "http://ya.ru", OwnerID: "XXX-YYY-ZZZ", Expire: expire},
+		{Token: "asdfgh", URL: "http://go.dev", OwnerID: "AAA-BBB-CCC", Expire: expire, Removed: true},
+	}, items)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestDbLinkHealth(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	expire := time.Unix(time.Now().Add(tkn.LifeTime).Unix(), 0)
+	checked := time.Unix(0, time.Now().Add(-time.Hour).UnixNano())
+	checkedBefore := time.Now()
+	columns := []string{
+		"token", "url", "user_id", "created", "expire", "removed", "clicks", "title", "notes", "tags",
+		"checked", "status_code", "reachable", "error",
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(
+		"LEFT JOIN link_health h ON h.token = t.token "+
+			"WHERE NOT t.removed AND t.expire > $1 AND COALESCE(h.checked, 0) < $2 "+
+			"ORDER BY COALESCE(h.checked, 0), t.token LIMIT $3",
+	)).
+		WithArgs(sqlmock.AnyArg(), checkedBefore.UnixNano(), 10).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow("asdfgh", "http://go.dev", "XXX-YYY-ZZZ", 0, expire.Unix(), false, 0, "", "", "", 0, 0, false, "").
+			AddRow("qwerty", "http://ya.ru", "XXX-YYY-ZZZ", 0, expire.Unix(), false, 0, "", "", "", checked.UnixNano(), 200, true, ""),
+		)
+	mock.ExpectBegin()
+	upsert := regexp.QuoteMeta(
+		"INSERT INTO link_health(token, checked, status_code, reachable, error) VALUES($1, $2, $3, $4, $5) " +
+			"ON CONFLICT (token) DO UPDATE SET checked = EXCLUDED.checked, status_code = EXCLUDED.status_code, " +
+			"reachable = EXCLUDED.reachable, error = EXCLUDED.error",
+	)
+	mock.ExpectExec(upsert).
+		WithArgs("asdfgh", checked.UnixNano(), 0, false, "timeout").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(upsert).
+		WithArgs("qwerty", checked.UnixNano(), 503, false, "").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	storage := &DB{db: db}
+
+	items, err := storage.GetLinksToCheck(checkedBefore, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []listing.Item{
+		{Token: "asdfgh", URL: "http://go.dev", OwnerID: "XXX-YYY-ZZZ", Expire: expire},
+		{
+			Token: "qwerty", URL: "http://ya.ru", OwnerID: "XXX-YYY-ZZZ", Expire: expire,
+			Health: linkcheck.Health{Checked: checked, StatusCode: 200, Reachable: true},
+		},
+	}, items)
+
+	err = storage.SetLinkHealth(map[string]linkcheck.Health{
+		"qwerty": {Checked: checked, StatusCode: 503},
+		"asdfgh": {Checked: checked, Error: "timeout"},
+	})
+	require.NoError(t, err)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestDbLinkWebhooks(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(regexp.QuoteMeta(
+		"INSERT INTO link_webhooks(user_id, url) VALUES($1, $2) "+
+			"ON CONFLICT (user_id) DO UPDATE SET url = EXCLUDED.url",
+	)).
+		WithArgs("XXX-YYY-ZZZ", "https://hooks.example.com/1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT url FROM link_webhooks WHERE user_id = $1")).
+		WithArgs("XXX-YYY-ZZZ").
+		WillReturnRows(sqlmock.NewRows([]string{"url"}).AddRow("https://hooks.example.com/1"))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM link_webhooks WHERE user_id = $1")).
+		WithArgs("XXX-YYY-ZZZ").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT url FROM link_webhooks WHERE user_id = $1")).
+		WithArgs("XXX-YYY-ZZZ").
+		WillReturnError(sql.ErrNoRows)
+
+	storage := &DB{db: db}
+
+	require.NoError(t, storage.SetLinkWebhook("XXX-YYY-ZZZ", "https://hooks.example.com/1"))
+	url, err := storage.GetLinkWebhook("XXX-YYY-ZZZ")
+	require.NoError(t, err)
+	assert.Equal(t, "https://hooks.example.com/1", url)
+
+	require.NoError(t, storage.SetLinkWebhook("XXX-YYY-ZZZ", ""))
+	url, err = storage.GetLinkWebhook("XXX-YYY-ZZZ")
+	require.NoError(t, err)
+	assert.Empty(t, url)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
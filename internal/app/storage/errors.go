@@ -3,7 +3,14 @@ package storage
 import "errors"
 
 var (
-	ErrURLAlreadyExists = errors.New("url already exists")
-	ErrURLNotFound      = errors.New("url not found")
-	ErrTokenNotFound    = errors.New("token not found")
+	ErrURLAlreadyExists  = errors.New("url already exists")
+	ErrURLNotFound       = errors.New("url not found")
+	ErrTokenNotFound     = errors.New("token not found")
+	ErrAPIKeyNotFound    = errors.New("api key not found")
+	ErrAccountNotFound   = errors.New("account not found")
+	ErrAccountExists     = errors.New("account already exists")
+	ErrWorkspaceNotFound = errors.New("workspace not found")
+	ErrMemberNotFound    = errors.New("workspace member not found")
+	ErrLastAdmin         = errors.New("workspace must keep an admin")
+	ErrTagNotFound       = errors.New("tag not found")
 )
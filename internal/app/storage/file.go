@@ -1,36 +1,125 @@
 package storage
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
+	"io"
 	"os"
 	"sync"
+	"time"
 
+	"github.com/alrund/yp-1/internal/app/account"
+	"github.com/alrund/yp-1/internal/app/apikey"
+	"github.com/alrund/yp-1/internal/app/audit"
+	"github.com/alrund/yp-1/internal/app/linkcheck"
+	"github.com/alrund/yp-1/internal/app/linkmeta"
+	"github.com/alrund/yp-1/internal/app/listing"
+	"github.com/alrund/yp-1/internal/app/logging"
+	"github.com/alrund/yp-1/internal/app/ratelimit"
+	"github.com/alrund/yp-1/internal/app/search"
 	tkn "github.com/alrund/yp-1/internal/app/token"
+	"github.com/alrund/yp-1/internal/app/workspace"
 )
 
+// dataFileSuffix names the file next to the URLs file, which keeps everything but the URLs.
+// So the URLs file keeps its original format.
+const dataFileSuffix = ".data"
+
+// clicksFileSuffix names the file of the click totals, a JSON object by the token.
+// The redirects are counted in memory, the totals are written by FlushClicks.
+// The file of the former format, one token a line for each redirect, is read too.
+const clicksFileSuffix = ".clicks"
+
+// auditFileSuffix names the append-only file of the audit events, one JSON object a line.
+// The events are not kept in memory, the queries read the file from the end.
+const auditFileSuffix = ".audit"
+
+// auditReadChunk the size of the blocks the audit file is read backwards with.
+const auditReadChunk = 64 << 10
+
+// fileData the state of the data file.
+type fileData struct {
+	APIKeys    map[string]*apikey.Key               `json:"api_keys"`
+	Accounts   map[string]*account.Account          `json:"accounts"`
+	Workspaces map[string]*workspace.Workspace      `json:"workspaces"`
+	Members    map[string]map[string]workspace.Role `json:"members"` // workspace ID: user ID: role
+	QuotaDay   string                               `json:"quota_day"`
+	QuotaUsage map[string]int                       `json:"quota_usage"`
+	LinkMeta   map[string]linkmeta.Meta             `json:"link_meta"`   // token: meta
+	LinkHealth map[string]linkcheck.Health          `json:"link_health"` // token: the last check
+	Webhooks   map[string]string                    `json:"webhooks"`    // user ID: the link check webhook URL
+}
+
 // File storage.
+// The rate buckets are kept in memory, writing the file on every request would cost too much.
 type File struct {
-	FileName string
-	state    map[string]composite
-	stateMx  sync.RWMutex
-	mx       sync.RWMutex
+	FileName      string
+	state         map[string]composite
+	data          fileData
+	apiKeyHashes  map[string]string // key hash: key ID, rebuilt on the start
+	accountLogins map[string]string // login: account ID, rebuilt on the start
+	rateBuckets   map[string]ratelimit.Bucket
+	auditCount    int64 // the events in the audit file, the last event ID
+	auditSize     int64 // the size of the complete lines of the audit file
+	auditMx       sync.RWMutex
+	clicks        map[string]int64
+	clicksDirty   bool // the clicks changed since the last flush
+	clicksMx      sync.RWMutex
+	flushMx       sync.Mutex
+	index         *search.Index // the links which are not removed, rebuilt on the start
+	stateMx       sync.RWMutex
+	mx            sync.RWMutex
 }
 
 func NewFile(fileName string) (*File, error) {
 	file := &File{
-		FileName: fileName,
-		state:    make(map[string]composite),
+		FileName:      fileName,
+		state:         make(map[string]composite),
+		data:          newFileData(),
+		apiKeyHashes:  make(map[string]string),
+		accountLogins: make(map[string]string),
+		rateBuckets:   make(map[string]ratelimit.Bucket),
+		clicks:        make(map[string]int64),
+		index:         search.NewIndex(),
 	}
 
 	if err := file.restoreState(); err != nil {
 		return nil, err
 	}
 
+	if err := file.restoreData(); err != nil {
+		return nil, err
+	}
+
+	if err := file.restoreAuditEvents(); err != nil {
+		return nil, err
+	}
+
+	if err := file.restoreClicks(); err != nil {
+		return nil, err
+	}
+
+	file.restoreIndex()
+
 	return file, nil
 }
 
+func newFileData() fileData {
+	return fileData{
+		APIKeys:    make(map[string]*apikey.Key),
+		Accounts:   make(map[string]*account.Account),
+		Workspaces: make(map[string]*workspace.Workspace),
+		Members:    make(map[string]map[string]workspace.Role),
+		QuotaUsage: make(map[string]int),
+		LinkMeta:   make(map[string]linkmeta.Meta),
+		LinkHealth: make(map[string]linkcheck.Health),
+		Webhooks:   make(map[string]string),
+	}
+}
+
 func (s *File) GetURLCount() (int, error) {
 	s.stateMx.RLock()
 	defer s.stateMx.RUnlock()
@@ -64,10 +153,14 @@ func (s *File) Set(userID string, url string, token *tkn.Token) error {
 	defer s.stateMx.Unlock()
 
 	composite := s.state[url]
+	if composite.Token != nil && composite.Token.Value != token.Value {
+		s.index.Remove(composite.Token.Value)
+	}
 	composite.Token = token
 	composite.URL = url
 	composite.UserID = userID
 	s.state[url] = composite
+	s.indexLink(&composite)
 
 	return s.saveState()
 }
@@ -99,6 +192,7 @@ func (s *File) RemoveTokens(tokenValues []string, userID string) error {
 		for _, tokenValue := range tokenValues {
 			if tokenValue == composite.Token.Value {
 				composite.Token.Removed = true
+				s.index.Remove(tokenValue)
 			}
 		}
 	}
@@ -220,6 +314,191 @@ func (s *File) GetURLsByUserID(userID string) ([]URLpairs, error) {
 	return nil, ErrURLNotFound
 }
 
+// GetURLsPage returns a page of the URLs of the owners and the cursor of the next page.
+func (s *File) GetURLsPage(ownerIDs []string, query listing.Query) ([]listing.Item, string, error) {
+	s.stateMx.RLock()
+	defer s.stateMx.RUnlock()
+	s.clicksMx.RLock()
+	defer s.clicksMx.RUnlock()
+
+	composites := s.ownedComposites(ownerIDs)
+	items := make([]listing.Item, 0, len(composites))
+	for _, c := range composites {
+		items = append(items, newListingItem(c, s.clicks[c.Token.Value], s.data.LinkMeta[c.Token.Value], s.data.LinkHealth[c.Token.Value]))
+	}
+
+	return query.Page(items, time.Now())
+}
+
+// GetURLsByTokens returns the links of the tokens without the clicks and the meta, the unknown tokens are skipped.
+func (s *File) GetURLsByTokens(tokenValues []string) ([]listing.Item, error) {
+	s.stateMx.RLock()
+	defer s.stateMx.RUnlock()
+
+	wanted := make(map[string]struct{}, len(tokenValues))
+	for _, tokenValue := range tokenValues {
+		wanted[tokenValue] = struct{}{}
+	}
+
+	items := make([]listing.Item, 0, len(wanted))
+	for _, composite := range s.state {
+		if composite.Token == nil {
+			continue
+		}
+		if _, ok := wanted[composite.Token.Value]; ok {
+			c := composite
+			items = append(items, newListingItem(&c, 0, linkmeta.Meta{}, linkcheck.Health{}))
+		}
+	}
+
+	return items, nil
+}
+
+// ownedComposites returns the links of the owners, the caller holds the state lock.
+func (s *File) ownedComposites(ownerIDs []string) []*composite {
+	owners := make(map[string]struct{}, len(ownerIDs))
+	for _, ownerID := range ownerIDs {
+		owners[ownerID] = struct{}{}
+	}
+
+	composites := make([]*composite, 0)
+	for _, composite := range s.state {
+		if _, ok := owners[composite.UserID]; !ok || composite.Token == nil {
+			continue
+		}
+		c := composite
+		composites = append(composites, &c)
+	}
+	return composites
+}
+
+// SearchURLs returns the links of the owners which are not removed and match the query, from the most relevant.
+func (s *File) SearchURLs(ownerIDs []string, query search.Query) ([]listing.Item, error) {
+	s.stateMx.RLock()
+	defer s.stateMx.RUnlock()
+	s.clicksMx.RLock()
+	defer s.clicksMx.RUnlock()
+
+	owned := make(map[string]*composite)
+	for _, c := range s.ownedComposites(ownerIDs) {
+		owned[c.Token.Value] = c
+	}
+
+	items := make([]listing.Item, 0)
+	for _, hit := range s.index.Search(query.Terms()) {
+		if c, ok := owned[hit.Token]; ok && len(items) < query.PageSize() {
+			items = append(items, newListingItem(c, s.clicks[c.Token.Value], s.data.LinkMeta[c.Token.Value], s.data.LinkHealth[c.Token.Value]))
+		}
+	}
+
+	return items, nil
+}
+
+// indexLink puts the link into the search index, the caller holds the state lock.
+func (s *File) indexLink(c *composite) {
+	if c.Token == nil || c.Token.Removed {
+		return
+	}
+	s.index.Put(newSearchDocument(c, s.data.LinkMeta[c.Token.Value]))
+}
+
+func (s *File) restoreIndex() {
+	s.stateMx.RLock()
+	defer s.stateMx.RUnlock()
+
+	for _, composite := range s.state {
+		s.indexLink(&composite)
+	}
+}
+
+// AddClick counts a redirect in memory, the totals are written by FlushClicks.
+func (s *File) AddClick(tokenValue string) error {
+	s.clicksMx.Lock()
+	defer s.clicksMx.Unlock()
+
+	s.clicks[tokenValue]++
+	s.clicksDirty = true
+
+	return nil
+}
+
+// FlushClicks writes the click totals, if they changed since the last flush.
+func (s *File) FlushClicks() error {
+	s.flushMx.Lock()
+	defer s.flushMx.Unlock()
+
+	s.clicksMx.Lock()
+	if !s.clicksDirty {
+		s.clicksMx.Unlock()
+		return nil
+	}
+	clicksJSON, err := json.Marshal(s.clicks)
+	s.clicksDirty = false
+	s.clicksMx.Unlock()
+	if err != nil {
+		return err
+	}
+
+	// The totals replace the file at once, so a crash leaves either the old or the new ones.
+	tmpName := s.FileName + clicksFileSuffix + ".tmp"
+	err = os.WriteFile(tmpName, clicksJSON, 0o600)
+	if err == nil {
+		err = os.Rename(tmpName, s.FileName+clicksFileSuffix)
+	}
+	if err != nil {
+		s.clicksMx.Lock()
+		s.clicksDirty = true
+		s.clicksMx.Unlock()
+		return err
+	}
+
+	return nil
+}
+
+// Close writes the clicks not flushed yet.
+func (s *File) Close() error {
+	return s.FlushClicks()
+}
+
+func (s *File) restoreClicks() error {
+	s.clicksMx.Lock()
+	defer s.clicksMx.Unlock()
+
+	file, err := os.Open(s.FileName + clicksFileSuffix)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	clicks := make(map[string]int64)
+	reader := bufio.NewReader(file)
+	if first, err := reader.Peek(1); err == nil && first[0] == '{' {
+		if err := json.NewDecoder(reader).Decode(&clicks); err != nil {
+			return err
+		}
+		s.clicks = clicks
+		return nil
+	}
+
+	// The former format, the next flush rewrites it.
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		if tokenValue := scanner.Text(); tokenValue != "" {
+			clicks[tokenValue]++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	s.clicks = clicks
+	s.clicksDirty = len(clicks) > 0
+
+	return nil
+}
+
 func (s *File) saveState() error {
 	s.mx.Lock()
 	defer s.mx.Unlock()
@@ -275,6 +554,699 @@ func (s *File) restoreState() error {
 	return nil
 }
 
+func (s *File) SetAPIKey(key *apikey.Key) error {
+	s.stateMx.Lock()
+	defer s.stateMx.Unlock()
+
+	// The lock is held, so the new key is seen only once it is written.
+	old, exists := s.data.APIKeys[key.ID]
+	k := *key
+	s.data.APIKeys[key.ID] = &k
+	if err := s.saveData(); err != nil {
+		if exists {
+			s.data.APIKeys[key.ID] = old
+		} else {
+			delete(s.data.APIKeys, key.ID)
+		}
+		return err
+	}
+
+	if exists {
+		delete(s.apiKeyHashes, old.Hash)
+	}
+	s.apiKeyHashes[key.Hash] = key.ID
+
+	return nil
+}
+
+func (s *File) GetAPIKey(hash string) (*apikey.Key, error) {
+	s.stateMx.RLock()
+	defer s.stateMx.RUnlock()
+
+	key, ok := s.data.APIKeys[s.apiKeyHashes[hash]]
+	if !ok {
+		return nil, ErrAPIKeyNotFound
+	}
+	k := *key
+
+	return &k, nil
+}
+
+func (s *File) GetAPIKeysByUserID(userID string) ([]*apikey.Key, error) {
+	s.stateMx.RLock()
+	defer s.stateMx.RUnlock()
+
+	keys := make([]*apikey.Key, 0)
+	for _, key := range s.data.APIKeys {
+		if key.UserID == userID {
+			k := *key
+			keys = append(keys, &k)
+		}
+	}
+	sortAPIKeys(keys)
+
+	return keys, nil
+}
+
+func (s *File) RevokeAPIKey(id, userID string) error {
+	s.stateMx.Lock()
+	defer s.stateMx.Unlock()
+
+	key, ok := s.data.APIKeys[id]
+	if !ok || key.UserID != userID {
+		return ErrAPIKeyNotFound
+	}
+
+	// The lock is held, so the revocation is seen only once it is written.
+	revoked := *key
+	revoked.Revoked = true
+	s.data.APIKeys[id] = &revoked
+	if err := s.saveData(); err != nil {
+		s.data.APIKeys[id] = key
+		return err
+	}
+
+	return nil
+}
+
+func (s *File) SetAccount(a *account.Account) error {
+	s.stateMx.Lock()
+	defer s.stateMx.Unlock()
+
+	if _, ok := s.accountLogins[a.Login]; ok {
+		return ErrAccountExists
+	}
+
+	acc := *a
+	s.data.Accounts[a.ID] = &acc
+	if err := s.saveData(); err != nil {
+		delete(s.data.Accounts, a.ID)
+		return err
+	}
+	s.accountLogins[a.Login] = a.ID
+
+	return nil
+}
+
+func (s *File) GetAccountByLogin(login string) (*account.Account, error) {
+	s.stateMx.RLock()
+	defer s.stateMx.RUnlock()
+
+	a, ok := s.data.Accounts[s.accountLogins[login]]
+	if !ok {
+		return nil, ErrAccountNotFound
+	}
+	acc := *a
+
+	return &acc, nil
+}
+
+func (s *File) GetAccountByID(id string) (*account.Account, error) {
+	s.stateMx.RLock()
+	defer s.stateMx.RUnlock()
+
+	a, ok := s.data.Accounts[id]
+	if !ok {
+		return nil, ErrAccountNotFound
+	}
+	acc := *a
+
+	return &acc, nil
+}
+
+func (s *File) ReassignUser(fromUserID, toUserID string) error {
+	s.stateMx.Lock()
+	defer s.stateMx.Unlock()
+
+	changed := false
+	for url, composite := range s.state {
+		if composite.UserID == fromUserID {
+			composite.UserID = toUserID
+			s.state[url] = composite
+			changed = true
+		}
+	}
+	if changed {
+		if err := s.saveState(); err != nil {
+			return err
+		}
+	}
+
+	dataChanged := reassignMembers(s.data.Members, fromUserID, toUserID)
+	if reassignWebhook(s.data.Webhooks, fromUserID, toUserID) {
+		dataChanged = true
+	}
+	for _, key := range s.data.APIKeys {
+		if key.UserID == fromUserID {
+			key.UserID = toUserID
+			dataChanged = true
+		}
+	}
+	if !dataChanged {
+		return nil
+	}
+
+	return s.saveData()
+}
+
+func (s *File) HasUser(userID string) (bool, error) {
+	s.stateMx.RLock()
+	defer s.stateMx.RUnlock()
+
+	for _, composite := range s.state {
+		if composite.UserID == userID {
+			return true, nil
+		}
+	}
+	if _, ok := s.data.Accounts[userID]; ok {
+		return true, nil
+	}
+	for _, key := range s.data.APIKeys {
+		if key.UserID == userID {
+			return true, nil
+		}
+	}
+	for _, roles := range s.data.Members {
+		if _, ok := roles[userID]; ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *File) SetWorkspace(w *workspace.Workspace) error {
+	s.stateMx.Lock()
+	defer s.stateMx.Unlock()
+
+	ws := *w
+	s.data.Workspaces[w.ID] = &ws
+
+	return s.saveData()
+}
+
+func (s *File) GetWorkspace(id string) (*workspace.Workspace, error) {
+	s.stateMx.RLock()
+	defer s.stateMx.RUnlock()
+
+	w, ok := s.data.Workspaces[id]
+	if !ok {
+		return nil, ErrWorkspaceNotFound
+	}
+	ws := *w
+
+	return &ws, nil
+}
+
+func (s *File) SetMember(m *workspace.Member) error {
+	s.stateMx.Lock()
+	defer s.stateMx.Unlock()
+
+	if _, ok := s.data.Workspaces[m.WorkspaceID]; !ok {
+		return ErrWorkspaceNotFound
+	}
+	if !keepsAdmin(s.data.Members[m.WorkspaceID], m.UserID, m.Role) {
+		return ErrLastAdmin
+	}
+	if _, ok := s.data.Members[m.WorkspaceID]; !ok {
+		s.data.Members[m.WorkspaceID] = make(map[string]workspace.Role)
+	}
+	s.data.Members[m.WorkspaceID][m.UserID] = m.Role
+
+	return s.saveData()
+}
+
+func (s *File) GetMember(workspaceID, userID string) (*workspace.Member, error) {
+	s.stateMx.RLock()
+	defer s.stateMx.RUnlock()
+
+	role, ok := s.data.Members[workspaceID][userID]
+	if !ok {
+		return nil, ErrMemberNotFound
+	}
+
+	return &workspace.Member{WorkspaceID: workspaceID, UserID: userID, Role: role}, nil
+}
+
+func (s *File) GetMembers(workspaceID string) ([]*workspace.Member, error) {
+	s.stateMx.RLock()
+	defer s.stateMx.RUnlock()
+
+	members := make([]*workspace.Member, 0, len(s.data.Members[workspaceID]))
+	for userID, role := range s.data.Members[workspaceID] {
+		members = append(members, &workspace.Member{WorkspaceID: workspaceID, UserID: userID, Role: role})
+	}
+	sortMembers(members)
+
+	return members, nil
+}
+
+func (s *File) GetMembershipsByUserID(userID string) ([]*workspace.Member, error) {
+	s.stateMx.RLock()
+	defer s.stateMx.RUnlock()
+
+	members := make([]*workspace.Member, 0)
+	for workspaceID, roles := range s.data.Members {
+		if role, ok := roles[userID]; ok {
+			members = append(members, &workspace.Member{WorkspaceID: workspaceID, UserID: userID, Role: role})
+		}
+	}
+	sortMemberships(members)
+
+	return members, nil
+}
+
+func (s *File) RemoveMember(workspaceID, userID string) error {
+	s.stateMx.Lock()
+	defer s.stateMx.Unlock()
+
+	if _, ok := s.data.Members[workspaceID][userID]; !ok {
+		return ErrMemberNotFound
+	}
+	if !keepsAdmin(s.data.Members[workspaceID], userID, "") {
+		return ErrLastAdmin
+	}
+	delete(s.data.Members[workspaceID], userID)
+
+	return s.saveData()
+}
+
+func (s *File) GetRateBucket(key string) (ratelimit.Bucket, error) {
+	s.stateMx.RLock()
+	defer s.stateMx.RUnlock()
+
+	return s.rateBuckets[key], nil
+}
+
+func (s *File) SetRateBucket(key string, b ratelimit.Bucket) error {
+	s.stateMx.Lock()
+	defer s.stateMx.Unlock()
+
+	s.rateBuckets[key] = b
+
+	return nil
+}
+
+// AddQuotaUsage keeps the usage of the current day only, the changes of a past day are ignored.
+func (s *File) AddQuotaUsage(key, day string, n int) (int, error) {
+	s.stateMx.Lock()
+	defer s.stateMx.Unlock()
+
+	switch {
+	case day < s.data.QuotaDay:
+		return 0, nil
+	case day > s.data.QuotaDay:
+		s.data.QuotaDay = day
+		s.data.QuotaUsage = make(map[string]int)
+	}
+	s.data.QuotaUsage[key] += n
+
+	return s.data.QuotaUsage[key], s.saveData()
+}
+
+func (s *File) PruneRateLimits(updatedBefore time.Time, day string) error {
+	s.stateMx.Lock()
+	defer s.stateMx.Unlock()
+
+	for key, b := range s.rateBuckets {
+		if b.Updated.Before(updatedBefore) {
+			delete(s.rateBuckets, key)
+		}
+	}
+	if s.data.QuotaDay >= day {
+		return nil
+	}
+	s.data.QuotaDay = day
+	s.data.QuotaUsage = make(map[string]int)
+
+	return s.saveData()
+}
+
+func (s *File) SetLinkMeta(tokenValue string, m linkmeta.Meta) error {
+	s.stateMx.Lock()
+	defer s.stateMx.Unlock()
+
+	s.data.LinkMeta[tokenValue] = m
+	for _, composite := range s.state {
+		if composite.Token != nil && composite.Token.Value == tokenValue {
+			s.indexLink(&composite)
+		}
+	}
+
+	return s.saveData()
+}
+
+// UpdateLinkMeta applies the update to the meta of the link of the owners which is not removed.
+func (s *File) UpdateLinkMeta(ownerIDs []string, tokenValue string, update linkmeta.Update) (linkmeta.Meta, error) {
+	s.stateMx.Lock()
+	defer s.stateMx.Unlock()
+
+	for _, composite := range s.state {
+		if composite.Token == nil || composite.Token.Value != tokenValue {
+			continue
+		}
+		if composite.Token.Removed || !ownedBy(ownerIDs, composite.UserID) {
+			break
+		}
+
+		m, err := update.Apply(s.data.LinkMeta[tokenValue])
+		if err != nil {
+			return linkmeta.Meta{}, err
+		}
+
+		s.data.LinkMeta[tokenValue] = m
+		s.indexLink(&composite)
+		return m, s.saveData()
+	}
+
+	return linkmeta.Meta{}, ErrTokenNotFound
+}
+
+// GetLinkMeta returns the meta of the link, the zero meta if it has none.
+func (s *File) GetLinkMeta(tokenValue string) (linkmeta.Meta, error) {
+	s.stateMx.RLock()
+	defer s.stateMx.RUnlock()
+
+	return s.data.LinkMeta[tokenValue], nil
+}
+
+// GetTags returns the tags of the links of the owners which are not removed.
+func (s *File) GetTags(ownerIDs []string) ([]linkmeta.TagCount, error) {
+	s.stateMx.RLock()
+	defer s.stateMx.RUnlock()
+
+	metas := make([]linkmeta.Meta, 0)
+	for _, c := range s.ownedComposites(ownerIDs) {
+		if !c.Token.Removed {
+			metas = append(metas, s.data.LinkMeta[c.Token.Value])
+		}
+	}
+
+	return linkmeta.CountTags(metas), nil
+}
+
+// RenameTag renames the tag on the links of the owners and returns the number of the links changed.
+func (s *File) RenameTag(ownerIDs []string, from, to string) (int, error) {
+	s.stateMx.Lock()
+	defer s.stateMx.Unlock()
+
+	renamed := 0
+	for _, c := range s.ownedComposites(ownerIDs) {
+		if m, ok := s.data.LinkMeta[c.Token.Value].RenameTag(from, to); ok {
+			s.data.LinkMeta[c.Token.Value] = m
+			s.indexLink(c)
+			renamed++
+		}
+	}
+	if renamed == 0 {
+		return 0, nil
+	}
+
+	return renamed, s.saveData()
+}
+
+// AddAuditEvents appends the events to the audit file.
+func (s *File) AddAuditEvents(events []*audit.Event) error {
+	s.auditMx.Lock()
+	defer s.auditMx.Unlock()
+
+	file, err := os.OpenFile(s.FileName+auditFileSuffix, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	lines := make([]byte, 0)
+	for i, e := range events {
+		e.ID = s.auditCount + int64(i) + 1
+		eventJSON, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		lines = append(append(lines, eventJSON...), '\n')
+	}
+
+	if _, err := file.Write(lines); err != nil {
+		// A torn line would glue the next events to itself.
+		_ = file.Truncate(s.auditSize)
+		return err
+	}
+	s.auditCount += int64(len(events))
+	s.auditSize += int64(len(lines))
+
+	return nil
+}
+
+// GetAuditEvents reads the audit file from the end till the page is filled.
+func (s *File) GetAuditEvents(filter audit.Filter) ([]*audit.Event, error) {
+	s.auditMx.RLock()
+	size := s.auditSize
+	s.auditMx.RUnlock()
+
+	found := make([]*audit.Event, 0)
+	if size == 0 {
+		return found, nil
+	}
+
+	file, err := os.Open(s.FileName + auditFileSuffix)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	limit := auditLimit(filter)
+	err = readLinesBackward(file, size, func(line []byte) (bool, error) {
+		var e audit.Event
+		if err := json.Unmarshal(line, &e); err != nil {
+			return false, err
+		}
+		if filter.Match(&e) {
+			found = append(found, &e)
+		}
+		return len(found) < limit, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return found, nil
+}
+
+// restoreAuditEvents counts the events of the audit file.
+// A torn last line, left by a crash in the middle of a write, is cut off.
+func (s *File) restoreAuditEvents() error {
+	s.auditMx.Lock()
+	defer s.auditMx.Unlock()
+
+	file, err := os.OpenFile(s.FileName+auditFileSuffix, os.O_RDWR, 0o600)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == nil {
+			s.auditCount++
+			s.auditSize += int64(len(line))
+			continue
+		}
+		if !errors.Is(err, io.EOF) {
+			return err
+		}
+		if len(line) > 0 {
+			logging.Errorf("Audit file: cutting off the torn last line of %d bytes", len(line))
+			return file.Truncate(s.auditSize)
+		}
+		return nil
+	}
+}
+
+// readLinesBackward calls fn with the lines of the first size bytes of r from the last one, till fn returns false.
+// The size must end with a newline.
+func readLinesBackward(r io.ReaderAt, size int64, fn func(line []byte) (bool, error)) error {
+	var pending []byte // the unread lines ending where the read block ends
+	end := size - 1    // the last newline is not a separator
+	for end > 0 {
+		start := end - auditReadChunk
+		if start < 0 {
+			start = 0
+		}
+		block := make([]byte, end-start, int(end-start)+len(pending))
+		if _, err := r.ReadAt(block, start); err != nil {
+			return err
+		}
+		pending = append(block, pending...)
+		end = start
+
+		for {
+			i := bytes.LastIndexByte(pending, '\n')
+			if i < 0 {
+				break
+			}
+			more, err := fn(pending[i+1:])
+			if err != nil || !more {
+				return err
+			}
+			pending = pending[:i]
+		}
+	}
+
+	if len(pending) > 0 {
+		_, err := fn(pending)
+		return err
+	}
+
+	return nil
+}
+
+func (s *File) saveData() error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	dataJSON, err := json.Marshal(s.data)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(s.FileName+dataFileSuffix, dataJSON, 0o600)
+}
+
+func (s *File) restoreData() error {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+
+	s.stateMx.Lock()
+	defer s.stateMx.Unlock()
+
+	dataJSON, err := os.ReadFile(s.FileName + dataFileSuffix)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	data := newFileData()
+	if len(dataJSON) > 0 {
+		if err := json.Unmarshal(dataJSON, &data); err != nil {
+			return err
+		}
+	}
+	if data.APIKeys == nil {
+		data.APIKeys = make(map[string]*apikey.Key)
+	}
+	if data.Accounts == nil {
+		data.Accounts = make(map[string]*account.Account)
+	}
+	if data.Workspaces == nil {
+		data.Workspaces = make(map[string]*workspace.Workspace)
+	}
+	if data.Members == nil {
+		data.Members = make(map[string]map[string]workspace.Role)
+	}
+	if data.QuotaUsage == nil {
+		data.QuotaUsage = make(map[string]int)
+	}
+	if data.LinkMeta == nil {
+		data.LinkMeta = make(map[string]linkmeta.Meta)
+	}
+	if data.LinkHealth == nil {
+		data.LinkHealth = make(map[string]linkcheck.Health)
+	}
+	if data.Webhooks == nil {
+		data.Webhooks = make(map[string]string)
+	}
+	s.data = data
+
+	s.apiKeyHashes = make(map[string]string, len(data.APIKeys))
+	for id, key := range data.APIKeys {
+		s.apiKeyHashes[key.Hash] = id
+	}
+	s.accountLogins = make(map[string]string, len(data.Accounts))
+	for id, a := range data.Accounts {
+		s.accountLogins[a.Login] = id
+	}
+
+	return nil
+}
+
+// GetLinksToCheck returns the active links checked before the time, the never checked ones first.
+func (s *File) GetLinksToCheck(checkedBefore time.Time, limit int) ([]listing.Item, error) {
+	s.stateMx.RLock()
+	defer s.stateMx.RUnlock()
+
+	s.clicksMx.RLock()
+	defer s.clicksMx.RUnlock()
+
+	items := make([]listing.Item, 0)
+	for _, composite := range s.state {
+		if composite.Token == nil || composite.Token.Removed || composite.Token.IsExpired() {
+			continue
+		}
+		c := composite
+		tokenValue := c.Token.Value
+		h := s.data.LinkHealth[tokenValue]
+		if !h.IsZero() && !h.Checked.Before(checkedBefore) {
+			continue
+		}
+		items = append(items, newListingItem(&c, s.clicks[tokenValue], s.data.LinkMeta[tokenValue], h))
+	}
+
+	sortLinksToCheck(items)
+	if limit > 0 && len(items) > limit {
+		items = items[:limit]
+	}
+
+	return items, nil
+}
+
+// SetLinkHealth keeps the results of the checks by the token.
+func (s *File) SetLinkHealth(health map[string]linkcheck.Health) error {
+	s.stateMx.Lock()
+	defer s.stateMx.Unlock()
+
+	for tokenValue, h := range health {
+		s.data.LinkHealth[tokenValue] = h
+	}
+
+	return s.saveData()
+}
+
+// SetLinkWebhook keeps the URL the user's link check events are posted to, an empty URL removes it.
+func (s *File) SetLinkWebhook(userID, url string) error {
+	s.stateMx.Lock()
+	defer s.stateMx.Unlock()
+
+	old, ok := s.data.Webhooks[userID]
+	if url == "" {
+		delete(s.data.Webhooks, userID)
+	} else {
+		s.data.Webhooks[userID] = url
+	}
+	if err := s.saveData(); err != nil {
+		if ok {
+			s.data.Webhooks[userID] = old
+		} else {
+			delete(s.data.Webhooks, userID)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// GetLinkWebhook returns the user's link check webhook URL, empty if the user has none.
+func (s *File) GetLinkWebhook(userID string) (string, error) {
+	s.stateMx.RLock()
+	defer s.stateMx.RUnlock()
+
+	return s.data.Webhooks[userID], nil
+}
+
 func (s *File) Ping(ctx context.Context) error {
 	return nil
 }
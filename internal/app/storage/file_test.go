@@ -4,11 +4,22 @@ import (
 	"context"
 	"log"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/alrund/yp-1/internal/app/account"
+	"github.com/alrund/yp-1/internal/app/apikey"
+	"github.com/alrund/yp-1/internal/app/audit"
+	"github.com/alrund/yp-1/internal/app/linkcheck"
+	"github.com/alrund/yp-1/internal/app/linkmeta"
+	"github.com/alrund/yp-1/internal/app/listing"
+	"github.com/alrund/yp-1/internal/app/ratelimit"
+	"github.com/alrund/yp-1/internal/app/search"
 	tkn "github.com/alrund/yp-1/internal/app/token"
+	"github.com/alrund/yp-1/internal/app/workspace"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -718,8 +729,14 @@ func TestNewFileStorage(t *testing.T) {
 		{
 			name: "success",
 			want: &File{
-				FileName: TestStorageFileName,
-				state:    make(map[string]composite),
+				FileName:      TestStorageFileName,
+				state:         make(map[string]composite),
+				data:          newFileData(),
+				apiKeyHashes:  make(map[string]string),
+				accountLogins: make(map[string]string),
+				rateBuckets:   make(map[string]ratelimit.Bucket),
+				clicks:        make(map[string]int64),
+				index:         search.NewIndex(),
 			},
 		},
 	}
@@ -807,3 +824,482 @@ func isTestDataContainsString(str string) bool {
 func clearTestData() {
 	_ = os.Remove(TestStorageFileName)
 }
+
+func TestFileAPIKeys(t *testing.T) {
+	defer clearTestData()
+	defer os.Remove(TestStorageFileName + dataFileSuffix)
+
+	storage, err := NewFile(TestStorageFileName)
+	require.NoError(t, err)
+
+	created := time.Now().Truncate(time.Second)
+	older := &apikey.Key{ID: "1", UserID: "XXX-YYY-ZZZ", Name: "ci", Hash: "hash1", Created: created.Add(-time.Hour)}
+	newer := &apikey.Key{ID: "2", UserID: "XXX-YYY-ZZZ", Name: "bot", Hash: "hash2", Created: created}
+	other := &apikey.Key{ID: "3", UserID: "AAA-BBB-CCC", Name: "ci", Hash: "hash3", Created: created}
+	for _, key := range []*apikey.Key{newer, older, other} {
+		require.NoError(t, storage.SetAPIKey(key))
+	}
+	require.NoError(t, storage.RevokeAPIKey("1", "XXX-YYY-ZZZ"))
+	assert.ErrorIs(t, storage.RevokeAPIKey("3", "XXX-YYY-ZZZ"), ErrAPIKeyNotFound)
+
+	require.NoError(t, storage.Set("XXX-YYY-ZZZ", "url", &tkn.Token{Value: "yyy", Expire: time.Now().Add(tkn.LifeTime)}))
+	assert.False(t, isTestDataContainsString("hash1"), "the URLs file keeps its format")
+
+	restored, err := NewFile(TestStorageFileName)
+	require.NoError(t, err)
+
+	got, err := restored.GetAPIKey("hash2")
+	require.NoError(t, err)
+	assert.Equal(t, "bot", got.Name)
+	assert.True(t, got.Created.Equal(created))
+
+	_, err = restored.GetAPIKey("unknown")
+	assert.ErrorIs(t, err, ErrAPIKeyNotFound)
+
+	keys, err := restored.GetAPIKeysByUserID("XXX-YYY-ZZZ")
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	assert.Equal(t, "1", keys[0].ID)
+	assert.True(t, keys[0].Revoked)
+	assert.Equal(t, "2", keys[1].ID)
+
+	// A failed write leaves the keys as they were.
+	restored.FileName = filepath.Join(t.TempDir(), "missing", "storage")
+	assert.Error(t, restored.RevokeAPIKey("2", "XXX-YYY-ZZZ"))
+	got, err = restored.GetAPIKey("hash2")
+	require.NoError(t, err)
+	assert.False(t, got.Revoked)
+	assert.Error(t, restored.SetAPIKey(&apikey.Key{ID: "4", UserID: "XXX-YYY-ZZZ", Hash: "hash4"}))
+	_, err = restored.GetAPIKey("hash4")
+	assert.ErrorIs(t, err, ErrAPIKeyNotFound)
+}
+
+func TestFileAccounts(t *testing.T) {
+	defer clearTestData()
+	defer os.Remove(TestStorageFileName + dataFileSuffix)
+
+	storage, err := NewFile(TestStorageFileName)
+	require.NoError(t, err)
+
+	created := time.Now().Truncate(time.Second)
+	alice := &account.Account{ID: "XXX-YYY-ZZZ", Login: "alice", PasswordHash: "hash", Created: created}
+	require.NoError(t, storage.SetAccount(alice))
+	assert.ErrorIs(t, storage.SetAccount(&account.Account{ID: "AAA-BBB-CCC", Login: "alice"}), ErrAccountExists)
+
+	restored, err := NewFile(TestStorageFileName)
+	require.NoError(t, err)
+
+	got, err := restored.GetAccountByLogin("alice")
+	require.NoError(t, err)
+	assert.Equal(t, "XXX-YYY-ZZZ", got.ID)
+	assert.Equal(t, "hash", got.PasswordHash)
+	assert.True(t, got.Created.Equal(created))
+
+	got, err = restored.GetAccountByID("XXX-YYY-ZZZ")
+	require.NoError(t, err)
+	assert.Equal(t, "alice", got.Login)
+
+	_, err = restored.GetAccountByLogin("bob")
+	assert.ErrorIs(t, err, ErrAccountNotFound)
+	_, err = restored.GetAccountByID("AAA-BBB-CCC")
+	assert.ErrorIs(t, err, ErrAccountNotFound)
+}
+
+func TestFileReassignUser(t *testing.T) {
+	defer clearTestData()
+	defer os.Remove(TestStorageFileName + dataFileSuffix)
+
+	storage, err := NewFile(TestStorageFileName)
+	require.NoError(t, err)
+	require.NoError(t, storage.Set("XXX-YYY-ZZZ", "url1", &tkn.Token{Value: "xxx", Expire: time.Now().Add(tkn.LifeTime)}))
+	require.NoError(t, storage.Set("anonymous", "url2", &tkn.Token{Value: "yyy", Expire: time.Now().Add(tkn.LifeTime)}))
+
+	require.NoError(t, storage.SetAPIKey(&apikey.Key{ID: "1", UserID: "anonymous", Hash: "hash1"}))
+	require.NoError(t, storage.SetWorkspace(&workspace.Workspace{ID: "team"}))
+	require.NoError(t, storage.SetMember(&workspace.Member{WorkspaceID: "team", UserID: "XXX-YYY-ZZZ", Role: workspace.RoleAdmin}))
+	require.NoError(t, storage.SetMember(&workspace.Member{WorkspaceID: "team", UserID: "anonymous", Role: workspace.RoleEditor}))
+
+	require.NoError(t, storage.ReassignUser("anonymous", "XXX-YYY-ZZZ"))
+	assert.False(t, isTestDataContainsString("anonymous"))
+
+	restored, err := NewFile(TestStorageFileName)
+	require.NoError(t, err)
+
+	keys, err := restored.GetAPIKeysByUserID("XXX-YYY-ZZZ")
+	require.NoError(t, err)
+	assert.Len(t, keys, 1)
+	members, err := restored.GetMembers("team")
+	require.NoError(t, err)
+	assert.Equal(t, []*workspace.Member{{WorkspaceID: "team", UserID: "XXX-YYY-ZZZ", Role: workspace.RoleAdmin}}, members)
+
+	urls, err := restored.GetURLsByUserID("XXX-YYY-ZZZ")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []URLpairs{
+		{ShortURL: "xxx", OriginalURL: "url1"},
+		{ShortURL: "yyy", OriginalURL: "url2"},
+	}, urls)
+}
+
+func TestFileWorkspaces(t *testing.T) {
+	defer clearTestData()
+	defer os.Remove(TestStorageFileName + dataFileSuffix)
+
+	storage, err := NewFile(TestStorageFileName)
+	require.NoError(t, err)
+
+	created := time.Now().Truncate(time.Second)
+	require.NoError(t, storage.SetWorkspace(&workspace.Workspace{ID: "WWW-WWW-WWW", Name: "team", Created: created}))
+	assert.ErrorIs(t, storage.SetMember(&workspace.Member{WorkspaceID: "unknown", UserID: "XXX-YYY-ZZZ"}), ErrWorkspaceNotFound)
+	require.NoError(t, storage.SetMember(&workspace.Member{WorkspaceID: "WWW-WWW-WWW", UserID: "XXX-YYY-ZZZ", Role: workspace.RoleAdmin}))
+	require.NoError(t, storage.SetMember(&workspace.Member{WorkspaceID: "WWW-WWW-WWW", UserID: "AAA-BBB-CCC", Role: workspace.RoleViewer}))
+	require.NoError(t, storage.RemoveMember("WWW-WWW-WWW", "AAA-BBB-CCC"))
+	assert.ErrorIs(t, storage.RemoveMember("WWW-WWW-WWW", "AAA-BBB-CCC"), ErrMemberNotFound)
+	assert.ErrorIs(t, storage.SetMember(&workspace.Member{WorkspaceID: "WWW-WWW-WWW", UserID: "XXX-YYY-ZZZ", Role: workspace.RoleViewer}), ErrLastAdmin)
+	assert.ErrorIs(t, storage.RemoveMember("WWW-WWW-WWW", "XXX-YYY-ZZZ"), ErrLastAdmin)
+
+	ok, err := storage.HasUser("XXX-YYY-ZZZ")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = storage.HasUser("AAA-BBB-CCC")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	restored, err := NewFile(TestStorageFileName)
+	require.NoError(t, err)
+
+	got, err := restored.GetWorkspace("WWW-WWW-WWW")
+	require.NoError(t, err)
+	assert.Equal(t, "team", got.Name)
+	assert.True(t, got.Created.Equal(created))
+
+	member, err := restored.GetMember("WWW-WWW-WWW", "XXX-YYY-ZZZ")
+	require.NoError(t, err)
+	assert.Equal(t, workspace.RoleAdmin, member.Role)
+
+	members, err := restored.GetMembers("WWW-WWW-WWW")
+	require.NoError(t, err)
+	assert.Equal(t, []*workspace.Member{
+		{WorkspaceID: "WWW-WWW-WWW", UserID: "XXX-YYY-ZZZ", Role: workspace.RoleAdmin},
+	}, members)
+
+	memberships, err := restored.GetMembershipsByUserID("XXX-YYY-ZZZ")
+	require.NoError(t, err)
+	assert.Len(t, memberships, 1)
+}
+
+func TestFileAuditEvents(t *testing.T) {
+	defer clearTestData()
+	defer os.Remove(TestStorageFileName + auditFileSuffix)
+
+	storage, err := NewFile(TestStorageFileName)
+	require.NoError(t, err)
+
+	created := time.Now().Truncate(time.Second)
+	require.NoError(t, storage.AddAuditEvents([]*audit.Event{
+		{Time: created, Action: audit.ActionCreate, Token: "qwerty", OwnerID: "XXX-YYY-ZZZ", ActorID: "XXX-YYY-ZZZ"},
+	}))
+	require.NoError(t, storage.AddAuditEvents([]*audit.Event{
+		{Time: created, Action: audit.ActionDelete, Token: "qwerty", OwnerID: "XXX-YYY-ZZZ", ActorID: "AAA-BBB-CCC"},
+	}))
+
+	restored, err := NewFile(TestStorageFileName)
+	require.NoError(t, err)
+
+	events, err := restored.GetAuditEvents(audit.Filter{Token: "qwerty"})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, int64(2), events[0].ID)
+	assert.Equal(t, audit.ActionDelete, events[0].Action)
+	assert.True(t, events[0].Time.Equal(created))
+
+	require.NoError(t, restored.AddAuditEvents([]*audit.Event{{Action: audit.ActionCreate, Token: "asdfgh"}}))
+	events, err = restored.GetAuditEvents(audit.Filter{Token: "asdfgh"})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, int64(3), events[0].ID, "the IDs continue after a restart")
+
+	// A crash in the middle of a write leaves a torn last line.
+	file, err := os.OpenFile(TestStorageFileName+auditFileSuffix, os.O_WRONLY|os.O_APPEND, 0o600)
+	require.NoError(t, err)
+	_, err = file.WriteString(`{"id":4,"action":"cre`)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	restored, err = NewFile(TestStorageFileName)
+	require.NoError(t, err)
+	require.NoError(t, restored.AddAuditEvents([]*audit.Event{{Action: audit.ActionCreate, Token: "zxcvbn"}}))
+	events, err = restored.GetAuditEvents(audit.Filter{})
+	require.NoError(t, err)
+	require.Len(t, events, 4)
+	assert.Equal(t, int64(4), events[0].ID)
+	assert.Equal(t, "zxcvbn", events[0].Token)
+}
+
+func TestFileAuditEventsPages(t *testing.T) {
+	defer clearTestData()
+	defer os.Remove(TestStorageFileName + auditFileSuffix)
+
+	storage, err := NewFile(TestStorageFileName)
+	require.NoError(t, err)
+
+	// The file is read backwards in blocks, the events cross their bounds.
+	events := make([]*audit.Event, 0, 3000)
+	for i := 0; i < 3000; i++ {
+		events = append(events, &audit.Event{Action: audit.ActionCreate, Token: strconv.Itoa(i % 3), OwnerID: "XXX-YYY-ZZZ"})
+	}
+	require.NoError(t, storage.AddAuditEvents(events))
+
+	got, err := storage.GetAuditEvents(audit.Filter{Token: "0", Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+	assert.Equal(t, int64(2998), got[0].ID)
+	assert.Equal(t, int64(2995), got[1].ID)
+
+	got, err = storage.GetAuditEvents(audit.Filter{Token: "1", Limit: 5000})
+	require.NoError(t, err)
+	require.Len(t, got, 1000)
+	assert.Equal(t, int64(2), got[999].ID)
+
+	got, err = storage.GetAuditEvents(audit.Filter{UserID: "XXX-YYY-ZZZ"})
+	require.NoError(t, err)
+	assert.Len(t, got, audit.DefaultLimit)
+}
+
+func TestFileURLsPage(t *testing.T) {
+	defer clearTestData()
+	defer os.Remove(TestStorageFileName + clicksFileSuffix)
+
+	storage, err := NewFile(TestStorageFileName)
+	require.NoError(t, err)
+
+	created := time.Now().Truncate(time.Second)
+	require.NoError(t, storage.Set("XXX-YYY-ZZZ", "http://ya.ru", &tkn.Token{
+		Value: "qwerty", Expire: created.Add(tkn.LifeTime), Created: created,
+	}))
+	require.NoError(t, storage.Set("XXX-YYY-ZZZ", "http://go.dev", &tkn.Token{
+		Value: "asdfgh", Expire: created.Add(tkn.LifeTime), Created: created.Add(-time.Hour), Removed: true,
+	}))
+	require.NoError(t, storage.Set("AAA-BBB-CCC", "http://other.ru", &tkn.Token{
+		Value: "other", Expire: created.Add(tkn.LifeTime), Created: created,
+	}))
+	require.NoError(t, storage.AddClick("asdfgh"))
+	require.NoError(t, storage.FlushClicks())
+	require.NoError(t, storage.AddClick("asdfgh"))
+	require.NoError(t, storage.Close())
+
+	restored, err := NewFile(TestStorageFileName)
+	require.NoError(t, err)
+
+	items, next, err := restored.GetURLsPage([]string{"XXX-YYY-ZZZ"}, listing.Query{Sort: listing.SortClicksDesc})
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	assert.Equal(t, "asdfgh", items[0].Token)
+	assert.Equal(t, int64(2), items[0].Clicks, "the clicks are kept after a restart")
+	assert.True(t, items[1].Created.Equal(created))
+	assert.Empty(t, next)
+
+	items, _, err = restored.GetURLsPage([]string{"XXX-YYY-ZZZ"}, listing.Query{Status: listing.StatusActive})
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, "qwerty", items[0].Token)
+}
+
+func TestFileRestoreFormerClicks(t *testing.T) {
+	defer clearTestData()
+	defer os.Remove(TestStorageFileName + clicksFileSuffix)
+
+	require.NoError(t, os.WriteFile(TestStorageFileName+clicksFileSuffix, []byte("qwerty\nasdfgh\nqwerty\n"), 0o600))
+
+	storage, err := NewFile(TestStorageFileName)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int64{"qwerty": 2, "asdfgh": 1}, storage.clicks)
+
+	// The next flush rewrites the clicks as the totals.
+	require.NoError(t, storage.FlushClicks())
+	clicksJSON, err := os.ReadFile(TestStorageFileName + clicksFileSuffix)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"qwerty": 2, "asdfgh": 1}`, string(clicksJSON))
+
+	restored, err := NewFile(TestStorageFileName)
+	require.NoError(t, err)
+	assert.Equal(t, storage.clicks, restored.clicks)
+}
+
+func TestFileLinkMeta(t *testing.T) {
+	defer clearTestData()
+	defer os.Remove(TestStorageFileName + dataFileSuffix)
+
+	storage, err := NewFile(TestStorageFileName)
+	require.NoError(t, err)
+
+	expire := time.Now().Add(tkn.LifeTime)
+	require.NoError(t, storage.Set("XXX-YYY-ZZZ", "http://ya.ru", &tkn.Token{Value: "qwerty", Expire: expire}))
+	require.NoError(t, storage.Set("XXX-YYY-ZZZ", "http://go.dev", &tkn.Token{Value: "asdfgh", Expire: expire, Removed: true}))
+	require.NoError(t, storage.SetLinkMeta("qwerty", linkmeta.Meta{Title: "Ya", Notes: "docs", Tags: []string{"ru"}}))
+	require.NoError(t, storage.SetLinkMeta("asdfgh", linkmeta.Meta{Tags: []string{"go", "ru"}}))
+
+	restored, err := NewFile(TestStorageFileName)
+	require.NoError(t, err)
+
+	m, err := restored.GetLinkMeta("qwerty")
+	require.NoError(t, err)
+	assert.Equal(t, linkmeta.Meta{Title: "Ya", Notes: "docs", Tags: []string{"ru"}}, m, "the meta is kept after a restart")
+
+	tags, err := restored.GetTags([]string{"XXX-YYY-ZZZ"})
+	require.NoError(t, err)
+	assert.Equal(t, []linkmeta.TagCount{{Tag: "ru", Count: 1}}, tags, "the removed links are not counted")
+
+	renamed, err := restored.RenameTag([]string{"XXX-YYY-ZZZ"}, "ru", "web")
+	require.NoError(t, err)
+	assert.Equal(t, 2, renamed)
+
+	restored, err = NewFile(TestStorageFileName)
+	require.NoError(t, err)
+
+	m, err = restored.GetLinkMeta("asdfgh")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"go", "web"}, m.Tags)
+}
+
+func TestFileUpdateLinkMeta(t *testing.T) {
+	defer clearTestData()
+	defer os.Remove(TestStorageFileName + dataFileSuffix)
+
+	storage, err := NewFile(TestStorageFileName)
+	require.NoError(t, err)
+
+	expire := time.Now().Add(tkn.LifeTime)
+	require.NoError(t, storage.Set("XXX-YYY-ZZZ", "http://ya.ru", &tkn.Token{Value: "qwerty", Expire: expire}))
+	require.NoError(t, storage.Set("XXX-YYY-ZZZ", "http://go.dev", &tkn.Token{Value: "asdfgh", Expire: expire, Removed: true}))
+	require.NoError(t, storage.SetLinkMeta("qwerty", linkmeta.Meta{Title: "Ya", Tags: []string{"ru"}}))
+
+	notes := "docs"
+	m, err := storage.UpdateLinkMeta([]string{"XXX-YYY-ZZZ"}, "qwerty", linkmeta.Update{Notes: &notes})
+	require.NoError(t, err)
+	assert.Equal(t, linkmeta.Meta{Title: "Ya", Notes: "docs", Tags: []string{"ru"}}, m)
+
+	_, err = storage.UpdateLinkMeta([]string{"AAA-BBB-CCC"}, "qwerty", linkmeta.Update{Notes: &notes})
+	assert.ErrorIs(t, err, ErrTokenNotFound, "the links of the other owners are not found")
+
+	_, err = storage.UpdateLinkMeta([]string{"XXX-YYY-ZZZ"}, "asdfgh", linkmeta.Update{Notes: &notes})
+	assert.ErrorIs(t, err, ErrTokenNotFound, "the removed links are not found")
+
+	restored, err := NewFile(TestStorageFileName)
+	require.NoError(t, err)
+
+	m, err = restored.GetLinkMeta("qwerty")
+	require.NoError(t, err)
+	assert.Equal(t, linkmeta.Meta{Title: "Ya", Notes: "docs", Tags: []string{"ru"}}, m, "the update is kept after a restart")
+}
+
+func TestFileSearchURLs(t *testing.T) {
+	defer clearTestData()
+	defer os.Remove(TestStorageFileName + dataFileSuffix)
+
+	storage, err := NewFile(TestStorageFileName)
+	require.NoError(t, err)
+
+	expire := time.Now().Add(tkn.LifeTime)
+	require.NoError(t, storage.Set("XXX-YYY-ZZZ", "http://ya.ru", &tkn.Token{Value: "qwerty", Expire: expire}))
+	require.NoError(t, storage.Set("XXX-YYY-ZZZ", "http://go.dev", &tkn.Token{Value: "asdfgh", Expire: expire}))
+	require.NoError(t, storage.SetLinkMeta("asdfgh", linkmeta.Meta{Title: "Golang"}))
+	require.NoError(t, storage.RemoveTokens([]string{"qwerty"}, "XXX-YYY-ZZZ"))
+
+	restored, err := NewFile(TestStorageFileName)
+	require.NoError(t, err)
+
+	items, err := restored.SearchURLs([]string{"XXX-YYY-ZZZ"}, search.Query{Text: "golang"})
+	require.NoError(t, err)
+	require.Len(t, items, 1, "the index is rebuilt after a restart")
+	assert.Equal(t, "asdfgh", items[0].Token)
+
+	items, err = restored.SearchURLs([]string{"XXX-YYY-ZZZ"}, search.Query{Text: "ya"})
+	require.NoError(t, err)
+	assert.Empty(t, items, "the removed links are not indexed")
+
+	items, err = restored.SearchURLs([]string{"AAA-BBB-CCC"}, search.Query{Text: "go"})
+	require.NoError(t, err)
+	assert.Empty(t, items)
+}
+
+func TestFileGetURLsByTokens(t *testing.T) {
+	defer clearTestData()
+	defer os.Remove(TestStorageFileName + dataFileSuffix)
+
+	storage, err := NewFile(TestStorageFileName)
+	require.NoError(t, err)
+
+	expire := time.Now().Add(tkn.LifeTime)
+	require.NoError(t, storage.Set("XXX-YYY-ZZZ", "http://ya.ru", &tkn.Token{Value: "qwerty", Expire: expire}))
+	require.NoError(t, storage.Set("AAA-BBB-CCC", "http://go.dev", &tkn.Token{Value: "asdfgh", Expire: expire}))
+	require.NoError(t, storage.RemoveTokens([]string{"asdfgh"}, "AAA-BBB-CCC"))
+
+	items, err := storage.GetURLsByTokens([]string{"asdfgh", "unknown", "qwerty", "qwerty"})
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	byToken := map[string]bool{}
+	for _, item := range items {
+		byToken[item.Token] = item.Removed
+		if item.Token == "qwerty" {
+			assert.Equal(t, "http://ya.ru", item.URL)
+		}
+	}
+	assert.Equal(t, map[string]bool{"qwerty": false, "asdfgh": true}, byToken)
+}
+
+func TestFileLinkHealth(t *testing.T) {
+	defer clearTestData()
+	defer os.Remove(TestStorageFileName + dataFileSuffix)
+
+	storage, err := NewFile(TestStorageFileName)
+	require.NoError(t, err)
+
+	expire := time.Now().Add(tkn.LifeTime)
+	checked := time.Unix(0, time.Now().Add(-time.Hour).UnixNano())
+	require.NoError(t, storage.Set("XXX-YYY-ZZZ", "http://ya.ru", &tkn.Token{Value: "qwerty", Expire: expire}))
+	require.NoError(t, storage.Set("XXX-YYY-ZZZ", "http://go.dev", &tkn.Token{Value: "asdfgh", Expire: expire}))
+	require.NoError(t, storage.RemoveTokens([]string{"asdfgh"}, "XXX-YYY-ZZZ"))
+	require.NoError(t, storage.SetLinkHealth(map[string]linkcheck.Health{
+		"qwerty": {Checked: checked, Error: "timeout"},
+	}))
+
+	restored, err := NewFile(TestStorageFileName)
+	require.NoError(t, err)
+
+	items, err := restored.GetLinksToCheck(time.Now(), 10)
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, "qwerty", items[0].Token)
+	assert.True(t, checked.Equal(items[0].Health.Checked))
+	assert.Equal(t, "timeout", items[0].Health.Error)
+
+	items, err = restored.GetLinksToCheck(checked, 10)
+	require.NoError(t, err)
+	assert.Empty(t, items)
+}
+
+func TestFileLinkWebhooks(t *testing.T) {
+	defer clearTestData()
+	defer os.Remove(TestStorageFileName + dataFileSuffix)
+
+	storage, err := NewFile(TestStorageFileName)
+	require.NoError(t, err)
+
+	require.NoError(t, storage.SetLinkWebhook("anonymous", "https://hooks.example.com/1"))
+	require.NoError(t, storage.SetLinkWebhook("AAA-BBB-CCC", "https://hooks.example.com/2"))
+	require.NoError(t, storage.SetLinkWebhook("AAA-BBB-CCC", ""))
+	require.NoError(t, storage.ReassignUser("anonymous", "XXX-YYY-ZZZ"))
+
+	restored, err := NewFile(TestStorageFileName)
+	require.NoError(t, err)
+
+	for userID, want := range map[string]string{
+		"XXX-YYY-ZZZ": "https://hooks.example.com/1",
+		"AAA-BBB-CCC": "",
+		"anonymous":   "",
+	} {
+		url, err := restored.GetLinkWebhook(userID)
+		require.NoError(t, err)
+		assert.Equal(t, want, url, userID)
+	}
+}
@@ -3,8 +3,18 @@ package storage
 import (
 	"context"
 	"sync"
+	"time"
 
+	"github.com/alrund/yp-1/internal/app/account"
+	"github.com/alrund/yp-1/internal/app/apikey"
+	"github.com/alrund/yp-1/internal/app/audit"
+	"github.com/alrund/yp-1/internal/app/linkcheck"
+	"github.com/alrund/yp-1/internal/app/linkmeta"
+	"github.com/alrund/yp-1/internal/app/listing"
+	"github.com/alrund/yp-1/internal/app/ratelimit"
+	"github.com/alrund/yp-1/internal/app/search"
 	tkn "github.com/alrund/yp-1/internal/app/token"
+	"github.com/alrund/yp-1/internal/app/workspace"
 )
 
 // Map hash map storage.
@@ -12,6 +22,21 @@ type Map struct {
 	userID2tokenValue    map[string][]string
 	url2tokenValue       map[string]string
 	tokenValue2composite map[string]*composite
+	apiKeys              map[string]*apikey.Key
+	apiKeyHashes         map[string]string // key hash: key ID
+	accounts             map[string]*account.Account
+	accountLogins        map[string]string // login: account ID
+	workspaces           map[string]*workspace.Workspace
+	members              map[string]map[string]workspace.Role // workspace ID: user ID: role
+	rateBuckets          map[string]ratelimit.Bucket
+	quotaDay             string
+	quotaUsage           map[string]int
+	auditEvents          []*audit.Event
+	clicks               map[string]int64
+	linkMeta             map[string]linkmeta.Meta
+	health               map[string]linkcheck.Health
+	webhooks             map[string]string // user ID: the link check webhook URL
+	index                *search.Index     // the links which are not removed
 	mx                   sync.RWMutex
 }
 
@@ -20,6 +45,19 @@ func NewMap() *Map {
 		userID2tokenValue:    make(map[string][]string),
 		url2tokenValue:       make(map[string]string),
 		tokenValue2composite: make(map[string]*composite),
+		apiKeys:              make(map[string]*apikey.Key),
+		apiKeyHashes:         make(map[string]string),
+		accounts:             make(map[string]*account.Account),
+		accountLogins:        make(map[string]string),
+		workspaces:           make(map[string]*workspace.Workspace),
+		members:              make(map[string]map[string]workspace.Role),
+		rateBuckets:          make(map[string]ratelimit.Bucket),
+		quotaUsage:           make(map[string]int),
+		clicks:               make(map[string]int64),
+		linkMeta:             make(map[string]linkmeta.Meta),
+		health:               make(map[string]linkcheck.Health),
+		webhooks:             make(map[string]string),
+		index:                search.NewIndex(),
 	}
 }
 
@@ -40,6 +78,7 @@ func (s *Map) Set(userID, url string, token *tkn.Token) error {
 	s.userID2tokenValue[userID] = append(s.userID2tokenValue[userID], token.Value)
 	s.url2tokenValue[url] = token.Value
 	s.tokenValue2composite[token.Value] = &composite{token, url, userID}
+	s.indexLink(token.Value)
 	s.mx.Unlock()
 	return nil
 }
@@ -81,6 +120,7 @@ func (s *Map) RemoveTokens(tokenValues []string, userID string) error {
 			continue
 		}
 		composite.Token.Removed = true
+		s.index.Remove(tokenValue)
 	}
 
 	return nil
@@ -175,6 +215,521 @@ func (s *Map) GetURLsByUserID(userID string) ([]URLpairs, error) {
 	return nil, ErrURLNotFound
 }
 
+// GetURLsPage returns a page of the URLs of the owners and the cursor of the next page.
+func (s *Map) GetURLsPage(ownerIDs []string, query listing.Query) ([]listing.Item, string, error) {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+
+	composites := s.ownedComposites(ownerIDs)
+	items := make([]listing.Item, 0, len(composites))
+	for _, c := range composites {
+		items = append(items, newListingItem(c, s.clicks[c.Token.Value], s.linkMeta[c.Token.Value], s.health[c.Token.Value]))
+	}
+
+	return query.Page(items, time.Now())
+}
+
+// GetURLsByTokens returns the links of the tokens without the clicks and the meta, the unknown tokens are skipped.
+func (s *Map) GetURLsByTokens(tokenValues []string) ([]listing.Item, error) {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+
+	items := make([]listing.Item, 0, len(tokenValues))
+	seen := make(map[string]struct{}, len(tokenValues))
+	for _, tokenValue := range tokenValues {
+		c, ok := s.tokenValue2composite[tokenValue]
+		if _, dup := seen[tokenValue]; dup || !ok {
+			continue
+		}
+		seen[tokenValue] = struct{}{}
+		items = append(items, newListingItem(c, 0, linkmeta.Meta{}, linkcheck.Health{}))
+	}
+
+	return items, nil
+}
+
+// ownedComposites returns the links of the owners, the caller holds the lock.
+func (s *Map) ownedComposites(ownerIDs []string) []*composite {
+	composites := make([]*composite, 0)
+	seen := make(map[string]struct{})
+	for _, ownerID := range ownerIDs {
+		for _, tokenValue := range s.userID2tokenValue[ownerID] {
+			composite, ok := s.tokenValue2composite[tokenValue]
+			if _, dup := seen[tokenValue]; dup || !ok || composite.UserID != ownerID {
+				continue
+			}
+			seen[tokenValue] = struct{}{}
+			composites = append(composites, composite)
+		}
+	}
+	return composites
+}
+
+// SearchURLs returns the links of the owners which are not removed and match the query, from the most relevant.
+func (s *Map) SearchURLs(ownerIDs []string, query search.Query) ([]listing.Item, error) {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+
+	owned := make(map[string]*composite)
+	for _, c := range s.ownedComposites(ownerIDs) {
+		owned[c.Token.Value] = c
+	}
+
+	items := make([]listing.Item, 0)
+	for _, hit := range s.index.Search(query.Terms()) {
+		if c, ok := owned[hit.Token]; ok && len(items) < query.PageSize() {
+			items = append(items, newListingItem(c, s.clicks[c.Token.Value], s.linkMeta[c.Token.Value], s.health[c.Token.Value]))
+		}
+	}
+
+	return items, nil
+}
+
+// indexLink puts the link into the search index, the caller holds the lock.
+func (s *Map) indexLink(tokenValue string) {
+	c, ok := s.tokenValue2composite[tokenValue]
+	if !ok || c.Token.Removed {
+		return
+	}
+	s.index.Put(newSearchDocument(c, s.linkMeta[tokenValue]))
+}
+
+// AddClick counts a redirect by the token.
+func (s *Map) AddClick(tokenValue string) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	s.clicks[tokenValue]++
+	return nil
+}
+
+// FlushClicks does nothing, the clicks are kept in memory only.
+func (s *Map) FlushClicks() error {
+	return nil
+}
+
+func (s *Map) SetLinkMeta(tokenValue string, m linkmeta.Meta) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	s.linkMeta[tokenValue] = m
+	s.indexLink(tokenValue)
+	return nil
+}
+
+// UpdateLinkMeta applies the update to the meta of the link of the owners which is not removed.
+func (s *Map) UpdateLinkMeta(ownerIDs []string, tokenValue string, update linkmeta.Update) (linkmeta.Meta, error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	c, ok := s.tokenValue2composite[tokenValue]
+	if !ok || c.Token.Removed || !ownedBy(ownerIDs, c.UserID) {
+		return linkmeta.Meta{}, ErrTokenNotFound
+	}
+
+	m, err := update.Apply(s.linkMeta[tokenValue])
+	if err != nil {
+		return linkmeta.Meta{}, err
+	}
+
+	s.linkMeta[tokenValue] = m
+	s.indexLink(tokenValue)
+	return m, nil
+}
+
+// GetLinkMeta returns the meta of the link, the zero meta if it has none.
+func (s *Map) GetLinkMeta(tokenValue string) (linkmeta.Meta, error) {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+
+	return s.linkMeta[tokenValue], nil
+}
+
+// GetTags returns the tags of the links of the owners which are not removed.
+func (s *Map) GetTags(ownerIDs []string) ([]linkmeta.TagCount, error) {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+
+	metas := make([]linkmeta.Meta, 0)
+	for _, c := range s.ownedComposites(ownerIDs) {
+		if !c.Token.Removed {
+			metas = append(metas, s.linkMeta[c.Token.Value])
+		}
+	}
+
+	return linkmeta.CountTags(metas), nil
+}
+
+// RenameTag renames the tag on the links of the owners and returns the number of the links changed.
+func (s *Map) RenameTag(ownerIDs []string, from, to string) (int, error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	renamed := 0
+	for _, c := range s.ownedComposites(ownerIDs) {
+		if m, ok := s.linkMeta[c.Token.Value].RenameTag(from, to); ok {
+			s.linkMeta[c.Token.Value] = m
+			s.indexLink(c.Token.Value)
+			renamed++
+		}
+	}
+
+	return renamed, nil
+}
+
+// GetLinksToCheck returns the active links checked before the time, the never checked ones first.
+func (s *Map) GetLinksToCheck(checkedBefore time.Time, limit int) ([]listing.Item, error) {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+
+	items := make([]listing.Item, 0)
+	for tokenValue, c := range s.tokenValue2composite {
+		h := s.health[tokenValue]
+		if c.Token.Removed || c.Token.IsExpired() || (!h.IsZero() && !h.Checked.Before(checkedBefore)) {
+			continue
+		}
+		items = append(items, newListingItem(c, s.clicks[tokenValue], s.linkMeta[tokenValue], h))
+	}
+
+	sortLinksToCheck(items)
+	if limit > 0 && len(items) > limit {
+		items = items[:limit]
+	}
+
+	return items, nil
+}
+
+// SetLinkHealth keeps the results of the checks by the token.
+func (s *Map) SetLinkHealth(health map[string]linkcheck.Health) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	for tokenValue, h := range health {
+		s.health[tokenValue] = h
+	}
+
+	return nil
+}
+
+// SetLinkWebhook keeps the URL the user's link check events are posted to, an empty URL removes it.
+func (s *Map) SetLinkWebhook(userID, url string) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	if url == "" {
+		delete(s.webhooks, userID)
+		return nil
+	}
+	s.webhooks[userID] = url
+
+	return nil
+}
+
+// GetLinkWebhook returns the user's link check webhook URL, empty if the user has none.
+func (s *Map) GetLinkWebhook(userID string) (string, error) {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+
+	return s.webhooks[userID], nil
+}
+
 func (s *Map) Ping(ctx context.Context) error {
 	return nil
 }
+
+func (s *Map) SetAPIKey(key *apikey.Key) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	if old, ok := s.apiKeys[key.ID]; ok {
+		delete(s.apiKeyHashes, old.Hash)
+	}
+	k := *key
+	s.apiKeys[key.ID] = &k
+	s.apiKeyHashes[key.Hash] = key.ID
+	return nil
+}
+
+func (s *Map) GetAPIKey(hash string) (*apikey.Key, error) {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+
+	key, ok := s.apiKeys[s.apiKeyHashes[hash]]
+	if !ok {
+		return nil, ErrAPIKeyNotFound
+	}
+	k := *key
+	return &k, nil
+}
+
+func (s *Map) GetAPIKeysByUserID(userID string) ([]*apikey.Key, error) {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+
+	keys := make([]*apikey.Key, 0)
+	for _, key := range s.apiKeys {
+		if key.UserID == userID {
+			k := *key
+			keys = append(keys, &k)
+		}
+	}
+	sortAPIKeys(keys)
+
+	return keys, nil
+}
+
+func (s *Map) RevokeAPIKey(id, userID string) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	key, ok := s.apiKeys[id]
+	if !ok || key.UserID != userID {
+		return ErrAPIKeyNotFound
+	}
+	key.Revoked = true
+
+	return nil
+}
+
+func (s *Map) SetAccount(a *account.Account) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	if _, ok := s.accountLogins[a.Login]; ok {
+		return ErrAccountExists
+	}
+
+	acc := *a
+	s.accounts[a.ID] = &acc
+	s.accountLogins[a.Login] = a.ID
+	return nil
+}
+
+func (s *Map) GetAccountByLogin(login string) (*account.Account, error) {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+
+	a, ok := s.accounts[s.accountLogins[login]]
+	if !ok {
+		return nil, ErrAccountNotFound
+	}
+	acc := *a
+	return &acc, nil
+}
+
+func (s *Map) GetAccountByID(id string) (*account.Account, error) {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+
+	a, ok := s.accounts[id]
+	if !ok {
+		return nil, ErrAccountNotFound
+	}
+	acc := *a
+	return &acc, nil
+}
+
+func (s *Map) ReassignUser(fromUserID, toUserID string) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	if tokenValues, ok := s.userID2tokenValue[fromUserID]; ok {
+		for _, tokenValue := range tokenValues {
+			if composite, ok := s.tokenValue2composite[tokenValue]; ok {
+				composite.UserID = toUserID
+			}
+		}
+		s.userID2tokenValue[toUserID] = append(s.userID2tokenValue[toUserID], tokenValues...)
+		delete(s.userID2tokenValue, fromUserID)
+	}
+
+	for _, key := range s.apiKeys {
+		if key.UserID == fromUserID {
+			key.UserID = toUserID
+		}
+	}
+	reassignMembers(s.members, fromUserID, toUserID)
+	reassignWebhook(s.webhooks, fromUserID, toUserID)
+
+	return nil
+}
+
+func (s *Map) HasUser(userID string) (bool, error) {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+
+	if len(s.userID2tokenValue[userID]) > 0 {
+		return true, nil
+	}
+	if _, ok := s.accounts[userID]; ok {
+		return true, nil
+	}
+	for _, key := range s.apiKeys {
+		if key.UserID == userID {
+			return true, nil
+		}
+	}
+	for _, roles := range s.members {
+		if _, ok := roles[userID]; ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *Map) SetWorkspace(w *workspace.Workspace) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	ws := *w
+	s.workspaces[w.ID] = &ws
+	return nil
+}
+
+func (s *Map) GetWorkspace(id string) (*workspace.Workspace, error) {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+
+	w, ok := s.workspaces[id]
+	if !ok {
+		return nil, ErrWorkspaceNotFound
+	}
+	ws := *w
+	return &ws, nil
+}
+
+func (s *Map) SetMember(m *workspace.Member) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	if _, ok := s.workspaces[m.WorkspaceID]; !ok {
+		return ErrWorkspaceNotFound
+	}
+	if !keepsAdmin(s.members[m.WorkspaceID], m.UserID, m.Role) {
+		return ErrLastAdmin
+	}
+	if _, ok := s.members[m.WorkspaceID]; !ok {
+		s.members[m.WorkspaceID] = make(map[string]workspace.Role)
+	}
+	s.members[m.WorkspaceID][m.UserID] = m.Role
+	return nil
+}
+
+func (s *Map) GetMember(workspaceID, userID string) (*workspace.Member, error) {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+
+	role, ok := s.members[workspaceID][userID]
+	if !ok {
+		return nil, ErrMemberNotFound
+	}
+	return &workspace.Member{WorkspaceID: workspaceID, UserID: userID, Role: role}, nil
+}
+
+func (s *Map) GetMembers(workspaceID string) ([]*workspace.Member, error) {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+
+	members := make([]*workspace.Member, 0, len(s.members[workspaceID]))
+	for userID, role := range s.members[workspaceID] {
+		members = append(members, &workspace.Member{WorkspaceID: workspaceID, UserID: userID, Role: role})
+	}
+	sortMembers(members)
+
+	return members, nil
+}
+
+func (s *Map) GetMembershipsByUserID(userID string) ([]*workspace.Member, error) {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+
+	members := make([]*workspace.Member, 0)
+	for workspaceID, roles := range s.members {
+		if role, ok := roles[userID]; ok {
+			members = append(members, &workspace.Member{WorkspaceID: workspaceID, UserID: userID, Role: role})
+		}
+	}
+	sortMemberships(members)
+
+	return members, nil
+}
+
+func (s *Map) RemoveMember(workspaceID, userID string) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	if _, ok := s.members[workspaceID][userID]; !ok {
+		return ErrMemberNotFound
+	}
+	if !keepsAdmin(s.members[workspaceID], userID, "") {
+		return ErrLastAdmin
+	}
+	delete(s.members[workspaceID], userID)
+	return nil
+}
+
+func (s *Map) GetRateBucket(key string) (ratelimit.Bucket, error) {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+
+	return s.rateBuckets[key], nil
+}
+
+func (s *Map) SetRateBucket(key string, b ratelimit.Bucket) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	s.rateBuckets[key] = b
+	return nil
+}
+
+// AddQuotaUsage keeps the usage of the current day only, the changes of a past day are ignored.
+func (s *Map) AddQuotaUsage(key, day string, n int) (int, error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	switch {
+	case day < s.quotaDay:
+		return 0, nil
+	case day > s.quotaDay:
+		s.quotaDay = day
+		s.quotaUsage = make(map[string]int)
+	}
+	s.quotaUsage[key] += n
+	return s.quotaUsage[key], nil
+}
+
+func (s *Map) PruneRateLimits(updatedBefore time.Time, day string) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	for key, b := range s.rateBuckets {
+		if b.Updated.Before(updatedBefore) {
+			delete(s.rateBuckets, key)
+		}
+	}
+	if s.quotaDay < day {
+		s.quotaDay = day
+		s.quotaUsage = make(map[string]int)
+	}
+	return nil
+}
+
+func (s *Map) AddAuditEvents(events []*audit.Event) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	for _, e := range events {
+		e.ID = int64(len(s.auditEvents) + 1)
+		stored := *e
+		s.auditEvents = append(s.auditEvents, &stored)
+	}
+	return nil
+}
+
+func (s *Map) GetAuditEvents(filter audit.Filter) ([]*audit.Event, error) {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+
+	return filterAuditEvents(s.auditEvents, filter), nil
+}
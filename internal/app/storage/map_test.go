@@ -2,10 +2,20 @@ package storage
 
 import (
 	"context"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/alrund/yp-1/internal/app/account"
+	"github.com/alrund/yp-1/internal/app/apikey"
+	"github.com/alrund/yp-1/internal/app/audit"
+	"github.com/alrund/yp-1/internal/app/linkcheck"
+	"github.com/alrund/yp-1/internal/app/linkmeta"
+	"github.com/alrund/yp-1/internal/app/listing"
+	"github.com/alrund/yp-1/internal/app/ratelimit"
+	"github.com/alrund/yp-1/internal/app/search"
 	tkn "github.com/alrund/yp-1/internal/app/token"
+	"github.com/alrund/yp-1/internal/app/workspace"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -490,6 +500,7 @@ func TestSet(t *testing.T) {
 				userID2tokenValue:    make(map[string][]string),
 				url2tokenValue:       map[string]string{},
 				tokenValue2composite: map[string]*composite{},
+				index:                search.NewIndex(),
 			},
 			args{
 				userID: "XXX-YYY-ZZZ",
@@ -532,6 +543,7 @@ func TestSetBatch(t *testing.T) {
 				userID2tokenValue:    make(map[string][]string),
 				url2tokenValue:       map[string]string{},
 				tokenValue2composite: map[string]*composite{},
+				index:                search.NewIndex(),
 			},
 			args{
 				userID: "XXX-YYY-ZZZ",
@@ -586,6 +598,7 @@ func TestRemoveTokens(t *testing.T) {
 						UserID: "XXX-YYY-ZZZ",
 					},
 				},
+				index: search.NewIndex(),
 			},
 			args{
 				tokenValues: []string{"xxx"},
@@ -687,6 +700,19 @@ func TestNewMapStorage(t *testing.T) {
 				userID2tokenValue:    make(map[string][]string),
 				url2tokenValue:       make(map[string]string),
 				tokenValue2composite: make(map[string]*composite),
+				apiKeys:              make(map[string]*apikey.Key),
+				apiKeyHashes:         make(map[string]string),
+				accounts:             make(map[string]*account.Account),
+				accountLogins:        make(map[string]string),
+				workspaces:           make(map[string]*workspace.Workspace),
+				members:              make(map[string]map[string]workspace.Role),
+				rateBuckets:          make(map[string]ratelimit.Bucket),
+				quotaUsage:           make(map[string]int),
+				clicks:               make(map[string]int64),
+				linkMeta:             make(map[string]linkmeta.Meta),
+				health:               make(map[string]linkcheck.Health),
+				webhooks:             make(map[string]string),
+				index:                search.NewIndex(),
 			},
 		},
 	}
@@ -751,3 +777,511 @@ func BenchmarkMapGet(b *testing.B) {
 		}
 	})
 }
+
+func TestMapAPIKeys(t *testing.T) {
+	storage := NewMap()
+
+	older := &apikey.Key{ID: "1", UserID: "XXX-YYY-ZZZ", Name: "ci", Hash: "hash1", Created: time.Now().Add(-time.Hour)}
+	newer := &apikey.Key{ID: "2", UserID: "XXX-YYY-ZZZ", Name: "bot", Hash: "hash2", Created: time.Now()}
+	other := &apikey.Key{ID: "3", UserID: "AAA-BBB-CCC", Name: "ci", Hash: "hash3", Created: time.Now()}
+	for _, key := range []*apikey.Key{newer, older, other} {
+		require.NoError(t, storage.SetAPIKey(key))
+	}
+
+	got, err := storage.GetAPIKey("hash2")
+	require.NoError(t, err)
+	assert.Equal(t, newer, got)
+
+	_, err = storage.GetAPIKey("unknown")
+	assert.ErrorIs(t, err, ErrAPIKeyNotFound)
+
+	keys, err := storage.GetAPIKeysByUserID("XXX-YYY-ZZZ")
+	require.NoError(t, err)
+	assert.Equal(t, []*apikey.Key{older, newer}, keys)
+
+	assert.ErrorIs(t, storage.RevokeAPIKey("3", "XXX-YYY-ZZZ"), ErrAPIKeyNotFound)
+	require.NoError(t, storage.RevokeAPIKey("1", "XXX-YYY-ZZZ"))
+
+	got, err = storage.GetAPIKey("hash1")
+	require.NoError(t, err)
+	assert.True(t, got.Revoked)
+
+	require.NoError(t, storage.SetAPIKey(&apikey.Key{ID: "2", UserID: "XXX-YYY-ZZZ", Hash: "rehashed"}))
+	_, err = storage.GetAPIKey("hash2")
+	assert.ErrorIs(t, err, ErrAPIKeyNotFound)
+	got, err = storage.GetAPIKey("rehashed")
+	require.NoError(t, err)
+	assert.Equal(t, "2", got.ID)
+}
+
+func TestMapAccounts(t *testing.T) {
+	storage := NewMap()
+
+	alice := &account.Account{ID: "XXX-YYY-ZZZ", Login: "alice", PasswordHash: "hash", Created: time.Now()}
+	require.NoError(t, storage.SetAccount(alice))
+	assert.ErrorIs(t, storage.SetAccount(&account.Account{ID: "AAA-BBB-CCC", Login: "alice"}), ErrAccountExists)
+
+	got, err := storage.GetAccountByLogin("alice")
+	require.NoError(t, err)
+	assert.Equal(t, alice, got)
+
+	got, err = storage.GetAccountByID("XXX-YYY-ZZZ")
+	require.NoError(t, err)
+	assert.Equal(t, alice, got)
+
+	_, err = storage.GetAccountByLogin("bob")
+	assert.ErrorIs(t, err, ErrAccountNotFound)
+	_, err = storage.GetAccountByID("AAA-BBB-CCC")
+	assert.ErrorIs(t, err, ErrAccountNotFound)
+}
+
+func TestMapReassignUser(t *testing.T) {
+	storage := NewMap()
+	require.NoError(t, storage.Set("XXX-YYY-ZZZ", "url1", &tkn.Token{Value: "xxx", Expire: time.Now().Add(tkn.LifeTime)}))
+	require.NoError(t, storage.Set("anonymous", "url2", &tkn.Token{Value: "yyy", Expire: time.Now().Add(tkn.LifeTime)}))
+	require.NoError(t, storage.SetAPIKey(&apikey.Key{ID: "1", UserID: "anonymous", Hash: "hash1"}))
+	for _, id := range []string{"team", "shared"} {
+		require.NoError(t, storage.SetWorkspace(&workspace.Workspace{ID: id}))
+	}
+	require.NoError(t, storage.SetMember(&workspace.Member{WorkspaceID: "team", UserID: "anonymous", Role: workspace.RoleAdmin}))
+	require.NoError(t, storage.SetMember(&workspace.Member{WorkspaceID: "shared", UserID: "anonymous", Role: workspace.RoleAdmin}))
+	require.NoError(t, storage.SetMember(&workspace.Member{WorkspaceID: "shared", UserID: "XXX-YYY-ZZZ", Role: workspace.RoleViewer}))
+
+	require.NoError(t, storage.ReassignUser("anonymous", "XXX-YYY-ZZZ"))
+	require.NoError(t, storage.ReassignUser("unknown", "XXX-YYY-ZZZ"))
+
+	urls, err := storage.GetURLsByUserID("XXX-YYY-ZZZ")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []URLpairs{
+		{ShortURL: "xxx", OriginalURL: "url1"},
+		{ShortURL: "yyy", OriginalURL: "url2"},
+	}, urls)
+
+	_, err = storage.GetURLsByUserID("anonymous")
+	assert.ErrorIs(t, err, ErrTokenNotFound)
+
+	require.NoError(t, storage.RemoveTokens([]string{"yyy"}, "XXX-YYY-ZZZ"))
+	token, err := storage.GetToken("yyy")
+	require.NoError(t, err)
+	assert.True(t, token.Removed, "the new owner can remove the merged links")
+
+	keys, err := storage.GetAPIKeysByUserID("XXX-YYY-ZZZ")
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	assert.Equal(t, "1", keys[0].ID)
+
+	memberships, err := storage.GetMembershipsByUserID("XXX-YYY-ZZZ")
+	require.NoError(t, err)
+	require.Len(t, memberships, 2)
+	for _, m := range memberships {
+		assert.Equal(t, workspace.RoleAdmin, m.Role, m.WorkspaceID)
+	}
+	memberships, err = storage.GetMembershipsByUserID("anonymous")
+	require.NoError(t, err)
+	assert.Empty(t, memberships)
+}
+
+func TestMapWorkspaces(t *testing.T) {
+	storage := NewMap()
+
+	team := &workspace.Workspace{ID: "WWW-WWW-WWW", Name: "team", Created: time.Now()}
+	require.NoError(t, storage.SetWorkspace(team))
+	assert.ErrorIs(t, storage.SetMember(&workspace.Member{WorkspaceID: "unknown", UserID: "XXX-YYY-ZZZ"}), ErrWorkspaceNotFound)
+
+	got, err := storage.GetWorkspace("WWW-WWW-WWW")
+	require.NoError(t, err)
+	assert.Equal(t, team, got)
+	_, err = storage.GetWorkspace("unknown")
+	assert.ErrorIs(t, err, ErrWorkspaceNotFound)
+
+	require.NoError(t, storage.SetMember(&workspace.Member{WorkspaceID: "WWW-WWW-WWW", UserID: "XXX-YYY-ZZZ", Role: workspace.RoleAdmin}))
+	require.NoError(t, storage.SetMember(&workspace.Member{WorkspaceID: "WWW-WWW-WWW", UserID: "AAA-BBB-CCC", Role: workspace.RoleViewer}))
+	require.NoError(t, storage.SetMember(&workspace.Member{WorkspaceID: "WWW-WWW-WWW", UserID: "AAA-BBB-CCC", Role: workspace.RoleEditor}))
+
+	member, err := storage.GetMember("WWW-WWW-WWW", "AAA-BBB-CCC")
+	require.NoError(t, err)
+	assert.Equal(t, workspace.RoleEditor, member.Role)
+	_, err = storage.GetMember("WWW-WWW-WWW", "unknown")
+	assert.ErrorIs(t, err, ErrMemberNotFound)
+
+	members, err := storage.GetMembers("WWW-WWW-WWW")
+	require.NoError(t, err)
+	assert.Equal(t, []*workspace.Member{
+		{WorkspaceID: "WWW-WWW-WWW", UserID: "AAA-BBB-CCC", Role: workspace.RoleEditor},
+		{WorkspaceID: "WWW-WWW-WWW", UserID: "XXX-YYY-ZZZ", Role: workspace.RoleAdmin},
+	}, members)
+
+	memberships, err := storage.GetMembershipsByUserID("XXX-YYY-ZZZ")
+	require.NoError(t, err)
+	assert.Equal(t, []*workspace.Member{
+		{WorkspaceID: "WWW-WWW-WWW", UserID: "XXX-YYY-ZZZ", Role: workspace.RoleAdmin},
+	}, memberships)
+
+	ok, err := storage.HasUser("AAA-BBB-CCC")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	require.NoError(t, storage.RemoveMember("WWW-WWW-WWW", "AAA-BBB-CCC"))
+	assert.ErrorIs(t, storage.RemoveMember("WWW-WWW-WWW", "AAA-BBB-CCC"), ErrMemberNotFound)
+
+	ok, err = storage.HasUser("AAA-BBB-CCC")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	// The last admin can neither step down nor leave.
+	assert.ErrorIs(t, storage.SetMember(&workspace.Member{WorkspaceID: "WWW-WWW-WWW", UserID: "XXX-YYY-ZZZ", Role: workspace.RoleEditor}), ErrLastAdmin)
+	assert.ErrorIs(t, storage.RemoveMember("WWW-WWW-WWW", "XXX-YYY-ZZZ"), ErrLastAdmin)
+	require.NoError(t, storage.SetMember(&workspace.Member{WorkspaceID: "WWW-WWW-WWW", UserID: "AAA-BBB-CCC", Role: workspace.RoleAdmin}))
+	require.NoError(t, storage.RemoveMember("WWW-WWW-WWW", "XXX-YYY-ZZZ"))
+
+	memberships, err = storage.GetMembershipsByUserID("XXX-YYY-ZZZ")
+	require.NoError(t, err)
+	assert.Empty(t, memberships)
+}
+
+func TestMapHasUser(t *testing.T) {
+	storage := NewMap()
+
+	require.NoError(t, storage.Set("XXX-YYY-ZZZ", "http://ya.ru", &tkn.Token{Value: "qwerty", Expire: time.Now().Add(time.Hour)}))
+	require.NoError(t, storage.SetAccount(&account.Account{ID: "AAA-BBB-CCC", Login: "user"}))
+
+	for _, userID := range []string{"XXX-YYY-ZZZ", "AAA-BBB-CCC"} {
+		ok, err := storage.HasUser(userID)
+		require.NoError(t, err)
+		assert.True(t, ok, userID)
+	}
+
+	ok, err := storage.HasUser("unknown")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestMapRateLimits(t *testing.T) {
+	storage := NewMap()
+
+	b, err := storage.GetRateBucket("create:user:1")
+	require.NoError(t, err)
+	assert.Equal(t, ratelimit.Bucket{}, b)
+
+	updated := time.Now()
+	require.NoError(t, storage.SetRateBucket("create:user:1", ratelimit.Bucket{Tokens: 1.5, Updated: updated}))
+	b, err = storage.GetRateBucket("create:user:1")
+	require.NoError(t, err)
+	assert.Equal(t, ratelimit.Bucket{Tokens: 1.5, Updated: updated}, b)
+
+	used, err := storage.AddQuotaUsage("user:1", "2022-11-20", 2)
+	require.NoError(t, err)
+	assert.Equal(t, 2, used)
+	used, err = storage.AddQuotaUsage("user:1", "2022-11-20", 3)
+	require.NoError(t, err)
+	assert.Equal(t, 5, used)
+	used, err = storage.AddQuotaUsage("user:1", "2022-11-21", 1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, used, "the usage starts over on the next day")
+	used, err = storage.AddQuotaUsage("user:1", "2022-11-20", -2)
+	require.NoError(t, err)
+	assert.Zero(t, used, "the refund of the past day is ignored")
+	used, err = storage.AddQuotaUsage("user:1", "2022-11-21", 1)
+	require.NoError(t, err)
+	assert.Equal(t, 2, used, "the usage of the current day is kept")
+
+	require.NoError(t, storage.SetRateBucket("create:user:2", ratelimit.Bucket{Tokens: 1, Updated: updated.Add(time.Minute)}))
+	require.NoError(t, storage.PruneRateLimits(updated.Add(time.Second), "2022-11-22"))
+	b, err = storage.GetRateBucket("create:user:1")
+	require.NoError(t, err)
+	assert.Equal(t, ratelimit.Bucket{}, b)
+	b, err = storage.GetRateBucket("create:user:2")
+	require.NoError(t, err)
+	assert.Equal(t, 1.0, b.Tokens)
+	used, err = storage.AddQuotaUsage("user:1", "2022-11-22", 1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, used)
+}
+
+func TestMapQuotaRefundNextDay(t *testing.T) {
+	storage := NewMap()
+
+	_, err := storage.AddQuotaUsage("user:1", "2022-11-20", 3)
+	require.NoError(t, err)
+	_, err = storage.AddQuotaUsage("user:1", "2022-11-21", 2)
+	require.NoError(t, err)
+	_, err = storage.AddQuotaUsage("user:2", "2022-11-21", 1)
+	require.NoError(t, err)
+
+	_, err = storage.AddQuotaUsage("user:1", "2022-11-20", -3)
+	require.NoError(t, err)
+
+	used, err := storage.AddQuotaUsage("user:1", "2022-11-21", 0)
+	require.NoError(t, err)
+	assert.Equal(t, 2, used, "the refund of the past day does not change the current one")
+	used, err = storage.AddQuotaUsage("user:2", "2022-11-21", 0)
+	require.NoError(t, err)
+	assert.Equal(t, 1, used, "the usage of the other keys is kept")
+}
+
+func TestMapAuditEvents(t *testing.T) {
+	storage := NewMap()
+
+	require.NoError(t, storage.AddAuditEvents([]*audit.Event{
+		{Action: audit.ActionCreate, Token: "qwerty", OwnerID: "XXX-YYY-ZZZ", ActorID: "XXX-YYY-ZZZ"},
+		{Action: audit.ActionCreate, Token: "asdfgh", OwnerID: "AAA-BBB-CCC", ActorID: "AAA-BBB-CCC"},
+	}))
+	require.NoError(t, storage.AddAuditEvents([]*audit.Event{
+		{Action: audit.ActionDelete, Token: "qwerty", OwnerID: "XXX-YYY-ZZZ", ActorID: "XXX-YYY-ZZZ"},
+	}))
+
+	events, err := storage.GetAuditEvents(audit.Filter{Token: "qwerty"})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, int64(3), events[0].ID)
+	assert.Equal(t, audit.ActionDelete, events[0].Action)
+	assert.Equal(t, int64(1), events[1].ID)
+
+	events, err = storage.GetAuditEvents(audit.Filter{UserID: "AAA-BBB-CCC"})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "asdfgh", events[0].Token)
+
+	events, err = storage.GetAuditEvents(audit.Filter{Limit: 1})
+	require.NoError(t, err)
+	assert.Len(t, events, 1)
+}
+
+func TestMapURLsPage(t *testing.T) {
+	storage := NewMap()
+	now := time.Now()
+
+	require.NoError(t, storage.Set("XXX-YYY-ZZZ", "http://ya.ru", &tkn.Token{
+		Value: "qwerty", Expire: now.Add(tkn.LifeTime), Created: now.Add(-time.Hour),
+	}))
+	require.NoError(t, storage.Set("XXX-YYY-ZZZ", "http://go.dev", &tkn.Token{
+		Value: "asdfgh", Expire: now.Add(tkn.LifeTime), Created: now,
+	}))
+	require.NoError(t, storage.Set("WORKSPACE", "http://team.ru", &tkn.Token{
+		Value: "zxcvbn", Expire: now.Add(-time.Hour), Created: now.Add(-2 * time.Hour),
+	}))
+	require.NoError(t, storage.Set("AAA-BBB-CCC", "http://other.ru", &tkn.Token{
+		Value: "other", Expire: now.Add(tkn.LifeTime), Created: now,
+	}))
+	require.NoError(t, storage.AddClick("qwerty"))
+
+	items, next, err := storage.GetURLsPage([]string{"XXX-YYY-ZZZ", "WORKSPACE"}, listing.Query{Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	assert.Equal(t, "asdfgh", items[0].Token)
+	assert.Equal(t, "qwerty", items[1].Token)
+	assert.Equal(t, int64(1), items[1].Clicks)
+
+	items, next, err = storage.GetURLsPage([]string{"XXX-YYY-ZZZ", "WORKSPACE"}, listing.Query{Limit: 2, Cursor: next})
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, listing.Item{
+		Token: "zxcvbn", URL: "http://team.ru", OwnerID: "WORKSPACE",
+		Created: now.Add(-2 * time.Hour), Expire: now.Add(-time.Hour),
+	}, items[0])
+	assert.Empty(t, next)
+
+	items, _, err = storage.GetURLsPage([]string{"XXX-YYY-ZZZ", "WORKSPACE"}, listing.Query{Status: listing.StatusExpired})
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, "zxcvbn", items[0].Token)
+}
+
+func TestMapLinkMeta(t *testing.T) {
+	storage := NewMap()
+	now := time.Now()
+
+	require.NoError(t, storage.Set("XXX-YYY-ZZZ", "http://ya.ru", &tkn.Token{Value: "qwerty", Expire: now.Add(tkn.LifeTime)}))
+	require.NoError(t, storage.Set("XXX-YYY-ZZZ", "http://go.dev", &tkn.Token{Value: "asdfgh", Expire: now.Add(tkn.LifeTime)}))
+	require.NoError(t, storage.Set("AAA-BBB-CCC", "http://other.ru", &tkn.Token{Value: "other", Expire: now.Add(tkn.LifeTime)}))
+	require.NoError(t, storage.SetLinkMeta("qwerty", linkmeta.Meta{Title: "Ya", Tags: []string{"ru", "search"}}))
+	require.NoError(t, storage.SetLinkMeta("asdfgh", linkmeta.Meta{Tags: []string{"go", "search"}}))
+	require.NoError(t, storage.SetLinkMeta("other", linkmeta.Meta{Tags: []string{"ru"}}))
+
+	m, err := storage.GetLinkMeta("qwerty")
+	require.NoError(t, err)
+	assert.Equal(t, linkmeta.Meta{Title: "Ya", Tags: []string{"ru", "search"}}, m)
+
+	m, err = storage.GetLinkMeta("unknown")
+	require.NoError(t, err)
+	assert.True(t, m.IsZero())
+
+	tags, err := storage.GetTags([]string{"XXX-YYY-ZZZ"})
+	require.NoError(t, err)
+	assert.Equal(t, []linkmeta.TagCount{{Tag: "go", Count: 1}, {Tag: "ru", Count: 1}, {Tag: "search", Count: 2}}, tags)
+
+	renamed, err := storage.RenameTag([]string{"XXX-YYY-ZZZ"}, "go", "search")
+	require.NoError(t, err)
+	assert.Equal(t, 1, renamed)
+
+	m, err = storage.GetLinkMeta("asdfgh")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"search"}, m.Tags)
+
+	renamed, err = storage.RenameTag([]string{"XXX-YYY-ZZZ"}, "ru", "web")
+	require.NoError(t, err)
+	assert.Equal(t, 1, renamed)
+
+	m, err = storage.GetLinkMeta("other")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ru"}, m.Tags, "the links of the other owners keep the tag")
+}
+
+func TestMapUpdateLinkMeta(t *testing.T) {
+	storage := NewMap()
+	expire := time.Now().Add(tkn.LifeTime)
+
+	require.NoError(t, storage.Set("XXX-YYY-ZZZ", "http://ya.ru", &tkn.Token{Value: "qwerty", Expire: expire}))
+	require.NoError(t, storage.Set("XXX-YYY-ZZZ", "http://go.dev", &tkn.Token{Value: "asdfgh", Expire: expire, Removed: true}))
+	require.NoError(t, storage.SetLinkMeta("qwerty", linkmeta.Meta{Title: "Ya", Tags: []string{"ru"}}))
+
+	notes := "docs"
+	m, err := storage.UpdateLinkMeta([]string{"WORKSPACE", "XXX-YYY-ZZZ"}, "qwerty", linkmeta.Update{Notes: &notes})
+	require.NoError(t, err)
+	assert.Equal(t, linkmeta.Meta{Title: "Ya", Notes: "docs", Tags: []string{"ru"}}, m)
+
+	_, err = storage.UpdateLinkMeta([]string{"AAA-BBB-CCC"}, "qwerty", linkmeta.Update{Notes: &notes})
+	assert.ErrorIs(t, err, ErrTokenNotFound, "the links of the other owners are not found")
+
+	_, err = storage.UpdateLinkMeta([]string{"XXX-YYY-ZZZ"}, "asdfgh", linkmeta.Update{Notes: &notes})
+	assert.ErrorIs(t, err, ErrTokenNotFound, "the removed links are not found")
+
+	_, err = storage.UpdateLinkMeta([]string{"XXX-YYY-ZZZ"}, "unknown", linkmeta.Update{Notes: &notes})
+	assert.ErrorIs(t, err, ErrTokenNotFound)
+
+	title, tags := "Yandex", []string{"search"}
+	var wg sync.WaitGroup
+	for _, update := range []linkmeta.Update{{Title: &title}, {Tags: &tags}} {
+		wg.Add(1)
+		go func(update linkmeta.Update) {
+			defer wg.Done()
+			_, err := storage.UpdateLinkMeta([]string{"XXX-YYY-ZZZ"}, "qwerty", update)
+			assert.NoError(t, err)
+		}(update)
+	}
+	wg.Wait()
+
+	m, err = storage.GetLinkMeta("qwerty")
+	require.NoError(t, err)
+	assert.Equal(t, linkmeta.Meta{Title: "Yandex", Notes: "docs", Tags: []string{"search"}}, m, "no update is lost")
+}
+
+func TestMapSearchURLs(t *testing.T) {
+	storage := NewMap()
+	expire := time.Now().Add(tkn.LifeTime)
+
+	require.NoError(t, storage.Set("XXX-YYY-ZZZ", "http://ya.ru", &tkn.Token{Value: "qwerty", Expire: expire}))
+	require.NoError(t, storage.Set("XXX-YYY-ZZZ", "http://go.dev", &tkn.Token{Value: "asdfgh", Expire: expire}))
+	require.NoError(t, storage.Set("AAA-BBB-CCC", "http://go.dev/other", &tkn.Token{Value: "other", Expire: expire}))
+	require.NoError(t, storage.SetLinkMeta("qwerty", linkmeta.Meta{Title: "Search engine", Tags: []string{"go"}}))
+
+	tokens := func(q string) []string {
+		items, err := storage.SearchURLs([]string{"XXX-YYY-ZZZ"}, search.Query{Text: q})
+		require.NoError(t, err)
+		values := make([]string, 0, len(items))
+		for _, item := range items {
+			values = append(values, item.Token)
+		}
+		return values
+	}
+
+	assert.Equal(t, []string{"qwerty", "asdfgh"}, tokens("go"), "the tag ranks above the URL")
+	assert.Equal(t, []string{"qwerty"}, tokens("search eng"))
+
+	_, err := storage.RenameTag([]string{"XXX-YYY-ZZZ"}, "go", "web")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"asdfgh"}, tokens("go"))
+	assert.Equal(t, []string{"qwerty"}, tokens("web"))
+
+	require.NoError(t, storage.RemoveTokens([]string{"asdfgh"}, "XXX-YYY-ZZZ"))
+	assert.Empty(t, tokens("go"))
+
+	items, err := storage.SearchURLs([]string{"XXX-YYY-ZZZ"}, search.Query{Text: "ya", Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, "Search engine", items[0].Title)
+}
+
+func TestMapGetURLsByTokens(t *testing.T) {
+	storage := NewMap()
+	expire := time.Now().Add(tkn.LifeTime)
+
+	require.NoError(t, storage.Set("XXX-YYY-ZZZ", "http://ya.ru", &tkn.Token{Value: "qwerty", Expire: expire}))
+	require.NoError(t, storage.Set("AAA-BBB-CCC", "http://go.dev", &tkn.Token{Value: "asdfgh", Expire: expire}))
+	require.NoError(t, storage.RemoveTokens([]string{"asdfgh"}, "AAA-BBB-CCC"))
+
+	items, err := storage.GetURLsByTokens([]string{"asdfgh", "unknown", "qwerty", "qwerty"})
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	assert.Equal(t, "asdfgh", items[0].Token)
+	assert.True(t, items[0].Removed)
+	assert.Equal(t, "qwerty", items[1].Token)
+	assert.Equal(t, "http://ya.ru", items[1].URL)
+	assert.Equal(t, "XXX-YYY-ZZZ", items[1].OwnerID)
+}
+
+func TestMapLinkHealth(t *testing.T) {
+	storage := NewMap()
+	expire := time.Now().Add(tkn.LifeTime)
+	checked := time.Now().Add(-time.Hour)
+
+	require.NoError(t, storage.Set("XXX-YYY-ZZZ", "http://ya.ru", &tkn.Token{Value: "qwerty", Expire: expire}))
+	require.NoError(t, storage.Set("XXX-YYY-ZZZ", "http://go.dev", &tkn.Token{Value: "asdfgh", Expire: expire}))
+	require.NoError(t, storage.Set("XXX-YYY-ZZZ", "http://old.ru", &tkn.Token{Value: "zxcvbn", Expire: time.Now().Add(-time.Hour)}))
+	require.NoError(t, storage.SetLinkHealth(map[string]linkcheck.Health{
+		"qwerty": {Checked: checked, StatusCode: 404},
+	}))
+
+	items, err := storage.GetLinksToCheck(time.Now(), 10)
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	assert.Equal(t, "asdfgh", items[0].Token)
+	assert.True(t, items[0].Health.IsZero())
+	assert.Equal(t, "qwerty", items[1].Token)
+	assert.Equal(t, linkcheck.Health{Checked: checked, StatusCode: 404}, items[1].Health)
+
+	items, err = storage.GetLinksToCheck(checked, 10)
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, "asdfgh", items[0].Token)
+
+	items, err = storage.GetLinksToCheck(time.Now(), 1)
+	require.NoError(t, err)
+	assert.Len(t, items, 1)
+
+	page, _, err := storage.GetURLsPage([]string{"XXX-YYY-ZZZ"}, listing.Query{Contains: "ya.ru"})
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+	assert.Equal(t, 404, page[0].Health.StatusCode)
+}
+
+func TestMapLinkWebhooks(t *testing.T) {
+	storage := NewMap()
+
+	url, err := storage.GetLinkWebhook("XXX-YYY-ZZZ")
+	require.NoError(t, err)
+	assert.Empty(t, url)
+
+	require.NoError(t, storage.SetLinkWebhook("XXX-YYY-ZZZ", "https://hooks.example.com/1"))
+	require.NoError(t, storage.SetLinkWebhook("anonymous", "https://hooks.example.com/2"))
+	require.NoError(t, storage.SetLinkWebhook("guest", "https://hooks.example.com/3"))
+
+	// The target of the reassignment keeps the webhook of their own.
+	require.NoError(t, storage.ReassignUser("anonymous", "XXX-YYY-ZZZ"))
+	require.NoError(t, storage.ReassignUser("guest", "AAA-BBB-CCC"))
+	for userID, want := range map[string]string{
+		"XXX-YYY-ZZZ": "https://hooks.example.com/1",
+		"AAA-BBB-CCC": "https://hooks.example.com/3",
+		"anonymous":   "",
+		"guest":       "",
+	} {
+		url, err = storage.GetLinkWebhook(userID)
+		require.NoError(t, err)
+		assert.Equal(t, want, url, userID)
+	}
+
+	require.NoError(t, storage.SetLinkWebhook("XXX-YYY-ZZZ", ""))
+	url, err = storage.GetLinkWebhook("XXX-YYY-ZZZ")
+	require.NoError(t, err)
+	assert.Empty(t, url)
+}
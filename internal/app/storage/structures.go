@@ -1,7 +1,17 @@
 package storage
 
 import (
+	"sort"
+	"time"
+
+	"github.com/alrund/yp-1/internal/app/apikey"
+	"github.com/alrund/yp-1/internal/app/audit"
+	"github.com/alrund/yp-1/internal/app/linkcheck"
+	"github.com/alrund/yp-1/internal/app/linkmeta"
+	"github.com/alrund/yp-1/internal/app/listing"
+	"github.com/alrund/yp-1/internal/app/search"
 	tkn "github.com/alrund/yp-1/internal/app/token"
+	"github.com/alrund/yp-1/internal/app/workspace"
 )
 
 type composite struct {
@@ -13,4 +23,145 @@ type composite struct {
 type URLpairs struct {
 	ShortURL    string `json:"short_url"`
 	OriginalURL string `json:"original_url"`
+	// WorkspaceID the workspace which owns the URL, empty for the URLs of the user.
+	WorkspaceID string `json:"workspace_id,omitempty"`
+	// Created is nil for the URLs shortened before the time was kept.
+	Created *time.Time     `json:"created,omitempty"`
+	Expire  time.Time      `json:"expire"`
+	Removed bool           `json:"removed"`
+	Clicks  int64          `json:"clicks"`
+	Status  listing.Status `json:"status,omitempty"`
+	Title   string         `json:"title,omitempty"`
+	Notes   string         `json:"notes,omitempty"`
+	Tags    []string       `json:"tags,omitempty"`
+	// Health is nil until the destination is checked.
+	Health *linkcheck.Health `json:"health,omitempty"`
+}
+
+func newListingItem(c *composite, clicks int64, m linkmeta.Meta, h linkcheck.Health) listing.Item {
+	return listing.Item{
+		Token:   c.Token.Value,
+		URL:     c.URL,
+		OwnerID: c.UserID,
+		Created: c.Token.Created,
+		Expire:  c.Token.Expire,
+		Removed: c.Token.Removed,
+		Clicks:  clicks,
+		Title:   m.Title,
+		Notes:   m.Notes,
+		Tags:    m.Tags,
+		Health:  h,
+	}
+}
+
+func newSearchDocument(c *composite, m linkmeta.Meta) search.Document {
+	return search.Document{Token: c.Token.Value, URL: c.URL, Title: m.Title, Tags: m.Tags}
+}
+
+// sortLinksToCheck sorts the links from the never checked ones to the most recently checked ones.
+func sortLinksToCheck(items []listing.Item) {
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Health.Checked.Equal(items[j].Health.Checked) {
+			return items[i].Token < items[j].Token
+		}
+		return items[i].Health.Checked.Before(items[j].Health.Checked)
+	})
+}
+
+// reassignMembers moves the memberships of a user to another one, who keeps the higher role where both are members.
+// It reports whether anything is moved.
+func reassignMembers(members map[string]map[string]workspace.Role, fromUserID, toUserID string) bool {
+	changed := false
+	for _, roles := range members {
+		role, ok := roles[fromUserID]
+		if !ok {
+			continue
+		}
+		if existing, ok := roles[toUserID]; ok {
+			role = existing.Higher(role)
+		}
+		roles[toUserID] = role
+		delete(roles, fromUserID)
+		changed = true
+	}
+	return changed
+}
+
+// reassignWebhook moves the link check webhook of a user to another one, who keeps their own if they have one.
+// It reports whether anything is moved.
+func reassignWebhook(webhooks map[string]string, fromUserID, toUserID string) bool {
+	url, ok := webhooks[fromUserID]
+	if !ok {
+		return false
+	}
+	if _, ok := webhooks[toUserID]; !ok {
+		webhooks[toUserID] = url
+	}
+	delete(webhooks, fromUserID)
+	return true
+}
+
+// keepsAdmin reports whether the workspace keeps an admin when the user gets the role,
+// the empty role stands for the removal of the user.
+func keepsAdmin(roles map[string]workspace.Role, userID string, role workspace.Role) bool {
+	if role.CanManage() {
+		return true
+	}
+	for id, r := range roles {
+		if id != userID && r.CanManage() {
+			return true
+		}
+	}
+	return false
+}
+
+// ownedBy checks that the user is one of the owners.
+func ownedBy(ownerIDs []string, userID string) bool {
+	for _, ownerID := range ownerIDs {
+		if ownerID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// sortAPIKeys sorts the keys from the oldest to the newest.
+func sortAPIKeys(keys []*apikey.Key) {
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].Created.Before(keys[j].Created)
+	})
+}
+
+// sortMembers sorts the members by the user ID.
+func sortMembers(members []*workspace.Member) {
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].UserID < members[j].UserID
+	})
+}
+
+// sortMemberships sorts the memberships by the workspace ID.
+func sortMemberships(members []*workspace.Member) {
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].WorkspaceID < members[j].WorkspaceID
+	})
+}
+
+// filterAuditEvents returns the matching events from the newest, events are kept from the oldest.
+func filterAuditEvents(events []*audit.Event, filter audit.Filter) []*audit.Event {
+	limit := auditLimit(filter)
+	found := make([]*audit.Event, 0)
+	for i := len(events) - 1; i >= 0 && len(found) < limit; i-- {
+		if filter.Match(events[i]) {
+			e := *events[i]
+			found = append(found, &e)
+		}
+	}
+	return found
+}
+
+func auditLimit(filter audit.Filter) int {
+	if filter.Limit <= 0 {
+		return audit.DefaultLimit
+	}
+	return filter.Limit
 }
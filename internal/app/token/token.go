@@ -22,6 +22,7 @@ type Token struct {
 	Value   string
 	Expire  time.Time
 	Removed bool
+	Created time.Time // zero for the tokens made before the time was kept
 }
 
 func NewToken(g Generator) (*Token, error) {
@@ -29,9 +30,11 @@ func NewToken(g Generator) (*Token, error) {
 	if err != nil {
 		return nil, err
 	}
+	now := time.Now()
 	return &Token{
-		Value:  val,
-		Expire: time.Now().Add(LifeTime),
+		Value:   val,
+		Expire:  now.Add(LifeTime),
+		Created: now,
 	}, nil
 }
 
@@ -0,0 +1,343 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	"github.com/alrund/yp-1/internal/app/account"
+	"github.com/alrund/yp-1/internal/app/apikey"
+	"github.com/alrund/yp-1/internal/app/audit"
+	"github.com/alrund/yp-1/internal/app/linkcheck"
+	"github.com/alrund/yp-1/internal/app/linkmeta"
+	"github.com/alrund/yp-1/internal/app/listing"
+	"github.com/alrund/yp-1/internal/app/ratelimit"
+	"github.com/alrund/yp-1/internal/app/search"
+	"github.com/alrund/yp-1/internal/app/storage"
+	tkn "github.com/alrund/yp-1/internal/app/token"
+	"github.com/alrund/yp-1/internal/app/workspace"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/alrund/yp-1/internal/app"
+
+func startSpan(ctx context.Context, name string) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name)
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// tracedStorage wraps every Storage call in a span that is a child of ctx.
+type tracedStorage struct {
+	ctx context.Context
+	Storage
+}
+
+func (us *URLShortener) storage(ctx context.Context) Storage {
+	return &tracedStorage{ctx: ctx, Storage: us.Storage}
+}
+
+func (s *tracedStorage) Set(userID, url string, token *tkn.Token) (err error) {
+	_, span := startSpan(s.ctx, "Storage.Set")
+	defer func() { endSpan(span, err) }()
+	return s.Storage.Set(userID, url, token)
+}
+
+func (s *tracedStorage) SetBatch(userID string, url2token map[string]*tkn.Token) (err error) {
+	_, span := startSpan(s.ctx, "Storage.SetBatch")
+	defer func() { endSpan(span, err) }()
+	return s.Storage.SetBatch(userID, url2token)
+}
+
+func (s *tracedStorage) GetToken(tokenValue string) (_ *tkn.Token, err error) {
+	_, span := startSpan(s.ctx, "Storage.GetToken")
+	defer func() { endSpan(span, err) }()
+	return s.Storage.GetToken(tokenValue)
+}
+
+func (s *tracedStorage) GetTokenByURL(url string) (_ *tkn.Token, err error) {
+	_, span := startSpan(s.ctx, "Storage.GetTokenByURL")
+	defer func() { endSpan(span, err) }()
+	return s.Storage.GetTokenByURL(url)
+}
+
+func (s *tracedStorage) GetTokensByUserID(userID string) (_ []*tkn.Token, err error) {
+	_, span := startSpan(s.ctx, "Storage.GetTokensByUserID")
+	defer func() { endSpan(span, err) }()
+	return s.Storage.GetTokensByUserID(userID)
+}
+
+func (s *tracedStorage) GetURL(tokenValue string) (_ string, err error) {
+	_, span := startSpan(s.ctx, "Storage.GetURL")
+	defer func() { endSpan(span, err) }()
+	return s.Storage.GetURL(tokenValue)
+}
+
+func (s *tracedStorage) GetURLsByUserID(userID string) (_ []storage.URLpairs, err error) {
+	_, span := startSpan(s.ctx, "Storage.GetURLsByUserID")
+	defer func() { endSpan(span, err) }()
+	return s.Storage.GetURLsByUserID(userID)
+}
+
+func (s *tracedStorage) GetURLsPage(ownerIDs []string, query listing.Query) (_ []listing.Item, _ string, err error) {
+	_, span := startSpan(s.ctx, "Storage.GetURLsPage")
+	defer func() { endSpan(span, err) }()
+	return s.Storage.GetURLsPage(ownerIDs, query)
+}
+
+func (s *tracedStorage) GetURLsByTokens(tokenValues []string) (_ []listing.Item, err error) {
+	_, span := startSpan(s.ctx, "Storage.GetURLsByTokens")
+	defer func() { endSpan(span, err) }()
+	return s.Storage.GetURLsByTokens(tokenValues)
+}
+
+func (s *tracedStorage) GetLinksToCheck(checkedBefore time.Time, limit int) (_ []listing.Item, err error) {
+	_, span := startSpan(s.ctx, "Storage.GetLinksToCheck")
+	defer func() { endSpan(span, err) }()
+	return s.Storage.GetLinksToCheck(checkedBefore, limit)
+}
+
+func (s *tracedStorage) SetLinkHealth(health map[string]linkcheck.Health) (err error) {
+	_, span := startSpan(s.ctx, "Storage.SetLinkHealth")
+	defer func() { endSpan(span, err) }()
+	return s.Storage.SetLinkHealth(health)
+}
+
+func (s *tracedStorage) SetLinkWebhook(userID, url string) (err error) {
+	_, span := startSpan(s.ctx, "Storage.SetLinkWebhook")
+	defer func() { endSpan(span, err) }()
+	return s.Storage.SetLinkWebhook(userID, url)
+}
+
+func (s *tracedStorage) GetLinkWebhook(userID string) (_ string, err error) {
+	_, span := startSpan(s.ctx, "Storage.GetLinkWebhook")
+	defer func() { endSpan(span, err) }()
+	return s.Storage.GetLinkWebhook(userID)
+}
+
+func (s *tracedStorage) AddClick(tokenValue string) (err error) {
+	_, span := startSpan(s.ctx, "Storage.AddClick")
+	defer func() { endSpan(span, err) }()
+	return s.Storage.AddClick(tokenValue)
+}
+
+func (s *tracedStorage) FlushClicks() (err error) {
+	_, span := startSpan(s.ctx, "Storage.FlushClicks")
+	defer func() { endSpan(span, err) }()
+	return s.Storage.FlushClicks()
+}
+
+func (s *tracedStorage) SetLinkMeta(tokenValue string, m linkmeta.Meta) (err error) {
+	_, span := startSpan(s.ctx, "Storage.SetLinkMeta")
+	defer func() { endSpan(span, err) }()
+	return s.Storage.SetLinkMeta(tokenValue, m)
+}
+
+func (s *tracedStorage) GetLinkMeta(tokenValue string) (_ linkmeta.Meta, err error) {
+	_, span := startSpan(s.ctx, "Storage.GetLinkMeta")
+	defer func() { endSpan(span, err) }()
+	return s.Storage.GetLinkMeta(tokenValue)
+}
+
+func (s *tracedStorage) UpdateLinkMeta(
+	ownerIDs []string,
+	tokenValue string,
+	update linkmeta.Update,
+) (_ linkmeta.Meta, err error) {
+	_, span := startSpan(s.ctx, "Storage.UpdateLinkMeta")
+	defer func() { endSpan(span, err) }()
+	return s.Storage.UpdateLinkMeta(ownerIDs, tokenValue, update)
+}
+
+func (s *tracedStorage) GetTags(ownerIDs []string) (_ []linkmeta.TagCount, err error) {
+	_, span := startSpan(s.ctx, "Storage.GetTags")
+	defer func() { endSpan(span, err) }()
+	return s.Storage.GetTags(ownerIDs)
+}
+
+func (s *tracedStorage) RenameTag(ownerIDs []string, from, to string) (_ int, err error) {
+	_, span := startSpan(s.ctx, "Storage.RenameTag")
+	defer func() { endSpan(span, err) }()
+	return s.Storage.RenameTag(ownerIDs, from, to)
+}
+
+func (s *tracedStorage) SearchURLs(ownerIDs []string, query search.Query) (_ []listing.Item, err error) {
+	_, span := startSpan(s.ctx, "Storage.SearchURLs")
+	defer func() { endSpan(span, err) }()
+	return s.Storage.SearchURLs(ownerIDs, query)
+}
+
+func (s *tracedStorage) HasURL(url string) (_ bool, err error) {
+	_, span := startSpan(s.ctx, "Storage.HasURL")
+	defer func() { endSpan(span, err) }()
+	return s.Storage.HasURL(url)
+}
+
+func (s *tracedStorage) HasToken(tokenValue string) (_ bool, err error) {
+	_, span := startSpan(s.ctx, "Storage.HasToken")
+	defer func() { endSpan(span, err) }()
+	return s.Storage.HasToken(tokenValue)
+}
+
+func (s *tracedStorage) Ping(ctx context.Context) (err error) {
+	ctx, span := startSpan(ctx, "Storage.Ping")
+	defer func() { endSpan(span, err) }()
+	return s.Storage.Ping(ctx)
+}
+
+func (s *tracedStorage) RemoveTokens(tokenValues []string, userID string) (err error) {
+	_, span := startSpan(s.ctx, "Storage.RemoveTokens")
+	defer func() { endSpan(span, err) }()
+	return s.Storage.RemoveTokens(tokenValues, userID)
+}
+
+func (s *tracedStorage) GetURLCount() (_ int, err error) {
+	_, span := startSpan(s.ctx, "Storage.GetURLCount")
+	defer func() { endSpan(span, err) }()
+	return s.Storage.GetURLCount()
+}
+
+func (s *tracedStorage) GetUserIDCount() (_ int, err error) {
+	_, span := startSpan(s.ctx, "Storage.GetUserIDCount")
+	defer func() { endSpan(span, err) }()
+	return s.Storage.GetUserIDCount()
+}
+
+func (s *tracedStorage) SetAPIKey(key *apikey.Key) (err error) {
+	_, span := startSpan(s.ctx, "Storage.SetAPIKey")
+	defer func() { endSpan(span, err) }()
+	return s.Storage.SetAPIKey(key)
+}
+
+func (s *tracedStorage) GetAPIKey(hash string) (_ *apikey.Key, err error) {
+	_, span := startSpan(s.ctx, "Storage.GetAPIKey")
+	defer func() { endSpan(span, err) }()
+	return s.Storage.GetAPIKey(hash)
+}
+
+func (s *tracedStorage) GetAPIKeysByUserID(userID string) (_ []*apikey.Key, err error) {
+	_, span := startSpan(s.ctx, "Storage.GetAPIKeysByUserID")
+	defer func() { endSpan(span, err) }()
+	return s.Storage.GetAPIKeysByUserID(userID)
+}
+
+func (s *tracedStorage) RevokeAPIKey(id, userID string) (err error) {
+	_, span := startSpan(s.ctx, "Storage.RevokeAPIKey")
+	defer func() { endSpan(span, err) }()
+	return s.Storage.RevokeAPIKey(id, userID)
+}
+
+func (s *tracedStorage) SetAccount(a *account.Account) (err error) {
+	_, span := startSpan(s.ctx, "Storage.SetAccount")
+	defer func() { endSpan(span, err) }()
+	return s.Storage.SetAccount(a)
+}
+
+func (s *tracedStorage) GetAccountByLogin(login string) (_ *account.Account, err error) {
+	_, span := startSpan(s.ctx, "Storage.GetAccountByLogin")
+	defer func() { endSpan(span, err) }()
+	return s.Storage.GetAccountByLogin(login)
+}
+
+func (s *tracedStorage) GetAccountByID(id string) (_ *account.Account, err error) {
+	_, span := startSpan(s.ctx, "Storage.GetAccountByID")
+	defer func() { endSpan(span, err) }()
+	return s.Storage.GetAccountByID(id)
+}
+
+func (s *tracedStorage) ReassignUser(fromUserID, toUserID string) (err error) {
+	_, span := startSpan(s.ctx, "Storage.ReassignUser")
+	defer func() { endSpan(span, err) }()
+	return s.Storage.ReassignUser(fromUserID, toUserID)
+}
+
+func (s *tracedStorage) HasUser(userID string) (_ bool, err error) {
+	_, span := startSpan(s.ctx, "Storage.HasUser")
+	defer func() { endSpan(span, err) }()
+	return s.Storage.HasUser(userID)
+}
+
+func (s *tracedStorage) SetWorkspace(w *workspace.Workspace) (err error) {
+	_, span := startSpan(s.ctx, "Storage.SetWorkspace")
+	defer func() { endSpan(span, err) }()
+	return s.Storage.SetWorkspace(w)
+}
+
+func (s *tracedStorage) GetWorkspace(id string) (_ *workspace.Workspace, err error) {
+	_, span := startSpan(s.ctx, "Storage.GetWorkspace")
+	defer func() { endSpan(span, err) }()
+	return s.Storage.GetWorkspace(id)
+}
+
+func (s *tracedStorage) SetMember(m *workspace.Member) (err error) {
+	_, span := startSpan(s.ctx, "Storage.SetMember")
+	defer func() { endSpan(span, err) }()
+	return s.Storage.SetMember(m)
+}
+
+func (s *tracedStorage) GetMember(workspaceID, userID string) (_ *workspace.Member, err error) {
+	_, span := startSpan(s.ctx, "Storage.GetMember")
+	defer func() { endSpan(span, err) }()
+	return s.Storage.GetMember(workspaceID, userID)
+}
+
+func (s *tracedStorage) GetMembers(workspaceID string) (_ []*workspace.Member, err error) {
+	_, span := startSpan(s.ctx, "Storage.GetMembers")
+	defer func() { endSpan(span, err) }()
+	return s.Storage.GetMembers(workspaceID)
+}
+
+func (s *tracedStorage) GetMembershipsByUserID(userID string) (_ []*workspace.Member, err error) {
+	_, span := startSpan(s.ctx, "Storage.GetMembershipsByUserID")
+	defer func() { endSpan(span, err) }()
+	return s.Storage.GetMembershipsByUserID(userID)
+}
+
+func (s *tracedStorage) RemoveMember(workspaceID, userID string) (err error) {
+	_, span := startSpan(s.ctx, "Storage.RemoveMember")
+	defer func() { endSpan(span, err) }()
+	return s.Storage.RemoveMember(workspaceID, userID)
+}
+
+func (s *tracedStorage) GetRateBucket(key string) (_ ratelimit.Bucket, err error) {
+	_, span := startSpan(s.ctx, "Storage.GetRateBucket")
+	defer func() { endSpan(span, err) }()
+	return s.Storage.GetRateBucket(key)
+}
+
+func (s *tracedStorage) SetRateBucket(key string, b ratelimit.Bucket) (err error) {
+	_, span := startSpan(s.ctx, "Storage.SetRateBucket")
+	defer func() { endSpan(span, err) }()
+	return s.Storage.SetRateBucket(key, b)
+}
+
+func (s *tracedStorage) AddQuotaUsage(key, day string, n int) (_ int, err error) {
+	_, span := startSpan(s.ctx, "Storage.AddQuotaUsage")
+	defer func() { endSpan(span, err) }()
+	return s.Storage.AddQuotaUsage(key, day, n)
+}
+
+func (s *tracedStorage) PruneRateLimits(updatedBefore time.Time, day string) (err error) {
+	_, span := startSpan(s.ctx, "Storage.PruneRateLimits")
+	defer func() { endSpan(span, err) }()
+	return s.Storage.PruneRateLimits(updatedBefore, day)
+}
+
+func (s *tracedStorage) AddAuditEvents(events []*audit.Event) (err error) {
+	_, span := startSpan(s.ctx, "Storage.AddAuditEvents")
+	defer func() { endSpan(span, err) }()
+	return s.Storage.AddAuditEvents(events)
+}
+
+func (s *tracedStorage) GetAuditEvents(filter audit.Filter) (_ []*audit.Event, err error) {
+	_, span := startSpan(s.ctx, "Storage.GetAuditEvents")
+	defer func() { endSpan(span, err) }()
+	return s.Storage.GetAuditEvents(filter)
+}
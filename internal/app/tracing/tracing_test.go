@@ -0,0 +1,56 @@
+package tracing
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alrund/yp-1/internal/app/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+)
+
+func TestInit(t *testing.T) {
+	traceFile := filepath.Join(t.TempDir(), "traces.json")
+
+	tests := []struct {
+		name    string
+		config  *config.Config
+		wantErr error
+	}{
+		{
+			name:   "success - none",
+			config: &config.Config{},
+		},
+		{
+			name:   "success - file",
+			config: &config.Config{TraceExporter: ExporterFile, TraceFile: traceFile},
+		},
+		{
+			name:    "fail - unknown exporter",
+			config:  &config.Config{TraceExporter: "zipkin"},
+			wantErr: ErrUnknownExporter,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shutdown, err := Init(context.Background(), tt.config)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.Nil(t, err)
+
+			_, span := otel.Tracer("test").Start(context.Background(), "test-span")
+			span.End()
+
+			require.Nil(t, shutdown(context.Background()))
+		})
+	}
+
+	data, err := os.ReadFile(traceFile)
+	require.Nil(t, err)
+	assert.Contains(t, string(data), "test-span")
+}
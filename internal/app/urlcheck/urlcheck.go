@@ -0,0 +1,242 @@
+package urlcheck
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// DefaultMaxLength the longest URL the storages keep, DefaultSchemes the schemes allowed when none are set.
+const DefaultMaxLength = 255
+
+var DefaultSchemes = []string{"http", "https"}
+
+var (
+	ErrEmptyURL    = errors.New("empty URL")
+	ErrInvalidURL  = errors.New("invalid URL")
+	ErrLongURL     = errors.New("the URL is too long")
+	ErrScheme      = errors.New("the URL scheme is not allowed")
+	ErrNoHost      = errors.New("the URL has no host")
+	ErrPrivateHost = errors.New("the URL host is private")
+)
+
+// IsInvalid reports whether the error is caused by a rejected URL.
+func IsInvalid(err error) bool {
+	return errors.Is(err, ErrEmptyURL) ||
+		errors.Is(err, ErrInvalidURL) ||
+		errors.Is(err, ErrLongURL) ||
+		errors.Is(err, ErrScheme) ||
+		errors.Is(err, ErrNoHost) ||
+		errors.Is(err, ErrPrivateHost)
+}
+
+// defaultPorts the ports dropped from the host of the scheme.
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+	"ftp":   "21",
+	"ws":    "80",
+	"wss":   "443",
+}
+
+// Policy the rules the URLs are checked and canonicalized with, the zero value allows the http(s) URLs.
+type Policy struct {
+	Schemes          []string // the allowed schemes, DefaultSchemes when empty
+	MaxLength        int      // the longest canonical URL, DefaultMaxLength when not positive
+	DenyPrivateHosts bool     // reject the loopback, private and link-local IP hosts and localhost
+	SortQuery        bool     // sort the query parameters by name
+	StripParams      []string // the query parameters to remove, a trailing * matches a prefix like utm_*
+}
+
+// Canonicalize checks the URL and returns its canonical form, so the same link is stored once.
+// The scheme and the host are lowercased, the default port is dropped and the percent-encoding is normalized.
+// The host names are not resolved, only the IP and the localhost hosts are private.
+func (p Policy) Canonicalize(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", ErrEmptyURL
+	}
+	if strings.IndexFunc(raw, unicode.IsSpace) >= 0 {
+		return "", fmt.Errorf("%w: it contains a whitespace", ErrInvalidURL)
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidURL, err)
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	if !p.allowsScheme(u.Scheme) {
+		return "", fmt.Errorf("%w: %q", ErrScheme, u.Scheme)
+	}
+	if u.Opaque != "" || u.Hostname() == "" {
+		return "", ErrNoHost
+	}
+
+	host, port := strings.ToLower(u.Hostname()), u.Port()
+	if p.DenyPrivateHosts && isPrivateHost(host) {
+		return "", fmt.Errorf("%w: %s", ErrPrivateHost, host)
+	}
+	if port == defaultPorts[u.Scheme] {
+		port = ""
+	}
+	switch {
+	case port != "":
+		u.Host = net.JoinHostPort(host, port)
+	case strings.Contains(host, ":"):
+		u.Host = "[" + host + "]"
+	default:
+		u.Host = host
+	}
+
+	escapedPath := normalizeEscapes(u.EscapedPath())
+	if u.Path, err = url.PathUnescape(escapedPath); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidURL, err)
+	}
+	u.RawPath = escapedPath
+
+	escapedFragment := normalizeEscapes(u.EscapedFragment())
+	if u.Fragment, err = url.PathUnescape(escapedFragment); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidURL, err)
+	}
+	u.RawFragment = escapedFragment
+
+	u.RawQuery = p.canonicalQuery(normalizeEscapes(u.RawQuery))
+	u.ForceQuery = false
+
+	canonical := u.String()
+	if len(canonical) > p.maxLength() {
+		return "", fmt.Errorf("%w, the maximum is %d", ErrLongURL, p.maxLength())
+	}
+
+	return canonical, nil
+}
+
+func (p Policy) allowsScheme(scheme string) bool {
+	schemes := p.Schemes
+	if len(schemes) == 0 {
+		schemes = DefaultSchemes
+	}
+	for _, allowed := range schemes {
+		if strings.EqualFold(allowed, scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p Policy) maxLength() int {
+	if p.MaxLength <= 0 {
+		return DefaultMaxLength
+	}
+	return p.MaxLength
+}
+
+// canonicalQuery strips and sorts the parameters of the raw query keeping their encoding.
+func (p Policy) canonicalQuery(rawQuery string) string {
+	if rawQuery == "" || (!p.SortQuery && len(p.StripParams) == 0) {
+		return rawQuery
+	}
+
+	params := make([]string, 0)
+	for _, param := range strings.Split(rawQuery, "&") {
+		if param != "" && !p.strips(paramName(param)) {
+			params = append(params, param)
+		}
+	}
+
+	if p.SortQuery {
+		sort.SliceStable(params, func(i, j int) bool {
+			return paramName(params[i]) < paramName(params[j])
+		})
+	}
+
+	return strings.Join(params, "&")
+}
+
+func (p Policy) strips(name string) bool {
+	name = strings.ToLower(name)
+	for _, pattern := range p.StripParams {
+		pattern = strings.ToLower(pattern)
+		if prefix := strings.TrimSuffix(pattern, "*"); prefix != pattern {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		} else if name == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// paramName returns the decoded name of the query parameter.
+func paramName(param string) string {
+	name := strings.SplitN(param, "=", 2)[0]
+	if decoded, err := url.QueryUnescape(name); err == nil {
+		return decoded
+	}
+	return name
+}
+
+// normalizeEscapes decodes the escaped unreserved characters and uppercases the hex digits of the other escapes.
+func normalizeEscapes(s string) string {
+	if !strings.Contains(s, "%") {
+		return s
+	}
+
+	var b strings.Builder
+	b.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' || i+2 >= len(s) || !isHex(s[i+1]) || !isHex(s[i+2]) {
+			b.WriteByte(s[i])
+			continue
+		}
+
+		c := unhex(s[i+1])<<4 | unhex(s[i+2])
+		if isUnreserved(c) {
+			b.WriteByte(c)
+		} else {
+			b.WriteString(strings.ToUpper(s[i : i+3]))
+		}
+		i += 2
+	}
+	return b.String()
+}
+
+func isUnreserved(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' ||
+		c == '-' || c == '.' || c == '_' || c == '~'
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+func unhex(c byte) byte {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0'
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10
+	default:
+		return c - 'A' + 10
+	}
+}
+
+// isPrivateHost reports whether the host is a loopback, private, link-local or unspecified IP or a localhost name.
+func isPrivateHost(host string) bool {
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return true
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsUnspecified()
+}
@@ -0,0 +1,60 @@
+package urlcheck
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCanonicalize(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  Policy
+		raw     string
+		want    string
+		wantErr error
+	}{
+		{"unchanged", Policy{}, "http://ya.ru", "http://ya.ru", nil},
+		{"trimmed", Policy{}, " https://ya.ru/path?q=1 \n", "https://ya.ru/path?q=1", nil},
+		{"empty", Policy{}, "  ", "", ErrEmptyURL},
+		{"whitespace", Policy{}, "http://ya.ru/a b", "", ErrInvalidURL},
+		{"unparsable", Policy{}, "http://ya.ru/%zz", "", ErrInvalidURL},
+		{"javascript", Policy{}, "javascript:alert(1)", "", ErrScheme},
+		{"relative", Policy{}, "ya.ru/path", "", ErrScheme},
+		{"scheme not allowed", Policy{}, "ftp://ya.ru/file", "", ErrScheme},
+		{"allowed scheme", Policy{Schemes: []string{"FTP"}}, "ftp://ya.ru:21/file", "ftp://ya.ru/file", nil},
+		{"no host", Policy{}, "http:///path", "", ErrNoHost},
+		{"too long", Policy{}, "http://ya.ru/" + strings.Repeat("x", DefaultMaxLength), "", ErrLongURL},
+		{"max length", Policy{MaxLength: 16}, "http://ya.ru/abc", "http://ya.ru/abc", nil},
+		{"host and scheme case", Policy{}, "HTTP://Ya.RU/Path", "http://ya.ru/Path", nil},
+		{"default port", Policy{}, "https://ya.ru:443/", "https://ya.ru/", nil},
+		{"other port", Policy{}, "http://ya.ru:8080/", "http://ya.ru:8080/", nil},
+		{"ipv6 default port", Policy{}, "http://[::1]:80/", "http://[::1]/", nil},
+		{"escapes", Policy{}, "http://ya.ru/%7euser/a%2fb?q=%e2%82%ac%41#%7E", "http://ya.ru/~user/a%2Fb?q=%E2%82%ACA#~", nil},
+		{"private allowed", Policy{}, "http://127.0.0.1/", "http://127.0.0.1/", nil},
+		{"loopback", Policy{DenyPrivateHosts: true}, "http://127.0.0.1:8080/", "", ErrPrivateHost},
+		{"private", Policy{DenyPrivateHosts: true}, "http://10.1.2.3/", "", ErrPrivateHost},
+		{"link-local", Policy{DenyPrivateHosts: true}, "http://169.254.169.254/", "", ErrPrivateHost},
+		{"localhost", Policy{DenyPrivateHosts: true}, "http://LocalHost/", "", ErrPrivateHost},
+		{"public", Policy{DenyPrivateHosts: true}, "http://8.8.8.8/", "http://8.8.8.8/", nil},
+		{"query kept", Policy{}, "http://ya.ru/?b=2&a=1&utm_source=x", "http://ya.ru/?b=2&a=1&utm_source=x", nil},
+		{"query sorted", Policy{SortQuery: true}, "http://ya.ru/?b=2&a=1&b=1", "http://ya.ru/?a=1&b=2&b=1", nil},
+		{"tracking stripped", Policy{StripParams: []string{"utm_*", "fbclid"}},
+			"http://ya.ru/?UTM_Source=x&q=go&fbclid=y&utm=z", "http://ya.ru/?q=go&utm=z", nil},
+		{"only tracking", Policy{StripParams: []string{"utm_*"}}, "http://ya.ru/?utm_source=x", "http://ya.ru/", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.policy.Canonicalize(tt.raw)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.True(t, IsInvalid(err))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package workspace
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Role of a member in a workspace.
+type Role string
+
+const (
+	RoleViewer Role = "viewer" // sees the links
+	RoleEditor Role = "editor" // also creates and deletes the links
+	RoleAdmin  Role = "admin"  // also manages the members
+)
+
+var ErrInvalidRole = errors.New("invalid workspace role")
+
+// ParseRole returns the role by its name.
+func ParseRole(name string) (Role, error) {
+	role := Role(name)
+	switch role {
+	case RoleViewer, RoleEditor, RoleAdmin:
+		return role, nil
+	}
+	return "", ErrInvalidRole
+}
+
+// CanEdit reports whether the role allows to change the links of the workspace.
+func (r Role) CanEdit() bool {
+	return r == RoleEditor || r == RoleAdmin
+}
+
+// CanManage reports whether the role allows to change the members of the workspace.
+func (r Role) CanManage() bool {
+	return r == RoleAdmin
+}
+
+// Higher returns the more permissive of the two roles.
+func (r Role) Higher(other Role) Role {
+	if other.rank() > r.rank() {
+		return other
+	}
+	return r
+}
+
+func (r Role) rank() int {
+	switch r {
+	case RoleViewer:
+		return 1
+	case RoleEditor:
+		return 2
+	case RoleAdmin:
+		return 3
+	}
+	return 0
+}
+
+// Workspace a team, its ID is used as the user ID of the links it owns.
+type Workspace struct {
+	ID      string
+	Name    string
+	Created time.Time
+}
+
+// Member a user of a workspace.
+type Member struct {
+	WorkspaceID string
+	UserID      string
+	Role        Role
+}
+
+// Membership a workspace and the role of a user in it.
+type Membership struct {
+	Workspace *Workspace
+	Role      Role
+}
+
+func New(name string) *Workspace {
+	return &Workspace{
+		ID:      uuid.NewString(),
+		Name:    name,
+		Created: time.Now(),
+	}
+}
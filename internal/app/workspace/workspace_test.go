@@ -0,0 +1,41 @@
+package workspace
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRole(t *testing.T) {
+	tests := []struct {
+		name       string
+		wantErr    bool
+		wantEdit   bool
+		wantManage bool
+	}{
+		{name: "viewer"},
+		{name: "editor", wantEdit: true},
+		{name: "admin", wantEdit: true, wantManage: true},
+		{name: "owner", wantErr: true},
+		{name: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			role, err := ParseRole(tt.name)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidRole)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantEdit, role.CanEdit())
+			assert.Equal(t, tt.wantManage, role.CanManage())
+		})
+	}
+}
+
+func TestRoleHigher(t *testing.T) {
+	assert.Equal(t, RoleAdmin, RoleViewer.Higher(RoleAdmin))
+	assert.Equal(t, RoleAdmin, RoleAdmin.Higher(RoleEditor))
+	assert.Equal(t, RoleEditor, RoleEditor.Higher(RoleEditor))
+}
@@ -0,0 +1,192 @@
+package app
+
+import (
+	"context"
+	"errors"
+
+	"github.com/alrund/yp-1/internal/app/storage"
+	"github.com/alrund/yp-1/internal/app/workspace"
+)
+
+const maxWorkspaceNameLength = 255
+
+var (
+	ErrForbidden          = errors.New("forbidden")
+	ErrEmptyWorkspaceName = errors.New("workspace name is empty")
+	ErrLongWorkspaceName  = errors.New("workspace name is too long")
+	ErrUnknownUser        = errors.New("unknown user")
+)
+
+// CreateWorkspace creates a workspace with the user as its admin.
+func (us *URLShortener) CreateWorkspace(ctx context.Context, userID, name string) (_ *workspace.Membership, err error) {
+	ctx, span := startSpan(ctx, "URLShortener.CreateWorkspace")
+	defer func() { endSpan(span, err) }()
+
+	if name == "" {
+		return nil, ErrEmptyWorkspaceName
+	}
+	if len(name) > maxWorkspaceNameLength {
+		return nil, ErrLongWorkspaceName
+	}
+
+	s := us.storage(ctx)
+	w := workspace.New(name)
+	err = s.SetWorkspace(w)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.SetMember(&workspace.Member{WorkspaceID: w.ID, UserID: userID, Role: workspace.RoleAdmin})
+	if err != nil {
+		return nil, err
+	}
+
+	return &workspace.Membership{Workspace: w, Role: workspace.RoleAdmin}, nil
+}
+
+// GetWorkspaces returns the workspaces of the user.
+func (us *URLShortener) GetWorkspaces(ctx context.Context, userID string) (_ []*workspace.Membership, err error) {
+	ctx, span := startSpan(ctx, "URLShortener.GetWorkspaces")
+	defer func() { endSpan(span, err) }()
+
+	s := us.storage(ctx)
+	members, err := s.GetMembershipsByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	memberships := make([]*workspace.Membership, 0, len(members))
+	for _, m := range members {
+		w, err := s.GetWorkspace(m.WorkspaceID)
+		if err != nil {
+			return nil, err
+		}
+		memberships = append(memberships, &workspace.Membership{Workspace: w, Role: m.Role})
+	}
+
+	return memberships, nil
+}
+
+// GetWorkspaceMembers returns the members of the workspace to any of its members.
+func (us *URLShortener) GetWorkspaceMembers(
+	ctx context.Context,
+	userID, workspaceID string,
+) (_ []*workspace.Member, err error) {
+	ctx, span := startSpan(ctx, "URLShortener.GetWorkspaceMembers")
+	defer func() { endSpan(span, err) }()
+
+	s := us.storage(ctx)
+	if _, err := memberRole(s, workspaceID, userID); err != nil {
+		return nil, err
+	}
+
+	return s.GetMembers(workspaceID)
+}
+
+// SetWorkspaceMember adds a member to the workspace or changes the role, only an admin can do it.
+func (us *URLShortener) SetWorkspaceMember(ctx context.Context, userID, workspaceID, memberID, roleName string) (err error) {
+	ctx, span := startSpan(ctx, "URLShortener.SetWorkspaceMember")
+	defer func() { endSpan(span, err) }()
+
+	role, err := workspace.ParseRole(roleName)
+	if err != nil {
+		return err
+	}
+
+	s := us.storage(ctx)
+	if err := checkManager(s, workspaceID, userID); err != nil {
+		return err
+	}
+
+	ok, err := s.HasUser(memberID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrUnknownUser
+	}
+
+	// The storage keeps the workspace from losing its last admin.
+	return s.SetMember(&workspace.Member{WorkspaceID: workspaceID, UserID: memberID, Role: role})
+}
+
+// RemoveWorkspaceMember removes a member from the workspace.
+// An admin can remove anyone and every member can leave.
+func (us *URLShortener) RemoveWorkspaceMember(ctx context.Context, userID, workspaceID, memberID string) (err error) {
+	ctx, span := startSpan(ctx, "URLShortener.RemoveWorkspaceMember")
+	defer func() { endSpan(span, err) }()
+
+	s := us.storage(ctx)
+	if userID == memberID {
+		if _, err := memberRole(s, workspaceID, userID); err != nil {
+			return err
+		}
+	} else if err := checkManager(s, workspaceID, userID); err != nil {
+		return err
+	}
+
+	return s.RemoveMember(workspaceID, memberID)
+}
+
+// LinkOwner returns the user ID the new links are stored under:
+// the user or the workspace, if it is given and the user can edit its links.
+func (us *URLShortener) LinkOwner(ctx context.Context, userID, workspaceID string) (_ string, err error) {
+	if workspaceID == "" {
+		return userID, nil
+	}
+
+	ctx, span := startSpan(ctx, "URLShortener.LinkOwner")
+	defer func() { endSpan(span, err) }()
+
+	role, err := memberRole(us.storage(ctx), workspaceID, userID)
+	if err != nil {
+		return "", err
+	}
+	if !role.CanEdit() {
+		return "", ErrForbidden
+	}
+
+	return workspaceID, nil
+}
+
+// linkOwners returns the user and the workspaces of the user whose role passes the filter.
+func linkOwners(s Storage, userID string, filter func(role workspace.Role) bool) ([]string, error) {
+	members, err := s.GetMembershipsByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	owners := []string{userID}
+	for _, m := range members {
+		if filter(m.Role) {
+			owners = append(owners, m.WorkspaceID)
+		}
+	}
+
+	return owners, nil
+}
+
+// memberRole returns the role of the user, a workspace of other users is reported as not found.
+func memberRole(s Storage, workspaceID, userID string) (workspace.Role, error) {
+	m, err := s.GetMember(workspaceID, userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrMemberNotFound) {
+			return "", storage.ErrWorkspaceNotFound
+		}
+		return "", err
+	}
+
+	return m.Role, nil
+}
+
+func checkManager(s Storage, workspaceID, userID string) error {
+	role, err := memberRole(s, workspaceID, userID)
+	if err != nil {
+		return err
+	}
+	if !role.CanManage() {
+		return ErrForbidden
+	}
+
+	return nil
+}